@@ -4,102 +4,713 @@ package controllers
 
 import (
 	// b64 "encoding/base64"
+	"crypto/sha256"
 	"encoding/json"
-	// "errors"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/rest"
-	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-var (
-	// ArgoNamespace represents the Namespace that hold ArgoCluster secrets.
-	ArgoNamespace string
-	// TestKubeConfig represents
-	TestKubeConfig *rest.Config
+// ErrClusterNameCollision is returned when an ArgoSecret with the same name is
+// already registered by a CAPI Secret of another namespace.
+var ErrClusterNameCollision = errors.New("cluster name collision")
+
+// ErrInsecureCluster is returned for kubeconfigs skipping TLS verification, unless
+// OperatorConfig.AllowInsecureClusters is enabled.
+var ErrInsecureCluster = errors.New("insecure cluster registrations are not allowed")
+
+const (
+	// AuthPreferenceBoth keeps both bearer token and client certificate of a kubeconfig.
+	AuthPreferenceBoth = "both"
+	// AuthPreferenceToken drops the client certificate of kubeconfigs holding a bearer token.
+	AuthPreferenceToken = "token"
+	// AuthPreferenceCert drops the bearer token of kubeconfigs holding a client certificate.
+	AuthPreferenceCert = "cert"
 )
 
+// ValidateAuthPreference checks that the given auth preference is supported.
+func ValidateAuthPreference(p string) error {
+	switch p {
+	case AuthPreferenceBoth, AuthPreferenceToken, AuthPreferenceCert:
+		return nil
+	}
+	return fmt.Errorf("unsupported auth preference: %s", p)
+}
+
+// ErrClusterTooYoung is returned for CAPI Clusters younger than OperatorConfig.MinClusterAge.
+var ErrClusterTooYoung = errors.New("cluster too young")
+
+// ClusterTooYoungError carries the time left until a CAPI Cluster reaches the minimum age.
+// It matches ErrClusterTooYoung.
+type ClusterTooYoungError struct {
+	Remaining time.Duration
+}
+
+// Error implements error.
+func (e *ClusterTooYoungError) Error() string {
+	return fmt.Sprintf("%s, %s left until minimum age", ErrClusterTooYoung, e.Remaining)
+}
+
+// Is reports whether target is ErrClusterTooYoung.
+func (e *ClusterTooYoungError) Is(target error) bool {
+	return target == ErrClusterTooYoung
+}
+
 const (
-	clusterTakeAlongKey        = "take-along-label.capi-to-argocd."
+	// DefaultArgoSecretNamePrefix is the default prefix of ArgoSecret names, see
+	// OperatorConfig.ArgoSecretNamePrefix.
+	DefaultArgoSecretNamePrefix = "cluster-"
+	// DefaultTakeAlongDomain is the default domain of take-along-label directives, see
+	// OperatorConfig.TakeAlongDomain.
+	DefaultTakeAlongDomain = "capi-to-argocd"
+
+	clusterTakeAlongKey        = "take-along-label." + DefaultTakeAlongDomain + "."
 	clusterTakenFromClusterKey = "taken-from-cluster-label.capi-to-argocd."
+	// clusterTakeAlongAnnotationKey prefixes the CAPI Cluster annotations selecting annotations to
+	// take along, and clusterTakenFromClusterAnnotationKey the markers of annotations taken along.
+	clusterTakeAlongAnnotationKey        = "take-along-annotation.capi-to-argocd."
+	clusterTakenFromClusterAnnotationKey = "taken-from-cluster-annotation.capi-to-argocd."
+	// operatorAnnotationPrefix prefixes the annotations owned by the operator.
+	operatorAnnotationPrefix = "capi-to-argocd/"
+
+	// computedClusterNameAnnotation holds the disambiguated cluster name on the CAPI Secret.
+	computedClusterNameAnnotation = "capi-to-argocd/computed-cluster-name"
+	// namespaceSuffixLength is the number of namespace characters used to disambiguate names.
+	namespaceSuffixLength = 6
+	// takeAlongLabelsPrefixAnnotation holds a comma separated list of prefixes selecting every
+	// CAPI Cluster label starting with one of them to take along.
+	takeAlongLabelsPrefixAnnotation = "capi-to-argocd/take-along-labels-prefix"
+	// takeAllLabelsAnnotation takes along every non-system label of a CAPI Cluster, overriding
+	// OperatorConfig.TakeAllLabels.
+	takeAllLabelsAnnotation = "capi-to-argocd/take-all-labels"
+	// takeAlongOwnerRefsAnnotation enables taking along the owner references of a CAPI Cluster.
+	takeAlongOwnerRefsAnnotation = "capi-to-argocd/take-along-owner-refs"
+	// ownerReferencesAnnotation holds the owner references taken along from the CAPI Cluster.
+	ownerReferencesAnnotation = "capi-to-argocd/owner-references"
+	// projectAnnotation scopes the ArgoSecret of a CAPI Cluster to an ArgoCD project.
+	projectAnnotation = "capi-to-argocd/project"
+	// namespacesAnnotation restricts the ArgoSecret of a CAPI Cluster to a comma separated
+	// list of namespaces, overriding OperatorConfig.ClusterNamespaces.
+	namespacesAnnotation = "capi-to-argocd/namespaces"
+	// clusterResourcesAnnotation allows or denies ArgoCD to manage cluster-scoped resources of a
+	// CAPI Cluster, overriding OperatorConfig.ClusterResources.
+	clusterResourcesAnnotation = "capi-to-argocd/cluster-resources"
+	// shardAnnotation assigns a CAPI Cluster to an argocd-application-controller shard,
+	// overriding OperatorConfig.ClusterShard.
+	shardAnnotation = "capi-to-argocd/shard"
+	// disableCompressionAnnotation disables response compression for a CAPI Cluster,
+	// overriding OperatorConfig.DisableCompression.
+	disableCompressionAnnotation = "capi-to-argocd/disable-compression"
+	// authPreferenceAnnotation selects the credential type kept for a CAPI Cluster,
+	// overriding OperatorConfig.AuthPreference.
+	authPreferenceAnnotation = "capi-to-argocd/auth-preference"
+	// clusterNameAnnotation overrides the Argo cluster name and ArgoSecret name of a CAPI Cluster,
+	// bypassing name templates, namespace suffixes and truncation.
+	clusterNameAnnotation = "capi-to-argocd/cluster-name"
+	// argoNamespaceAnnotation overrides the namespace the ArgoSecret of a CAPI Cluster is written
+	// to, one of OperatorConfig.ArgoNamespaces.
+	argoNamespaceAnnotation = "capi-to-argocd/argo-namespace"
+	// tlsServerNameAnnotation overrides the tls-server-name of the kubeconfig of a CAPI Cluster.
+	tlsServerNameAnnotation = "capi-to-argocd/tls-server-name"
+	// configOverridesAnnotation holds a JSON object merged into the generated config of a CAPI Cluster.
+	configOverridesAnnotation = "capi-to-argocd/config-overrides"
+
+	// wiTenantIDAnnotation, wiClientIDAnnotation and wiTokenFileAnnotation configure
+	// Workload Identity authentication on a CAPI Cluster.
+	wiTenantIDAnnotation  = "capi-to-argocd/wi-tenant-id"
+	wiClientIDAnnotation  = "capi-to-argocd/wi-client-id"
+	wiTokenFileAnnotation = "capi-to-argocd/wi-token-file"
+	// awsClusterNameAnnotation, awsRoleARNAnnotation and awsProfileAnnotation configure IAM
+	// authentication of CAPA managed EKS clusters.
+	awsClusterNameAnnotation = "capi-to-argocd/aws-cluster-name"
+	awsRoleARNAnnotation     = "capi-to-argocd/aws-role-arn"
+	awsProfileAnnotation     = "capi-to-argocd/aws-profile"
+	// awsManagedClusterKind and awsManagedControlPlaneKind are the CAPA kinds of EKS clusters.
+	awsManagedClusterKind      = "AWSManagedCluster"
+	awsManagedControlPlaneKind = "AWSManagedControlPlane"
+	// clusterNameHashLength is the length of the hash suffix of truncated cluster names.
+	clusterNameHashLength = 8
+	// argoTokenSecretKey is the data key of referenced bearer token Secrets.
+	argoTokenSecretKey = "token"
 )
 
-// GetArgoCommonLabels holds a map of labels that reconciled objects must have.
-func GetArgoCommonLabels() map[string]string {
-	return map[string]string{
-		"capi-to-argocd/owned":           "true",
-		"argocd.argoproj.io/secret-type": "cluster",
+// GetArgoCommonLabels holds the labels that reconciled objects must have.
+// These keys are reserved and cannot be overridden by other labels.
+func GetArgoCommonLabels() LabelMap {
+	labels := LabelMap{m: map[string]string{}}
+	for k, v := range reservedLabels {
+		labels.m[k] = v
 	}
+	return labels
 }
 
 // ArgoCluster holds all information needed for CAPI --> Argo Cluster conversion
 type ArgoCluster struct {
-	NamespacedName  types.NamespacedName
-	ClusterName     string
-	ClusterServer   string
-	ClusterLabels   map[string]string
-	TakeAlongLabels map[string]string
-	ClusterConfig   ArgoConfig
+	NamespacedName    types.NamespacedName
+	ClusterName       string
+	ClusterServer     string
+	ClusterProject    string
+	ClusterNamespaces []string
+	ClusterResources  *bool
+	ClusterShard      *int
+	ClusterLabels     map[string]string
+	// DefaultLabels are the labels of the ArgoClusterDefaults of the namespace, overridden by
+	// take-along labels.
+	DefaultLabels        map[string]string
+	TakeAlongLabels      map[string]string
+	TakeAlongAnnotations map[string]string
+	ClusterConfig        ArgoConfig
+	// ConfigOverrides is a JSON merge patch applied to the marshalled ClusterConfig.
+	ConfigOverrides []byte
+	// ReferencedToken is the bearer token stored in the Secret referenced by
+	// ClusterConfig.BearerTokenSecret, nil when the token is embedded.
+	ReferencedToken *string
+	Immutable       bool
+	// NameOverridden is set when the name comes from the cluster name annotation of the CAPI
+	// Cluster, which is then kept as is on collisions.
+	NameOverridden bool
+	// NameTruncated is set when the name got truncated to ArgoClusterNameMaxLength.
+	NameTruncated bool
 }
 
 // ArgoConfig represents Argo Cluster.JSON.config
 type ArgoConfig struct {
-	TLSClientConfig *ArgoTLS `json:"tlsClientConfig,omitempty"`
-	BearerToken     *string  `json:"bearerToken,omitempty"`
+	TLSClientConfig        *ArgoTLS                    `json:"tlsClientConfig,omitempty"`
+	BearerToken            *string                     `json:"bearerToken,omitempty"`
+	BearerTokenSecret      *ArgoTokenRef               `json:"bearerTokenSecret,omitempty"`
+	WorkloadIdentityConfig *ArgoWorkloadIdentityConfig `json:"workloadIdentityConfig,omitempty"`
+	ExecProviderConfig     *ArgoExecProviderConfig     `json:"execProviderConfig,omitempty"`
+	AWSAuthConfig          *ArgoAWSAuthConfig          `json:"awsAuthConfig,omitempty"`
+	ProxyURL               string                      `json:"proxyUrl,omitempty"`
+	DisableCompression     bool                        `json:"disableCompression,omitempty"`
 }
 
 // ArgoTLS represents Argo Cluster.JSON.config.tlsClientConfig
 type ArgoTLS struct {
-	CaData   *string `json:"caData,omitempty"`
-	CertData *string `json:"certData,omitempty"`
-	KeyData  *string `json:"keyData,omitempty"`
+	Insecure   bool    `json:"insecure,omitempty"`
+	ServerName string  `json:"serverName,omitempty"`
+	CaData     *string `json:"caData,omitempty"`
+	CertData   *string `json:"certData,omitempty"`
+	KeyData    *string `json:"keyData,omitempty"`
+}
+
+// ArgoTokenRef represents Argo Cluster.JSON.config.bearerTokenSecret
+type ArgoTokenRef struct {
+	SecretName string `json:"secretName"`
+	Key        string `json:"key"`
+}
+
+// ArgoWorkloadIdentityConfig represents Argo Cluster.JSON.config.workloadIdentityConfig
+type ArgoWorkloadIdentityConfig struct {
+	AzureTenantID           string `json:"azureTenantID,omitempty"`
+	AzureClientID           string `json:"azureClientID,omitempty"`
+	AzureFederatedTokenFile string `json:"azureFederatedTokenFile,omitempty"`
+}
+
+// ArgoExecProviderConfig represents Argo Cluster.JSON.config.execProviderConfig
+type ArgoExecProviderConfig struct {
+	Command     string            `json:"command,omitempty"`
+	Args        []string          `json:"args,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	APIVersion  string            `json:"apiVersion,omitempty"`
+	InstallHint string            `json:"installHint,omitempty"`
+}
+
+// buildExecProviderConfig translates a kubeconfig exec credential plugin into its Argo
+// representation, or returns nil when the kubeconfig user has none.
+func buildExecProviderConfig(exec *ExecConfig) *ArgoExecProviderConfig {
+	if exec == nil {
+		return nil
+	}
+	var env map[string]string
+	if len(exec.Env) > 0 {
+		env = make(map[string]string, len(exec.Env))
+		for _, e := range exec.Env {
+			env[e.Name] = e.Value
+		}
+	}
+	return &ArgoExecProviderConfig{
+		Command:     exec.Command,
+		Args:        exec.Args,
+		Env:         env,
+		APIVersion:  exec.APIVersion,
+		InstallHint: exec.InstallHint,
+	}
+}
+
+// ArgoAWSAuthConfig represents Argo Cluster.JSON.config.awsAuthConfig
+type ArgoAWSAuthConfig struct {
+	ClusterName string `json:"clusterName,omitempty"`
+	RoleARN     string `json:"roleARN,omitempty"`
+	Profile     string `json:"profile,omitempty"`
+}
+
+// buildAWSAuthConfig returns the IAM authentication configuration of a CAPA managed EKS
+// cluster, or nil for other clusters. The EKS cluster name defaults to the CAPA convention
+// of <namespace>_<name>, and may be overridden like the role and profile with annotations.
+func buildAWSAuthConfig(rc *ReconcileContext, cluster CAPICluster) *ArgoAWSAuthConfig {
+	if cluster == nil || !rc.Config.EnableAWSAuthConfig || !isEKSCluster(cluster) {
+		return nil
+	}
+	a := cluster.GetAnnotations()
+	clusterName := a[awsClusterNameAnnotation]
+	if clusterName == "" {
+		clusterName = cluster.GetNamespace() + "_" + cluster.GetName()
+	}
+	return &ArgoAWSAuthConfig{
+		ClusterName: clusterName,
+		RoleARN:     a[awsRoleARNAnnotation],
+		Profile:     a[awsProfileAnnotation],
+	}
+}
+
+// isEKSCluster reports whether a CAPI Cluster is managed by CAPA as an EKS cluster.
+func isEKSCluster(cluster CAPICluster) bool {
+	for _, ref := range []*corev1.ObjectReference{cluster.GetInfrastructureRef(), cluster.GetControlPlaneRef()} {
+		if ref != nil && (ref.Kind == awsManagedClusterKind || ref.Kind == awsManagedControlPlaneKind) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildWorkloadIdentityConfig returns the Workload Identity configuration annotated on a
+// CAPI Cluster, or nil when none of its annotations is set.
+func buildWorkloadIdentityConfig(cluster CAPICluster) *ArgoWorkloadIdentityConfig {
+	if cluster == nil {
+		return nil
+	}
+	a := cluster.GetAnnotations()
+	wi := &ArgoWorkloadIdentityConfig{
+		AzureTenantID:           a[wiTenantIDAnnotation],
+		AzureClientID:           a[wiClientIDAnnotation],
+		AzureFederatedTokenFile: a[wiTokenFileAnnotation],
+	}
+	if *wi == (ArgoWorkloadIdentityConfig{}) {
+		return nil
+	}
+	return wi
+}
+
+// RoundTripArgoConfig marshals an ArgoConfig to JSON and unmarshals it back.
+func RoundTripArgoConfig(cfg ArgoConfig) (ArgoConfig, error) {
+	var out ArgoConfig
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(b, &out)
+	return out, err
 }
 
 // NewArgoCluster return a new ArgoCluster
-func NewArgoCluster(c *CapiCluster, s *corev1.Secret, cluster *clusterv1.Cluster) (*ArgoCluster, error) {
-	log := ctrl.Log.WithName("argoCluster")
+func NewArgoCluster(rc *ReconcileContext, c *CapiCluster, s *corev1.Secret, cluster CAPICluster) (*ArgoCluster, error) {
+	log := rc.Logger
 
 	takeAlongLabels := map[string]string{}
+	takeAlongAnnotations := map[string]string{}
 	var errList []string
+	if cluster != nil && rc.Config.MinClusterAge > 0 {
+		// Fresh clusters may still carry provisional credentials, that get rotated right away.
+		if age := time.Since(cluster.GetCreationTimestamp().Time); age < rc.Config.MinClusterAge {
+			return nil, &ClusterTooYoungError{Remaining: rc.Config.MinClusterAge - age}
+		}
+	}
 	if cluster != nil {
-		takeAlongLabels, errList = buildTakeAlongLabels(cluster)
+		takeAlongLabels, errList = buildTakeAlongLabels(rc, cluster)
+		for _, e := range errList {
+			log.Info(e)
+		}
+		templateLabels, errList := buildTemplateLabels(rc, cluster)
+		for _, e := range errList {
+			log.Info(e)
+		}
+		for k, v := range templateLabels {
+			if _, ok := takeAlongLabels[k]; ok && !strings.HasPrefix(k, clusterTakenFromClusterKey) {
+				log.Info("Warning: take-along template label is taken along from the cluster already. Ignoring", "label", k)
+				continue
+			}
+			takeAlongLabels[k] = v
+		}
+		takeAlongAnnotations, errList = buildTakeAlongAnnotations(cluster)
 		for _, e := range errList {
 			log.Info(e)
 		}
+		if cluster.GetAnnotations()[takeAlongOwnerRefsAnnotation] == "true" {
+			refs, err := buildOwnerReferences(cluster.GetOwnerReferences())
+			if err != nil {
+				return nil, err
+			}
+			if refs != "" {
+				takeAlongAnnotations[ownerReferencesAnnotation] = refs
+			}
+		}
 	}
-	return &ArgoCluster{
-		NamespacedName: BuildNamespacedName(s.ObjectMeta.Name, s.ObjectMeta.Namespace),
-		ClusterName:    BuildClusterName(c.KubeConfig.Clusters[0].Name, s.ObjectMeta.Namespace),
-		ClusterServer:  c.KubeConfig.Clusters[0].Cluster.Server,
-		ClusterLabels: map[string]string{
-			"capi-to-argocd/cluster-secret-name": c.Name + "-kubeconfig",
-			"capi-to-argocd/cluster-namespace":   c.Namespace,
-		},
-		TakeAlongLabels: takeAlongLabels,
-		ClusterConfig: ArgoConfig{
-			BearerToken: c.KubeConfig.Users[0].User.Token,
-			TLSClientConfig: &ArgoTLS{
-				CaData:   &c.KubeConfig.Clusters[0].Cluster.CaData,
-				CertData: c.KubeConfig.Users[0].User.CertData,
-				KeyData:  c.KubeConfig.Users[0].User.KeyData,
-			},
+	if c.KubeConfig.Clusters[0].Cluster.InsecureSkipTLSVerify && !rc.Config.AllowInsecureClusters {
+		return nil, fmt.Errorf("%w: kubeconfig of %s/%s sets insecure-skip-tls-verify", ErrInsecureCluster, c.Namespace, c.Name)
+	}
+	userExec, warnings := buildUserExecConfig(rc, c.KubeConfig.Users[0].User)
+	for _, w := range warnings {
+		log.Info(w)
+	}
+	config := ArgoConfig{
+		BearerToken: c.KubeConfig.Users[0].User.Token,
+		TLSClientConfig: &ArgoTLS{
+			ServerName: c.KubeConfig.Clusters[0].Cluster.TLSServerName,
+			CaData:     &c.KubeConfig.Clusters[0].Cluster.CaData,
+			CertData:   c.KubeConfig.Users[0].User.CertData,
+			KeyData:    c.KubeConfig.Users[0].User.KeyData,
 		},
+		ExecProviderConfig: buildExecProviderConfig(userExec),
+		ProxyURL:           c.KubeConfig.Clusters[0].Cluster.ProxyURL,
+	}
+	if cluster != nil {
+		if serverName := cluster.GetAnnotations()[tlsServerNameAnnotation]; serverName != "" {
+			config.TLSClientConfig.ServerName = serverName
+		}
+	}
+	authPreference, err := buildAuthPreference(rc, cluster)
+	if err != nil {
+		log.Info("Warning: "+err.Error()+". Ignoring", "annotation", authPreferenceAnnotation)
+	}
+	applyAuthPreference(&config, authPreference)
+	disableCompression, err := buildDisableCompression(rc, cluster)
+	config.DisableCompression = disableCompression
+	if err != nil {
+		log.Info("Warning: "+err.Error()+". Ignoring", "annotation", disableCompressionAnnotation)
+	}
+	if c.KubeConfig.Clusters[0].Cluster.InsecureSkipTLSVerify {
+		config.TLSClientConfig.Insecure = true
+		// An empty CA would make ArgoCD fail to build its TLS config.
+		if c.KubeConfig.Clusters[0].Cluster.CaData == "" {
+			config.TLSClientConfig.CaData = nil
+		}
+	}
+	// Workload Identity replaces long-lived credentials, only the CA is kept.
+	if wi := buildWorkloadIdentityConfig(cluster); wi != nil {
+		config.WorkloadIdentityConfig = wi
+		config.BearerToken = nil
+		config.TLSClientConfig.CertData = nil
+		config.TLSClientConfig.KeyData = nil
+		config.ExecProviderConfig = nil
+	} else if aws := buildAWSAuthConfig(rc, cluster); aws != nil {
+		// EKS clusters authenticate through IAM in place of static credentials, only the CA is kept.
+		config.AWSAuthConfig = aws
+		config.BearerToken = nil
+		config.TLSClientConfig.CertData = nil
+		config.TLSClientConfig.KeyData = nil
+		config.ExecProviderConfig = nil
+	}
+	var clusterProject string
+	var defaultLabels map[string]string
+	if rc.Defaults != nil {
+		clusterProject = rc.Defaults.Project
+		defaultLabels = rc.Defaults.Labels
+	}
+	if rc.Tenant != nil && rc.Tenant.Project != "" {
+		clusterProject = rc.Tenant.Project
+	}
+	clusterNamespaces := ParseNamespaces(rc.Config.ClusterNamespaces)
+	if cluster != nil {
+		if project, ok := cluster.GetAnnotations()[projectAnnotation]; ok {
+			clusterProject = project
+		}
+		if namespaces, ok := cluster.GetAnnotations()[namespacesAnnotation]; ok {
+			clusterNamespaces = ParseNamespaces(namespaces)
+		}
+	}
+	if rc.Registration != nil {
+		if rc.Registration.Project != "" {
+			clusterProject = rc.Registration.Project
+		}
+		if len(rc.Registration.Labels) > 0 {
+			labels := map[string]string{}
+			for k, v := range defaultLabels {
+				labels[k] = v
+			}
+			for k, v := range rc.Registration.Labels {
+				labels[k] = v
+			}
+			defaultLabels = labels
+		}
+	}
+	clusterResources, err := buildClusterResources(rc, cluster)
+	if err != nil {
+		log.Info("Warning: "+err.Error()+". Ignoring", "annotation", clusterResourcesAnnotation)
+	}
+	clusterShard, err := buildClusterShard(rc, cluster)
+	if err != nil {
+		log.Info("Warning: "+err.Error()+". Ignoring", "annotation", shardAnnotation)
+	}
+	configOverrides, err := buildConfigOverrides(cluster)
+	if err != nil {
+		log.Info("Warning: "+err.Error()+". Ignoring", "annotation", configOverridesAnnotation)
+	}
+	clusterName, nameTruncated := rc.Config.buildClusterName(c.KubeConfig.Clusters[0].Name, s.ObjectMeta.Namespace)
+	namespacedName := rc.Config.BuildNamespacedName(c.Name, s.ObjectMeta.Namespace)
+	nameOverride, err := buildClusterNameOverride(rc, cluster)
+	if err != nil {
+		log.Info("Warning: "+err.Error()+". Ignoring", "annotation", clusterNameAnnotation)
+	}
+	if rc.Registration != nil && rc.Registration.Name != "" {
+		nameOverride = rc.Registration.Name
+	}
+	if nameOverride != "" {
+		clusterName = nameOverride
+		nameTruncated = false
+		namespacedName.Name = rc.Config.BuildArgoSecretName(nameOverride)
+	}
+	// The Argo namespace of an ArgoClusterRegistration takes precedence over the annotation.
+	if rc.Registration == nil || rc.Registration.ArgoNamespace == "" {
+		argoNamespace, err := buildArgoNamespace(rc, cluster)
+		if err != nil {
+			log.Info("Warning: " + err.Error() + ". Ignoring")
+		}
+		if argoNamespace != "" {
+			namespacedName.Namespace = argoNamespace
+		}
+	}
+	// Move the bearer token to a Secret of its own, referenced from the config.
+	var referencedToken *string
+	if rc.Config.UseTokenReference && config.BearerToken != nil {
+		referencedToken = config.BearerToken
+		config.BearerToken = nil
+		config.BearerTokenSecret = &ArgoTokenRef{SecretName: BuildTokenSecretName(clusterName), Key: argoTokenSecretKey}
+	}
+
+	clusterLabels := map[string]string{
+		clusterSecretNameLabel: s.Name,
+		clusterNamespaceLabel:  c.Namespace,
+	}
+	if rc.Config.InstanceID != "" {
+		clusterLabels[instanceLabel] = rc.Config.InstanceID
+	}
+	if cluster != nil && cluster.GetInfrastructureRef() != nil {
+		if provider := InfrastructureProvider(cluster.GetInfrastructureRef().Kind); provider != "" {
+			clusterLabels[infrastructureProviderLabel] = provider
+		}
+	}
+
+	return &ArgoCluster{
+		NamespacedName:       namespacedName,
+		ClusterName:          clusterName,
+		ClusterServer:        buildClusterServer(rc, c.KubeConfig.Clusters[0].Cluster.Server, cluster),
+		ClusterProject:       clusterProject,
+		ClusterNamespaces:    clusterNamespaces,
+		ClusterResources:     clusterResources,
+		ClusterShard:         clusterShard,
+		ClusterLabels:        clusterLabels,
+		DefaultLabels:        defaultLabels,
+		TakeAlongLabels:      takeAlongLabels,
+		TakeAlongAnnotations: takeAlongAnnotations,
+		Immutable:            rc.Config.ArgoSecretImmutable,
+		ClusterConfig:        config,
+		ConfigOverrides:      configOverrides,
+		ReferencedToken:      referencedToken,
+		NameOverridden:       nameOverride != "",
+		NameTruncated:        nameTruncated,
 	}, nil
 }
 
-// extractTakeAlongLabel returns the take-along label key from a cluster resource
-func extractTakeAlongLabel(key string) (string, error) {
-	if strings.HasPrefix(key, clusterTakeAlongKey) {
-		splitResult := strings.Split(key, clusterTakeAlongKey)
-		if len(splitResult) >= 2 {
-			if splitResult[1] != "" {
-				return splitResult[1], nil
-			}
+// buildClusterServer returns the API server URL of a cluster. With
+// OperatorConfig.UseControlPlaneEndpoint it is built from the controlPlaneEndpoint of the CAPI
+// Cluster, falling back to the kubeconfig server while the endpoint is not set.
+func buildClusterServer(rc *ReconcileContext, server string, cluster CAPICluster) string {
+	if !rc.Config.UseControlPlaneEndpoint || cluster == nil {
+		return server
+	}
+	host, port := cluster.GetControlPlaneEndpoint()
+	if host == "" {
+		rc.Logger.Info("Warning: Cluster has no controlPlaneEndpoint, using kubeconfig server", "server", server)
+		return server
+	}
+	if port == 0 {
+		return "https://" + host
+	}
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// buildConfigOverrides returns the config overrides of a cluster, nil when none are set.
+func buildConfigOverrides(cluster CAPICluster) ([]byte, error) {
+	if cluster == nil {
+		return nil, nil
+	}
+	annotation, ok := cluster.GetAnnotations()[configOverridesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(annotation), &overrides); err != nil {
+		return nil, fmt.Errorf("invalid config overrides, expected a JSON object: %w", err)
+	}
+	if overrides == nil {
+		return nil, fmt.Errorf("invalid config overrides, expected a JSON object: %s", annotation)
+	}
+	return []byte(annotation), nil
+}
+
+// ParseNamespaces splits a comma separated list of namespaces, dropping blank and duplicate entries.
+func ParseNamespaces(s string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(s, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// buildClusterResources returns whether ArgoCD may manage cluster-scoped resources of a cluster,
+// from its annotation or OperatorConfig.ClusterResources, nil when neither is set. An invalid
+// annotation falls back to the operator default and is returned as error.
+func buildClusterResources(rc *ReconcileContext, cluster CAPICluster) (*bool, error) {
+	var clusterResources *bool
+	if v, err := strconv.ParseBool(rc.Config.ClusterResources); err == nil {
+		clusterResources = &v
+	}
+	if cluster == nil {
+		return clusterResources, nil
+	}
+	annotation, ok := cluster.GetAnnotations()[clusterResourcesAnnotation]
+	if !ok {
+		return clusterResources, nil
+	}
+	v, err := strconv.ParseBool(annotation)
+	if err != nil {
+		return clusterResources, fmt.Errorf("invalid %s annotation %q on cluster resource: %s, namespace: %s", clusterResourcesAnnotation, annotation, cluster.GetName(), cluster.GetNamespace())
+	}
+	return &v, nil
+}
+
+// buildAuthPreference returns the credential type kept for a cluster, from its annotation or
+// OperatorConfig.AuthPreference. An invalid annotation falls back to the operator default and
+// is returned as error.
+func buildAuthPreference(rc *ReconcileContext, cluster CAPICluster) (string, error) {
+	if cluster == nil {
+		return rc.Config.AuthPreference, nil
+	}
+	annotation, ok := cluster.GetAnnotations()[authPreferenceAnnotation]
+	if !ok {
+		return rc.Config.AuthPreference, nil
+	}
+	if err := ValidateAuthPreference(annotation); err != nil {
+		return rc.Config.AuthPreference, fmt.Errorf("invalid %s annotation %q on cluster resource: %s, namespace: %s", authPreferenceAnnotation, annotation, cluster.GetName(), cluster.GetNamespace())
+	}
+	return annotation, nil
+}
+
+// applyAuthPreference drops the credential type losing against preference, when a config holds
+// both a bearer token and a client certificate. A single credential type is always kept.
+func applyAuthPreference(config *ArgoConfig, preference string) {
+	hasCert := config.TLSClientConfig.CertData != nil || config.TLSClientConfig.KeyData != nil
+	if config.BearerToken == nil || !hasCert {
+		return
+	}
+	switch preference {
+	case AuthPreferenceToken:
+		config.TLSClientConfig.CertData = nil
+		config.TLSClientConfig.KeyData = nil
+	case AuthPreferenceCert:
+		config.BearerToken = nil
+	}
+}
+
+// buildDisableCompression returns whether response compression is disabled for a cluster, from
+// its annotation or OperatorConfig.DisableCompression. An invalid annotation falls back to the
+// operator default and is returned as error.
+func buildDisableCompression(rc *ReconcileContext, cluster CAPICluster) (bool, error) {
+	if cluster == nil {
+		return rc.Config.DisableCompression, nil
+	}
+	annotation, ok := cluster.GetAnnotations()[disableCompressionAnnotation]
+	if !ok {
+		return rc.Config.DisableCompression, nil
+	}
+	v, err := strconv.ParseBool(annotation)
+	if err != nil {
+		return rc.Config.DisableCompression, fmt.Errorf("invalid %s annotation %q on cluster resource: %s, namespace: %s", disableCompressionAnnotation, annotation, cluster.GetName(), cluster.GetNamespace())
+	}
+	return v, nil
+}
+
+// buildClusterShard returns the application controller shard of a cluster, from its annotation
+// or OperatorConfig.ClusterShard, nil when neither is set. An invalid annotation falls back to
+// the operator default and is returned as error.
+func buildClusterShard(rc *ReconcileContext, cluster CAPICluster) (*int, error) {
+	var shard *int
+	if rc.Config.ClusterShard >= 0 {
+		v := rc.Config.ClusterShard
+		shard = &v
+	}
+	if cluster == nil {
+		return shard, nil
+	}
+	annotation, ok := cluster.GetAnnotations()[shardAnnotation]
+	if !ok {
+		return shard, nil
+	}
+	v, err := strconv.Atoi(annotation)
+	if err != nil || v < 0 {
+		return shard, fmt.Errorf("invalid %s annotation %q on cluster resource: %s, namespace: %s", shardAnnotation, annotation, cluster.GetName(), cluster.GetNamespace())
+	}
+	return &v, nil
+}
+
+// buildClusterNameOverride returns the name set by the cluster name annotation of a cluster, empty
+// when unset. A name that is not a valid object name is returned as error.
+func buildClusterNameOverride(rc *ReconcileContext, cluster CAPICluster) (string, error) {
+	if cluster == nil {
+		return "", nil
+	}
+	name, ok := cluster.GetAnnotations()[clusterNameAnnotation]
+	if !ok {
+		return "", nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid %s annotation %q on cluster resource: %s, namespace: %s: %s", clusterNameAnnotation, name, cluster.GetName(), cluster.GetNamespace(), strings.Join(errs, "; "))
+	}
+	return name, nil
+}
+
+// buildArgoNamespace returns the namespace set by the Argo namespace annotation of a cluster, else
+// by its ArgoTenantMapping, empty when unset. A namespace the operator does not manage is returned
+// as error.
+func buildArgoNamespace(rc *ReconcileContext, cluster CAPICluster) (string, error) {
+	var namespace, source string
+	if rc.Tenant != nil && rc.Tenant.ArgoNamespace != "" {
+		namespace, source = rc.Tenant.ArgoNamespace, "argoNamespace of ArgoTenantMapping "+rc.Tenant.Name
+	}
+	if cluster != nil {
+		if v, ok := cluster.GetAnnotations()[argoNamespaceAnnotation]; ok {
+			namespace, source = v, fmt.Sprintf("%s annotation on cluster resource: %s, namespace: %s", argoNamespaceAnnotation, cluster.GetName(), cluster.GetNamespace())
+		}
+	}
+	if source == "" {
+		return "", nil
+	}
+	if !slices.Contains(rc.Config.ArgoNamespaces(), namespace) {
+		return "", fmt.Errorf("invalid %s %q: not one of %s", source, namespace, strings.Join(rc.Config.ArgoNamespaces(), ", "))
+	}
+	return namespace, nil
+}
+
+// extractTakeAlongLabel returns the take-along label key from a cluster resource, for take-along
+// directives prefixed with prefix.
+func extractTakeAlongLabel(prefix, key string) (string, error) {
+	if strings.HasPrefix(key, prefix) {
+		if label := strings.TrimPrefix(key, prefix); label != "" {
+			return label, nil
 		}
 		return "", fmt.Errorf("invalid take-along label. missing key after '/': %s", key)
 	}
@@ -108,15 +719,16 @@ func extractTakeAlongLabel(key string) (string, error) {
 }
 
 // buildTakeAlongLabels returns a list of valid take-along labels from a cluster
-func buildTakeAlongLabels(cluster *clusterv1.Cluster) (map[string]string, []string) {
-	name := cluster.Name
-	namespace := cluster.Namespace
-	clusterLabels := cluster.Labels
+func buildTakeAlongLabels(rc *ReconcileContext, cluster CAPICluster) (map[string]string, []string) {
+	name := cluster.GetName()
+	namespace := cluster.GetNamespace()
+	clusterLabels := cluster.GetLabels()
+	prefix := rc.Config.TakeAlongLabelKey()
 
 	takeAlongLabels := []string{}
-	// Check labels keys that begin with clusterTakeAlongKey and extract the value after the last '/
+	// Check labels keys that begin with the take-along prefix and extract the value after the last '/
 	for k := range clusterLabels {
-		l, err := extractTakeAlongLabel(k)
+		l, err := extractTakeAlongLabel(prefix, k)
 		if err != nil {
 			return nil, []string{err.Error()}
 		}
@@ -124,40 +736,343 @@ func buildTakeAlongLabels(cluster *clusterv1.Cluster) (map[string]string, []stri
 			takeAlongLabels = append(takeAlongLabels, l)
 		}
 	}
+	selected, warnings := selectTakeAlongLabels(rc, cluster)
+	for _, l := range selected {
+		if !slices.Contains(takeAlongLabels, l) {
+			takeAlongLabels = append(takeAlongLabels, l)
+		}
+	}
 
+	slices.Sort(takeAlongLabels)
 	takeAlongLabelsMap := make(map[string]string)
 
-	errors := []string{}
+	errors := warnings
 	if len(takeAlongLabels) > 0 {
 		for _, label := range takeAlongLabels {
 			if label != "" {
+				if depth := takeAlongDepth(label); depth > max(rc.Config.MaxOwnerTakeAlongDepth, 1) {
+					errors = append(errors, fmt.Sprintf("Warning: take-along label '%s' is itself a take-along directive of depth %d on cluster resource: %s, namespace: %s. Ignoring", label, depth, name, namespace))
+					continue
+				}
+				if IsReservedLabel(label) {
+					errors = append(errors, fmt.Sprintf("take-along label '%s' is reserved by the operator on cluster resource: %s, namespace: %s. Ignoring", label, name, namespace))
+					continue
+				}
 				if _, ok := clusterLabels[label]; !ok {
 					errors = append(errors, fmt.Sprintf("take-along label '%s' not found on cluster resource: %s, namespace: %s. Ignoring", label, name, namespace))
 					continue
 				}
-				takeAlongLabelsMap[label] = clusterLabels[label]
-				takeAlongLabelsMap[fmt.Sprintf("%s%s", clusterTakenFromClusterKey, label)] = ""
+				target, err := takeAlongTarget(clusterLabels, prefix, label)
+				if err != nil {
+					errors = append(errors, fmt.Sprintf("Warning: %v on cluster resource: %s, namespace: %s. Ignoring", err, name, namespace))
+					continue
+				}
+				if _, ok := takeAlongLabelsMap[target]; ok {
+					errors = append(errors, fmt.Sprintf("Warning: take-along label '%s' targets '%s' taken along already on cluster resource: %s, namespace: %s. Ignoring", label, target, name, namespace))
+					continue
+				}
+				value, err := ApplyValuePipeline(rc.Pipeline, label, clusterLabels[label])
+				if err != nil {
+					errors = append(errors, fmt.Sprintf("Warning: failed to transform value of take-along label '%s' on cluster resource: %s, namespace: %s: %v. Ignoring", label, name, namespace, err))
+					continue
+				}
+				takeAlongLabelsMap[target] = value
+				takeAlongLabelsMap[fmt.Sprintf("%s%s", clusterTakenFromClusterKey, target)] = ""
 			}
 		}
 	}
 	return takeAlongLabelsMap, errors
 }
 
-// BuildNamespacedName returns k8s native object identifier.
-func BuildNamespacedName(s string, namespace string) types.NamespacedName {
+// takeAlongTarget returns the key a take-along label is written to on the ArgoSecret: the value of
+// its take-along directive when set, e.g. `take-along-label.capi-to-argocd.env: environment`,
+// else the label key itself.
+func takeAlongTarget(clusterLabels map[string]string, prefix, label string) (string, error) {
+	target := clusterLabels[prefix+label]
+	if target == "" {
+		return label, nil
+	}
+	if takeAlongDepth(target) > 1 || IsReservedLabel(target) || strings.HasPrefix(target, clusterTakenFromClusterKey) {
+		return "", fmt.Errorf("take-along label '%s' targets '%s' reserved by the operator", label, target)
+	}
+	return target, nil
+}
+
+// selectTakeAlongLabels returns the sorted labels of a cluster selected without a take-along label
+// each: all non-system labels not excluded by OperatorConfig.TakeAllLabelsExclusions when taking
+// all labels, else those starting with one of the prefixes of its take-along-labels-prefix
+// annotation. Take-along directives and operator-reserved labels are never selected.
+func selectTakeAlongLabels(rc *ReconcileContext, cluster CAPICluster) ([]string, []string) {
+	warnings := []string{}
+	takeAll := rc.Config.TakeAllLabels
+	if annotation, ok := cluster.GetAnnotations()[takeAllLabelsAnnotation]; ok {
+		v, err := strconv.ParseBool(annotation)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Warning: invalid %s annotation %q on cluster resource: %s, namespace: %s. Ignoring", takeAllLabelsAnnotation, annotation, cluster.GetName(), cluster.GetNamespace()))
+		} else {
+			takeAll = v
+		}
+	}
+	prefixes := []string{}
+	for _, p := range strings.Split(cluster.GetAnnotations()[takeAlongLabelsPrefixAnnotation], ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	exclusions := rc.Config.TakeAllLabelsExclusions()
+	labels := []string{}
+	for k := range cluster.GetLabels() {
+		if takeAlongDepth(k) > 1 || IsReservedLabel(k) {
+			continue
+		}
+		hasPrefix := func(p string) bool { return strings.HasPrefix(k, p) }
+		if takeAll && !isSystemLabel(k) && !slices.ContainsFunc(exclusions, hasPrefix) || slices.ContainsFunc(prefixes, hasPrefix) {
+			labels = append(labels, k)
+		}
+	}
+	slices.Sort(labels)
+	return labels, warnings
+}
+
+// isSystemLabel returns true for labels of the kubernetes.io and k8s.io domains and their subdomains.
+func isSystemLabel(key string) bool {
+	domain, _, ok := strings.Cut(key, "/")
+	if !ok {
+		return false
+	}
+	for _, system := range []string{"kubernetes.io", "k8s.io"} {
+		if domain == system || strings.HasSuffix(domain, "."+system) {
+			return true
+		}
+	}
+	return false
+}
+
+// TakeAllLabelsExclusions returns the label key prefixes never taken along when taking all labels.
+func (c OperatorConfig) TakeAllLabelsExclusions() []string {
+	exclusions := []string{}
+	for _, p := range strings.Split(c.TakeAllLabelsExclude, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			exclusions = append(exclusions, p)
+		}
+	}
+	return exclusions
+}
+
+// buildTakeAlongAnnotations returns the annotations of a cluster selected by take-along-annotation
+// directives, along with a taken-from marker for each of them.
+func buildTakeAlongAnnotations(cluster CAPICluster) (map[string]string, []string) {
+	name := cluster.GetName()
+	namespace := cluster.GetNamespace()
+	clusterAnnotations := cluster.GetAnnotations()
+
+	takeAlongAnnotations := map[string]string{}
+	errors := []string{}
+	for k := range clusterAnnotations {
+		if !strings.HasPrefix(k, clusterTakeAlongAnnotationKey) {
+			continue
+		}
+		annotation := strings.TrimPrefix(k, clusterTakeAlongAnnotationKey)
+		if annotation == "" {
+			errors = append(errors, fmt.Sprintf("invalid take-along annotation. missing key after '/': %s", k))
+			continue
+		}
+		if strings.HasPrefix(annotation, operatorAnnotationPrefix) {
+			errors = append(errors, fmt.Sprintf("take-along annotation '%s' is reserved by the operator on cluster resource: %s, namespace: %s. Ignoring", annotation, name, namespace))
+			continue
+		}
+		value, ok := clusterAnnotations[annotation]
+		if !ok {
+			errors = append(errors, fmt.Sprintf("take-along annotation '%s' not found on cluster resource: %s, namespace: %s. Ignoring", annotation, name, namespace))
+			continue
+		}
+		takeAlongAnnotations[annotation] = value
+		takeAlongAnnotations[clusterTakenFromClusterAnnotationKey+annotation] = ""
+	}
+	slices.Sort(errors)
+	return takeAlongAnnotations, errors
+}
+
+// takeAlongDepth returns the length of the take-along chain a take-along label starts,
+// where a plain label key has depth 1.
+func takeAlongDepth(label string) int {
+	depth := 1
+	for strings.HasPrefix(label, "take-along-") {
+		depth++
+		if !strings.HasPrefix(label, clusterTakeAlongKey) {
+			break
+		}
+		label = strings.TrimPrefix(label, clusterTakeAlongKey)
+	}
+	return depth
+}
+
+// buildOwnerReferences marshals owner references as a JSON array of `<kind>.<group>/<name>`
+// entries, where controller references are prefixed with `*`. Returns empty for no owners.
+func buildOwnerReferences(refs []metav1.OwnerReference) (string, error) {
+	if len(refs) == 0 {
+		return "", nil
+	}
+	entries := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		kind := ref.Kind
+		if gv, err := schema.ParseGroupVersion(ref.APIVersion); err == nil && gv.Group != "" {
+			kind += "." + gv.Group
+		}
+		entry := kind + "/" + ref.Name
+		if ref.Controller != nil && *ref.Controller {
+			entry = "*" + entry
+		}
+		entries = append(entries, entry)
+	}
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// BuildNamespacedName returns k8s native object identifier. The name is rendered from
+// OperatorConfig.ArgoSecretNameTemplate when set, else it is built by BuildArgoSecretName.
+func (c OperatorConfig) BuildNamespacedName(s string, namespace string) types.NamespacedName {
+	s = strings.TrimSuffix(s, "-kubeconfig")
+	if c.ArgoSecretNameTemplate != "" {
+		// Templates are validated at startup, a failing one falls back to the default scheme.
+		if name, err := RenderNameTemplate(c.ArgoSecretNameTemplate, NameTemplateData{ClusterName: s, ClusterNamespace: namespace}); err == nil && name != "" {
+			return types.NamespacedName{Name: SanitizeObjectName(name), Namespace: c.ArgoNamespace}
+		}
+	}
 	return types.NamespacedName{
-		Name:      "cluster-" + BuildClusterName(strings.TrimSuffix(s, "-kubeconfig"), namespace),
-		Namespace: ArgoNamespace,
+		Name:      c.BuildArgoSecretName(c.BuildClusterName(s, namespace)),
+		Namespace: c.ArgoNamespace,
 	}
 }
 
+// BuildSuffixedNamespacedName returns the NamespacedName of the ArgoSecret of a CAPI Secret
+// disambiguated with its namespace, built like BuildNamespacedName.
+func (c OperatorConfig) BuildSuffixedNamespacedName(s string, namespace string) types.NamespacedName {
+	return c.BuildNamespacedName(BuildSuffixedClusterName(strings.TrimSuffix(s, "-kubeconfig"), namespace), namespace)
+}
+
+// BuildArgoSecretName returns the ArgoSecret name of a cluster name, wrapped in
+// OperatorConfig.ArgoSecretNamePrefix and OperatorConfig.ArgoSecretNameSuffix, sanitized by
+// SanitizeObjectName.
+func (c OperatorConfig) BuildArgoSecretName(clusterName string) string {
+	return SanitizeObjectName(c.ArgoSecretNamePrefix + clusterName + c.ArgoSecretNameSuffix)
+}
+
+// TrimArgoSecretName returns the cluster name of an ArgoSecret name built by BuildArgoSecretName.
+func (c OperatorConfig) TrimArgoSecretName(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, c.ArgoSecretNamePrefix), c.ArgoSecretNameSuffix)
+}
+
 // BuildClusterName returns cluster name after transformations applied (with/without namespace suffix, etc).
-func BuildClusterName(s string, namespace string) string {
-	prefix := ""
-	if EnableNamespacedNames {
-		prefix += namespace + "-"
+// The name is rendered from OperatorConfig.ClusterNameTemplate when set.
+func (c OperatorConfig) BuildClusterName(s string, namespace string) string {
+	name, _ := c.buildClusterName(s, namespace)
+	return name
+}
+
+// buildClusterName returns the name of BuildClusterName and whether it got truncated.
+func (c OperatorConfig) buildClusterName(s string, namespace string) (string, bool) {
+	name := s
+	if c.EnableNamespacedNames {
+		name = namespace + "-" + s
+	}
+	if c.ClusterNameTemplate != "" {
+		if rendered, err := RenderNameTemplate(c.ClusterNameTemplate, NameTemplateData{ClusterName: s, ClusterNamespace: namespace}); err == nil && rendered != "" {
+			name = rendered
+		}
+	}
+	truncated := TruncateClusterName(name, c.ArgoClusterNameMaxLength)
+	return truncated, truncated != name
+}
+
+// TruncateClusterName shortens names longer than maxLength, cutting at a dash-separated
+// segment when possible, and appends `-<hash>` of the full name to keep truncated names unique.
+func TruncateClusterName(name string, maxLength int) string {
+	if maxLength <= 0 || len(name) <= maxLength {
+		return name
+	}
+	return truncateName(name, maxLength)
+}
+
+// truncateName shortens a name to maxLength, see TruncateClusterName.
+func truncateName(name string, maxLength int) string {
+	hash := nameHash(name)
+	budget := maxLength - clusterNameHashLength - 1
+	if budget <= 0 {
+		return hash[:min(maxLength, clusterNameHashLength)]
+	}
+	truncated := name[:budget]
+	// Cut at a segment boundary, unless it would drop most of the name.
+	if i := strings.LastIndex(truncated, "-"); i > budget/2 && name[budget] != '-' {
+		truncated = truncated[:i]
+	}
+	return strings.TrimRight(truncated, "-.") + "-" + hash
+}
+
+// nameHash returns the hash suffix of a shortened or sanitized name.
+func nameHash(name string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:clusterNameHashLength]
+}
+
+// SanitizeObjectName turns a generated name into a valid DNS-1123 subdomain: invalid characters
+// are lowercased or replaced by `-`, and names longer than 253 characters are truncated. Changed
+// names get `-<hash>` of the original name appended, so distinct names stay distinct.
+func SanitizeObjectName(name string) string {
+	if len(validation.IsDNS1123Subdomain(name)) == 0 {
+		return name
+	}
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, name)
+	// Dots next to dashes or other dots make empty or dash-bounded subdomain segments.
+	if strings.Contains(sanitized, "..") || strings.Contains(sanitized, ".-") || strings.Contains(sanitized, "-.") {
+		sanitized = strings.ReplaceAll(sanitized, ".", "-")
+	}
+	sanitized = strings.Trim(sanitized, "-.")
+	if sanitized != name {
+		if sanitized == "" {
+			sanitized = nameHash(name)
+		} else {
+			sanitized += "-" + nameHash(name)
+		}
+	}
+	if len(sanitized) > validation.DNS1123SubdomainMaxLength {
+		sanitized = truncateName(sanitized, validation.DNS1123SubdomainMaxLength)
+	}
+	return sanitized
+}
+
+// BuildSuffixedClusterName returns cluster name suffixed by the first characters of its namespace.
+func BuildSuffixedClusterName(s string, namespace string) string {
+	suffix := namespace
+	if len(suffix) > namespaceSuffixLength {
+		suffix = suffix[:namespaceSuffixLength]
 	}
-	return prefix + s
+	return s + "-" + strings.TrimRight(suffix, "-")
+}
+
+// SetComputedName overrides the cluster name and the ArgoSecret name with a computed name.
+func (a *ArgoCluster) SetComputedName(name, secretName string) {
+	a.ClusterName = name
+	a.NamespacedName.Name = secretName
+	if a.ClusterConfig.BearerTokenSecret != nil {
+		a.ClusterConfig.BearerTokenSecret.SecretName = BuildTokenSecretName(name)
+	}
+}
+
+// BuildTokenSecretName returns the name of the Secret holding the bearer token of a cluster,
+// sanitized by SanitizeObjectName.
+func BuildTokenSecretName(clusterName string) string {
+	return SanitizeObjectName(clusterName + "-token")
 }
 
 // ConvertToSecret converts an ArgoCluster into k8s native secret object.
@@ -169,16 +1084,36 @@ func (a *ArgoCluster) ConvertToSecret() (*corev1.Secret, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	mergedLabels := make(map[string]string)
-	for key, value := range GetArgoCommonLabels() {
-		mergedLabels[key] = value
+	if len(a.ConfigOverrides) > 0 {
+		if c, err = jsonpatch.MergePatch(c, a.ConfigOverrides); err != nil {
+			return nil, err
+		}
 	}
+
+	labels := NewLabelMap()
 	for key, value := range a.ClusterLabels {
-		mergedLabels[key] = value
+		if err := labels.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range a.DefaultLabels {
+		if err := labels.Set(key, value); err != nil {
+			return nil, err
+		}
 	}
 	for key, value := range a.TakeAlongLabels {
-		mergedLabels[key] = value
+		if err := labels.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	mergedLabels := GetArgoCommonLabels().Merge(labels).ToMap()
+
+	var annotations map[string]string
+	if len(a.TakeAlongAnnotations) > 0 {
+		annotations = make(map[string]string, len(a.TakeAlongAnnotations))
+		for k, v := range a.TakeAlongAnnotations {
+			annotations[k] = v
+		}
 	}
 
 	argoSecret := &corev1.Secret{
@@ -187,9 +1122,10 @@ func (a *ArgoCluster) ConvertToSecret() (*corev1.Secret, error) {
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      a.NamespacedName.Name,
-			Namespace: a.NamespacedName.Namespace,
-			Labels:    mergedLabels,
+			Name:        a.NamespacedName.Name,
+			Namespace:   a.NamespacedName.Namespace,
+			Labels:      mergedLabels,
+			Annotations: annotations,
 		},
 		Data: map[string][]byte{
 			"name":   []byte(a.ClusterName),
@@ -197,9 +1133,54 @@ func (a *ArgoCluster) ConvertToSecret() (*corev1.Secret, error) {
 			"config": c,
 		},
 	}
+	if a.ClusterProject != "" {
+		argoSecret.Data["project"] = []byte(a.ClusterProject)
+	}
+	if len(a.ClusterNamespaces) > 0 {
+		argoSecret.Data["namespaces"] = []byte(strings.Join(a.ClusterNamespaces, ","))
+	}
+	if a.ClusterResources != nil {
+		argoSecret.Data["clusterResources"] = []byte(strconv.FormatBool(*a.ClusterResources))
+	}
+	if a.ClusterShard != nil {
+		argoSecret.Data["shard"] = []byte(strconv.Itoa(*a.ClusterShard))
+	}
+	if a.Immutable {
+		immutable := true
+		argoSecret.Immutable = &immutable
+	}
 	return argoSecret, nil
 }
 
+// ConvertToTokenSecret returns the Secret holding the bearer token referenced by the
+// ArgoCluster config, or nil when the token is embedded in the config.
+func (a *ArgoCluster) ConvertToTokenSecret() *corev1.Secret {
+	ref := a.ClusterConfig.BearerTokenSecret
+	if ref == nil || a.ReferencedToken == nil {
+		return nil
+	}
+	labels := make(map[string]string, len(a.ClusterLabels)+1)
+	for k, v := range a.ClusterLabels {
+		labels[k] = v
+	}
+	labels[ownedLabel] = reservedLabels[ownedLabel]
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ref.SecretName,
+			Namespace: a.NamespacedName.Namespace,
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			ref.Key: []byte(*a.ReferencedToken),
+		},
+	}
+}
+
 // ValidateClusterTLSConfig validates that we got proper based64 k/v fields.
 // func ValidateClusterTLSConfig(a *ArgoTLS) error {
 // 	for _, v := range []string{a.CaData, a.CertData, a.KeyData} {
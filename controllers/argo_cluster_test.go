@@ -2,12 +2,22 @@ package controllers
 
 import (
 	// b64 "encoding/base64"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/yaml"
 )
@@ -29,7 +39,7 @@ func TestExtractTakeAlongLabel(t *testing.T) {
 		tt := tt
 		t.Run(tt.testName, func(t *testing.T) {
 			t.Parallel()
-			v, err := extractTakeAlongLabel(tt.testMock)
+			v, err := extractTakeAlongLabel(clusterTakeAlongKey, tt.testMock)
 			if tt.testExpectedError {
 				assert.NotNil(t, err)
 			} else {
@@ -106,6 +116,37 @@ func TestBuildTakeAlongLabels(t *testing.T) {
 					},
 				},
 			}, true, map[string]string{}},
+		{"Test with take-along-labels label of an operator-reserved key",
+			&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels: map[string]string{
+						"foo":                            "bar",
+						"argocd.argoproj.io/secret-type": "repository",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "foo"):                            "",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "argocd.argoproj.io/secret-type"): "",
+					},
+				},
+			}, true, map[string]string{
+				"foo": "bar",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "foo"): "",
+			}},
+		{"Test with take-along-labels label chained to another take-along-labels label",
+			&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels: map[string]string{
+						"foo": "bar",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "foo"):                        "",
+						fmt.Sprintf("%s%s%s", clusterTakeAlongKey, clusterTakeAlongKey, "foo"): "",
+					},
+				},
+			}, true, map[string]string{
+				"foo": "bar",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "foo"): "",
+			}},
 		{"Test with take-along-labels label (multiple) and take-along-labels label not found",
 			&clusterv1.Cluster{
 				ObjectMeta: metav1.ObjectMeta{
@@ -123,12 +164,87 @@ func TestBuildTakeAlongLabels(t *testing.T) {
 				"my.mydomain.com/subkey": "bar",
 				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "my.mydomain.com/subkey"): "",
 			}},
+		{"Test with take-along-labels label renamed",
+			&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels: map[string]string{
+						"env":                  "prod",
+						"my.mydomain.com/team": "platform",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "env"):                  "environment",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "my.mydomain.com/team"): "team",
+					},
+				},
+			}, false, map[string]string{
+				"environment": "prod",
+				"team":        "platform",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "environment"): "",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "team"):        "",
+			}},
+		{"Test with take-along-labels label renamed to a taken along or reserved key",
+			&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels: map[string]string{
+						"env":         "prod",
+						"environment": "stage",
+						"foo":         "bar",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "env"):         "environment",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "environment"): "",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "foo"):         "take-along-label.capi-to-argocd.bar",
+					},
+				},
+			}, true, map[string]string{
+				"environment": "prod",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "environment"): "",
+			}},
+		{"Test with take-along-labels-prefix annotation",
+			&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Namespace:   "test",
+					Annotations: map[string]string{takeAlongLabelsPrefixAnnotation: "team.example.com/, env"},
+					Labels: map[string]string{
+						"test":                           "dont-take-along",
+						"team.example.com/name":          "platform",
+						"team.example.com/cost":          "42",
+						"environment":                    "prod",
+						"argocd.argoproj.io/secret-type": "repository",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "envoy"): "",
+					},
+				},
+			}, true, map[string]string{
+				"team.example.com/name": "platform",
+				"team.example.com/cost": "42",
+				"environment":           "prod",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "team.example.com/name"): "",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "team.example.com/cost"): "",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "environment"):           "",
+			}},
+		{"Test with take-along-labels-prefix annotation overlapping a take-along-labels label",
+			&clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Namespace:   "test",
+					Annotations: map[string]string{takeAlongLabelsPrefixAnnotation: "fo,,take-along,argocd.argoproj.io/"},
+					Labels: map[string]string{
+						"foo":                            "bar",
+						"argocd.argoproj.io/secret-type": "repository",
+						fmt.Sprintf("%s%s", clusterTakeAlongKey, "foo"): "",
+					},
+				},
+			}, false, map[string]string{
+				"foo": "bar",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "foo"): "",
+			}},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.testName, func(t *testing.T) {
 			t.Parallel()
-			v, errors := buildTakeAlongLabels(tt.testMock)
+			v, errors := buildTakeAlongLabels(MockReconcileContext(MockOperatorConfig()), &V1Beta1ClusterAdapter{tt.testMock})
 			if tt.testExpectedError {
 				assert.NotEmpty(t, errors)
 			} else {
@@ -139,6 +255,82 @@ func TestBuildTakeAlongLabels(t *testing.T) {
 	}
 }
 
+func TestTakeAllLabels(t *testing.T) {
+	t.Parallel()
+	labels := map[string]string{
+		"foo":                              "bar",
+		"team.example.com/name":            "platform",
+		"cluster.x-k8s.io/cluster-name":    "test",
+		"topology.cluster.x-k8s.io/owned":  "",
+		"node.kubernetes.io/instance-type": "m5.large",
+		"kubernetes.io/os":                 "linux",
+		"argocd.argoproj.io/secret-type":   "repository",
+	}
+	all := map[string]string{
+		"foo":                   "bar",
+		"team.example.com/name": "platform",
+		fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "foo"):                   "",
+		fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "team.example.com/name"): "",
+	}
+	tests := []struct {
+		testName           string
+		testFlag           bool
+		testExclude        string
+		testAnnotation     string
+		testExpectedError  bool
+		testExpectedValues map[string]string
+	}{
+		{"test disabled", false, "cluster.x-k8s.io/", "", false, map[string]string{}},
+		{"test flag", true, "cluster.x-k8s.io/,topology.cluster.x-k8s.io/", "", false, all},
+		{"test annotation", false, "cluster.x-k8s.io/,topology.cluster.x-k8s.io/", "true", false, all},
+		{"test annotation opting out of flag", true, "", "false", false, map[string]string{}},
+		{"test invalid annotation", true, "cluster.x-k8s.io/,topology.cluster.x-k8s.io/", "yes", true, all},
+		{"test custom exclusions", true, "team.,topology.", "", false, map[string]string{
+			"foo":                           "bar",
+			"cluster.x-k8s.io/cluster-name": "test",
+			fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "foo"):                           "",
+			fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "cluster.x-k8s.io/cluster-name"): "",
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Labels: labels}}
+			if tt.testAnnotation != "" {
+				cluster.Annotations = map[string]string{takeAllLabelsAnnotation: tt.testAnnotation}
+			}
+			config := MockOperatorConfig()
+			config.TakeAllLabels = tt.testFlag
+			config.TakeAllLabelsExclude = tt.testExclude
+			v, errors := buildTakeAlongLabels(MockReconcileContext(config), &V1Beta1ClusterAdapter{cluster})
+			if tt.testExpectedError {
+				assert.NotEmpty(t, errors)
+			} else {
+				assert.Empty(t, errors)
+			}
+			assert.Equal(t, tt.testExpectedValues, v)
+		})
+	}
+}
+
+func TestBuildTakeAlongLabelsDomain(t *testing.T) {
+	t.Parallel()
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Labels: map[string]string{
+		"foo": "bar", "env": "prod", "take-along-label.example.com.foo": "", "take-along-label.example.com.env": "environment", clusterTakeAlongKey + "env": "",
+	}}}
+	config := MockOperatorConfig()
+	config.TakeAlongDomain = "example.com"
+	v, errors := buildTakeAlongLabels(MockReconcileContext(config), &V1Beta1ClusterAdapter{cluster})
+	assert.Empty(t, errors)
+	assert.Equal(t, map[string]string{
+		"foo":                              "bar",
+		"environment":                      "prod",
+		clusterTakenFromClusterKey + "foo": "",
+		clusterTakenFromClusterKey + "environment": "",
+	}, v)
+}
+
 func TestConvertToSecret(t *testing.T) {
 	t.Parallel()
 	validMock := true
@@ -153,9 +345,9 @@ func TestConvertToSecret(t *testing.T) {
 				"Kind":            "Secret",
 				"APIVersion":      "v1",
 				"Name":            "cluster-test",
-				"Namespace":       ArgoNamespace,
-				"OperatorLabel":   GetArgoCommonLabels()["capi-to-argocd/owned"],
-				"ArgoLabel":       GetArgoCommonLabels()["argocd.argoproj.io/secret-type"],
+				"Namespace":       TestArgoNamespace,
+				"OperatorLabel":   "true",
+				"ArgoLabel":       "cluster",
 				"SecretNameLabel": "test-kubeconfig",
 				"NamespaceLabel":  "test",
 			},
@@ -232,30 +424,30 @@ func TestBuildNamespacedName(t *testing.T) {
 	}{
 		{"test type with valid fields", "test-XXX-kubeconfig", "test-ns", false, false,
 			types.NamespacedName{
-				Name:      "cluster-test-XXX",
-				Namespace: ArgoNamespace,
+				Name:      "cluster-test-xxx-" + nameHash("cluster-test-XXX"),
+				Namespace: TestArgoNamespace,
 			},
 		},
 		{"test type with valid fields and namespaced names", "test-XXX-kubeconfig", "test-ns", true, false,
 			types.NamespacedName{
-				Name:      "cluster-test-ns-test-XXX",
-				Namespace: ArgoNamespace,
+				Name:      "cluster-test-ns-test-xxx-" + nameHash("cluster-test-ns-test-XXX"),
+				Namespace: TestArgoNamespace,
 			},
 		},
 		{"test type with non-valid fields", "capi-XXX", "test-ns", false, false,
 			types.NamespacedName{
-				Name:      "cluster-capi-XXX",
-				Namespace: ArgoNamespace,
+				Name:      "cluster-capi-xxx-" + nameHash("cluster-capi-XXX"),
+				Namespace: TestArgoNamespace,
 			},
 		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.testName, func(t *testing.T) {
-			oldConf := EnableNamespacedNames
-			EnableNamespacedNames = tt.testEnableNamespacedNames
-			s := BuildNamespacedName(tt.testMock, tt.testNamespace)
-			EnableNamespacedNames = oldConf
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.EnableNamespacedNames = tt.testEnableNamespacedNames
+			s := config.BuildNamespacedName(tt.testMock, tt.testNamespace)
 			if !tt.testExpectedError {
 				assert.NotNil(t, s)
 				assert.Equal(t, tt.testExpectedValues.Name, s.Name)
@@ -266,3 +458,981 @@ func TestBuildNamespacedName(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildSuffixedClusterName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testNamespace      string
+		testExpectedValues string
+	}{
+		{"test short namespace", "mycluster", "team-a", "mycluster-team-a"},
+		{"test long namespace", "mycluster", "team-b-production", "mycluster-team-b"},
+		{"test namespace ending with hyphen after cut", "mycluster", "teamb-prod", "mycluster-teamb"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, BuildSuffixedClusterName(tt.testMock, tt.testNamespace))
+		})
+	}
+}
+
+func TestBuildSuffixedNamespacedName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testTemplate       string
+		testExpectedValues string
+	}{
+		{"test default", "", "cluster-mycluster-team-b"},
+		{"test secret name template", "{{ .ClusterNamespace }}.{{ .ClusterName }}", "team-b.mycluster-team-b"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.ArgoSecretNameTemplate = tt.testTemplate
+			assert.Equal(t, tt.testExpectedValues, c.BuildSuffixedNamespacedName("mycluster-kubeconfig", "team-b").Name)
+		})
+	}
+}
+
+func TestRoundTripArgoConfig(t *testing.T) {
+	t.Parallel()
+	value := func(s string) *string { return &s }
+	tests := []struct {
+		testName string
+		testMock ArgoConfig
+	}{
+		{"test all fields populated", ArgoConfig{
+			BearerToken:     value("token"),
+			TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: value("cert"), KeyData: value("key")},
+		}},
+		{"test only bearer token", ArgoConfig{BearerToken: value("token")}},
+		{"test only TLS", ArgoConfig{
+			TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: value("cert"), KeyData: value("key")},
+		}},
+		{"test nil TLSClientConfig", ArgoConfig{BearerToken: value("token"), TLSClientConfig: nil}},
+		{"test empty TLSClientConfig", ArgoConfig{TLSClientConfig: &ArgoTLS{}}},
+		{"test empty config", ArgoConfig{}},
+		{"test empty bearer token", ArgoConfig{BearerToken: value("")}},
+		{"test empty caData", ArgoConfig{TLSClientConfig: &ArgoTLS{CaData: value(""), CertData: value("cert"), KeyData: value("key")}}},
+		{"test nil caData", ArgoConfig{TLSClientConfig: &ArgoTLS{CaData: nil, CertData: value("cert"), KeyData: value("key")}}},
+		{"test empty certData", ArgoConfig{TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: value(""), KeyData: value("key")}}},
+		{"test nil certData", ArgoConfig{TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: nil, KeyData: value("key")}}},
+		{"test empty keyData", ArgoConfig{TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: value("cert"), KeyData: value("")}}},
+		{"test nil keyData", ArgoConfig{TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: value("cert"), KeyData: nil}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			out, err := RoundTripArgoConfig(tt.testMock)
+			assert.Nil(t, err)
+			assertArgoConfigEqual(t, tt.testMock, out)
+		})
+	}
+}
+
+func FuzzArgoConfigRoundTrip(f *testing.F) {
+	f.Add("token", "ca", "cert", "key", true, true)
+	f.Add("", "", "", "", false, true)
+	f.Add("", "", "", "", false, false)
+	f.Fuzz(func(t *testing.T, token, ca, cert, key string, withToken, withTLS bool) {
+		// encoding/json replaces invalid UTF-8 with U+FFFD, which is not a data loss we care for.
+		for _, s := range []string{token, ca, cert, key} {
+			if !utf8.ValidString(s) {
+				t.Skip()
+			}
+		}
+		cfg := ArgoConfig{}
+		if withToken {
+			cfg.BearerToken = &token
+		}
+		if withTLS {
+			cfg.TLSClientConfig = &ArgoTLS{CaData: &ca, CertData: &cert, KeyData: &key}
+		}
+		out, err := RoundTripArgoConfig(cfg)
+		if err != nil {
+			t.Fatalf("roundtrip failed: %v", err)
+		}
+		assertArgoConfigEqual(t, cfg, out)
+	})
+}
+
+// assertArgoConfigEqual compares two ArgoConfig values and reports the field that differs.
+func assertArgoConfigEqual(t *testing.T, expected, actual ArgoConfig) {
+	t.Helper()
+	if reflect.DeepEqual(expected, actual) {
+		return
+	}
+	if !reflect.DeepEqual(expected.BearerToken, actual.BearerToken) {
+		t.Errorf("field BearerToken differs: expected %s, got %s", describeValue(expected.BearerToken), describeValue(actual.BearerToken))
+	}
+	if (expected.TLSClientConfig == nil) != (actual.TLSClientConfig == nil) {
+		t.Errorf("field TLSClientConfig differs: expected nil=%t, got nil=%t", expected.TLSClientConfig == nil, actual.TLSClientConfig == nil)
+		return
+	}
+	if expected.TLSClientConfig == nil {
+		return
+	}
+	e, a := reflect.ValueOf(*expected.TLSClientConfig), reflect.ValueOf(*actual.TLSClientConfig)
+	for i := 0; i < e.NumField(); i++ {
+		if !reflect.DeepEqual(e.Field(i).Interface(), a.Field(i).Interface()) {
+			t.Errorf("field TLSClientConfig.%s differs: expected %s, got %s", e.Type().Field(i).Name,
+				describeValue(e.Field(i).Interface()), describeValue(a.Field(i).Interface()))
+		}
+	}
+}
+
+// describeValue formats a field value, dereferencing pointers.
+func describeValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "<nil>"
+		}
+		rv = rv.Elem()
+	}
+	return fmt.Sprintf("%#v", rv.Interface())
+}
+
+func TestTruncateClusterName(t *testing.T) {
+	t.Parallel()
+	atLimit := strings.Repeat("a", 63)
+	overLimit := "team-platform-production-europe-west-" + strings.Repeat("b", 20) + "-cluster"
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues string
+	}{
+		{"test short name", "mycluster", "mycluster"},
+		{"test name exactly at limit", atLimit, atLimit},
+		{"test name one over limit", atLimit + "a", strings.Repeat("a", 54) + "-" + fmt.Sprintf("%x", sha256.Sum256([]byte(atLimit+"a")))[:8]},
+		{"test name truncated at segment boundary", overLimit, "team-platform-production-europe-west-" + fmt.Sprintf("%x", sha256.Sum256([]byte(overLimit)))[:8]},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v := TruncateClusterName(tt.testMock, 63)
+			assert.Equal(t, tt.testExpectedValues, v)
+			assert.LessOrEqual(t, len(v), 63)
+			// Same input always produces the same output.
+			assert.Equal(t, v, TruncateClusterName(tt.testMock, 63))
+		})
+	}
+
+	// Long names sharing a prefix produce different short names.
+	prefix := strings.Repeat("c", 70)
+	a, b := TruncateClusterName(prefix+"-a", 63), TruncateClusterName(prefix+"-b", 63)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, 63)
+	assert.Len(t, b, 63)
+
+	assert.Equal(t, prefix, TruncateClusterName(prefix, 0))
+}
+
+func TestSanitizeObjectName(t *testing.T) {
+	t.Parallel()
+	long := strings.Repeat("a", 250) + "-cluster"
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues string
+	}{
+		{"test valid name", "cluster-test.example", "cluster-test.example"},
+		{"test uppercase name", "cluster-Test", "cluster-test-" + nameHash("cluster-Test")},
+		{"test invalid characters", "cluster-team_a/test", "cluster-team-a-test-" + nameHash("cluster-team_a/test")},
+		{"test invalid dots", "cluster-test.-a..b", "cluster-test--a--b-" + nameHash("cluster-test.-a..b")},
+		{"test invalid boundaries", "-cluster-test_", "cluster-test-" + nameHash("-cluster-test_")},
+		{"test only invalid characters", "___", nameHash("___")},
+		{"test long name", long, strings.Repeat("a", 244) + "-" + nameHash(long)},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v := SanitizeObjectName(tt.testMock)
+			assert.Equal(t, tt.testExpectedValues, v)
+			assert.Empty(t, validation.IsDNS1123Subdomain(v))
+			// Same input always produces the same output.
+			assert.Equal(t, v, SanitizeObjectName(tt.testMock))
+		})
+	}
+	assert.NotEqual(t, SanitizeObjectName("cluster-Test"), SanitizeObjectName("cluster-test"))
+}
+
+func TestTakeAlongDepth(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testMaxDepth       int
+		testExpectedValues int
+		testExpectedTaken  bool
+	}{
+		{"test plain label", "foo", 1, 1, true},
+		{"test chained label", clusterTakeAlongKey + "foo", 1, 2, false},
+		{"test chained label within depth", clusterTakeAlongKey + "foo", 2, 2, true},
+		{"test chained label of three levels", clusterTakeAlongKey + clusterTakeAlongKey + "foo", 2, 3, false},
+		{"test other take-along directive", "take-along-annotation.capi-to-argocd.foo", 1, 2, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, takeAlongDepth(tt.testMock))
+
+			config := MockOperatorConfig()
+			config.MaxOwnerTakeAlongDepth = tt.testMaxDepth
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Labels: map[string]string{
+				tt.testMock:                       "value",
+				clusterTakeAlongKey + tt.testMock: "",
+			}}}
+			v, _ := buildTakeAlongLabels(MockReconcileContext(config), &V1Beta1ClusterAdapter{cluster})
+			_, taken := v[tt.testMock]
+			assert.Equal(t, tt.testExpectedTaken, taken)
+		})
+	}
+}
+
+func TestBuildOwnerReferences(t *testing.T) {
+	t.Parallel()
+	controller := true
+	tests := []struct {
+		testName           string
+		testMock           []metav1.OwnerReference
+		testExpectedValues string
+	}{
+		{"test no owners", nil, ""},
+		{"test one owner", []metav1.OwnerReference{
+			{APIVersion: "platform.example.com/v1", Kind: "Tenant", Name: "team-a"},
+		}, `["Tenant.platform.example.com/team-a"]`},
+		{"test multiple owners", []metav1.OwnerReference{
+			{APIVersion: "platform.example.com/v1", Kind: "Tenant", Name: "team-a"},
+			{APIVersion: "v1", Kind: "ConfigMap", Name: "owners"},
+		}, `["Tenant.platform.example.com/team-a","ConfigMap/owners"]`},
+		{"test controller owner", []metav1.OwnerReference{
+			{APIVersion: "cluster.x-k8s.io/v1beta1", Kind: "ClusterClass", Name: "eks", Controller: &controller},
+			{APIVersion: "platform.example.com/v1", Kind: "Tenant", Name: "team-a"},
+		}, `["*ClusterClass.cluster.x-k8s.io/eks","Tenant.platform.example.com/team-a"]`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v, err := buildOwnerReferences(tt.testMock)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, v)
+		})
+	}
+}
+
+func TestTakeAlongOwnerReferences(t *testing.T) {
+	t.Parallel()
+	owners := []metav1.OwnerReference{{APIVersion: "platform.example.com/v1", Kind: "Tenant", Name: "team-a"}}
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testOwners         []metav1.OwnerReference
+		testExpectedValues map[string]string
+	}{
+		{"test take-along owner refs", map[string]string{takeAlongOwnerRefsAnnotation: "true"}, owners,
+			map[string]string{ownerReferencesAnnotation: `["Tenant.platform.example.com/team-a"]`}},
+		{"test take-along owner refs without owners", map[string]string{takeAlongOwnerRefsAnnotation: "true"}, nil, nil},
+		{"test owner refs not taken along", nil, owners, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:            "test",
+				Namespace:       "test",
+				Annotations:     tt.testAnnotations,
+				OwnerReferences: tt.testOwners,
+			}}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, argoSecret.Annotations)
+		})
+	}
+}
+
+func TestWorkloadIdentityConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testExpectedValues string
+	}{
+		{"test workload identity", map[string]string{
+			"capi-to-argocd/wi-tenant-id":  "tenant",
+			"capi-to-argocd/wi-client-id":  "client",
+			"capi-to-argocd/wi-token-file": "/var/run/secrets/azure/tokens/azure-identity-token",
+		}, `{"tlsClientConfig":{"caData":"%s"},"workloadIdentityConfig":{"azureTenantID":"tenant","azureClientID":"client","azureFederatedTokenFile":"/var/run/secrets/azure/tokens/azure-identity-token"}}`},
+		{"test partial workload identity", map[string]string{
+			"capi-to-argocd/wi-client-id": "client",
+		}, `{"tlsClientConfig":{"caData":"%s"},"workloadIdentityConfig":{"azureClientID":"client"}}`},
+		{"test bearer token", nil, `{"tlsClientConfig":{"caData":"%s"},"bearerToken":"%s"}`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			if a.ClusterConfig.WorkloadIdentityConfig != nil {
+				assert.Nil(t, a.ClusterConfig.BearerToken)
+				assert.Nil(t, a.ClusterConfig.TLSClientConfig.CertData)
+				assert.Nil(t, a.ClusterConfig.TLSClientConfig.KeyData)
+			}
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			ca := c.KubeConfig.Clusters[0].Cluster.CaData
+			expected := fmt.Sprintf(tt.testExpectedValues, ca)
+			if tt.testAnnotations == nil {
+				expected = fmt.Sprintf(tt.testExpectedValues, ca, *c.KubeConfig.Users[0].User.Token)
+			}
+			assert.JSONEq(t, expected, string(argoSecret.Data["config"]))
+		})
+	}
+}
+
+func TestMinClusterAge(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAge            time.Duration
+		testMinAge         time.Duration
+		testExpectedError  bool
+		testExpectedValues time.Duration
+	}{
+		{"test check disabled", time.Second, 0, false, 0},
+		{"test cluster older than minimum age", 10 * time.Minute, 5 * time.Minute, false, 0},
+		{"test cluster younger than minimum age", 2 * time.Minute, 5 * time.Minute, true, 3 * time.Minute},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.MinClusterAge = tt.testMinAge
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{
+				Name:              "test",
+				Namespace:         "test",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-tt.testAge)),
+			}}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, &V1Beta1ClusterAdapter{cluster})
+			if !tt.testExpectedError {
+				assert.Nil(t, err)
+				assert.NotNil(t, a)
+				return
+			}
+			assert.ErrorIs(t, err, ErrClusterTooYoung)
+			var tooYoung *ClusterTooYoungError
+			if assert.True(t, errors.As(err, &tooYoung)) {
+				assert.InDelta(t, tt.testExpectedValues, tooYoung.Remaining, float64(5*time.Second))
+			}
+		})
+	}
+}
+
+func TestReconcileMinClusterAge(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "young-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "young"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "young", Namespace: TestNamespace, CreationTimestamp: metav1.Now()}, Status: MockReadyClusterStatus()}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("young-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("young-kubeconfig", TestNamespace)
+
+	// Young clusters are requeued for the remaining time without an error, so no backoff applies.
+	r.Config.MinClusterAge = time.Hour
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.False(t, result.Requeue)
+	assert.InDelta(t, time.Hour, result.RequeueAfter, float64(5*time.Second))
+	assert.NotNil(t, c.Get(ctx, nn, &corev1.Secret{}))
+
+	r.Config.MinClusterAge = time.Nanosecond
+	result, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.Nil(t, c.Get(ctx, nn, &corev1.Secret{}))
+}
+
+func TestClusterProject(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testExpectedValues []byte
+	}{
+		{"test project annotation", map[string]string{projectAnnotation: "team-a"}, []byte("team-a")},
+		{"test empty project annotation", map[string]string{projectAnnotation: ""}, nil},
+		{"test no project annotation", nil, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, argoSecret.Data["project"])
+			_, ok := argoSecret.Data["project"]
+			assert.Equal(t, tt.testExpectedValues != nil, ok)
+		})
+	}
+}
+
+func TestParseNamespaces(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues []string
+	}{
+		{"test empty", "", nil},
+		{"test single", "team-a", []string{"team-a"}},
+		{"test multiple", "team-a,team-b", []string{"team-a", "team-b"}},
+		{"test blanks and duplicates", " team-a, ,team-b,team-a,", []string{"team-a", "team-b"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, ParseNamespaces(tt.testMock))
+		})
+	}
+}
+
+func TestClusterNamespaces(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testConfig         string
+		testAnnotations    map[string]string
+		testExpectedValues []byte
+	}{
+		{"test no namespaces", "", nil, nil},
+		{"test operator namespaces", "default, kube-system", nil, []byte("default,kube-system")},
+		{"test annotation namespaces", "", map[string]string{namespacesAnnotation: "team-a,team-b"}, []byte("team-a,team-b")},
+		{"test annotation overrides operator namespaces", "default", map[string]string{namespacesAnnotation: "team-a"}, []byte("team-a")},
+		{"test empty annotation grants whole cluster", "default", map[string]string{namespacesAnnotation: ""}, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.ClusterNamespaces = tt.testConfig
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, argoSecret.Data["namespaces"])
+		})
+	}
+}
+
+func TestClusterResources(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testConfig         string
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedValues []byte
+	}{
+		{"test unset", "", nil, false, nil},
+		{"test operator default", "false", nil, false, []byte("false")},
+		{"test annotation", "", map[string]string{clusterResourcesAnnotation: "true"}, false, []byte("true")},
+		{"test annotation overrides operator default", "false", map[string]string{clusterResourcesAnnotation: "true"}, false, []byte("true")},
+		{"test invalid annotation falls back to operator default", "false", map[string]string{clusterResourcesAnnotation: "maybe"}, true, []byte("false")},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.ClusterResources = tt.testConfig
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			_, err := buildClusterResources(MockReconcileContext(config), cluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, cluster)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, argoSecret.Data["clusterResources"])
+		})
+	}
+}
+
+func TestClusterShard(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testConfig         int
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedValues []byte
+	}{
+		{"test unset", -1, nil, false, nil},
+		{"test operator default", 0, nil, false, []byte("0")},
+		{"test annotation", -1, map[string]string{shardAnnotation: "3"}, false, []byte("3")},
+		{"test annotation overrides operator default", 1, map[string]string{shardAnnotation: "2"}, false, []byte("2")},
+		{"test invalid annotation falls back to operator default", 1, map[string]string{shardAnnotation: "two"}, true, []byte("1")},
+		{"test negative annotation falls back to operator default", -1, map[string]string{shardAnnotation: "-2"}, true, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.ClusterShard = tt.testConfig
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			_, err := buildClusterShard(MockReconcileContext(config), cluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, cluster)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, argoSecret.Data["shard"])
+		})
+	}
+}
+
+func TestAWSAuthConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testEnabled        bool
+		testSpec           clusterv1.ClusterSpec
+		testAnnotations    map[string]string
+		testExpectedValues *ArgoAWSAuthConfig
+	}{
+		{"test EKS infrastructure", true, clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{Kind: "AWSManagedCluster"},
+		}, nil, &ArgoAWSAuthConfig{ClusterName: "test_test"}},
+		{"test EKS control plane", true, clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{Kind: "AWSManagedControlPlane"},
+		}, nil, &ArgoAWSAuthConfig{ClusterName: "test_test"}},
+		{"test EKS annotations", true, clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{Kind: "AWSManagedCluster"},
+			ControlPlaneRef:   &corev1.ObjectReference{Kind: "AWSManagedControlPlane"},
+		}, map[string]string{
+			awsClusterNameAnnotation: "eks-test",
+			awsRoleARNAnnotation:     "arn:aws:iam::123456789012:role/argocd",
+			awsProfileAnnotation:     "prod",
+		}, &ArgoAWSAuthConfig{ClusterName: "eks-test", RoleARN: "arn:aws:iam::123456789012:role/argocd", Profile: "prod"}},
+		{"test self-managed AWS cluster", true, clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{Kind: "AWSCluster"},
+			ControlPlaneRef:   &corev1.ObjectReference{Kind: "KubeadmControlPlane"},
+		}, nil, nil},
+		{"test disabled", false, clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{Kind: "AWSManagedCluster"},
+		}, nil, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.EnableAWSAuthConfig = tt.testEnabled
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}, Spec: tt.testSpec}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, a.ClusterConfig.AWSAuthConfig)
+			if tt.testExpectedValues == nil {
+				assert.NotNil(t, a.ClusterConfig.BearerToken)
+				return
+			}
+			// Static credentials are replaced, only the CA is kept.
+			assert.Nil(t, a.ClusterConfig.BearerToken)
+			assert.Nil(t, a.ClusterConfig.TLSClientConfig.CertData)
+			assert.Nil(t, a.ClusterConfig.TLSClientConfig.KeyData)
+			assert.NotNil(t, a.ClusterConfig.TLSClientConfig.CaData)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Contains(t, string(argoSecret.Data["config"]), `"awsAuthConfig":{"clusterName":"`+tt.testExpectedValues.ClusterName+`"`)
+		})
+	}
+}
+
+func TestDisableCompression(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testConfig         bool
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedValues bool
+	}{
+		{"test unset", false, nil, false, false},
+		{"test operator default", true, nil, false, true},
+		{"test annotation", false, map[string]string{disableCompressionAnnotation: "true"}, false, true},
+		{"test annotation overrides operator default", true, map[string]string{disableCompressionAnnotation: "false"}, false, false},
+		{"test invalid annotation falls back to operator default", true, map[string]string{disableCompressionAnnotation: "yes please"}, true, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.DisableCompression = tt.testConfig
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			_, err := buildDisableCompression(MockReconcileContext(config), cluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, cluster)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, strings.Contains(string(argoSecret.Data["config"]), `"disableCompression":true`))
+		})
+	}
+}
+
+func TestAuthPreference(t *testing.T) {
+	t.Parallel()
+	kubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: test\n  cluster:\n    server: https://test.domain.com:6443\n    certificate-authority-data: Y2E=\n" +
+		"users:\n- name: test-admin\n  user:\n    token: dG9rZW4=\n    client-certificate-data: Y2VydA==\n    client-key-data: a2V5\n"
+	tests := []struct {
+		testName           string
+		testConfig         string
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test both", AuthPreferenceBoth, nil, false, `{"tlsClientConfig":{"caData":"Y2E=","certData":"Y2VydA==","keyData":"a2V5"},"bearerToken":"dG9rZW4="}`},
+		{"test token", AuthPreferenceToken, nil, false, `{"tlsClientConfig":{"caData":"Y2E="},"bearerToken":"dG9rZW4="}`},
+		{"test cert", AuthPreferenceCert, nil, false, `{"tlsClientConfig":{"caData":"Y2E=","certData":"Y2VydA==","keyData":"a2V5"}}`},
+		{"test annotation overrides operator default", AuthPreferenceBoth, map[string]string{authPreferenceAnnotation: AuthPreferenceToken}, false, `{"tlsClientConfig":{"caData":"Y2E="},"bearerToken":"dG9rZW4="}`},
+		{"test invalid annotation falls back to operator default", AuthPreferenceCert, map[string]string{authPreferenceAnnotation: "password"}, true, `{"tlsClientConfig":{"caData":"Y2E=","certData":"Y2VydA==","keyData":"a2V5"}}`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.AuthPreference = tt.testConfig
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			_, err := buildAuthPreference(MockReconcileContext(config), cluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			s.Data["value"] = []byte(kubeConfig)
+			c := NewCapiCluster("test", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, cluster)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.testExpectedValues, string(argoSecret.Data["config"]))
+		})
+	}
+}
+
+func TestApplyAuthPreferenceSingleCredential(t *testing.T) {
+	t.Parallel()
+	token, cert := "token", "cert"
+	// A single credential type is kept whatever the preference.
+	config := ArgoConfig{BearerToken: &token, TLSClientConfig: &ArgoTLS{}}
+	applyAuthPreference(&config, AuthPreferenceCert)
+	assert.Equal(t, &token, config.BearerToken)
+	config = ArgoConfig{TLSClientConfig: &ArgoTLS{CertData: &cert}}
+	applyAuthPreference(&config, AuthPreferenceToken)
+	assert.Equal(t, &cert, config.TLSClientConfig.CertData)
+}
+
+func TestTLSServerName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testAnnotations    map[string]string
+		testExpectedValues string
+	}{
+		{"test no server name", "", nil, ""},
+		{"test kubeconfig server name", "    tls-server-name: api.internal\n", nil, "api.internal"},
+		{"test annotation server name", "", map[string]string{tlsServerNameAnnotation: "api.example.com"}, "api.example.com"},
+		{"test annotation overrides kubeconfig server name", "    tls-server-name: api.internal\n", map[string]string{tlsServerNameAnnotation: "api.example.com"}, "api.example.com"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: test\n  cluster:\n    server: https://lb.domain.com:6443\n    certificate-authority-data: Y2E=\n" +
+				tt.testMock + "users:\n- name: test-admin\n  user:\n    token: dG9rZW4=\n"
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			s.Data["value"] = []byte(kubeConfig)
+			c := NewCapiCluster("test", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, cluster)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, a.ClusterConfig.TLSClientConfig.ServerName)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues != "", strings.Contains(string(argoSecret.Data["config"]), `"serverName":"`+tt.testExpectedValues+`"`))
+		})
+	}
+}
+
+func TestBuildTakeAlongAnnotations(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedValues map[string]string
+	}{
+		{"test without take-along annotations", map[string]string{"contact": "team-a@example.com"}, false, map[string]string{}},
+		{"test with take-along annotations",
+			map[string]string{
+				"contact":                 "team-a@example.com",
+				"example.com/cost-center": "cc-42",
+				"unrelated":               "dont-take-along",
+				clusterTakeAlongAnnotationKey + "contact":                 "",
+				clusterTakeAlongAnnotationKey + "example.com/cost-center": "",
+			}, false, map[string]string{
+				"contact":                 "team-a@example.com",
+				"example.com/cost-center": "cc-42",
+				clusterTakenFromClusterAnnotationKey + "contact":                 "",
+				clusterTakenFromClusterAnnotationKey + "example.com/cost-center": "",
+			}},
+		{"test with missing take-along annotation", map[string]string{clusterTakeAlongAnnotationKey + "contact": ""}, true, map[string]string{}},
+		{"test with empty take-along annotation key", map[string]string{clusterTakeAlongAnnotationKey: ""}, true, map[string]string{}},
+		{"test with reserved take-along annotation",
+			map[string]string{
+				projectAnnotation: "team-a",
+				clusterTakeAlongAnnotationKey + projectAnnotation: "",
+			}, true, map[string]string{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}
+			v, errors := buildTakeAlongAnnotations(&V1Beta1ClusterAdapter{cluster})
+			if tt.testExpectedError {
+				assert.NotEmpty(t, errors)
+			} else {
+				assert.Empty(t, errors)
+			}
+			assert.Equal(t, tt.testExpectedValues, v)
+
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			for k, value := range tt.testExpectedValues {
+				assert.Equal(t, value, argoSecret.Annotations[k])
+			}
+		})
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           map[string]string
+		testExpectedValues map[string]interface{}
+	}{
+		{"test no overrides", nil, map[string]interface{}{"insecure": nil, "serverName": nil, "caData": "Y2E="}},
+		{"test new fields", map[string]string{configOverridesAnnotation: `{"tlsClientConfig":{"serverName":"api.example.com"}}`},
+			map[string]interface{}{"serverName": "api.example.com", "caData": "Y2E="}},
+		{"test overridden and removed fields", map[string]string{configOverridesAnnotation: `{"tlsClientConfig":{"insecure":true,"caData":null}}`},
+			map[string]interface{}{"insecure": true, "caData": nil}},
+		{"test invalid overrides", map[string]string{configOverridesAnnotation: `{"tlsClientConfig":`}, map[string]interface{}{"caData": "Y2E="}},
+		{"test non-object overrides", map[string]string{configOverridesAnnotation: `null`}, map[string]interface{}{"caData": "Y2E="}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: test\n  cluster:\n    server: https://lb.domain.com:6443\n    certificate-authority-data: Y2E=\n" +
+				"users:\n- name: test-admin\n  user:\n    token: dG9rZW4=\n"
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			s.Data["value"] = []byte(kubeConfig)
+			c := NewCapiCluster("test", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testMock}}}
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, cluster)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			var config struct {
+				TLSClientConfig map[string]interface{} `json:"tlsClientConfig"`
+				BearerToken     string                 `json:"bearerToken"`
+			}
+			assert.Nil(t, json.Unmarshal([]byte(argoSecret.Data["config"]), &config))
+			assert.Equal(t, "dG9rZW4=", config.BearerToken)
+			for k, v := range tt.testExpectedValues {
+				assert.Equal(t, v, config.TLSClientConfig[k], k)
+			}
+		})
+	}
+}
+
+func TestClusterServer(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testEnabled        bool
+		testEndpoint       clusterv1.APIEndpoint
+		testCluster        bool
+		testExpectedValues string
+	}{
+		{"test kubeconfig server", false, clusterv1.APIEndpoint{Host: "10.0.0.1", Port: 6443}, true, "https://lb.domain.com:6443"},
+		{"test control plane endpoint", true, clusterv1.APIEndpoint{Host: "api.example.com", Port: 443}, true, "https://api.example.com:443"},
+		{"test control plane endpoint without port", true, clusterv1.APIEndpoint{Host: "api.example.com"}, true, "https://api.example.com"},
+		{"test IPv6 control plane endpoint", true, clusterv1.APIEndpoint{Host: "fd00::1", Port: 6443}, true, "https://[fd00::1]:6443"},
+		{"test control plane endpoint not set", true, clusterv1.APIEndpoint{}, true, "https://lb.domain.com:6443"},
+		{"test without Cluster object", true, clusterv1.APIEndpoint{}, false, "https://lb.domain.com:6443"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: test\n  cluster:\n    server: https://lb.domain.com:6443\n    certificate-authority-data: Y2E=\n" +
+				"users:\n- name: test-admin\n  user:\n    token: dG9rZW4=\n"
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			s.Data["value"] = []byte(kubeConfig)
+			c := NewCapiCluster("test", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			var cluster CAPICluster
+			if tt.testCluster {
+				cluster = &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}, Spec: clusterv1.ClusterSpec{ControlPlaneEndpoint: tt.testEndpoint}}}
+			}
+			config := MockOperatorConfig()
+			config.UseControlPlaneEndpoint = tt.testEnabled
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, cluster)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, string(argoSecret.Data["server"]))
+		})
+	}
+}
+
+func TestClusterNameOverride(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testTemplate       string
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedValues []string
+	}{
+		{"test unset", "", nil, false, []string{"kube-cluster-test", "cluster-test"}},
+		{"test override", "", map[string]string{clusterNameAnnotation: "prod-eu"}, false, []string{"prod-eu", "cluster-prod-eu"}},
+		{"test override bypasses templates", "{{ .ClusterNamespace }}-{{ .ClusterName }}", map[string]string{clusterNameAnnotation: "prod-eu"}, false, []string{"prod-eu", "cluster-prod-eu"}},
+		{"test invalid override falls back to default", "", map[string]string{clusterNameAnnotation: "Prod_EU"}, true, []string{"kube-cluster-test", "cluster-test"}},
+		{"test empty override falls back to default", "", map[string]string{clusterNameAnnotation: ""}, true, []string{"kube-cluster-test", "cluster-test"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.ClusterNameTemplate = tt.testTemplate
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			_, err := buildClusterNameOverride(MockReconcileContext(config), cluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, cluster)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, []string{a.ClusterName, a.NamespacedName.Name})
+		})
+	}
+}
+
+func TestArgoNamespaceAnnotation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testRegistration   *ClusterRegistration
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test unset", nil, nil, false, TestArgoNamespace},
+		{"test override", map[string]string{argoNamespaceAnnotation: "team-a-argocd"}, nil, false, "team-a-argocd"},
+		{"test unmanaged namespace falls back to default", map[string]string{argoNamespaceAnnotation: "kube-system"}, nil, true, TestArgoNamespace},
+		{"test registration wins", map[string]string{argoNamespaceAnnotation: "team-a-argocd"}, &ClusterRegistration{ArgoNamespace: "team-b-argocd"}, false, "team-b-argocd"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.AdditionalArgoNamespaces = "team-a-argocd,team-b-argocd"
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: tt.testAnnotations}}}
+			_, err := buildArgoNamespace(MockReconcileContext(config), cluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+
+			rc := MockReconcileContext(config)
+			if tt.testRegistration != nil {
+				assert.Empty(t, tt.testRegistration.Apply(rc))
+			}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(rc, c, s, cluster)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, a.NamespacedName.Namespace)
+		})
+	}
+}
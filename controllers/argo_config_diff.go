@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// redactedValue replaces the values of sensitive fields in a FieldDiff.
+const redactedValue = "[CHANGED]"
+
+// FieldDiff describes a single ArgoConfig field that differs between two configs.
+// Values of sensitive fields are redacted.
+type FieldDiff struct {
+	Field     string
+	OldValue  string
+	NewValue  string
+	Sensitive bool
+}
+
+// DiffArgoConfig returns the fields that differ between two ArgoConfig values.
+func DiffArgoConfig(oldConfig, newConfig ArgoConfig) []FieldDiff {
+	oldTLS, newTLS := oldConfig.TLSClientConfig, newConfig.TLSClientConfig
+	if oldTLS == nil {
+		oldTLS = &ArgoTLS{}
+	}
+	if newTLS == nil {
+		newTLS = &ArgoTLS{}
+	}
+
+	oldWI, newWI := workloadIdentityFields(oldConfig.WorkloadIdentityConfig), workloadIdentityFields(newConfig.WorkloadIdentityConfig)
+	oldExec, newExec := execProviderFields(oldConfig.ExecProviderConfig), execProviderFields(newConfig.ExecProviderConfig)
+	oldAWS, newAWS := awsAuthFields(oldConfig.AWSAuthConfig), awsAuthFields(newConfig.AWSAuthConfig)
+	oldProxy, newProxy := optionalString(oldConfig.ProxyURL), optionalString(newConfig.ProxyURL)
+	oldServerName, newServerName := optionalString(oldTLS.ServerName), optionalString(newTLS.ServerName)
+	oldInsecure, newInsecure := optionalString(strconv.FormatBool(oldTLS.Insecure)), optionalString(strconv.FormatBool(newTLS.Insecure))
+	oldCompression, newCompression := optionalString(strconv.FormatBool(oldConfig.DisableCompression)), optionalString(strconv.FormatBool(newConfig.DisableCompression))
+
+	diffs := []FieldDiff{}
+	for _, f := range []struct {
+		name      string
+		old, new  *string
+		sensitive bool
+	}{
+		{"bearerToken", oldConfig.BearerToken, newConfig.BearerToken, true},
+		{"tlsClientConfig.insecure", oldInsecure, newInsecure, false},
+		{"tlsClientConfig.serverName", oldServerName, newServerName, false},
+		{"tlsClientConfig.caData", oldTLS.CaData, newTLS.CaData, false},
+		{"tlsClientConfig.certData", oldTLS.CertData, newTLS.CertData, true},
+		{"tlsClientConfig.keyData", oldTLS.KeyData, newTLS.KeyData, true},
+		{"workloadIdentityConfig.azureTenantID", oldWI[0], newWI[0], false},
+		{"workloadIdentityConfig.azureClientID", oldWI[1], newWI[1], false},
+		{"workloadIdentityConfig.azureFederatedTokenFile", oldWI[2], newWI[2], false},
+		{"execProviderConfig.command", oldExec[0], newExec[0], false},
+		{"execProviderConfig.args", oldExec[1], newExec[1], false},
+		{"execProviderConfig.env", oldExec[2], newExec[2], true},
+		{"execProviderConfig.apiVersion", oldExec[3], newExec[3], false},
+		{"execProviderConfig.installHint", oldExec[4], newExec[4], false},
+		{"awsAuthConfig.clusterName", oldAWS[0], newAWS[0], false},
+		{"awsAuthConfig.roleARN", oldAWS[1], newAWS[1], false},
+		{"awsAuthConfig.profile", oldAWS[2], newAWS[2], false},
+		{"proxyUrl", oldProxy, newProxy, false},
+		{"disableCompression", oldCompression, newCompression, false},
+	} {
+		if stringValue(f.old) == stringValue(f.new) && (f.old == nil) == (f.new == nil) {
+			continue
+		}
+		d := FieldDiff{Field: f.name, OldValue: stringValue(f.old), NewValue: stringValue(f.new), Sensitive: f.sensitive}
+		if f.sensitive {
+			d.OldValue, d.NewValue = redactedValue, redactedValue
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// workloadIdentityFields returns pointers to the tenant, client and token file of wi, nil when unset.
+func workloadIdentityFields(wi *ArgoWorkloadIdentityConfig) [3]*string {
+	fields := [3]*string{}
+	if wi == nil {
+		return fields
+	}
+	for i, v := range []string{wi.AzureTenantID, wi.AzureClientID, wi.AzureFederatedTokenFile} {
+		if v != "" {
+			v := v
+			fields[i] = &v
+		}
+	}
+	return fields
+}
+
+// awsAuthFields returns pointers to the cluster name, role ARN and profile of aws, nil when unset.
+func awsAuthFields(aws *ArgoAWSAuthConfig) [3]*string {
+	fields := [3]*string{}
+	if aws == nil {
+		return fields
+	}
+	for i, v := range []string{aws.ClusterName, aws.RoleARN, aws.Profile} {
+		if v != "" {
+			v := v
+			fields[i] = &v
+		}
+	}
+	return fields
+}
+
+// execProviderFields returns pointers to the command, args, env, apiVersion and installHint of
+// exec, nil when unset. Args and env are rendered as JSON.
+func execProviderFields(exec *ArgoExecProviderConfig) [5]*string {
+	fields := [5]*string{}
+	if exec == nil {
+		return fields
+	}
+	args, env := "", ""
+	if len(exec.Args) > 0 {
+		b, _ := json.Marshal(exec.Args)
+		args = string(b)
+	}
+	if len(exec.Env) > 0 {
+		b, _ := json.Marshal(exec.Env)
+		env = string(b)
+	}
+	for i, v := range []string{exec.Command, args, env, exec.APIVersion, exec.InstallHint} {
+		if v != "" {
+			v := v
+			fields[i] = &v
+		}
+	}
+	return fields
+}
+
+// optionalString returns a pointer to s, nil when empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringValue dereferences a string pointer, returning an empty string for nil.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffArgoConfig(t *testing.T) {
+	t.Parallel()
+	value := func(s string) *string { return &s }
+	base := func() ArgoConfig {
+		return ArgoConfig{
+			BearerToken:     value("token"),
+			TLSClientConfig: &ArgoTLS{CaData: value("ca"), CertData: value("cert"), KeyData: value("key")},
+		}
+	}
+	tests := []struct {
+		testName           string
+		testMock           func(c *ArgoConfig)
+		testExpectedValues []FieldDiff
+	}{
+		{"test no changes", func(c *ArgoConfig) {}, []FieldDiff{}},
+		{"test bearerToken changed", func(c *ArgoConfig) { c.BearerToken = value("other") },
+			[]FieldDiff{{"bearerToken", "[CHANGED]", "[CHANGED]", true}}},
+		{"test bearerToken removed", func(c *ArgoConfig) { c.BearerToken = nil },
+			[]FieldDiff{{"bearerToken", "[CHANGED]", "[CHANGED]", true}}},
+		{"test caData changed", func(c *ArgoConfig) { c.TLSClientConfig.CaData = value("other-ca") },
+			[]FieldDiff{{"tlsClientConfig.caData", "ca", "other-ca", false}}},
+		{"test certData changed", func(c *ArgoConfig) { c.TLSClientConfig.CertData = value("other") },
+			[]FieldDiff{{"tlsClientConfig.certData", "[CHANGED]", "[CHANGED]", true}}},
+		{"test keyData changed", func(c *ArgoConfig) { c.TLSClientConfig.KeyData = value("other") },
+			[]FieldDiff{{"tlsClientConfig.keyData", "[CHANGED]", "[CHANGED]", true}}},
+		{"test workloadIdentityConfig added", func(c *ArgoConfig) {
+			c.WorkloadIdentityConfig = &ArgoWorkloadIdentityConfig{AzureTenantID: "tenant"}
+		}, []FieldDiff{{"workloadIdentityConfig.azureTenantID", "", "tenant", false}}},
+		{"test execProviderConfig added", func(c *ArgoConfig) {
+			c.ExecProviderConfig = &ArgoExecProviderConfig{Command: "aws", Args: []string{"eks", "get-token"}, Env: map[string]string{"AWS_PROFILE": "prod"}}
+		}, []FieldDiff{
+			{"execProviderConfig.command", "", "aws", false},
+			{"execProviderConfig.args", "", `["eks","get-token"]`, false},
+			{"execProviderConfig.env", "[CHANGED]", "[CHANGED]", true},
+		}},
+		{"test proxyUrl added", func(c *ArgoConfig) { c.ProxyURL = "http://proxy.example.com:3128" },
+			[]FieldDiff{{"proxyUrl", "", "http://proxy.example.com:3128", false}}},
+		{"test disableCompression enabled", func(c *ArgoConfig) { c.DisableCompression = true },
+			[]FieldDiff{{"disableCompression", "false", "true", false}}},
+		{"test serverName added", func(c *ArgoConfig) { c.TLSClientConfig.ServerName = "api.internal" },
+			[]FieldDiff{{"tlsClientConfig.serverName", "", "api.internal", false}}},
+		{"test multiple fields changed", func(c *ArgoConfig) {
+			c.BearerToken = value("other")
+			c.TLSClientConfig.CaData = value("")
+			c.TLSClientConfig.KeyData = value("other")
+		}, []FieldDiff{
+			{"bearerToken", "[CHANGED]", "[CHANGED]", true},
+			{"tlsClientConfig.caData", "ca", "", false},
+			{"tlsClientConfig.keyData", "[CHANGED]", "[CHANGED]", true},
+		}},
+		{"test TLSClientConfig removed", func(c *ArgoConfig) { c.TLSClientConfig = nil }, []FieldDiff{
+			{"tlsClientConfig.caData", "ca", "", false},
+			{"tlsClientConfig.certData", "[CHANGED]", "[CHANGED]", true},
+			{"tlsClientConfig.keyData", "[CHANGED]", "[CHANGED]", true},
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			newConfig := base()
+			tt.testMock(&newConfig)
+			diffs := DiffArgoConfig(base(), newConfig)
+			assert.Equal(t, tt.testExpectedValues, diffs)
+			for _, d := range diffs {
+				for _, secret := range []string{"token", "cert", "key", "other"} {
+					if d.Sensitive {
+						assert.NotEqual(t, secret, d.OldValue)
+						assert.NotEqual(t, secret, d.NewValue)
+					}
+				}
+			}
+		})
+	}
+}
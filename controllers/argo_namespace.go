@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// argoServerNameLabel is the label key/value pair that identifies the ArgoCD server Deployment.
+	argoServerNameLabel = "app.kubernetes.io/name"
+	argoServerName      = "argocd-server"
+
+	// StatusConfigMapName is the name of the ConfigMap holding the operator status.
+	StatusConfigMapName = "capi2argo-status"
+	// argoNamespaceValidKey is the status ConfigMap key holding the ArgoNamespace check result.
+	argoNamespaceValidKey = "ArgoNamespaceValid"
+)
+
+// ArgoNamespaceValidator periodically checks that ArgoNamespace holds an ArgoCD server
+// Deployment, and publishes the result on the status ConfigMap.
+type ArgoNamespaceValidator struct {
+	// Client lists the ArgoCD server Deployments.
+	Client client.Client
+	// StatusClient writes the status ConfigMap on the cluster running the operator, defaults to
+	// Client.
+	StatusClient client.Client
+	Log          logr.Logger
+	// Namespace is the namespace expected to run ArgoCD.
+	Namespace string
+	// StatusConfigMap is the ConfigMap the result gets written to.
+	StatusConfigMap types.NamespacedName
+	// Interval is how often the check runs.
+	Interval time.Duration
+
+	valid atomic.Bool
+}
+
+// NewArgoNamespaceValidator returns an ArgoNamespaceValidator for the given namespace, writing its
+// status on a ConfigMap in the operator namespace (POD_NAMESPACE, or namespace when unset).
+func NewArgoNamespaceValidator(c client.Client, log logr.Logger, namespace string, interval time.Duration) *ArgoNamespaceValidator {
+	statusNamespace := os.Getenv("POD_NAMESPACE")
+	if statusNamespace == "" {
+		statusNamespace = namespace
+	}
+	v := &ArgoNamespaceValidator{
+		Client:          c,
+		Log:             log,
+		Namespace:       namespace,
+		StatusConfigMap: types.NamespacedName{Name: StatusConfigMapName, Namespace: statusNamespace},
+		Interval:        interval,
+	}
+	v.valid.Store(true)
+	return v
+}
+
+// Valid returns the result of the last check. It is true until a check fails.
+func (v *ArgoNamespaceValidator) Valid() bool {
+	return v.valid.Load()
+}
+
+// Check verifies that Namespace holds an ArgoCD server Deployment and updates the status ConfigMap.
+func (v *ArgoNamespaceValidator) Check(ctx context.Context) (bool, error) {
+	deployments := &appsv1.DeploymentList{}
+	err := v.Client.List(ctx, deployments, client.InNamespace(v.Namespace), client.MatchingLabels{argoServerNameLabel: argoServerName})
+	if err != nil {
+		return v.Valid(), err
+	}
+	valid := len(deployments.Items) > 0
+	v.valid.Store(valid)
+	if !valid {
+		v.Log.Info("Warning: no ArgoCD server Deployment found in ArgoNamespace", "namespace", v.Namespace)
+	}
+	return valid, v.writeStatus(ctx, valid)
+}
+
+// Start runs the check until ctx is done. It implements manager.Runnable.
+func (v *ArgoNamespaceValidator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(v.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := v.Check(ctx); err != nil {
+			v.Log.Error(err, "Failed to validate ArgoNamespace")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusClient returns StatusClient, or Client when unset.
+func (v *ArgoNamespaceValidator) statusClient() client.Client {
+	if v.StatusClient != nil {
+		return v.StatusClient
+	}
+	return v.Client
+}
+
+// writeStatus stores the check result on the status ConfigMap.
+func (v *ArgoNamespaceValidator) writeStatus(ctx context.Context, valid bool) error {
+	c := v.statusClient()
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, v.StatusConfigMap, cm)
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      v.StatusConfigMap.Name,
+				Namespace: v.StatusConfigMap.Namespace,
+			},
+			Data: map[string]string{argoNamespaceValidKey: strconv.FormatBool(valid)},
+		}
+		return c.Create(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if cm.Data[argoNamespaceValidKey] == strconv.FormatBool(valid) {
+		return nil
+	}
+	cm.Data[argoNamespaceValidKey] = strconv.FormatBool(valid)
+	return c.Update(ctx, cm)
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func MockArgoServerDeployment(namespace string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "argocd-server",
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/name": "argocd-server"},
+		},
+	}
+}
+
+func TestArgoNamespaceValidator(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	deployment := MockArgoServerDeployment("argocd")
+	c := MockClient(deployment)
+	v := NewArgoNamespaceValidator(c, TestLog, "argocd", time.Minute)
+	v.StatusConfigMap.Namespace = "capi"
+
+	valid, err := v.Check(ctx)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	assert.True(t, v.Valid())
+	cm := &corev1.ConfigMap{}
+	assert.Nil(t, c.Get(ctx, v.StatusConfigMap, cm))
+	assert.Equal(t, "true", cm.Data["ArgoNamespaceValid"])
+
+	// ArgoCD gets uninstalled.
+	assert.Nil(t, c.Delete(ctx, deployment))
+	valid, err = v.Check(ctx)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+	assert.False(t, v.Valid())
+	assert.Nil(t, c.Get(ctx, v.StatusConfigMap, cm))
+	assert.Equal(t, "false", cm.Data["ArgoNamespaceValid"])
+
+	// Deployments in other namespaces or without the label are ignored.
+	other := MockArgoServerDeployment("other")
+	unlabeled := MockArgoServerDeployment("argocd")
+	unlabeled.Labels = nil
+	c = MockClient(other, unlabeled)
+	v = NewArgoNamespaceValidator(c, TestLog, "argocd", time.Minute)
+	valid, err = v.Check(ctx)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+
+	// The status gets written through StatusClient when set.
+	status := MockClient()
+	c = MockClient(deployment)
+	v = NewArgoNamespaceValidator(c, TestLog, "argocd", time.Minute)
+	v.StatusClient = status
+	valid, err = v.Check(ctx)
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	assert.Nil(t, status.Get(ctx, v.StatusConfigMap, cm))
+	assert.True(t, errors.IsNotFound(c.Get(ctx, v.StatusConfigMap, cm)))
+}
+
+func TestReconcileInvalidArgoNamespace(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		testName           string
+		testDeployment     bool
+		testExpectedEvents int
+	}{
+		{"test valid ArgoNamespace", true, 2},
+		{"test invalid ArgoNamespace", false, 3},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			c := MockClient(MockCapiSecret(validMock, validType, validKey, "events-kubeconfig", TestNamespace))
+			if tt.testDeployment {
+				assert.Nil(t, c.Create(ctx, MockArgoServerDeployment(TestArgoNamespace)))
+			}
+			v := NewArgoNamespaceValidator(c, TestLog, TestArgoNamespace, time.Minute)
+			_, err := v.Check(ctx)
+			assert.Nil(t, err)
+
+			recorder := record.NewFakeRecorder(10)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder, ArgoNamespaceValidator: v}
+			_, err = r.Reconcile(ctx, MockReconcileReq("events-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+
+			// The ArgoSecret is created in both states.
+			assert.Nil(t, c.Get(ctx, MockOperatorConfig().BuildNamespacedName("events-kubeconfig", TestNamespace), &corev1.Secret{}))
+			assert.Len(t, recorder.Events, tt.testExpectedEvents)
+			// Registered is emitted on both the CAPI Secret and the ArgoSecret.
+			assert.Contains(t, <-recorder.Events, "Normal "+EventReasonRegistered)
+			assert.Contains(t, <-recorder.Events, "Normal "+EventReasonRegistered)
+			if !tt.testDeployment {
+				assert.Contains(t, <-recorder.Events, "Warning ArgoNamespaceInvalid")
+			}
+		})
+	}
+}
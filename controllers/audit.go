@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditAction represents the kind of mutation recorded in the audit log.
+type AuditAction string
+
+// AuditOutcome represents the result of an audited mutation.
+type AuditOutcome string
+
+const (
+	// AuditActionCreate is recorded when an ArgoSecret gets created.
+	AuditActionCreate AuditAction = "Create"
+	// AuditActionUpdate is recorded when an ArgoSecret gets updated.
+	AuditActionUpdate AuditAction = "Update"
+	// AuditActionDelete is recorded when an ArgoSecret gets deleted.
+	AuditActionDelete AuditAction = "Delete"
+
+	// AuditOutcomeSuccess means the mutation got applied.
+	AuditOutcomeSuccess AuditOutcome = "Success"
+	// AuditOutcomeFailure means the mutation failed.
+	AuditOutcomeFailure AuditOutcome = "Failure"
+
+	// AuditOutputStdout writes audit entries to the process stdout.
+	AuditOutputStdout = "stdout"
+
+	// serviceAccountTokenPath is where the operator ServiceAccount token gets mounted.
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// AuditEntry is a single audit log record. It must never hold secret values,
+// DiffSummary only lists the names of the fields that changed.
+type AuditEntry struct {
+	Timestamp         time.Time    `json:"timestamp"`
+	Action            AuditAction  `json:"action"`
+	OperatorUser      string       `json:"operator-user"`
+	ResourceName      string       `json:"resource-name"`
+	ResourceNamespace string       `json:"resource-namespace"`
+	ClusterName       string       `json:"cluster-name"`
+	DiffSummary       []string     `json:"diff-summary"`
+	Outcome           AuditOutcome `json:"outcome"`
+}
+
+// AuditLogger records mutations of ArgoSecrets on a dedicated stream.
+type AuditLogger interface {
+	Record(entry AuditEntry)
+}
+
+// NoopAuditLogger discards all audit entries.
+type NoopAuditLogger struct{}
+
+// Record implements AuditLogger.
+func (NoopAuditLogger) Record(AuditEntry) {}
+
+// FileAuditLogger writes audit entries as JSON lines.
+type FileAuditLogger struct {
+	mu           sync.Mutex
+	w            io.Writer
+	operatorUser string
+	now          func() time.Time
+}
+
+// NewFileAuditLogger returns a FileAuditLogger writing to w on behalf of operatorUser.
+func NewFileAuditLogger(w io.Writer, operatorUser string) *FileAuditLogger {
+	return &FileAuditLogger{
+		w:            w,
+		operatorUser: operatorUser,
+		now:          time.Now,
+	}
+}
+
+// Record implements AuditLogger.
+func (l *FileAuditLogger) Record(entry AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = l.now().UTC()
+	}
+	if entry.OperatorUser == "" {
+		entry.OperatorUser = l.operatorUser
+	}
+	if entry.DiffSummary == nil {
+		entry.DiffSummary = []string{}
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_, _ = l.w.Write(append(b, '\n'))
+}
+
+// OpenAuditLogger returns the AuditLogger matching the given configuration.
+// Output `stdout` takes precedence over file, and when neither is set audit entries are dropped.
+func OpenAuditLogger(output, file, operatorUser string) (AuditLogger, error) {
+	switch {
+	case output == AuditOutputStdout:
+		return NewFileAuditLogger(os.Stdout, operatorUser), nil
+	case output != "":
+		return nil, errors.New("unsupported audit log output: " + output)
+	case file != "":
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileAuditLogger(f, operatorUser), nil
+	}
+	return NoopAuditLogger{}, nil
+}
+
+// OperatorServiceAccount returns the operator ServiceAccount username, as read from
+// the `sub` claim of its mounted token, or "unknown" when running out of cluster.
+func OperatorServiceAccount() string {
+	return serviceAccountFromToken(serviceAccountTokenPath)
+}
+
+func serviceAccountFromToken(path string) string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "unknown"
+	}
+	parts := strings.Split(strings.TrimSpace(string(raw)), ".")
+	if len(parts) != 3 {
+		return "unknown"
+	}
+	payload, err := b64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "unknown"
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return "unknown"
+	}
+	return claims.Subject
+}
+
+// auditOutcome maps a mutation error to an AuditOutcome.
+func auditOutcome(err error) AuditOutcome {
+	if err != nil {
+		return AuditOutcomeFailure
+	}
+	return AuditOutcomeSuccess
+}
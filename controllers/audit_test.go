@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MockAuditLogger keeps audit entries in memory.
+type MockAuditLogger struct {
+	mu      sync.Mutex
+	Entries []AuditEntry
+}
+
+// Record implements AuditLogger.
+func (m *MockAuditLogger) Record(entry AuditEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Entries = append(m.Entries, entry)
+}
+
+func TestFileAuditLogger(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l := NewFileAuditLogger(&buf, "system:serviceaccount:capi:capi2argo")
+	l.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	l.Record(AuditEntry{
+		Action:            AuditActionUpdate,
+		ResourceName:      "cluster-test",
+		ResourceNamespace: "argocd",
+		ClusterName:       "test",
+		DiffSummary:       []string{"data.config"},
+		Outcome:           auditOutcome(nil),
+	})
+	l.Record(AuditEntry{Action: AuditActionDelete, Outcome: auditOutcome(errors.New("boom"))})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "2024-01-02T03:04:05Z", entry["timestamp"])
+	assert.Equal(t, "Update", entry["action"])
+	assert.Equal(t, "system:serviceaccount:capi:capi2argo", entry["operator-user"])
+	assert.Equal(t, "cluster-test", entry["resource-name"])
+	assert.Equal(t, "argocd", entry["resource-namespace"])
+	assert.Equal(t, "test", entry["cluster-name"])
+	assert.Equal(t, []interface{}{"data.config"}, entry["diff-summary"])
+	assert.Equal(t, "Success", entry["outcome"])
+
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "Failure", entry["outcome"])
+	assert.Equal(t, []interface{}{}, entry["diff-summary"])
+}
+
+func TestOpenAuditLogger(t *testing.T) {
+	t.Parallel()
+	l, err := OpenAuditLogger("", "", "user")
+	assert.Nil(t, err)
+	assert.IsType(t, NoopAuditLogger{}, l)
+
+	l, err = OpenAuditLogger(AuditOutputStdout, "", "user")
+	assert.Nil(t, err)
+	assert.IsType(t, &FileAuditLogger{}, l)
+
+	_, err = OpenAuditLogger("stderr", "", "user")
+	assert.NotNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err = OpenAuditLogger("", path, "user")
+	assert.Nil(t, err)
+	l.Record(AuditEntry{Action: AuditActionCreate})
+	raw, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), `"action":"Create"`)
+}
+
+func TestServiceAccountFromToken(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	payload := b64.RawURLEncoding.EncodeToString([]byte(`{"sub":"system:serviceaccount:capi:capi2argo"}`))
+	valid := filepath.Join(dir, "token")
+	assert.Nil(t, os.WriteFile(valid, []byte("e30."+payload+".c2ln"), 0o600))
+	invalid := filepath.Join(dir, "invalid")
+	assert.Nil(t, os.WriteFile(invalid, []byte("not-a-jwt"), 0o600))
+
+	assert.Equal(t, "system:serviceaccount:capi:capi2argo", serviceAccountFromToken(valid))
+	assert.Equal(t, "unknown", serviceAccountFromToken(invalid))
+	assert.Equal(t, "unknown", serviceAccountFromToken(filepath.Join(dir, "missing")))
+}
+
+func TestReconcileAudit(t *testing.T) {
+	ctx := context.Background()
+	audit := &MockAuditLogger{}
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "audit-kubeconfig", TestNamespace)
+	c := MockClient(capiSecret)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Audit: audit}
+	r.Config.EnableGarbageCollection = true
+	req := MockReconcileReq("audit-kubeconfig", TestNamespace)
+
+	// Create.
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	// Update, by changing the ArgoSecret behind the controller back.
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, MockOperatorConfig().BuildNamespacedName("audit-kubeconfig", TestNamespace), &argoSecret))
+	argoSecret.Data["server"] = []byte("https://stale")
+	argoSecret.Data["config"] = []byte("{}")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	// Delete.
+	assert.Nil(t, c.Delete(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	assert.Len(t, audit.Entries, 3)
+	assert.Equal(t, AuditActionCreate, audit.Entries[0].Action)
+	assert.Equal(t, AuditActionUpdate, audit.Entries[1].Action)
+	assert.Equal(t, []string{"data.server", "data.config"}, audit.Entries[1].DiffSummary)
+	assert.Equal(t, AuditActionDelete, audit.Entries[2].Action)
+	for _, e := range audit.Entries {
+		assert.Equal(t, AuditOutcomeSuccess, e.Outcome)
+		assert.Equal(t, "cluster-audit", e.ResourceName)
+		assert.Equal(t, TestArgoNamespace, e.ResourceNamespace)
+		assert.Equal(t, "kube-cluster-test", e.ClusterName)
+	}
+
+	// TLS material must never leak into the audit stream.
+	raw, err := json.Marshal(audit.Entries)
+	assert.Nil(t, err)
+	c2 := NewCapiCluster("audit", TestNamespace)
+	assert.Nil(t, c2.Unmarshal(capiSecret, DefaultKubeConfigKey))
+	assert.NotContains(t, string(raw), c2.KubeConfig.Clusters[0].Cluster.CaData)
+	assert.NotContains(t, string(raw), "tlsClientConfig")
+	assert.NotContains(t, string(raw), "bearerToken")
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	// azureAuthProviderName is the legacy auth provider of AKS kubeconfigs.
+	azureAuthProviderName = "azure"
+	// kubeloginCommand is the Azure credential plugin replacing the azure auth provider.
+	kubeloginCommand = "kubelogin"
+	// AzureLoginModeWorkloadIdentity makes kubelogin authenticate with the Workload Identity of ArgoCD.
+	AzureLoginModeWorkloadIdentity = "workloadidentity"
+	// AzureLoginModeSPN makes kubelogin authenticate as the service principal of the kubeconfig.
+	AzureLoginModeSPN = "spn"
+	// azureDefaultEnvironment is the Azure cloud assumed when a kubeconfig names none.
+	azureDefaultEnvironment = "AzurePublicCloud"
+)
+
+// AzureLogin holds the kubelogin parameters of an AKS kubeconfig user.
+type AzureLogin struct {
+	Environment string
+	ServerID    string
+	ClientID    string
+	TenantID    string
+	Mode        string
+}
+
+// ParseAzureLogin returns the kubelogin parameters of a kubeconfig user authenticating through
+// the azure auth provider or a kubelogin exec plugin, false for other users.
+func ParseAzureLogin(u UserInfo) (*AzureLogin, bool) {
+	if u.Exec != nil && path.Base(u.Exec.Command) == kubeloginCommand {
+		l := &AzureLogin{Mode: "devicecode"}
+		flags := map[string]*string{
+			"--environment": &l.Environment, "-e": &l.Environment,
+			"--server-id": &l.ServerID, "--client-id": &l.ClientID,
+			"--tenant-id": &l.TenantID, "-t": &l.TenantID,
+			"--login": &l.Mode, "-l": &l.Mode,
+		}
+		for i, arg := range u.Exec.Args {
+			name, value, inline := strings.Cut(arg, "=")
+			field, ok := flags[name]
+			if !ok {
+				continue
+			}
+			if inline {
+				*field = value
+			} else if i+1 < len(u.Exec.Args) {
+				*field = u.Exec.Args[i+1]
+			}
+		}
+		return l, true
+	}
+	if u.AuthProvider != nil && u.AuthProvider.Name == azureAuthProviderName {
+		c := u.AuthProvider.Config
+		return &AzureLogin{
+			Environment: c["environment"],
+			ServerID:    c["apiserver-id"],
+			ClientID:    c["client-id"],
+			TenantID:    c["tenant-id"],
+			Mode:        "devicecode",
+		}, true
+	}
+	return nil, false
+}
+
+// ExecConfig returns the kubelogin exec plugin running the login non-interactively with the
+// given mode. Client and tenant are only passed on to service principal logins, other modes
+// take them from the environment of ArgoCD.
+func (l *AzureLogin) ExecConfig(mode string) *ExecConfig {
+	environment := l.Environment
+	if environment == "" {
+		environment = azureDefaultEnvironment
+	}
+	args := []string{"get-token", "--environment", environment, "--server-id", l.ServerID, "--login", mode}
+	if mode == AzureLoginModeSPN {
+		args = append(args, "--client-id", l.ClientID, "--tenant-id", l.TenantID)
+	}
+	return &ExecConfig{
+		APIVersion:  "client.authentication.k8s.io/v1beta1",
+		Command:     kubeloginCommand,
+		Args:        args,
+		InstallHint: "kubelogin is required in the ArgoCD image, see https://azure.github.io/kubelogin",
+	}
+}
+
+// buildUserExecConfig returns the exec plugin of a kubeconfig user. GKE users are converted with
+// buildGKEExecConfig, and AKS users authenticating through the azure auth provider or an
+// interactive kubelogin to a kubelogin running with OperatorConfig.AzureLoginMode. Other auth
+// providers are reported as warnings.
+func buildUserExecConfig(rc *ReconcileContext, u UserInfo) (*ExecConfig, []string) {
+	if exec, ok := buildGKEExecConfig(u); ok {
+		return exec, nil
+	}
+	login, ok := ParseAzureLogin(u)
+	if !ok {
+		if u.AuthProvider != nil {
+			return u.Exec, []string{"Warning: unsupported auth-provider '" + u.AuthProvider.Name + "' in KubeConfig. Ignoring"}
+		}
+		return u.Exec, nil
+	}
+	if u.Exec != nil && !slices.Contains([]string{"devicecode", "interactive"}, login.Mode) {
+		return u.Exec, nil
+	}
+	if login.ServerID == "" {
+		return u.Exec, []string{"Warning: AKS KubeConfig without apiserver-id, cannot convert to kubelogin. Ignoring"}
+	}
+	return login.ExecConfig(rc.Config.AzureLoginMode), nil
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAzureLogin(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           UserInfo
+		testExpectedValues *AzureLogin
+	}{
+		{"test azure auth-provider", UserInfo{AuthProvider: &AuthProviderConfig{Name: "azure", Config: map[string]string{
+			"apiserver-id": "server", "client-id": "client", "tenant-id": "tenant", "environment": "AzureChinaCloud"}}},
+			&AzureLogin{Environment: "AzureChinaCloud", ServerID: "server", ClientID: "client", TenantID: "tenant", Mode: "devicecode"}},
+		{"test kubelogin exec", UserInfo{Exec: &ExecConfig{Command: "/usr/local/bin/kubelogin", Args: []string{
+			"get-token", "--environment", "AzurePublicCloud", "--server-id", "server", "--client-id=client", "-t", "tenant", "--login", "spn"}}},
+			&AzureLogin{Environment: "AzurePublicCloud", ServerID: "server", ClientID: "client", TenantID: "tenant", Mode: "spn"}},
+		{"test kubelogin exec default login", UserInfo{Exec: &ExecConfig{Command: "kubelogin", Args: []string{"get-token", "--server-id", "server"}}},
+			&AzureLogin{ServerID: "server", Mode: "devicecode"}},
+		{"test other exec", UserInfo{Exec: &ExecConfig{Command: "aws", Args: []string{"eks", "get-token"}}}, nil},
+		{"test other auth-provider", UserInfo{AuthProvider: &AuthProviderConfig{Name: "gcp"}}, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			login, ok := ParseAzureLogin(tt.testMock)
+			assert.Equal(t, tt.testExpectedValues != nil, ok)
+			assert.Equal(t, tt.testExpectedValues, login)
+		})
+	}
+}
+
+func TestAKSKubeConfig(t *testing.T) {
+	t.Parallel()
+	kubeConfigPrefix := "apiVersion: v1\nkind: Config\nclusters:\n- name: aks\n  cluster:\n    server: https://aks.hcp.westeurope.azmk8s.io:443\n    certificate-authority-data: Y2E=\n" +
+		"users:\n- name: clusterUser_aks\n  user:\n"
+	tests := []struct {
+		testName           string
+		testMock           string
+		testLoginMode      string
+		testExpectedValues *ArgoExecProviderConfig
+	}{
+		{"test azure auth-provider", "    auth-provider:\n      name: azure\n      config:\n        apiserver-id: server\n        client-id: client\n        tenant-id: tenant\n        environment: AzurePublicCloud\n        config-mode: '1'\n",
+			AzureLoginModeWorkloadIdentity, &ArgoExecProviderConfig{Command: "kubelogin", APIVersion: "client.authentication.k8s.io/v1beta1",
+				Args: []string{"get-token", "--environment", "AzurePublicCloud", "--server-id", "server", "--login", "workloadidentity"}}},
+		{"test interactive kubelogin as spn", "    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: kubelogin\n      args: [get-token, --server-id, server, --client-id, client, --tenant-id, tenant, --login, devicecode]\n",
+			AzureLoginModeSPN, &ArgoExecProviderConfig{Command: "kubelogin", APIVersion: "client.authentication.k8s.io/v1beta1",
+				Args: []string{"get-token", "--environment", "AzurePublicCloud", "--server-id", "server", "--login", "spn", "--client-id", "client", "--tenant-id", "tenant"}}},
+		{"test non-interactive kubelogin kept", "    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: kubelogin\n      args: [get-token, --server-id, server, --login, msi]\n",
+			AzureLoginModeWorkloadIdentity, &ArgoExecProviderConfig{Command: "kubelogin", APIVersion: "client.authentication.k8s.io/v1beta1",
+				Args: []string{"get-token", "--server-id", "server", "--login", "msi"}}},
+		{"test azure auth-provider without apiserver-id", "    auth-provider:\n      name: azure\n", AzureLoginModeWorkloadIdentity, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := MockCapiSecret(validMock, validType, validKey, "aks-kubeconfig", "test")
+			s.Data["value"] = []byte(kubeConfigPrefix + tt.testMock)
+			c := NewCapiCluster("aks", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			config := MockOperatorConfig()
+			config.AzureLoginMode = tt.testLoginMode
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, nil)
+			assert.Nil(t, err)
+			if tt.testExpectedValues != nil {
+				tt.testExpectedValues.InstallHint = a.ClusterConfig.ExecProviderConfig.InstallHint
+			}
+			assert.Equal(t, tt.testExpectedValues, a.ClusterConfig.ExecProviderConfig)
+			assert.Nil(t, a.ClusterConfig.BearerToken)
+			assert.Nil(t, a.ClusterConfig.TLSClientConfig.CertData)
+
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			var argoConfig map[string]interface{}
+			assert.Nil(t, json.Unmarshal(argoSecret.Data["config"], &argoConfig))
+			assert.Equal(t, "Y2E=", argoConfig["tlsClientConfig"].(map[string]interface{})["caData"])
+		})
+	}
+}
@@ -3,59 +3,117 @@ package controllers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	goErr "errors"
-	"os"
-	"strconv"
+	"fmt"
 
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-var (
-	// EnableGarbageCollection enables experimental GC feature
-	EnableGarbageCollection bool
-
-	// EnableNamespacedNames represents a mode where the cluster name is always
-	// prepended by the cluster namespace in all generated secrets
-	EnableNamespacedNames bool
+const (
+	// firstSyncedAtAnnotation holds the time the ArgoSecret got created.
+	firstSyncedAtAnnotation = "capi-to-argocd/first-synced-at"
+	// lastUpdatedAtAnnotation holds the time the ArgoSecret got last written.
+	lastUpdatedAtAnnotation = "capi-to-argocd/last-updated-at"
+	// syncedByVersionAnnotation holds the operator version that last wrote the ArgoSecret.
+	syncedByVersionAnnotation = "capi-to-argocd/synced-by-version"
+	// lastSyncedAtAnnotation holds the time the current content of the CAPI Secret got synced.
+	lastSyncedAtAnnotation = "capi-to-argocd/last-synced-at"
+	// pausedRequeueAfter is how often paused clusters get checked for being resumed, as CAPI
+	// Cluster changes do not trigger reconciles.
+	pausedRequeueAfter = time.Minute
+	// readinessGatesRequeueAfter is how often clusters with unmet readiness gates get checked again.
+	readinessGatesRequeueAfter = 30 * time.Second
+	// sourceGenerationAnnotation holds the generation of the CAPI Secret that produced the ArgoSecret.
+	sourceGenerationAnnotation = "capi-to-argocd/source-generation"
+	// sourceUIDAnnotation holds the UID of the CAPI Secret that produced the ArgoSecret, as
+	// generations of different Secrets cannot be compared.
+	sourceUIDAnnotation = "capi-to-argocd/source-uid"
 )
 
-func init() {
-	// Dummy configuration init.
-	// TODO: Handle this as part of root config.
-	ArgoNamespace = os.Getenv("ARGOCD_NAMESPACE")
-	if ArgoNamespace == "" {
-		ArgoNamespace = "argocd"
-	}
-
-	EnableGarbageCollection, _ = strconv.ParseBool(os.Getenv("ENABLE_GARBAGE_COLLECTION"))
-	EnableNamespacedNames, _ = strconv.ParseBool(os.Getenv("ENABLE_NAMESPACED_NAMES"))
-}
-
 // Capi2Argo reconciles a Secret object
 type Capi2Argo struct {
+	// Client reads CAPI resources (kubeconfig Secrets and Clusters).
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	// ArgoClient reads and writes ArgoCD cluster Secrets. Defaults to Client when nil.
+	ArgoClient client.Client
+	// SourceCluster is the remote management cluster watched for CAPI Secrets.
+	// When nil, CAPI Secrets are watched on the manager cluster.
+	SourceCluster cluster.Cluster
+	// PlatformCluster is the remote cluster running ArgoCD, that ArgoClient reads and writes
+	// ArgoCD cluster Secrets on. When nil, ArgoCD cluster Secrets live on the manager cluster.
+	PlatformCluster cluster.Cluster
+	Log             logr.Logger
+	Scheme          *runtime.Scheme
+	// Config holds the operator configuration.
+	Config OperatorConfig
+	// SealedSecretKeys provides the sealing key when Config.OutputFormat is sealed-secret.
+	SealedSecretKeys *SealedSecretKeyCache
+	// Audit records every ArgoSecret mutation. Nothing is recorded when nil.
+	Audit AuditLogger
+	// Changelog keeps the last changes of every ArgoSecret. Nothing is recorded when nil.
+	Changelog *ChangelogRecorder
+	// Recorder emits Kubernetes events. No events are emitted when nil.
+	Recorder record.EventRecorder
+	// SourceRecorder emits events on CAPI resources. Defaults to Recorder when nil.
+	SourceRecorder record.EventRecorder
+	// ArgoNamespaceValidator reports whether ArgoNamespace runs ArgoCD. Not checked when nil.
+	ArgoNamespaceValidator *ArgoNamespaceValidator
+	// Pipeline transforms take-along label values in order, before they are written to ArgoSecrets.
+	Pipeline []ValueTransformer
+	// Triggers receives CAPI Secrets to reconcile out of band, e.g. from the EndpointHealthChecker.
+	Triggers <-chan event.GenericEvent
+
+	// now returns the current time, defaults to time.Now.
+	now func() time.Time
 }
 
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=services/proxy,verbs=get
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters/status,verbs=get;update
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinedeployments;machinepools,verbs=list
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=list
+// +kubebuilder:rbac:groups=bitnami.com,resources=sealedsecrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=list
+// +kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=*,verbs=get
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=*,verbs=get
+// +kubebuilder:rbac:groups=capi2argo.dntosas.io,resources=argoclusterdefaults,verbs=get;list;watch
 
 // Reconcile holds all the logic for syncing CAPI to Argo Clusters.
 func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("secret", req.NamespacedName)
 
-	// TODO: Check if secret is on allowed Namespaces.
+	// Skip CAPI Secrets of namespaces left out by the namespace filters.
+	if !r.Config.WatchesNamespace(req.Namespace) {
+		return ctrl.Result{}, nil
+	}
 
 	// Validate Secret.Metadata.Name complies with CAPI pattern: <clusterName>-kubeconfig
 	if !ValidateCapiNaming(req.NamespacedName) {
@@ -72,75 +130,307 @@ func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resul
 		}
 
 		// If secret is deleted and GC is enabled, mark ArgoSecret for deletion.
-		if EnableGarbageCollection {
-			labelSelector := map[string]string{
-				"capi-to-argocd/cluster-secret-name": req.NamespacedName.Name,
-				"capi-to-argocd/cluster-namespace":   req.NamespacedName.Namespace,
-			}
-			listOption := client.MatchingLabels(labelSelector)
-			secretList := &corev1.SecretList{}
-			err = r.List(context.Background(), secretList, listOption)
-			if err != nil {
-				log.Error(err, "Failed to list Cluster Secrets")
-				return ctrl.Result{}, err
-			}
-			if err := r.Delete(ctx, &secretList.Items[0]); err != nil {
-				log.Error(err, "Failed to delete ArgoSecret")
+		// Secrets deleted by clusterctl move keep their ArgoSecret, the target takes it over.
+		if r.Config.EnableGarbageCollection {
+			moved, err := r.isMovedCapiSecret(ctx, log, req.NamespacedName, nil)
+			if err != nil || moved {
 				return ctrl.Result{}, err
 			}
-			log.Info("Deleted successfully of ArgoSecret")
-			return ctrl.Result{}, nil
+			return r.deleteArgoSecrets(ctx, log, req.NamespacedName)
 		}
 
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 	log.Info("Fetched CapiSecret")
 
+	// Secrets held by the deletion protection finalizer are only gone once it got removed.
+	if !capiSecret.DeletionTimestamp.IsZero() {
+		return r.finalizeCapiSecret(ctx, log, &capiSecret)
+	}
+
+	// Kubeconfigs of hosted control planes are converted like CAPI Secrets, when their provider is enabled.
+	hosted, isHosted := ParseHostedControlPlane(&capiSecret)
+	if isHosted && !r.Config.HostedControlPlaneEnabled(hosted.Provider.Name) {
+		return ctrl.Result{}, nil
+	}
+	if isHosted {
+		shadowed, err := r.shadowedByCapiSecret(ctx, &capiSecret, hosted)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if shadowed {
+			log.Info("Hosted control plane is managed through CAPI, skipping", "provider", hosted.Provider.Name)
+			return ctrl.Result{}, nil
+		}
+	}
+
 	// Validate CapiSecret.type is matching CAPI convention.
 	// if capiSecret.Type != "cluster.x-k8s.io/secret" {
-	err = ValidateCapiSecret(&capiSecret)
+	kubeConfigKey := KubeConfigKey(&capiSecret, r.Config.KubeConfigKey)
+	if isHosted {
+		kubeConfigKey = KubeConfigKey(&capiSecret, hosted.Provider.Key)
+	}
+	err = ValidateCapiSecret(&capiSecret, kubeConfigKey)
 	if err != nil {
 		log.Info("Ignoring secret as it's missing proper CAPI type", "type", capiSecret.Type)
 		return ctrl.Result{}, err
 	}
 
+	if r.Config.EnableDeletionProtection && controllerutil.AddFinalizer(&capiSecret, deletionProtectionFinalizer) {
+		if err := r.Update(ctx, &capiSecret); err != nil {
+			log.Error(err, "Failed to add deletion protection finalizer to CapiSecret")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Hosted control planes have no CAPI Cluster.
+	var clusterObject CAPICluster
+	if !isHosted {
+		clusterObject, err = GetCAPICluster(ctx, r.Client, r.Config.ClusterAPIVersion, types.NamespacedName{Name: capiSecret.Labels[clusterv1.ClusterNameLabel], Namespace: req.Namespace})
+		if err != nil {
+			log.Info("Failed to get Cluster object", "error", err)
+		}
+	}
+
+	// Clusters opted out of registration lose their ArgoSecrets.
+	if IsRegistrationSkipped(&capiSecret, clusterObject) {
+		log.Info("Cluster opted out of registration, removing ArgoSecrets", "annotation", skipAnnotation)
+		return r.deregisterSkippedCluster(ctx, log, &capiSecret)
+	}
+
 	// Construct CapiCluster from CapiSecret.
 	nn := strings.TrimSuffix(req.NamespacedName.Name, "-kubeconfig")
+	if isHosted {
+		nn = hosted.Name
+	}
 	ns := req.NamespacedName.Namespace
+
+	// The management cluster ArgoCD runs on is either skipped, or registered as in-cluster below.
+	isManagementCluster := r.Config.IsManagementCluster(ns, nn)
+	if isManagementCluster && r.Config.SelfRegistration == SelfRegistrationSkip {
+		log.Info("Cluster is the management cluster, removing ArgoSecrets", "selfRegistration", r.Config.SelfRegistration)
+		return r.deregisterSkippedCluster(ctx, log, &capiSecret)
+	}
+
+	// In registration mode, only clusters declared by an ArgoClusterRegistration get registered.
+	var registration *ClusterRegistration
+	if r.Config.EnableClusterRegistrations {
+		registration, err = r.readClusterRegistration(ctx, log, ns, nn)
+		if err != nil {
+			log.Error(err, "Failed to read ArgoClusterRegistrations")
+			return ctrl.Result{}, err
+		}
+		if registration == nil {
+			log.Info("Cluster has no ArgoClusterRegistration, removing ArgoSecrets")
+			return r.deregisterSkippedCluster(ctx, log, &capiSecret)
+		}
+	}
+
+	// Only clusters matching the cluster selector and infrastructure kinds get registered, hosted control planes have no Cluster to match.
+	if !isHosted {
+		matches, err := r.Config.MatchesClusterSelector(clusterObject)
+		if err != nil {
+			log.Error(err, "Failed to parse cluster selector")
+			return ctrl.Result{}, err
+		}
+		if !matches {
+			log.Info("Cluster does not match the cluster selector, skipping", "selector", r.Config.ClusterSelector)
+			return ctrl.Result{}, nil
+		}
+		if !r.Config.MatchesInfrastructureKind(clusterObject) {
+			log.Info("Cluster infrastructure kind is not allowed, skipping", "kinds", r.Config.InfrastructureKinds)
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// In opt-in mode, only clusters labeled as enabled get registered.
+	if r.Config.OptInRegistration && !IsRegistrationEnabled(&capiSecret, clusterObject) {
+		log.Info("Cluster did not opt into registration, skipping", "label", enabledLabel)
+		return ctrl.Result{}, nil
+	}
+
 	capiCluster := NewCapiCluster(nn, ns)
-	err = capiCluster.Unmarshal(&capiSecret)
+	kubeConfigSecret, err := r.kubeconfigSource(ctx, &capiSecret)
+	if err != nil {
+		log.Error(err, "Failed to fetch user kubeconfig Secret")
+		return ctrl.Result{}, err
+	}
+	err = capiCluster.Unmarshal(kubeConfigSecret, kubeConfigKey)
 	if err != nil {
 		log.Error(err, "Failed to unmarshal CapiCluster")
+		r.registrationEvent(&capiSecret, nil, nil, corev1.EventTypeWarning, EventReasonConversionFailed, "Failed to read kubeconfig: "+err.Error())
 		return ctrl.Result{}, err
 	}
+	// Kubeconfigs of hosted control planes have a generic cluster name.
+	if isHosted {
+		capiCluster.KubeConfig.Clusters[0].Name = hosted.Name
+	}
 
-	clusterObject := &clusterv1.Cluster{}
-	err = r.Get(ctx, types.NamespacedName{Name: capiSecret.Labels[clusterv1.ClusterNameLabel], Namespace: req.Namespace}, clusterObject)
-	if err != nil {
-		log.Info("Failed to get Cluster object", "error", err)
+	// Leave the ArgoSecret alone during maintenance and pivot operations, like other CAPI controllers.
+	if IsClusterPaused(clusterObject) {
+		log.Info("Cluster is paused, skipping", "after", pausedRequeueAfter)
+		return ctrl.Result{RequeueAfter: pausedRequeueAfter}, nil
+	}
+
+	// Hold back ArgoSecret writes until the topology version of the Cluster got approved.
+	if r.Config.RequireTopologyVersionAnnotation {
+		blocked, err := r.checkTopologyVersion(ctx, log, &capiSecret, clusterObject)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if blocked {
+			return ctrl.Result{RequeueAfter: topologyVersionRequeueAfter}, nil
+		}
 	}
 
 	// Construct ArgoCluster from CapiCluster and CapiSecret.Metadata.
-	argoCluster, err := NewArgoCluster(capiCluster, &capiSecret, clusterObject)
+	rc := NewReconcileContext(ctx, log, r.Config, r.Recorder)
+	rc.Pipeline = r.Pipeline
+	if r.Config.EnableClusterDefaults {
+		defaults, err := r.readClusterDefaults(ctx, log, req.Namespace)
+		if err != nil {
+			log.Error(err, "Failed to read ArgoClusterDefaults")
+			return ctrl.Result{}, err
+		}
+		if defaults != nil {
+			defaults.Apply(rc)
+		}
+	}
+	if r.Config.EnableTenantMappings {
+		tenant, err := r.readTenantMapping(ctx, log, req.Namespace, clusterObject)
+		if err != nil {
+			log.Error(err, "Failed to read ArgoTenantMappings")
+			return ctrl.Result{}, err
+		}
+		rc.Tenant = tenant
+	}
+	if registration != nil {
+		for _, w := range registration.Apply(rc) {
+			log.Info(w)
+		}
+	}
+	r.applyNamespacedNames(ctx, rc, clusterObject, req.Namespace)
+	argoCluster, err := NewArgoCluster(rc, capiCluster, &capiSecret, clusterObject)
+	var tooYoung *ClusterTooYoungError
+	if goErr.As(err, &tooYoung) {
+		// Requeue once the cluster is old enough, bypassing the error backoff.
+		log.Info("Cluster is younger than the minimum age, requeueing", "after", tooYoung.Remaining)
+		return ctrl.Result{RequeueAfter: tooYoung.Remaining}, nil
+	}
+	if goErr.Is(err, ErrInsecureCluster) {
+		// Retrying does not help until the kubeconfig or the operator config changes.
+		log.Error(err, "Refusing to register insecure cluster")
+		r.sourceEvent(&capiSecret, clusterObject, corev1.EventTypeWarning, "InsecureClusterRejected", err.Error())
+		return ctrl.Result{}, nil
+	}
 	if err != nil {
 		log.Error(err, "Failed to construct ArgoCluster")
+		r.registrationEvent(&capiSecret, clusterObject, nil, corev1.EventTypeWarning, EventReasonConversionFailed, "Failed to convert to ArgoCluster: "+err.Error())
 		return ctrl.Result{}, err
 	}
+	if isManagementCluster && r.Config.SelfRegistration == SelfRegistrationInCluster {
+		argoCluster.SetInCluster()
+	}
+	if r.Config.TakeAlongNamespaceLabels {
+		mergeTakeAlong(log, argoCluster.TakeAlongLabels, r.readNamespaceTakeAlongLabels(ctx, rc, req.Namespace), clusterTakenFromNamespaceKey)
+	}
+	for _, t := range ReferencedTakeAlongs() {
+		labels, annotations := r.readReferencedTakeAlong(ctx, rc, clusterObject, t)
+		mergeTakeAlong(log, argoCluster.TakeAlongLabels, labels, t.TakenLabelKey)
+		mergeTakeAlong(log, argoCluster.TakeAlongAnnotations, annotations, t.TakenAnnotationKey)
+	}
+	if version := r.clusterKubernetesVersion(ctx, log, clusterObject); version != "" {
+		argoCluster.ClusterLabels[kubernetesVersionLabel] = version
+	}
+	if r.Config.ReadInfrastructureTopology {
+		for k, v := range r.readInfrastructureTopology(ctx, log, clusterObject) {
+			argoCluster.ClusterLabels[k] = v
+		}
+	}
+	if r.Config.ReplicaCountLabels {
+		for k, v := range r.clusterReplicaCounts(ctx, log, clusterObject) {
+			argoCluster.ClusterLabels[k] = v
+		}
+	}
+	if r.Config.TopologyVariableLabels != "" && clusterObject != nil {
+		labels, warnings := BuildTopologyVariableLabels(clusterObject.GetTopologyVariables(), r.Config.TopologyVariables())
+		for _, w := range warnings {
+			log.Info(w)
+		}
+		for k, v := range labels {
+			argoCluster.ClusterLabels[k] = v
+		}
+	}
+	if r.Config.ReadClusterTags {
+		tags, err := r.readClusterTags(ctx, log, capiCluster.Name, capiCluster.Namespace)
+		if err != nil {
+			log.Error(err, "Failed to read cluster tags")
+			return ctrl.Result{}, err
+		}
+		for k, v := range tags {
+			argoCluster.TakeAlongAnnotations[k] = v
+		}
+	}
+
+	// Make sure ArgoCluster does not shadow a cluster of another namespace.
+	if !rc.Config.EnableNamespacedNames {
+		err = r.resolveNameCollision(ctx, log, &capiSecret, argoCluster)
+		if goErr.Is(err, ErrClusterNameCollision) {
+			ClusterNameCollisionsTotal.Inc()
+			r.sourceEvent(&capiSecret, clusterObject, corev1.EventTypeWarning, EventReasonNameCollision, "Refusing to overwrite ArgoSecret: "+err.Error())
+		}
+		if err != nil {
+			log.Error(err, "Failed to resolve ArgoCluster name")
+			return ctrl.Result{}, err
+		}
+	}
 
 	// Convert ArgoCluster into ArgoSecret to work natively on k8s objects.
 	log = r.Log.WithValues("cluster", argoCluster.NamespacedName)
 	argoSecret, err := argoCluster.ConvertToSecret()
 	if err != nil {
 		log.Error(err, "Failed to convert ArgoCluster to ArgoSecret")
+		r.registrationEvent(&capiSecret, clusterObject, nil, corev1.EventTypeWarning, EventReasonConversionFailed, "Failed to convert to ArgoSecret "+argoCluster.NamespacedName.String()+": "+err.Error())
 		return ctrl.Result{}, err
 	}
 
+	// Kubeconfigs get published before the API server is reachable, hold back the registration until then.
+	// Clusters registered already are kept up to date.
+	if clusterObject != nil {
+		if unmet := UnmetConditions(clusterObject, r.Config.RequiredConditions()); len(unmet) > 0 {
+			registered, err := r.isRegistered(ctx, argoCluster.NamespacedName)
+			if err != nil {
+				log.Error(err, "Failed to fetch ArgoSecret to check if exists")
+				return ctrl.Result{}, err
+			}
+			if !registered {
+				log.Info("Readiness gates of Cluster are not met yet, requeueing", "conditions", unmet, "after", readinessGatesRequeueAfter)
+				r.registrationEvent(&capiSecret, clusterObject, nil, corev1.EventTypeNormal, EventReasonSkippedNotReady, "Holding back ArgoSecret "+argoCluster.NamespacedName.String()+" until conditions are True: "+strings.Join(unmet, ", "))
+				return ctrl.Result{RequeueAfter: readinessGatesRequeueAfter}, nil
+			}
+		}
+	}
+
+	if r.Config.OutputFormat == OutputFormatSealedSecret {
+		return r.reconcileSealedSecret(ctx, log, &capiSecret, clusterObject, argoCluster, argoSecret)
+	}
+
+	// ArgoSecrets under the names of a previous naming scheme get replaced once the current one is written.
+	var stale []corev1.Secret
+	if r.Config.MigrateSecretNames {
+		stale, err = r.staleArgoSecrets(ctx, &capiSecret, argoCluster)
+		if err != nil {
+			log.Error(err, "Failed to list ArgoSecrets of previous naming schemes")
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Represent a possible existing ArgoSecret.
 	var existingSecret corev1.Secret
 	var exists bool
 
 	// Check if ArgoSecret exists.
-	err = r.Get(ctx, argoCluster.NamespacedName, &existingSecret)
+	err = r.argoClient().Get(ctx, argoCluster.NamespacedName, &existingSecret)
 	if errors.IsNotFound(err) {
 		exists = false
 		log.Info("ArgoSecret does not exists, creating..")
@@ -152,6 +442,11 @@ func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resul
 		return ctrl.Result{}, err
 	}
 
+	// Hashes of the CAPI Secret and the rendered config let metadata-only changes skip the credentials.
+	metadataHash := MetadataHash(&capiSecret)
+	credentialsHash := CredentialsHash(argoSecret.Data["config"])
+	sourceHash := SourceHash(&capiSecret)
+
 	// Reconcile ArgoSecret:
 	// - If does not exists:
 	//     1) Create it.
@@ -160,37 +455,117 @@ func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resul
 	//     2) If it is controller-managed, check if updates needed and apply them.
 	switch exists {
 	case false:
-		if err := r.Create(ctx, argoSecret); err != nil {
+		r.setLifecycleAnnotations(argoSecret, true)
+		for i := range stale {
+			if stale[i].Labels[argoSecretTypeLabel] == reservedLabels[argoSecretTypeLabel] {
+				CarryForeignMetadata(&stale[i], argoSecret)
+			}
+		}
+		setSourceGeneration(argoSecret, &capiSecret)
+		r.setSourceHash(argoSecret, sourceHash)
+		if r.Config.SkipTLSRotationIfMatching {
+			setSecretHashes(argoSecret, metadataHash, credentialsHash)
+		}
+		if err := r.reconcileTokenSecret(ctx, log, argoCluster); err != nil {
+			return ctrl.Result{}, err
+		}
+		err := r.argoClient().Create(ctx, argoSecret)
+		r.auditRecord(AuditActionCreate, argoSecret, argoCluster.ClusterName, secretFieldNames(argoSecret), err)
+		r.changelogRecord(AuditActionCreate, argoSecret, secretFieldNames(argoSecret), capiSecret.ResourceVersion, err)
+		if err != nil {
 			log.Error(err, "Failed to create ArgoSecret")
 			return ctrl.Result{}, err
 		}
 		log.Info("Created new ArgoSecret")
-		return ctrl.Result{}, nil
+		if argoCluster.NameTruncated {
+			ClusterNameTruncatedTotal.Inc()
+		}
+		if err := r.deleteStaleArgoSecrets(ctx, log, argoCluster, stale); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.registrationEvent(&capiSecret, clusterObject, argoSecret, corev1.EventTypeNormal, EventReasonRegistered, "Registered as ArgoSecret "+argoCluster.NamespacedName.String())
+		if r.ArgoNamespaceValidator != nil && !r.ArgoNamespaceValidator.Valid() {
+			r.event(argoSecret, corev1.EventTypeWarning, "ArgoNamespaceInvalid", "No ArgoCD server Deployment found in namespace "+argoSecret.Namespace)
+		}
+		return ctrl.Result{}, r.completeRegistration(ctx, log, clusterObject, argoCluster, argoSecret)
 
 	case true:
 
 		log.Info("Checking if ArgoSecret is managed by the Controller")
-		err := ValidateObjectOwner(existingSecret)
+		err := r.Config.ValidateOwner(existingSecret)
 		if err != nil {
 			log.Info("Not managed by Controller, skipping...")
 			return ctrl.Result{}, nil
 		}
+		if err := r.deleteStaleArgoSecrets(ctx, log, argoCluster, stale); err != nil {
+			return ctrl.Result{}, err
+		}
 
 		log.Info("Checking if ArgoSecret is out-of-sync with")
+		original := existingSecret.DeepCopy()
 		changed := false
+		diff := []string{}
+		credentialsMatch := r.Config.SkipTLSRotationIfMatching && existingSecret.Annotations[credentialsHashAnnotation] == credentialsHash
 		if !bytes.Equal(existingSecret.Data["name"], []byte(argoCluster.ClusterName)) {
 			existingSecret.Data["name"] = []byte(argoCluster.ClusterName)
 			changed = true
+			diff = append(diff, "data.name")
 		}
 
 		if !bytes.Equal(existingSecret.Data["server"], []byte(argoCluster.ClusterServer)) {
 			existingSecret.Data["server"] = []byte(argoCluster.ClusterServer)
 			changed = true
+			diff = append(diff, "data.server")
 		}
 
-		if !bytes.Equal(existingSecret.Data["config"], []byte(argoSecret.Data["config"])) {
+		// Optional fields are only present on the ArgoSecret when set.
+		for _, k := range []string{"project", "namespaces", "clusterResources", "shard"} {
+			if bytes.Equal(existingSecret.Data[k], argoSecret.Data[k]) {
+				continue
+			}
+			if v, ok := argoSecret.Data[k]; ok {
+				existingSecret.Data[k] = v
+			} else {
+				delete(existingSecret.Data, k)
+			}
+			changed = true
+			diff = append(diff, "data."+k)
+		}
+
+		if !credentialsMatch && !bytes.Equal(existingSecret.Data["config"], []byte(argoSecret.Data["config"])) {
+			var existingConfig ArgoConfig
+			_ = json.Unmarshal(existingSecret.Data["config"], &existingConfig)
+			for _, d := range DiffArgoConfig(existingConfig, argoCluster.ClusterConfig) {
+				log.V(1).Info("ArgoSecret config field changed", "field", d.Field, "old", d.OldValue, "new", d.NewValue)
+			}
 			existingSecret.Data["config"] = []byte(argoSecret.Data["config"])
 			changed = true
+			diff = append(diff, "data.config")
+		}
+
+		// Labels and annotations added by other tools are kept, only operator-owned keys are enforced.
+		ownedLabels := GetArgoCommonLabels().ToMap()
+		for k, v := range argoCluster.DefaultLabels {
+			if _, ok := argoCluster.TakeAlongLabels[k]; !ok {
+				ownedLabels[k] = v
+			}
+		}
+		for k, v := range argoCluster.ClusterLabels {
+			ownedLabels[k] = v
+		}
+		for k := range existingSecret.Labels {
+			if strings.HasPrefix(k, failureDomainLabelPrefix) || strings.HasPrefix(k, topologyVariableLabelPrefix) {
+				if _, ok := ownedLabels[k]; !ok {
+					delete(existingSecret.Labels, k)
+					changed = true
+					diff = append(diff, "labels."+k)
+				}
+			}
+		}
+		for _, k := range MergeOwnedLabels(existingSecret.Labels, ownedLabels) {
+			log.Info("Restoring operator-owned label in ArgoSecret", "label", k)
+			changed = true
+			diff = append(diff, "labels."+k)
 		}
 
 		// Check if take-along labels from argoCluster.TakeAlongLabels exist existingSecret.Labels and have the same values.
@@ -214,12 +589,32 @@ func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resul
 					delete(existingSecret.Labels, k)
 					delete(existingSecret.Labels, key)
 					changed = true
+					diff = append(diff, "labels."+k, "labels."+key)
+				}
+			}
+		}
+
+		// Likewise for labels no longer taken along from the namespace or referenced objects, unless
+		// taken along from elsewhere now.
+		for k := range existingSecret.Labels {
+			key, takenFrom := trimTakenFromPrefix(k, takenFromLabelPrefixes())
+			if !takenFrom {
+				continue
+			}
+			if _, ok := argoCluster.TakeAlongLabels[k]; !ok {
+				delete(existingSecret.Labels, k)
+				diff = append(diff, "labels."+k)
+				if _, ok := argoCluster.TakeAlongLabels[key]; !ok {
+					delete(existingSecret.Labels, key)
+					diff = append(diff, "labels."+key)
 				}
+				changed = true
 			}
 		}
 
 		// Update secrets labels with current values
-		for k, v := range argoCluster.TakeAlongLabels {
+		takeAlongLabels := r.resolveTakeAlongConflicts(&capiSecret, clusterObject, argoCluster.TakeAlongLabels, existingSecret.Labels)
+		for k, v := range takeAlongLabels {
 			// check if label exists in map
 			if val, ok := existingSecret.Labels[k]; ok {
 				// check if label value is the same
@@ -227,26 +622,132 @@ func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resul
 					log.Info("Updating value of label in ArgoSecret", "label", k, "value", val)
 					existingSecret.Labels[k] = v
 					changed = true
+					diff = append(diff, "labels."+k)
 				}
 			} else {
 				log.Info("Adding missing label in ArgoSecret", "label", k)
 				existingSecret.Labels[k] = v
 				changed = true
+				diff = append(diff, "labels."+k)
 			}
 		}
 
+		// Sync take-along annotations, removing owner references, tags and annotations no longer taken along.
+		stale := []string{}
+		for k := range existingSecret.Annotations {
+			key, takenFrom := trimTakenFromPrefix(k, takenFromAnnotationPrefixes())
+			if k != ownerReferencesAnnotation && !strings.HasPrefix(k, clusterTagAnnotationPrefix) && !takenFrom {
+				continue
+			}
+			if _, ok := argoCluster.TakeAlongAnnotations[k]; !ok {
+				stale = append(stale, k)
+				if _, ok := argoCluster.TakeAlongAnnotations[key]; takenFrom && !ok {
+					stale = append(stale, key)
+				}
+			}
+		}
+		slices.Sort(stale)
+		for _, k := range stale {
+			delete(existingSecret.Annotations, k)
+			changed = true
+			diff = append(diff, "annotations."+k)
+		}
+		for k, v := range argoCluster.TakeAlongAnnotations {
+			if existingSecret.Annotations[k] != v {
+				if existingSecret.Annotations == nil {
+					existingSecret.Annotations = map[string]string{}
+				}
+				existingSecret.Annotations[k] = v
+				changed = true
+				diff = append(diff, "annotations."+k)
+			}
+		}
+
+		// Never let an older generation of a CAPI Secret overwrite the state of a newer one.
+		if generation, ok := SourceGeneration(&capiSecret); ok {
+			sameSource := capiSecret.UID != "" && existingSecret.Annotations[sourceUIDAnnotation] == string(capiSecret.UID)
+			if last, err := strconv.ParseInt(existingSecret.Annotations[sourceGenerationAnnotation], 10, 64); sameSource && err == nil && last > generation {
+				log.Info("Warning: skipping ArgoSecret write from an older CAPI Secret generation", "generation", generation, "current", last)
+				return ctrl.Result{}, nil
+			}
+		}
+		if d := setSourceGeneration(&existingSecret, &capiSecret); len(d) > 0 {
+			changed = true
+			diff = append(diff, d...)
+		}
+
+		if r.setSourceHash(&existingSecret, sourceHash) {
+			changed = true
+			diff = append(diff, "annotations."+sourceHashAnnotation, "annotations."+lastSyncedAtAnnotation)
+		}
+
+		if err := r.reconcileTokenSecret(ctx, log, argoCluster); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		dataChanged := slices.ContainsFunc(diff, func(d string) bool { return strings.HasPrefix(d, "data.") })
+		if r.Config.SkipTLSRotationIfMatching {
+			if d := setSecretHashes(&existingSecret, metadataHash, credentialsHash); len(d) > 0 {
+				changed = true
+				diff = append(diff, d...)
+			}
+		}
+
+		// A mutable ArgoSecret can be made immutable in place, the other way around needs a replace.
+		replace := isImmutable(&existingSecret)
+		if argoCluster.Immutable && !replace {
+			existingSecret.Immutable = argoSecret.Immutable
+			changed = true
+			diff = append(diff, "immutable")
+		}
+
+		if changed && replace {
+			log.Info("Replacing out-of-sync immutable ArgoSecret")
+			r.setLifecycleAnnotations(&existingSecret, false)
+			existingSecret.Immutable = argoSecret.Immutable
+			err := r.replaceArgoSecret(ctx, log, &existingSecret)
+			r.auditRecord(AuditActionUpdate, &existingSecret, argoCluster.ClusterName, diff, err)
+			r.changelogRecord(AuditActionUpdate, &existingSecret, diff, capiSecret.ResourceVersion, err)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			log.Info("Replaced successfully of ArgoSecret")
+			r.registrationEvent(&capiSecret, clusterObject, &existingSecret, corev1.EventTypeNormal, EventReasonUpdated, "Replaced ArgoSecret "+argoCluster.NamespacedName.String())
+			return ctrl.Result{}, r.completeRegistration(ctx, log, clusterObject, argoCluster, &existingSecret)
+		}
+
+		if changed && r.Config.SkipTLSRotationIfMatching && !dataChanged {
+			log.Info("Patching metadata of out-of-sync ArgoSecret")
+			r.setLifecycleAnnotations(&existingSecret, false)
+			err := r.argoClient().Patch(ctx, &existingSecret, client.MergeFrom(original))
+			r.auditRecord(AuditActionUpdate, &existingSecret, argoCluster.ClusterName, diff, err)
+			r.changelogRecord(AuditActionUpdate, &existingSecret, diff, capiSecret.ResourceVersion, err)
+			if err != nil {
+				log.Error(err, "Failed to patch ArgoSecret")
+				return ctrl.Result{}, err
+			}
+			log.Info("Patched successfully of ArgoSecret")
+			r.registrationEvent(&capiSecret, clusterObject, &existingSecret, corev1.EventTypeNormal, EventReasonUpdated, "Updated ArgoSecret "+argoCluster.NamespacedName.String())
+			return ctrl.Result{}, r.completeRegistration(ctx, log, clusterObject, argoCluster, &existingSecret)
+		}
+
 		if changed {
 			log.Info("Updating out-of-sync ArgoSecret")
-			if err := r.Update(ctx, &existingSecret); err != nil {
+			r.setLifecycleAnnotations(&existingSecret, false)
+			err := r.argoClient().Update(ctx, &existingSecret)
+			r.auditRecord(AuditActionUpdate, &existingSecret, argoCluster.ClusterName, diff, err)
+			r.changelogRecord(AuditActionUpdate, &existingSecret, diff, capiSecret.ResourceVersion, err)
+			if err != nil {
 				log.Error(err, "Failed to update ArgoSecret")
 				return ctrl.Result{}, err
 			}
 			log.Info("Updated successfully of ArgoSecret")
-			return ctrl.Result{}, nil
+			r.registrationEvent(&capiSecret, clusterObject, &existingSecret, corev1.EventTypeNormal, EventReasonUpdated, "Updated ArgoSecret "+argoCluster.NamespacedName.String())
+			return ctrl.Result{}, r.completeRegistration(ctx, log, clusterObject, argoCluster, &existingSecret)
 		}
 
 		log.Info("ArgoSecret is in-sync with CapiCluster, skipping...")
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.completeRegistration(ctx, log, clusterObject, argoCluster, &existingSecret)
 	}
 
 	return ctrl.Result{}, nil
@@ -254,9 +755,418 @@ func (r *Capi2Argo) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resul
 
 // SetupWithManager ..
 func (r *Capi2Argo) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		Complete(r)
+	indexer := mgr.GetFieldIndexer()
+	if r.PlatformCluster != nil {
+		indexer = r.PlatformCluster.GetFieldIndexer()
+	}
+	if err := indexer.IndexField(context.Background(), &corev1.Secret{}, clusterSecretNameIndex, r.Config.ClusterSecretIndex); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &corev1.Secret{}, clusterNameIndex, r.Config.ClusterNameIndex); err != nil {
+		return err
+	}
+	var b *builder.Builder
+	namespaceFilter := builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return r.Config.WatchesNamespace(obj.GetNamespace())
+	}))
+	if r.SourceCluster != nil {
+		b = ctrl.NewControllerManagedBy(mgr).
+			Named("capi2argo").
+			WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), &corev1.Secret{}), &handler.EnqueueRequestForObject{}, namespaceFilter)
+	} else {
+		b = ctrl.NewControllerManagedBy(mgr).
+			For(&corev1.Secret{}, namespaceFilter)
+	}
+	// Metadata changes of Clusters, like removed take-along labels, only show on their ArgoSecret
+	// once their CAPI Secret got reconciled.
+	clusterObject, err := NewClusterObject(r.Config.ClusterAPIVersion)
+	if err != nil {
+		return err
+	}
+	clusterHandler := handler.EnqueueRequestsFromMapFunc(mapClusterToCapiSecret)
+	clusterPredicates := builder.WithPredicates(predicate.Or(predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{}))
+	if r.SourceCluster != nil {
+		b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), clusterObject), clusterHandler, clusterPredicates)
+	} else {
+		b = b.Watches(clusterObject, clusterHandler, clusterPredicates)
+	}
+	if r.Config.ReadClusterTags {
+		tagsHandler := handler.EnqueueRequestsFromMapFunc(mapClusterTagsConfigMap)
+		if r.SourceCluster != nil {
+			b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), &corev1.ConfigMap{}), tagsHandler)
+		} else {
+			b = b.Watches(&corev1.ConfigMap{}, tagsHandler)
+		}
+	}
+	if r.Config.KubeconfigSource == KubeconfigSourceUser {
+		userKubeconfigHandler := handler.EnqueueRequestsFromMapFunc(mapUserKubeconfigSecret)
+		if r.SourceCluster != nil {
+			b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), &corev1.Secret{}), userKubeconfigHandler)
+		} else {
+			b = b.Watches(&corev1.Secret{}, userKubeconfigHandler)
+		}
+	}
+	if r.Config.TakeAlongNamespaceLabels {
+		namespaceHandler := handler.EnqueueRequestsFromMapFunc(r.mapNamespace)
+		namespacePredicates := builder.WithPredicates(predicate.LabelChangedPredicate{})
+		if r.SourceCluster != nil {
+			b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), &corev1.Namespace{}), namespaceHandler, namespacePredicates)
+		} else {
+			b = b.Watches(&corev1.Namespace{}, namespaceHandler, namespacePredicates)
+		}
+	}
+	if r.Config.EnableClusterDefaults {
+		defaults := &unstructured.Unstructured{}
+		defaults.SetGroupVersionKind(ArgoClusterDefaultsGVK())
+		defaultsHandler := handler.EnqueueRequestsFromMapFunc(r.mapClusterDefaults)
+		if r.SourceCluster != nil {
+			b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), defaults), defaultsHandler)
+		} else {
+			b = b.Watches(defaults, defaultsHandler)
+		}
+	}
+	if r.Config.EnableTenantMappings {
+		mapping := &unstructured.Unstructured{}
+		mapping.SetGroupVersionKind(ArgoTenantMappingGVK())
+		mappingHandler := handler.EnqueueRequestsFromMapFunc(r.mapTenantMapping)
+		if r.SourceCluster != nil {
+			b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), mapping), mappingHandler)
+		} else {
+			b = b.Watches(mapping, mappingHandler)
+		}
+	}
+	if r.Config.EnableClusterRegistrations {
+		registration := &unstructured.Unstructured{}
+		registration.SetGroupVersionKind(ArgoClusterRegistrationGVK())
+		registrationHandler := handler.EnqueueRequestsFromMapFunc(r.mapClusterRegistration)
+		if r.SourceCluster != nil {
+			b = b.WatchesRawSource(source.Kind(r.SourceCluster.GetCache(), registration), registrationHandler)
+		} else {
+			b = b.Watches(registration, registrationHandler)
+		}
+	}
+	if r.Triggers != nil {
+		b = b.WatchesRawSource(&source.Channel{Source: r.Triggers}, &handler.EnqueueRequestForObject{})
+	}
+	return b.Complete(r)
+}
+
+// isRegistered returns true if the ArgoSecret of a cluster exists, or its SealedSecret with the
+// sealed-secret output format.
+func (r *Capi2Argo) isRegistered(ctx context.Context, nn types.NamespacedName) (bool, error) {
+	var obj client.Object = &corev1.Secret{}
+	if r.Config.OutputFormat == OutputFormatSealedSecret {
+		obj = &SealedSecret{}
+	}
+	err := r.argoClient().Get(ctx, nn, obj)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// mapClusterToCapiSecret maps CAPI Clusters to a reconcile of their <name>-kubeconfig CAPI Secret.
+func mapClusterToCapiSecret(_ context.Context, obj client.Object) []ctrl.Request {
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName() + "-kubeconfig", Namespace: obj.GetNamespace()}}}
+}
+
+// deleteArgoSecrets deletes the ArgoSecrets generated from a CAPI Secret, along with their
+// referenced token Secrets.
+func (r *Capi2Argo) deleteArgoSecrets(ctx context.Context, log logr.Logger, capiSecret types.NamespacedName) (ctrl.Result, error) {
+	if r.Config.OutputFormat == OutputFormatSealedSecret {
+		labelSelector := map[string]string{
+			clusterSecretNameLabel: capiSecret.Name,
+			clusterNamespaceLabel:  capiSecret.Namespace,
+		}
+		if r.Config.InstanceID != "" {
+			labelSelector[instanceLabel] = r.Config.InstanceID
+		}
+		return r.deleteSealedSecrets(ctx, log, client.MatchingLabels(labelSelector))
+	}
+	secrets, err := r.listArgoSecrets(ctx, capiSecret.Name, capiSecret.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to list Cluster Secrets")
+		return ctrl.Result{}, err
+	}
+	if len(secrets) == 0 {
+		return ctrl.Result{}, nil
+	}
+	// Referenced token Secrets share the back-reference labels and go along.
+	for i := range secrets {
+		err = r.argoClient().Delete(ctx, &secrets[i])
+		r.auditRecord(AuditActionDelete, &secrets[i], string(secrets[i].Data["name"]), nil, err)
+		r.changelogRecord(AuditActionDelete, &secrets[i], nil, "", err)
+		if err != nil {
+			log.Error(err, "Failed to delete ArgoSecret", "name", secrets[i].Name)
+			return ctrl.Result{}, err
+		}
+		if secrets[i].Labels[argoSecretTypeLabel] == reservedLabels[argoSecretTypeLabel] {
+			r.deregistrationEvent(ctx, capiSecret, &secrets[i])
+		}
+	}
+	log.Info("Deleted successfully of ArgoSecret")
+	return ctrl.Result{}, nil
+}
+
+// resolveNameCollision checks whether the ArgoSecret name or the Argo cluster name of a cluster
+// is already taken by another CAPI Secret. When Config.AutoNamespaceSuffixOnCollision is enabled, the name
+// gets a namespace suffix which is persisted on the CAPI Secret to stay stable. Names overridden
+// by the cluster name annotation are never suffixed.
+func (r *Capi2Argo) resolveNameCollision(ctx context.Context, log logr.Logger, capiSecret *corev1.Secret, a *ArgoCluster) error {
+	if a.NameOverridden {
+		collision, err := r.nameCollision(ctx, capiSecret, a)
+		if err != nil || collision == "" {
+			return err
+		}
+		return fmt.Errorf("%w: overridden %s", ErrClusterNameCollision, collision)
+	}
+	if name, ok := capiSecret.Annotations[computedClusterNameAnnotation]; ok && name != "" {
+		a.SetComputedName(name, r.Config.BuildSuffixedNamespacedName(capiSecret.Name, capiSecret.Namespace).Name)
+		return nil
+	}
+
+	collision, err := r.nameCollision(ctx, capiSecret, a)
+	if err != nil || collision == "" {
+		return err
+	}
+	if !r.Config.AutoNamespaceSuffixOnCollision {
+		return fmt.Errorf("%w: %s", ErrClusterNameCollision, collision)
+	}
+
+	suffixed := BuildSuffixedClusterName(a.ClusterName, capiSecret.Namespace)
+	name := TruncateClusterName(suffixed, r.Config.ArgoClusterNameMaxLength)
+	a.NameTruncated = name != suffixed
+	a.SetComputedName(name, r.Config.BuildSuffixedNamespacedName(capiSecret.Name, capiSecret.Namespace).Name)
+	collision, err = r.nameCollision(ctx, capiSecret, a)
+	if err != nil {
+		return err
+	}
+	if collision != "" {
+		return fmt.Errorf("%w: suffixed %s", ErrClusterNameCollision, collision)
+	}
+
+	if capiSecret.Annotations == nil {
+		capiSecret.Annotations = map[string]string{}
+	}
+	capiSecret.Annotations[computedClusterNameAnnotation] = name
+	if err := r.Update(ctx, capiSecret); err != nil {
+		return err
+	}
+	log.Info("Disambiguated colliding cluster name with namespace suffix", "name", name)
+	return nil
+}
+
+// nameCollision describes the collision of the ArgoSecret name or the Argo cluster name of a with
+// an ArgoSecret owned by another CAPI Secret, empty when there is none. Argo cluster names are
+// looked up through the ClusterNameIndex.
+func (r *Capi2Argo) nameCollision(ctx context.Context, capiSecret *corev1.Secret, a *ArgoCluster) (string, error) {
+	var existing corev1.Secret
+	err := r.argoClient().Get(ctx, a.NamespacedName, &existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	// The ArgoSecret under the name of a cluster is its own unless generated from another namespace,
+	// so tampered CAPI Secret name labels get repaired.
+	if err == nil && r.Config.ValidateOwner(existing) == nil && existing.Labels[clusterNamespaceLabel] != capiSecret.Namespace {
+		return fmt.Sprintf("ArgoSecret %s is registered by %s", a.NamespacedName, secretOwner(&existing)), nil
+	}
+
+	secrets, err := r.listArgoSecretsByClusterName(ctx, a.NamespacedName.Namespace, a.ClusterName)
+	if err != nil {
+		return "", err
+	}
+	for i := range secrets {
+		if secrets[i].Name != a.NamespacedName.Name && isOwnedByOther(&secrets[i], capiSecret) {
+			return fmt.Sprintf("cluster name %s is registered by %s", a.ClusterName, secretOwner(&secrets[i])), nil
+		}
+	}
+	return "", nil
+}
+
+// isOwnedByOther returns true if an ArgoSecret got generated from another CAPI Secret than
+// capiSecret. ArgoSecrets predating the CAPI Secret name label are matched by namespace only.
+func isOwnedByOther(s *corev1.Secret, capiSecret *corev1.Secret) bool {
+	if s.Labels[clusterNamespaceLabel] != capiSecret.Namespace {
+		return true
+	}
+	name := s.Labels[clusterSecretNameLabel]
+	return name != "" && name != capiSecret.Name
+}
+
+// secretOwner returns the namespaced name of the CAPI Secret an ArgoSecret got generated from.
+func secretOwner(s *corev1.Secret) string {
+	return types.NamespacedName{Name: s.Labels[clusterSecretNameLabel], Namespace: s.Labels[clusterNamespaceLabel]}.String()
+}
+
+// isImmutable returns true if the Secret cannot be updated in place.
+func isImmutable(s *corev1.Secret) bool {
+	return s.Immutable != nil && *s.Immutable
+}
+
+// replaceArgoSecret applies changes on an immutable ArgoSecret by deleting and recreating it.
+// When the recreate fails the ArgoSecret is gone until the request gets requeued, which
+// then goes down the create path.
+func (r *Capi2Argo) replaceArgoSecret(ctx context.Context, log logr.Logger, s *corev1.Secret) error {
+	if err := r.argoClient().Delete(ctx, s); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to delete immutable ArgoSecret")
+		return err
+	}
+	s.ResourceVersion = ""
+	s.UID = ""
+	s.CreationTimestamp = metav1.Time{}
+	if err := r.argoClient().Create(ctx, s); err != nil {
+		log.Error(err, "Deleted immutable ArgoSecret but failed to recreate it, requeueing")
+		return err
+	}
+	return nil
+}
+
+// SourceGeneration returns the generation of a CAPI Secret. It returns false when the Secret
+// carries no metadata.generation, as resourceVersions are opaque and cannot be compared.
+func SourceGeneration(s *corev1.Secret) (int64, bool) {
+	if s.Generation > 0 {
+		return s.Generation, true
+	}
+	return 0, false
+}
+
+// setSourceGeneration stamps an ArgoSecret with the generation and UID of the CAPI Secret producing
+// it, removing them when unknown. It returns the names of the annotations that changed.
+func setSourceGeneration(s, capiSecret *corev1.Secret) []string {
+	values := map[string]string{sourceUIDAnnotation: string(capiSecret.UID)}
+	if generation, ok := SourceGeneration(capiSecret); ok {
+		values[sourceGenerationAnnotation] = strconv.FormatInt(generation, 10)
+	}
+	changed := []string{}
+	for _, k := range []string{sourceGenerationAnnotation, sourceUIDAnnotation} {
+		v := values[k]
+		if s.Annotations[k] == v {
+			continue
+		}
+		if v == "" {
+			delete(s.Annotations, k)
+		} else {
+			if s.Annotations == nil {
+				s.Annotations = map[string]string{}
+			}
+			s.Annotations[k] = v
+		}
+		changed = append(changed, "annotations."+k)
+	}
+	return changed
+}
+
+// setSourceHash stamps an ArgoSecret with the hash of the CAPI Secret content it got synced from,
+// along with the time of the sync. It returns true if the hash changed.
+func (r *Capi2Argo) setSourceHash(s *corev1.Secret, hash string) bool {
+	if s.Annotations[sourceHashAnnotation] == hash {
+		return false
+	}
+	if s.Annotations == nil {
+		s.Annotations = map[string]string{}
+	}
+	s.Annotations[sourceHashAnnotation] = hash
+	s.Annotations[lastSyncedAtAnnotation] = r.timestamp()
+	return true
+}
+
+// setLifecycleAnnotations stamps an ArgoSecret about to be written. first-synced-at is only
+// set on create, so updates carry forward the value of the existing ArgoSecret.
+func (r *Capi2Argo) setLifecycleAnnotations(s *corev1.Secret, create bool) {
+	ts := r.timestamp()
+	if s.Annotations == nil {
+		s.Annotations = map[string]string{}
+	}
+	if create {
+		s.Annotations[firstSyncedAtAnnotation] = ts
+	}
+	s.Annotations[lastUpdatedAtAnnotation] = ts
+	if r.Config.Version != "" {
+		s.Annotations[syncedByVersionAnnotation] = r.Config.Version
+	}
+}
+
+// timestamp returns the current time in UTC, RFC3339 formatted.
+func (r *Capi2Argo) timestamp() string {
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	return now().UTC().Format(time.RFC3339)
+}
+
+// event emits a Kubernetes event for obj, if a Recorder is configured.
+func (r *Capi2Argo) event(obj runtime.Object, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(obj, eventType, reason, message)
+}
+
+// sourceEvent emits an event on the CAPI Secret and, with Config.EmitClusterEvents enabled,
+// on its CAPI Cluster object when it exists.
+func (r *Capi2Argo) sourceEvent(capiSecret *corev1.Secret, cluster CAPICluster, eventType, reason, message string) {
+	recorder := r.SourceRecorder
+	if recorder == nil {
+		recorder = r.Recorder
+	}
+	if recorder == nil {
+		return
+	}
+	if capiSecret != nil {
+		recorder.Event(capiSecret, eventType, reason, message)
+	}
+	if r.Config.EmitClusterEvents && cluster != nil {
+		recorder.Event(cluster.Object(), eventType, reason, message)
+	}
+}
+
+// auditRecord records a mutation of an Argo object on the audit log.
+func (r *Capi2Argo) auditRecord(action AuditAction, obj client.Object, clusterName string, diff []string, err error) {
+	if r.Audit == nil {
+		return
+	}
+	r.Audit.Record(AuditEntry{
+		Action:            action,
+		ResourceName:      obj.GetName(),
+		ResourceNamespace: obj.GetNamespace(),
+		ClusterName:       clusterName,
+		DiffSummary:       diff,
+		Outcome:           auditOutcome(err),
+	})
+}
+
+// changelogRecord records a successful mutation of an ArgoSecret on the Changelog.
+func (r *Capi2Argo) changelogRecord(action AuditAction, obj client.Object, diff []string, triggeredBy string, err error) {
+	if r.Changelog == nil || err != nil {
+		return
+	}
+	r.Changelog.Record(client.ObjectKeyFromObject(obj), ChangeEntry{
+		Action:        action,
+		FieldsChanged: diff,
+		TriggeredBy:   triggeredBy,
+	})
+}
+
+// secretFieldNames lists the data and label field names of a Secret, without their values.
+func secretFieldNames(s *corev1.Secret) []string {
+	fields := []string{}
+	for k := range s.Data {
+		fields = append(fields, "data."+k)
+	}
+	for k := range s.Labels {
+		fields = append(fields, "labels."+k)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// argoClient returns the client that owns ArgoCD cluster Secrets.
+func (r *Capi2Argo) argoClient() client.Client {
+	if r.ArgoClient != nil {
+		return r.ArgoClient
+	}
+	return r.Client
 }
 
 // ValidateObjectOwner checks whether reconciled object is managed by CACO or not.
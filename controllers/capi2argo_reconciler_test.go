@@ -2,20 +2,28 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
 	"testing"
 	"time"
 )
@@ -56,6 +64,7 @@ var _ = BeforeSuite(func() {
 		Client: K8sManager.GetClient(),
 		Log:    TestLog,
 		Scheme: K8sManager.GetScheme(),
+		Config: MockOperatorConfig(),
 	}
 	err = C2A.SetupWithManager(K8sManager)
 	Expect(err).ToNot(HaveOccurred())
@@ -90,14 +99,14 @@ func TestReconcile(t *testing.T) {
 				"ErrorMsg": "none",
 			},
 		},
-		{"process existing valid secret", MockReconcileReq("cluster-test", ArgoNamespace), false,
+		{"process existing valid secret", MockReconcileReq("cluster-test", TestArgoNamespace), false,
 			map[string]string{
 				"ErrorMsg": "none",
 			},
 		},
 		{"process secret with wrong Data[key]", MockReconcileReq("err-key-kubeconfig", TestNamespace), true,
 			map[string]string{
-				"ErrorMsg": "wrong secret key",
+				"ErrorMsg": "wrong secret key: value not found in secret data",
 			},
 		},
 		{"process secret with wrong Type", MockReconcileReq("err-type-kubeconfig", TestNamespace), true,
@@ -152,6 +161,622 @@ func TestValidateObjectOwner(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestReconcileNameCollision(t *testing.T) {
+	ctx := context.Background()
+	teamA := MockCapiSecret(true, true, true, "mycluster-kubeconfig", "team-a")
+	teamB := MockCapiSecret(true, true, true, "mycluster-kubeconfig", "team-b")
+	c := MockClient(teamA, teamB)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+
+	_, err := r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-a"))
+	assert.Nil(t, err)
+
+	// Without auto suffixing, the colliding cluster is rejected.
+	r.Config.AutoNamespaceSuffixOnCollision = false
+	_, err = r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-b"))
+	assert.ErrorIs(t, err, ErrClusterNameCollision)
+
+	r.Config.AutoNamespaceSuffixOnCollision = true
+	for i := 0; i < 2; i++ {
+		_, err = r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-b"))
+		assert.Nil(t, err)
+		_, err = r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-a"))
+		assert.Nil(t, err)
+	}
+
+	var secrets corev1.SecretList
+	assert.Nil(t, c.List(ctx, &secrets, client.InNamespace(TestArgoNamespace)))
+	names := map[string]string{}
+	for _, s := range secrets.Items {
+		names[s.Name] = s.Labels["capi-to-argocd/cluster-namespace"]
+	}
+	assert.Equal(t, map[string]string{
+		"cluster-mycluster":        "team-a",
+		"cluster-mycluster-team-b": "team-b",
+	}, names)
+
+	var updated corev1.Secret
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "mycluster-kubeconfig", Namespace: "team-b"}, &updated))
+	assert.Equal(t, "kube-cluster-test-team-b", updated.Annotations[computedClusterNameAnnotation])
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "mycluster-kubeconfig", Namespace: "team-a"}, &updated))
+	assert.NotContains(t, updated.Annotations, computedClusterNameAnnotation)
+}
+
+func TestReconcileClusterNameCollision(t *testing.T) {
+	ctx := context.Background()
+	teamA := MockCapiSecret(true, true, true, "mycluster-kubeconfig", "team-a")
+	teamB := MockCapiSecret(true, true, true, "mycluster-kubeconfig", "team-b")
+	c := MockClient(teamA, teamB)
+	recorder := record.NewFakeRecorder(10)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder}
+	// Distinct ArgoSecret names, yet both clusters produce the same Argo cluster name.
+	r.Config.ArgoSecretNameTemplate = "{{ .ClusterNamespace }}-{{ .ClusterName }}"
+
+	_, err := r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-a"))
+	assert.Nil(t, err)
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	before := clusterNameCollisionsTotal(t)
+	_, err = r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-b"))
+	assert.ErrorIs(t, err, ErrClusterNameCollision)
+	assert.Equal(t, before+1, clusterNameCollisionsTotal(t))
+	assert.Contains(t, <-recorder.Events, "Warning "+EventReasonNameCollision)
+
+	var argoSecret corev1.Secret
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: "team-b-mycluster", Namespace: TestArgoNamespace}, &argoSecret)))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "team-a-mycluster", Namespace: TestArgoNamespace}, &argoSecret))
+	assert.Equal(t, "team-a", argoSecret.Labels[clusterNamespaceLabel])
+}
+
+func clusterNameCollisionsTotal(t *testing.T) float64 {
+	m := &dto.Metric{}
+	assert.Nil(t, ClusterNameCollisionsTotal.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestReconcileClusterNameTruncated(t *testing.T) {
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(true, true, true, "mycluster-kubeconfig", TestNamespace))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.ArgoClusterNameMaxLength = 16
+
+	// Truncations are counted once, when the ArgoSecret gets created, not on every reconcile.
+	before := clusterNameTruncatedTotal(t)
+	for i := 0; i < 2; i++ {
+		_, err := r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", TestNamespace))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, before+1, clusterNameTruncatedTotal(t))
+}
+
+func clusterNameTruncatedTotal(t *testing.T) float64 {
+	m := &dto.Metric{}
+	assert.Nil(t, ClusterNameTruncatedTotal.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestReconcileClusterSelector(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(true, true, true, "selected-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "selected"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "selected", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.ClusterSelector = "argocd=enabled"
+	req := MockReconcileReq("selected-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("selected-kubeconfig", TestNamespace)
+
+	var argoSecret corev1.Secret
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+	cluster.Labels = map[string]string{"argocd": "enabled"}
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+}
+
+func TestReconcileClusterNameOverride(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	teamA := MockCapiSecret(true, true, true, "mycluster-kubeconfig", "team-a")
+	teamB := MockCapiSecret(true, true, true, "other-kubeconfig", "team-b")
+	teamB.Labels = map[string]string{clusterv1.ClusterNameLabel: "other"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "team-b",
+		Annotations: map[string]string{clusterNameAnnotation: "mycluster"},
+	}, Status: MockReadyClusterStatus()}
+	c := MockClient(teamA, teamB, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.AutoNamespaceSuffixOnCollision = true
+
+	_, err := r.Reconcile(ctx, MockReconcileReq("mycluster-kubeconfig", "team-a"))
+	assert.Nil(t, err)
+	// Overridden names are never suffixed on collision.
+	_, err = r.Reconcile(ctx, MockReconcileReq("other-kubeconfig", "team-b"))
+	assert.ErrorIs(t, err, ErrClusterNameCollision)
+
+	cluster.Annotations[clusterNameAnnotation] = "prod-eu"
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, MockReconcileReq("other-kubeconfig", "team-b"))
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "cluster-prod-eu", Namespace: TestArgoNamespace}, &argoSecret))
+	assert.Equal(t, "prod-eu", string(argoSecret.Data["name"]))
+}
+
+func TestReconcileArgoNamespaceAnnotation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config := MockOperatorConfig()
+	config.AdditionalArgoNamespaces = "team-a-argocd"
+	capiSecret := MockCapiSecret(true, true, true, "tenant-kubeconfig", "team-a")
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "tenant"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "team-a",
+		Annotations: map[string]string{argoNamespaceAnnotation: "team-a-argocd"},
+	}, Status: MockReadyClusterStatus()}
+	c := fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(capiSecret, cluster).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, config.ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, config.ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: config}
+	req := MockReconcileReq("tenant-kubeconfig", "team-a")
+	nn := config.BuildNamespacedName("tenant-kubeconfig", "team-a")
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "team-a-argocd"}, &argoSecret))
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+	// ArgoSecrets in other Argo namespaces are still deleted on deregistration.
+	cluster.Annotations[skipAnnotation] = "true"
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "team-a-argocd"}, &argoSecret)))
+}
+
+func TestReconcileLifecycleAnnotations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(true, true, true, "lifecycle-kubeconfig", TestNamespace))
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.Version = "v1.2.3"
+	r.now = func() time.Time { return now }
+	req := MockReconcileReq("lifecycle-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("lifecycle-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "2024-01-02T03:04:05Z", argoSecret.Annotations["capi-to-argocd/first-synced-at"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", argoSecret.Annotations["capi-to-argocd/last-updated-at"])
+	assert.Equal(t, "v1.2.3", argoSecret.Annotations["capi-to-argocd/synced-by-version"])
+
+	// In-sync reconciles do not write.
+	now = now.Add(time.Hour)
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "2024-01-02T03:04:05Z", argoSecret.Annotations["capi-to-argocd/last-updated-at"])
+
+	// Updates advance last-updated-at and preserve first-synced-at.
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	r.Config.Version = "v1.2.4"
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "2024-01-02T03:04:05Z", argoSecret.Annotations["capi-to-argocd/first-synced-at"])
+	assert.Equal(t, "2024-01-02T04:04:05Z", argoSecret.Annotations["capi-to-argocd/last-updated-at"])
+	assert.Equal(t, "v1.2.4", argoSecret.Annotations["capi-to-argocd/synced-by-version"])
+}
+
+func TestReconcileReadinessGates(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testCluster       bool
+		testConditions    map[string]corev1.ConditionStatus
+		testRequire       bool
+		testGates         string
+		testExpectedError bool
+	}{
+		{"test control plane ready", true, map[string]corev1.ConditionStatus{"ControlPlaneReady": corev1.ConditionTrue}, true, "", false},
+		{"test control plane not ready", true, map[string]corev1.ConditionStatus{"ControlPlaneReady": corev1.ConditionFalse}, true, "", true},
+		{"test control plane condition missing", true, nil, true, "", true},
+		{"test without Cluster object", false, nil, true, "", false},
+		{"test control plane check disabled", true, map[string]corev1.ConditionStatus{"ControlPlaneReady": corev1.ConditionFalse}, false, "", false},
+		{"test readiness gates met", true, map[string]corev1.ConditionStatus{"ControlPlaneReady": corev1.ConditionTrue, "InfrastructureReady": corev1.ConditionTrue, "AddonsReady": corev1.ConditionTrue}, true, "InfrastructureReady, AddonsReady", false},
+		{"test readiness gate not met", true, map[string]corev1.ConditionStatus{"ControlPlaneReady": corev1.ConditionTrue, "InfrastructureReady": corev1.ConditionTrue}, true, "InfrastructureReady,AddonsReady", true},
+		{"test readiness gates without control plane check", true, map[string]corev1.ConditionStatus{"AddonsReady": corev1.ConditionFalse}, false, "AddonsReady", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(true, true, true, "ready-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "ready"}
+			objs := []client.Object{capiSecret}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: TestNamespace}}
+			for k, v := range tt.testConditions {
+				cluster.Status.Conditions = append(cluster.Status.Conditions, clusterv1.Condition{Type: clusterv1.ConditionType(k), Status: v})
+			}
+			if tt.testCluster {
+				objs = append(objs, cluster)
+			}
+			c := MockClient(objs...)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			r.Config.RequireControlPlaneReady = tt.testRequire
+			r.Config.ReadinessGates = tt.testGates
+			req := MockReconcileReq("ready-kubeconfig", TestNamespace)
+			nn := r.Config.BuildNamespacedName("ready-kubeconfig", TestNamespace)
+
+			result, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			if !tt.testExpectedError {
+				assert.Zero(t, result.RequeueAfter)
+				assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+				return
+			}
+			assert.Equal(t, readinessGatesRequeueAfter, result.RequeueAfter)
+			assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+			// The ArgoSecret gets created once all conditions are met.
+			cluster.Status.Conditions = nil
+			for _, k := range r.Config.RequiredConditions() {
+				cluster.Status.Conditions = append(cluster.Status.Conditions, clusterv1.Condition{Type: clusterv1.ConditionType(k), Status: corev1.ConditionTrue})
+			}
+			assert.Nil(t, c.Update(ctx, cluster))
+			result, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Zero(t, result.RequeueAfter)
+			assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+		})
+	}
+}
+
+func TestReconcilePausedCluster(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(true, true, true, "paused-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "paused"}
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "paused", Namespace: TestNamespace, Annotations: map[string]string{clusterv1.PausedAnnotation: ""}},
+		Status:     MockReadyClusterStatus(),
+	}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("paused-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("paused-kubeconfig", TestNamespace)
+
+	// Paused clusters are skipped and checked again later.
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, pausedRequeueAfter, result.RequeueAfter)
+	var argoSecret corev1.Secret
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+	// Reconciles resume once the cluster got unpaused.
+	delete(cluster.Annotations, clusterv1.PausedAnnotation)
+	assert.Nil(t, c.Update(ctx, cluster))
+	result, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+
+	// Pausing leaves the existing ArgoSecret untouched.
+	cluster.Spec.Paused = true
+	assert.Nil(t, c.Update(ctx, cluster))
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "https://stale", string(argoSecret.Data["server"]))
+}
+
+func TestReconcileSourceHash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(true, true, true, "hash-kubeconfig", TestNamespace)
+	c := MockClient(capiSecret)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.now = func() time.Time { return now }
+	req := MockReconcileReq("hash-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("hash-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, SourceHash(capiSecret), argoSecret.Annotations["capi-to-argocd/source-hash"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", argoSecret.Annotations["capi-to-argocd/last-synced-at"])
+
+	// Unchanged CAPI Secrets keep the sync timestamp.
+	now = now.Add(time.Hour)
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "2024-01-02T03:04:05Z", argoSecret.Annotations["capi-to-argocd/last-synced-at"])
+
+	// New CAPI Secret content gets synced, even when the ArgoSecret stays the same.
+	assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+	capiSecret.Data["unrelated"] = []byte("value")
+	assert.Nil(t, c.Update(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, SourceHash(capiSecret), argoSecret.Annotations["capi-to-argocd/source-hash"])
+	assert.Equal(t, "2024-01-02T04:04:05Z", argoSecret.Annotations["capi-to-argocd/last-synced-at"])
+}
+
+func TestReconcilePreservesForeignMetadata(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(true, true, true, "foreign-kubeconfig", TestNamespace))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("foreign-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("foreign-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+
+	// Other tools add their metadata and tamper with an operator-owned label.
+	argoSecret.Labels["appset.example.com/env"] = "prod"
+	argoSecret.Labels[clusterSecretNameLabel] = "other"
+	argoSecret.Annotations["example.com/contact"] = "team-a"
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "prod", argoSecret.Labels["appset.example.com/env"])
+	assert.Equal(t, "foreign-kubeconfig", argoSecret.Labels[clusterSecretNameLabel])
+	assert.Equal(t, "team-a", argoSecret.Annotations["example.com/contact"])
+	assert.NotEqual(t, "https://stale", string(argoSecret.Data["server"]))
+}
+
+func TestMapClusterToCapiSecret(t *testing.T) {
+	t.Parallel()
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace}}
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace}}},
+		mapClusterToCapiSecret(context.Background(), cluster))
+}
+
+func TestReconcileRemovedTakeAlong(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "stale-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "stale"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: TestNamespace,
+		Labels: map[string]string{"foo": "bar", clusterTakeAlongKey + "foo": "", "env": "prod", clusterTakeAlongKey + "env": "environment", "team": "a"},
+		Annotations: map[string]string{
+			"owner":                                 "team-a",
+			clusterTakeAlongAnnotationKey + "owner": "",
+			takeAlongLabelsPrefixAnnotation:         "te",
+			clusterTakeAlongTemplateKey + "cluster": "{{ .Name }}",
+		},
+	}, Status: MockReadyClusterStatus()}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("stale-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("stale-kubeconfig", TestNamespace)
+	takenAlong := []string{"foo", "environment", "team", "cluster"}
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	for _, k := range takenAlong {
+		assert.Contains(t, argoSecret.Labels, k)
+		assert.Contains(t, argoSecret.Labels, clusterTakenFromClusterKey+k)
+	}
+	assert.Equal(t, "team-a", argoSecret.Annotations["owner"])
+
+	// Keys and markers go away along the take-along directives on the Cluster.
+	assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+	cluster.Labels = map[string]string{"foo": "bar", "env": "prod", "team": "a"}
+	cluster.Annotations = map[string]string{"owner": "team-a"}
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	for _, k := range takenAlong {
+		assert.NotContains(t, argoSecret.Labels, k)
+		assert.NotContains(t, argoSecret.Labels, clusterTakenFromClusterKey+k)
+	}
+	assert.NotContains(t, argoSecret.Annotations, "owner")
+	assert.NotContains(t, argoSecret.Annotations, clusterTakenFromClusterAnnotationKey+"owner")
+}
+
+func TestReconcileImmutableArgoSecret(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var calls []string
+	failCreate := false
+	c := fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(MockCapiSecret(true, true, true, "immutable-kubeconfig", TestNamespace)).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				calls = append(calls, "Create")
+				if failCreate {
+					return errors.New("create failed")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				calls = append(calls, "Update")
+				return c.Update(ctx, obj, opts...)
+			},
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				calls = append(calls, "Delete")
+				return c.Delete(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.ArgoSecretImmutable = true
+	req := MockReconcileReq("immutable-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("immutable-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.True(t, *argoSecret.Immutable)
+	firstSynced := argoSecret.Annotations["capi-to-argocd/first-synced-at"]
+
+	// Equal secrets are never written.
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Empty(t, calls)
+
+	// Changes get applied with a delete and create.
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Delete", "Create"}, calls)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.True(t, *argoSecret.Immutable)
+	assert.NotEqual(t, "https://stale", string(argoSecret.Data["server"]))
+	assert.Equal(t, firstSynced, argoSecret.Annotations["capi-to-argocd/first-synced-at"])
+
+	// A failed create after the delete errors out, and the requeue recreates the ArgoSecret.
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	failCreate = true
+	_, err = r.Reconcile(ctx, req)
+	assert.NotNil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &corev1.Secret{})))
+	failCreate = false
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.True(t, *argoSecret.Immutable)
+	assert.NotEqual(t, "https://stale", string(argoSecret.Data["server"]))
+
+	// Mutable ArgoSecrets are made immutable in place.
+	argoSecret.Immutable = nil
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Update"}, calls)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.True(t, *argoSecret.Immutable)
+}
+
+func TestReconcileSourceGeneration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(true, true, true, "generation-kubeconfig", TestNamespace)
+	capiSecret.Generation = 2
+	capiSecret.UID = "generation-uid"
+	c := MockClient(capiSecret)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("generation-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("generation-kubeconfig", TestNamespace)
+
+	// Generation 2 gets processed first.
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "2", argoSecret.Annotations["capi-to-argocd/source-generation"])
+	assert.Equal(t, "generation-uid", argoSecret.Annotations["capi-to-argocd/source-uid"])
+	expected := argoSecret.DeepCopy()
+
+	// The delayed generation 1 event carries an older kubeconfig.
+	stale := MockCapiSecret(true, true, true, "generation-kubeconfig", TestNamespace)
+	assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+	capiSecret.Generation = 1
+	capiSecret.Data = stale.Data
+	capiSecret.Data["value"] = []byte(strings.Replace(string(stale.Data["value"]), "server: https://", "server: https://stale.", 1))
+	assert.Nil(t, c.Update(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, expected.Data, argoSecret.Data)
+	assert.Equal(t, "2", argoSecret.Annotations["capi-to-argocd/source-generation"])
+
+	// Newer generations are applied.
+	capiSecret.Generation = 3
+	assert.Nil(t, c.Update(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Contains(t, string(argoSecret.Data["server"]), "https://stale.")
+	assert.Equal(t, "3", argoSecret.Annotations["capi-to-argocd/source-generation"])
+
+	// A recreated or moved CAPI Secret is never held back by the generation of the previous one.
+	recreated := MockCapiSecret(true, true, true, "generation-kubeconfig", TestNamespace)
+	recreated.Generation = 1
+	recreated.UID = "recreated-uid"
+	assert.Nil(t, c.Delete(ctx, capiSecret))
+	assert.Nil(t, c.Create(ctx, recreated))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.NotContains(t, string(argoSecret.Data["server"]), "https://stale.")
+	assert.Equal(t, "1", argoSecret.Annotations["capi-to-argocd/source-generation"])
+	assert.Equal(t, "recreated-uid", argoSecret.Annotations["capi-to-argocd/source-uid"])
+
+	// Without a generation, writes are never skipped.
+	assert.Nil(t, c.Get(ctx, req.NamespacedName, recreated))
+	recreated.Generation = 0
+	recreated.Data["value"] = capiSecret.Data["value"]
+	assert.Nil(t, c.Update(ctx, recreated))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Contains(t, string(argoSecret.Data["server"]), "https://stale.")
+	assert.NotContains(t, argoSecret.Annotations, "capi-to-argocd/source-generation")
+}
+
+func TestSourceGeneration(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           metav1.ObjectMeta
+		testExpectedError  bool
+		testExpectedValues int64
+	}{
+		{"test generation", metav1.ObjectMeta{Generation: 4, ResourceVersion: "999"}, false, 4},
+		{"test resourceVersion only", metav1.ObjectMeta{ResourceVersion: "999"}, true, 0},
+		{"test unset", metav1.ObjectMeta{}, true, 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			generation, ok := SourceGeneration(&corev1.Secret{ObjectMeta: tt.testMock})
+			assert.Equal(t, !tt.testExpectedError, ok)
+			assert.Equal(t, tt.testExpectedValues, generation)
+		})
+	}
+}
+
 func MockReconcileReq(name string, namespace string) reconcile.Request {
 	r := reconcile.Request{
 		NamespacedName: types.NamespacedName{
@@ -168,7 +793,7 @@ func MockReconcileEnv() error {
 		return err
 	}
 
-	ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ArgoNamespace}}
+	ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: TestArgoNamespace}}
 	if err := K8sClient.Create(context.Background(), ns); err != nil {
 		return err
 	}
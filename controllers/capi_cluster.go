@@ -1,54 +1,130 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"slices"
 	"strings"
 )
 
 // CapiClusterSecretType represents the CAPI managed secret type.
 const CapiClusterSecretType corev1.SecretType = "cluster.x-k8s.io/secret"
 
+const (
+	// kubeConfigAPIVersionV1 is the kubeconfig apiVersion the parser is built for.
+	kubeConfigAPIVersionV1 = "v1"
+
+	// KubeConfigFormatJSON marks kubeconfigs stored as JSON.
+	KubeConfigFormatJSON = "json"
+	// KubeConfigFormatYAML marks kubeconfigs stored as YAML.
+	KubeConfigFormatYAML = "yaml"
+
+	// DefaultKubeConfigKey is the CAPI Secret data key holding the kubeconfig.
+	DefaultKubeConfigKey = "value"
+	// kubeConfigKeyAnnotation overrides the kubeconfig data key of a single CAPI Secret.
+	kubeConfigKeyAnnotation = "capi-to-argocd/kubeconfig-secret-key"
+	// defaultKubeConfigClusterName is the generic cluster name of k3s and RKE2 kubeconfigs.
+	defaultKubeConfigClusterName = "default"
+)
+
 // CapiCluster is an one-on-one representation of KubeConfig fields.
 type CapiCluster struct {
 	Name       string     `yaml:"name"`
 	Namespace  string     `yaml:"namespace"`
 	KubeConfig KubeConfig `yaml:"kubeConfig"`
+	// KubeConfigAPIVersion is the kubeconfig apiVersion detected while parsing.
+	KubeConfigAPIVersion string `yaml:"-"`
+	// KubeConfigFormat is the kubeconfig format detected while parsing, json or yaml.
+	KubeConfigFormat string `yaml:"-"`
+}
+
+// KubeConfigData is a parsed kubeconfig along with the format it was stored in.
+type KubeConfigData struct {
+	KubeConfig KubeConfig
+	Format     string
 }
 
 // KubeConfig is an one-on-one representation of KubeConfig fields.
 type KubeConfig struct {
-	APIVersion string    `yaml:"apiVersion"`
-	Kind       string    `yaml:"kind"`
-	Clusters   []Cluster `yaml:"clusters"`
-	Users      []User    `yaml:"users"`
+	APIVersion string         `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string         `yaml:"kind" json:"kind"`
+	Clusters   []Cluster      `yaml:"clusters" json:"clusters"`
+	Users      []User         `yaml:"users" json:"users"`
+	Contexts   []NamedContext `yaml:"contexts,omitempty" json:"contexts,omitempty"`
+	// CurrentContext selects the cluster and user of kubeconfigs holding several of them.
+	CurrentContext string `yaml:"current-context,omitempty" json:"current-context,omitempty"`
+}
+
+// NamedContext represents kubeconfig.[]Contexts fields.
+type NamedContext struct {
+	Name    string      `yaml:"name" json:"name"`
+	Context ContextInfo `yaml:"context" json:"context"`
+}
+
+// ContextInfo represents kubeconfig.[]Contexts.Context fields.
+type ContextInfo struct {
+	Cluster string `yaml:"cluster" json:"cluster"`
+	User    string `yaml:"user" json:"user"`
 }
 
 // Cluster represents kubeconfig.[]Clusters.Cluster fields.
 type Cluster struct {
-	Name    string      `yaml:"name"`
-	Cluster ClusterInfo `yaml:"cluster"`
+	Name    string      `yaml:"name" json:"name"`
+	Cluster ClusterInfo `yaml:"cluster" json:"cluster"`
 }
 
 // ClusterInfo represents kubeconfig.[]Clusters.Cluster.Clusterinfo fields.
 type ClusterInfo struct {
-	CaData string `yaml:"certificate-authority-data"`
-	Server string `yaml:"server"`
+	CaData string `yaml:"certificate-authority-data" json:"certificate-authority-data"`
+	Server string `yaml:"server" json:"server"`
+	// ProxyURL is the proxy the cluster is reached through, empty for direct connections.
+	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+	// InsecureSkipTLSVerify disables server certificate verification, usually without CA data.
+	InsecureSkipTLSVerify bool `yaml:"insecure-skip-tls-verify,omitempty" json:"insecure-skip-tls-verify,omitempty"`
+	// TLSServerName is the name the server certificate is verified against, instead of the server host.
+	TLSServerName string `yaml:"tls-server-name,omitempty" json:"tls-server-name,omitempty"`
 }
 
 // User represents kubeconfig.[]Users fields.
 type User struct {
-	Name string   `yaml:"name"`
-	User UserInfo `yaml:"user"`
+	Name string   `yaml:"name" json:"name"`
+	User UserInfo `yaml:"user" json:"user"`
 }
 
 // UserInfo represents kubeconfig.[]Users.User fields.
 type UserInfo struct {
-	CertData *string `yaml:"client-certificate-data,omitempty"`
-	KeyData  *string `yaml:"client-key-data,omitempty"`
-	Token    *string `yaml:"token,omitempty"`
+	CertData *string     `yaml:"client-certificate-data,omitempty" json:"client-certificate-data,omitempty"`
+	KeyData  *string     `yaml:"client-key-data,omitempty" json:"client-key-data,omitempty"`
+	Token    *string     `yaml:"token,omitempty" json:"token,omitempty"`
+	Exec     *ExecConfig `yaml:"exec,omitempty" json:"exec,omitempty"`
+	// AuthProvider is a legacy auth provider plugin, like the azure one of older AKS kubeconfigs.
+	AuthProvider *AuthProviderConfig `yaml:"auth-provider,omitempty" json:"auth-provider,omitempty"`
+}
+
+// AuthProviderConfig represents kubeconfig.[]Users.User.AuthProvider fields.
+type AuthProviderConfig struct {
+	Name   string            `yaml:"name" json:"name"`
+	Config map[string]string `yaml:"config,omitempty" json:"config,omitempty"`
+}
+
+// ExecConfig represents kubeconfig.[]Users.User.Exec credential plugin fields.
+type ExecConfig struct {
+	APIVersion  string       `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
+	Command     string       `yaml:"command" json:"command"`
+	Args        []string     `yaml:"args,omitempty" json:"args,omitempty"`
+	Env         []ExecEnvVar `yaml:"env,omitempty" json:"env,omitempty"`
+	InstallHint string       `yaml:"installHint,omitempty" json:"installHint,omitempty"`
+}
+
+// ExecEnvVar represents kubeconfig.[]Users.User.Exec.[]Env fields.
+type ExecEnvVar struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
 }
 
 // NewCapiCluster returns an empty CapiCluster type.
@@ -60,30 +136,102 @@ func NewCapiCluster(name, namespace string) *CapiCluster {
 	}
 }
 
-// Unmarshal k8s secret into CapiCluster type.
-func (c *CapiCluster) Unmarshal(s *corev1.Secret) error {
-	if err := ValidateCapiSecret(s); err != nil {
+// Unmarshal k8s secret into CapiCluster type, reading the kubeconfig from the given data key.
+func (c *CapiCluster) Unmarshal(s *corev1.Secret, key string) error {
+	if err := ValidateCapiSecret(s, key); err != nil {
 		return err
 	}
-	err := yaml.Unmarshal(s.Data["value"], &c.KubeConfig)
-	if err != nil || len(c.KubeConfig.Clusters) == 0 || len(c.KubeConfig.Users) == 0 || c.KubeConfig.APIVersion != "v1" || c.KubeConfig.Kind != "Config" {
+	data, err := ParseKubeConfig(s.Data[key])
+	if err != nil {
+		return errors.New("invalid KubeConfig")
+	}
+	c.KubeConfig = data.KubeConfig
+	c.KubeConfigFormat = data.Format
+	if len(c.KubeConfig.Clusters) == 0 || len(c.KubeConfig.Users) == 0 || c.KubeConfig.Kind != "Config" {
 		return errors.New("invalid KubeConfig")
+	}
+	if err := c.KubeConfig.SelectCurrentContext(); err != nil {
+		return err
+	}
+	// k3s and RKE2 control planes name every cluster the same, tell them apart by their CAPI Secret.
+	if c.KubeConfig.Clusters[0].Name == defaultKubeConfigClusterName && c.Name != "" {
+		c.KubeConfig.Clusters[0].Name = c.Name
+	}
+
+	// Older kubeconfigs (e.g. v1beta1) share the v1 fields we care for, so parse them best-effort.
+	c.KubeConfigAPIVersion = c.KubeConfig.APIVersion
+	if c.KubeConfigAPIVersion == "" {
+		c.KubeConfigAPIVersion = kubeConfigAPIVersionV1
+	}
+	if c.KubeConfigAPIVersion != kubeConfigAPIVersionV1 {
+		ctrl.Log.WithName("capiCluster").Info("Warning: unexpected KubeConfig apiVersion, parsing best-effort",
+			"apiVersion", c.KubeConfigAPIVersion, "cluster", c.Name, "namespace", c.Namespace)
+	}
+	return nil
+}
 
+// SelectCurrentContext moves the cluster and user of the current context first, where they get
+// read from. Kubeconfigs without current context are left as they are.
+func (kc *KubeConfig) SelectCurrentContext() error {
+	if kc.CurrentContext == "" {
+		return nil
+	}
+	i := slices.IndexFunc(kc.Contexts, func(ctx NamedContext) bool { return ctx.Name == kc.CurrentContext })
+	if i < 0 {
+		return fmt.Errorf("invalid KubeConfig: current-context %s not found", kc.CurrentContext)
+	}
+	current := kc.Contexts[i].Context
+	cluster := slices.IndexFunc(kc.Clusters, func(c Cluster) bool { return c.Name == current.Cluster })
+	user := slices.IndexFunc(kc.Users, func(u User) bool { return u.Name == current.User })
+	if cluster < 0 || user < 0 {
+		return fmt.Errorf("invalid KubeConfig: cluster %s or user %s of current-context %s not found", current.Cluster, current.User, kc.CurrentContext)
 	}
+	kc.Clusters[0], kc.Clusters[cluster] = kc.Clusters[cluster], kc.Clusters[0]
+	kc.Users[0], kc.Users[user] = kc.Users[user], kc.Users[0]
 	return nil
 }
 
+// ParseKubeConfig parses a kubeconfig stored either as JSON or YAML.
+// JSON is tried first, falling back to YAML on JSON syntax errors.
+func ParseKubeConfig(data []byte) (*KubeConfigData, error) {
+	var kc KubeConfig
+	jsonErr := json.Unmarshal(data, &kc)
+	if jsonErr == nil {
+		return &KubeConfigData{KubeConfig: kc, Format: KubeConfigFormatJSON}, nil
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(jsonErr, &syntaxErr) {
+		return nil, jsonErr
+	}
+
+	kc = KubeConfig{}
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("failed to parse KubeConfig as json: %v, nor as yaml: %v", jsonErr, err)
+	}
+	return &KubeConfigData{KubeConfig: kc, Format: KubeConfigFormatYAML}, nil
+}
+
 // ValidateCapiSecret validates that we got proper defined types for a given secret.
-func ValidateCapiSecret(s *corev1.Secret) error {
-	if s.Type != CapiClusterSecretType {
+// Kubeconfigs of hosted control planes are accepted whatever their type.
+func ValidateCapiSecret(s *corev1.Secret, key string) error {
+	if s.Type != CapiClusterSecretType && !IsHostedControlPlaneSecret(s) {
 		return errors.New("wrong secret type")
 	}
-	if _, ok := s.Data["value"]; !ok {
-		return errors.New("wrong secret key")
+	if _, ok := s.Data[key]; !ok {
+		return fmt.Errorf("wrong secret key: %s not found in secret data", key)
 	}
 	return nil
 }
 
+// KubeConfigKey returns the data key holding the kubeconfig of a CAPI Secret.
+// The `capi-to-argocd/kubeconfig-secret-key` annotation takes precedence over defaultKey.
+func KubeConfigKey(s *corev1.Secret, defaultKey string) string {
+	if key := s.Annotations[kubeConfigKeyAnnotation]; key != "" {
+		return key
+	}
+	return defaultKey
+}
+
 // ValidateCapiNaming validates CAPI kubeconfig naming convention.
 func ValidateCapiNaming(n types.NamespacedName) bool {
 	return strings.HasSuffix(n.Name, "-kubeconfig") && !strings.HasSuffix(n.Name, "-user-kubeconfig")
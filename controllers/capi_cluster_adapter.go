@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1alpha4 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ClusterAPIVersionV1Beta1 selects cluster.x-k8s.io/v1beta1 Cluster objects.
+	ClusterAPIVersionV1Beta1 = "v1beta1"
+	// ClusterAPIVersionV1Alpha4 selects cluster.x-k8s.io/v1alpha4 Cluster objects.
+	ClusterAPIVersionV1Alpha4 = "v1alpha4"
+	// ClusterAPIVersionV1Beta2 selects cluster.x-k8s.io/v1beta2 Cluster objects.
+	ClusterAPIVersionV1Beta2 = "v1beta2"
+	// ClusterAPIVersionAuto selects the preferred Cluster API version of the management cluster.
+	ClusterAPIVersionAuto = "auto"
+)
+
+// CAPICluster abstracts the CAPI Cluster fields the operator needs,
+// so that Cluster objects of any supported API version can be converted.
+type CAPICluster interface {
+	GetName() string
+	GetNamespace() string
+	GetLabels() map[string]string
+	GetAnnotations() map[string]string
+	GetOwnerReferences() []metav1.OwnerReference
+	GetCreationTimestamp() metav1.Time
+	GetPhase() string
+	// GetTopologyVersion returns the ClusterClass topology version, empty without topology.
+	GetTopologyVersion() string
+	// GetTopologyVariables returns the raw JSON values of the ClusterClass topology variables by name.
+	GetTopologyVariables() map[string][]byte
+	// IsPaused returns true if spec.paused is set.
+	IsPaused() bool
+	// GetInfrastructureRef returns the reference to the infrastructure provider object, if any.
+	GetInfrastructureRef() *corev1.ObjectReference
+	// GetControlPlaneRef returns the reference to the control plane provider object, if any.
+	GetControlPlaneRef() *corev1.ObjectReference
+	// GetControlPlaneEndpoint returns the host and port of the API server, empty when not set yet.
+	GetControlPlaneEndpoint() (string, int32)
+	// GetConditionStatus returns the status of a status condition, empty when it is not set.
+	GetConditionStatus(conditionType string) corev1.ConditionStatus
+	// SetCondition sets a status condition, returning false when it was already set.
+	SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) bool
+	// RemoveCondition removes a status condition, returning false when it was not set.
+	RemoveCondition(conditionType string) bool
+	// Object returns the underlying Cluster object.
+	Object() client.Object
+}
+
+// V1Beta1ClusterAdapter adapts a v1beta1 Cluster to CAPICluster.
+type V1Beta1ClusterAdapter struct {
+	*clusterv1.Cluster
+}
+
+// GetPhase returns the Cluster phase.
+func (a *V1Beta1ClusterAdapter) GetPhase() string {
+	return a.Status.Phase
+}
+
+// GetTopologyVersion returns the ClusterClass topology version.
+func (a *V1Beta1ClusterAdapter) GetTopologyVersion() string {
+	if a.Spec.Topology == nil {
+		return ""
+	}
+	return a.Spec.Topology.Version
+}
+
+// GetTopologyVariables returns the ClusterClass topology variables.
+func (a *V1Beta1ClusterAdapter) GetTopologyVariables() map[string][]byte {
+	if a.Spec.Topology == nil {
+		return nil
+	}
+	variables := map[string][]byte{}
+	for _, v := range a.Spec.Topology.Variables {
+		variables[v.Name] = v.Value.Raw
+	}
+	return variables
+}
+
+// IsPaused returns true if spec.paused is set.
+func (a *V1Beta1ClusterAdapter) IsPaused() bool {
+	return a.Spec.Paused
+}
+
+// GetInfrastructureRef returns the reference to the infrastructure provider object.
+func (a *V1Beta1ClusterAdapter) GetInfrastructureRef() *corev1.ObjectReference {
+	return a.Spec.InfrastructureRef
+}
+
+// GetControlPlaneRef returns the reference to the control plane provider object.
+func (a *V1Beta1ClusterAdapter) GetControlPlaneRef() *corev1.ObjectReference {
+	return a.Spec.ControlPlaneRef
+}
+
+// GetControlPlaneEndpoint returns the host and port of the API server.
+func (a *V1Beta1ClusterAdapter) GetControlPlaneEndpoint() (string, int32) {
+	return a.Spec.ControlPlaneEndpoint.Host, a.Spec.ControlPlaneEndpoint.Port
+}
+
+// GetConditionStatus returns the status of a status condition.
+func (a *V1Beta1ClusterAdapter) GetConditionStatus(conditionType string) corev1.ConditionStatus {
+	for _, c := range a.Status.Conditions {
+		if string(c.Type) == conditionType {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+// SetCondition sets a status condition.
+func (a *V1Beta1ClusterAdapter) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) bool {
+	for i, c := range a.Status.Conditions {
+		if string(c.Type) != conditionType {
+			continue
+		}
+		if c.Status == status && c.Reason == reason && c.Message == message {
+			return false
+		}
+		if c.Status != status {
+			a.Status.Conditions[i].LastTransitionTime = metav1.Now()
+		}
+		a.Status.Conditions[i].Status = status
+		a.Status.Conditions[i].Reason = reason
+		a.Status.Conditions[i].Message = message
+		return true
+	}
+	a.Status.Conditions = append(a.Status.Conditions, clusterv1.Condition{
+		Type:               clusterv1.ConditionType(conditionType),
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+	return true
+}
+
+// RemoveCondition removes a status condition.
+func (a *V1Beta1ClusterAdapter) RemoveCondition(conditionType string) bool {
+	for i, c := range a.Status.Conditions {
+		if string(c.Type) == conditionType {
+			a.Status.Conditions = append(a.Status.Conditions[:i], a.Status.Conditions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Object returns the underlying Cluster object.
+func (a *V1Beta1ClusterAdapter) Object() client.Object {
+	return a.Cluster
+}
+
+// V1Alpha4ClusterAdapter adapts a v1alpha4 Cluster to CAPICluster.
+type V1Alpha4ClusterAdapter struct {
+	*clusterv1alpha4.Cluster
+}
+
+// GetPhase returns the Cluster phase.
+func (a *V1Alpha4ClusterAdapter) GetPhase() string {
+	return a.Status.Phase
+}
+
+// GetTopologyVersion returns the ClusterClass topology version.
+func (a *V1Alpha4ClusterAdapter) GetTopologyVersion() string {
+	if a.Spec.Topology == nil {
+		return ""
+	}
+	return a.Spec.Topology.Version
+}
+
+// GetTopologyVariables returns nil, v1alpha4 topologies have no variables.
+func (a *V1Alpha4ClusterAdapter) GetTopologyVariables() map[string][]byte {
+	return nil
+}
+
+// IsPaused returns true if spec.paused is set.
+func (a *V1Alpha4ClusterAdapter) IsPaused() bool {
+	return a.Spec.Paused
+}
+
+// GetInfrastructureRef returns the reference to the infrastructure provider object.
+func (a *V1Alpha4ClusterAdapter) GetInfrastructureRef() *corev1.ObjectReference {
+	return a.Spec.InfrastructureRef
+}
+
+// GetControlPlaneRef returns the reference to the control plane provider object.
+func (a *V1Alpha4ClusterAdapter) GetControlPlaneRef() *corev1.ObjectReference {
+	return a.Spec.ControlPlaneRef
+}
+
+// GetControlPlaneEndpoint returns the host and port of the API server.
+func (a *V1Alpha4ClusterAdapter) GetControlPlaneEndpoint() (string, int32) {
+	return a.Spec.ControlPlaneEndpoint.Host, a.Spec.ControlPlaneEndpoint.Port
+}
+
+// GetConditionStatus returns the status of a status condition.
+func (a *V1Alpha4ClusterAdapter) GetConditionStatus(conditionType string) corev1.ConditionStatus {
+	for _, c := range a.Status.Conditions {
+		if string(c.Type) == conditionType {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+// SetCondition sets a status condition.
+func (a *V1Alpha4ClusterAdapter) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) bool {
+	for i, c := range a.Status.Conditions {
+		if string(c.Type) != conditionType {
+			continue
+		}
+		if c.Status == status && c.Reason == reason && c.Message == message {
+			return false
+		}
+		if c.Status != status {
+			a.Status.Conditions[i].LastTransitionTime = metav1.Now()
+		}
+		a.Status.Conditions[i].Status = status
+		a.Status.Conditions[i].Reason = reason
+		a.Status.Conditions[i].Message = message
+		return true
+	}
+	a.Status.Conditions = append(a.Status.Conditions, clusterv1alpha4.Condition{
+		Type:               clusterv1alpha4.ConditionType(conditionType),
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+	return true
+}
+
+// RemoveCondition removes a status condition.
+func (a *V1Alpha4ClusterAdapter) RemoveCondition(conditionType string) bool {
+	for i, c := range a.Status.Conditions {
+		if string(c.Type) == conditionType {
+			a.Status.Conditions = append(a.Status.Conditions[:i], a.Status.Conditions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Object returns the underlying Cluster object.
+func (a *V1Alpha4ClusterAdapter) Object() client.Object {
+	return a.Cluster
+}
+
+// GetReferencedObject returns the object a CAPI Cluster references, like its infrastructure or
+// control plane object. References without namespace point to the namespace of the Cluster, and
+// references without version, as of v1beta2 Clusters, to the preferred version of their group.
+func GetReferencedObject(ctx context.Context, c client.Client, namespace string, ref *corev1.ObjectReference) (*unstructured.Unstructured, error) {
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	if gvk.Version == "" {
+		mapping, err := c.RESTMapper().RESTMapping(gvk.GroupKind())
+		if err != nil {
+			return nil, err
+		}
+		gvk = mapping.GroupVersionKind
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// IsClusterPaused returns true if a CAPI Cluster is paused, either by spec.paused or by the
+// cluster.x-k8s.io/paused annotation.
+func IsClusterPaused(cluster CAPICluster) bool {
+	if cluster == nil {
+		return false
+	}
+	_, annotated := cluster.GetAnnotations()[clusterv1.PausedAnnotation]
+	return cluster.IsPaused() || annotated
+}
+
+// RequiredConditions returns the CAPI Cluster conditions that must be True before ArgoSecrets
+// get created.
+func (c OperatorConfig) RequiredConditions() []string {
+	conditions := []string{}
+	if c.RequireControlPlaneReady {
+		conditions = append(conditions, string(clusterv1.ControlPlaneReadyCondition))
+	}
+	for _, gate := range strings.Split(c.ReadinessGates, ",") {
+		if gate = strings.TrimSpace(gate); gate != "" && !slices.Contains(conditions, gate) {
+			conditions = append(conditions, gate)
+		}
+	}
+	return conditions
+}
+
+// UnmetConditions returns the conditions that are not True on a CAPI Cluster.
+func UnmetConditions(cluster CAPICluster, conditions []string) []string {
+	unmet := []string{}
+	for _, c := range conditions {
+		if cluster.GetConditionStatus(c) != corev1.ConditionTrue {
+			unmet = append(unmet, c)
+		}
+	}
+	return unmet
+}
+
+// ValidateClusterAPIVersion checks that the given CAPI API version is supported.
+func ValidateClusterAPIVersion(v string) error {
+	switch v {
+	case ClusterAPIVersionV1Beta2, ClusterAPIVersionV1Beta1, ClusterAPIVersionV1Alpha4:
+		return nil
+	}
+	return fmt.Errorf("unsupported cluster-api version: %s", v)
+}
+
+// NewClusterObject returns an empty Cluster object of the given CAPI API version, to watch Clusters with.
+func NewClusterObject(version string) (client.Object, error) {
+	switch version {
+	case ClusterAPIVersionV1Alpha4:
+		return &clusterv1alpha4.Cluster{}, nil
+	case ClusterAPIVersionV1Beta1:
+		return &clusterv1.Cluster{}, nil
+	case ClusterAPIVersionV1Beta2:
+		cluster := &unstructured.Unstructured{}
+		cluster.SetGroupVersionKind(ClusterV1Beta2GVK())
+		return cluster, nil
+	}
+	return nil, ValidateClusterAPIVersion(version)
+}
+
+// GetCAPICluster fetches the Cluster object of the given CAPI API version.
+func GetCAPICluster(ctx context.Context, c client.Reader, version string, nn types.NamespacedName) (CAPICluster, error) {
+	switch version {
+	case ClusterAPIVersionV1Alpha4:
+		cluster := &clusterv1alpha4.Cluster{}
+		if err := c.Get(ctx, nn, cluster); err != nil {
+			return nil, err
+		}
+		return &V1Alpha4ClusterAdapter{cluster}, nil
+	case ClusterAPIVersionV1Beta1:
+		cluster := &clusterv1.Cluster{}
+		if err := c.Get(ctx, nn, cluster); err != nil {
+			return nil, err
+		}
+		return &V1Beta1ClusterAdapter{cluster}, nil
+	case ClusterAPIVersionV1Beta2:
+		cluster := &unstructured.Unstructured{}
+		cluster.SetGroupVersionKind(ClusterV1Beta2GVK())
+		if err := c.Get(ctx, nn, cluster); err != nil {
+			return nil, err
+		}
+		return &V1Beta2ClusterAdapter{cluster}, nil
+	}
+	return nil, ValidateClusterAPIVersion(version)
+}
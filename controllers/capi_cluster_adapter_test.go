@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1alpha4 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func MockClusterMeta() metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "test",
+		Labels: map[string]string{
+			"foo": "bar",
+			fmt.Sprintf("%s%s", clusterTakeAlongKey, "foo"): "",
+		},
+	}
+}
+
+func TestCAPIClusterAdapters(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName string
+		testMock CAPICluster
+	}{
+		{"test v1beta1 adapter", &V1Beta1ClusterAdapter{&clusterv1.Cluster{
+			ObjectMeta: MockClusterMeta(),
+			Spec: clusterv1.ClusterSpec{
+				Topology:          &clusterv1.Topology{Version: "v1.28.0"},
+				InfrastructureRef: &corev1.ObjectReference{Kind: "AWSManagedCluster"},
+				ControlPlaneRef:   &corev1.ObjectReference{Kind: "AWSManagedControlPlane"},
+				Paused:            true,
+			},
+			Status: clusterv1.ClusterStatus{Phase: "Provisioned"},
+		}}},
+		{"test v1alpha4 adapter", &V1Alpha4ClusterAdapter{&clusterv1alpha4.Cluster{
+			ObjectMeta: MockClusterMeta(),
+			Spec: clusterv1alpha4.ClusterSpec{
+				Topology:          &clusterv1alpha4.Topology{Version: "v1.28.0"},
+				InfrastructureRef: &corev1.ObjectReference{Kind: "AWSManagedCluster"},
+				ControlPlaneRef:   &corev1.ObjectReference{Kind: "AWSManagedControlPlane"},
+				Paused:            true,
+			},
+			Status: clusterv1alpha4.ClusterStatus{Phase: "Provisioned"},
+		}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, "test", tt.testMock.GetName())
+			assert.Equal(t, "test", tt.testMock.GetNamespace())
+			assert.Equal(t, "Provisioned", tt.testMock.GetPhase())
+			assert.Equal(t, "bar", tt.testMock.GetLabels()["foo"])
+			assert.Equal(t, "v1.28.0", tt.testMock.GetTopologyVersion())
+			assert.True(t, tt.testMock.IsPaused())
+			assert.Equal(t, "AWSManagedCluster", tt.testMock.GetInfrastructureRef().Kind)
+			assert.Equal(t, "AWSManagedControlPlane", tt.testMock.GetControlPlaneRef().Kind)
+
+			assert.True(t, tt.testMock.SetCondition("Test", corev1.ConditionTrue, "Reason", "message"))
+			assert.False(t, tt.testMock.SetCondition("Test", corev1.ConditionTrue, "Reason", "message"))
+			assert.True(t, tt.testMock.SetCondition("Test", corev1.ConditionFalse, "Reason", "message"))
+			assert.True(t, tt.testMock.RemoveCondition("Test"))
+			assert.False(t, tt.testMock.RemoveCondition("Test"))
+
+			c := NewCapiCluster("test", "test")
+			assert.Nil(t, c.Unmarshal(MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test"), DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test"), tt.testMock)
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{
+				"foo": "bar",
+				fmt.Sprintf("%s%s", clusterTakenFromClusterKey, "foo"): "",
+			}, a.TakeAlongLabels)
+		})
+	}
+}
+
+func TestGetCAPICluster(t *testing.T) {
+	t.Parallel()
+	c := MockClient(
+		&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "beta", Namespace: "test"}},
+		&clusterv1alpha4.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "alpha", Namespace: "test"}},
+	)
+	cluster, err := GetCAPICluster(context.Background(), c, ClusterAPIVersionV1Beta1, types.NamespacedName{Name: "beta", Namespace: "test"})
+	assert.Nil(t, err)
+	assert.IsType(t, &V1Beta1ClusterAdapter{}, cluster)
+	_, err = GetCAPICluster(context.Background(), c, ClusterAPIVersionV1Beta1, types.NamespacedName{Name: "alpha", Namespace: "test"})
+	assert.NotNil(t, err)
+
+	cluster, err = GetCAPICluster(context.Background(), c, ClusterAPIVersionV1Alpha4, types.NamespacedName{Name: "alpha", Namespace: "test"})
+	assert.Nil(t, err)
+	assert.IsType(t, &V1Alpha4ClusterAdapter{}, cluster)
+	assert.Equal(t, "alpha", cluster.GetName())
+
+	_, err = GetCAPICluster(context.Background(), c, "v1alpha3", types.NamespacedName{Name: "alpha", Namespace: "test"})
+	assert.NotNil(t, err)
+}
+
+func TestNewClusterObject(t *testing.T) {
+	t.Parallel()
+	obj, err := NewClusterObject(ClusterAPIVersionV1Beta1)
+	assert.Nil(t, err)
+	assert.IsType(t, &clusterv1.Cluster{}, obj)
+	obj, err = NewClusterObject(ClusterAPIVersionV1Alpha4)
+	assert.Nil(t, err)
+	assert.IsType(t, &clusterv1alpha4.Cluster{}, obj)
+	obj, err = NewClusterObject(ClusterAPIVersionV1Beta2)
+	assert.Nil(t, err)
+	assert.Equal(t, ClusterV1Beta2GVK(), obj.GetObjectKind().GroupVersionKind())
+	_, err = NewClusterObject("v1alpha3")
+	assert.NotNil(t, err)
+}
+
+func TestValidateClusterAPIVersion(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ValidateClusterAPIVersion("v1beta1"))
+	assert.Nil(t, ValidateClusterAPIVersion("v1alpha4"))
+	assert.Nil(t, ValidateClusterAPIVersion("v1beta2"))
+	assert.NotNil(t, ValidateClusterAPIVersion("v1alpha3"))
+}
+
+func TestRequiredConditions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testRequire        bool
+		testMock           string
+		testExpectedValues []string
+	}{
+		{"test defaults", true, "", []string{"ControlPlaneReady"}},
+		{"test disabled", false, "", []string{}},
+		{"test readiness gates", true, "InfrastructureReady, ,AddonsReady", []string{"ControlPlaneReady", "InfrastructureReady", "AddonsReady"}},
+		{"test duplicate readiness gates", true, "ControlPlaneReady,AddonsReady,AddonsReady", []string{"ControlPlaneReady", "AddonsReady"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.RequireControlPlaneReady = tt.testRequire
+			config.ReadinessGates = tt.testMock
+			assert.Equal(t, tt.testExpectedValues, config.RequiredConditions())
+		})
+	}
+}
+
+func TestIsClusterPaused(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          CAPICluster
+		testExpectedValue bool
+	}{
+		{"test without cluster", nil, false},
+		{"test running cluster", &V1Beta1ClusterAdapter{&clusterv1.Cluster{}}, false},
+		{"test spec paused", &V1Beta1ClusterAdapter{&clusterv1.Cluster{Spec: clusterv1.ClusterSpec{Paused: true}}}, true},
+		{"test paused annotation", &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{clusterv1.PausedAnnotation: ""}}}}, true},
+		{"test v1alpha4 spec paused", &V1Alpha4ClusterAdapter{&clusterv1alpha4.Cluster{Spec: clusterv1alpha4.ClusterSpec{Paused: true}}}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValue, IsClusterPaused(tt.testMock))
+		})
+	}
+}
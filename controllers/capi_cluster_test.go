@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"context"
 	b64 "encoding/base64"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/yaml"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,7 +43,7 @@ func TestUnmarshal(t *testing.T) {
 		},
 		{"test type with wrong secret.Data[key]", MockCapiSecret(validMock, validType, !validKey, name, namespace), true,
 			map[string]string{
-				"ErrorMsg": "wrong secret key",
+				"ErrorMsg": "wrong secret key: value not found in secret data",
 			},
 		},
 		{"test type with wrong secret.Type", MockCapiSecret(validMock, !validType, validKey, name, namespace), true,
@@ -54,7 +57,7 @@ func TestUnmarshal(t *testing.T) {
 		t.Run(tt.testName, func(t *testing.T) {
 			t.Parallel()
 			c := NewCapiCluster(name, namespace)
-			err := c.Unmarshal(tt.testMock)
+			err := c.Unmarshal(tt.testMock, DefaultKubeConfigKey)
 			if !tt.testExpectedError {
 				assert.NotNil(t, c)
 				assert.Nil(t, err)
@@ -104,6 +107,115 @@ func TestUnmarshal(t *testing.T) {
 	}
 }
 
+func TestUnmarshalKubeConfigAPIVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues string
+	}{
+		{"test v1 kubeconfig", "apiVersion: v1\n", "v1"},
+		{"test v1beta1 kubeconfig", "apiVersion: v1beta1\n", "v1beta1"},
+		{"test kubeconfig without apiVersion", "", "v1"},
+		{"test kubeconfig with unknown apiVersion", "apiVersion: v9alpha0\n", "v9alpha0"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+			s.Data["value"] = []byte(tt.testMock + strings.TrimPrefix(string(s.Data["value"]), "apiVersion: v1\n"))
+			c := NewCapiCluster(name, namespace)
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			assert.Equal(t, tt.testExpectedValues, c.KubeConfigAPIVersion)
+			assert.Equal(t, "kube-cluster-test", c.KubeConfig.Clusters[0].Name)
+			assert.Equal(t, "https://kube-cluster-test.domain.com:6443", c.KubeConfig.Clusters[0].Cluster.Server)
+		})
+	}
+}
+
+func TestParseKubeConfig(t *testing.T) {
+	t.Parallel()
+	validYAML, _ := b64.StdEncoding.DecodeString(MockCapiKubeConfig())
+	validJSON := `{"apiVersion":"v1","kind":"Config","clusters":[{"name":"kube-cluster-test","cluster":{"server":"https://kube-cluster-test.domain.com:6443"}}],"users":[{"name":"kube-cluster-test-admin","user":{"token":"ZQ=="}}]}`
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test valid json", validJSON, false, KubeConfigFormatJSON},
+		{"test valid yaml", string(validYAML), false, KubeConfigFormatYAML},
+		{"test invalid json valid yaml", "kind: Config\nclusters:\n- name: kube-cluster-test\n  cluster:\n    server: https://kube-cluster-test.domain.com:6443\n", false, KubeConfigFormatYAML},
+		// `\/` is a valid JSON escape, unknown to YAML.
+		{"test invalid yaml valid json", strings.ReplaceAll(validJSON, "https://", `https:\/\/`), false, KubeConfigFormatJSON},
+		{"test invalid json and yaml", "{kind: [Config", true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			data, err := ParseKubeConfig([]byte(tt.testMock))
+			if tt.testExpectedError {
+				assert.ErrorContains(t, err, "failed to parse KubeConfig as json")
+				assert.ErrorContains(t, err, "nor as yaml")
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, data.Format)
+			assert.Equal(t, "kube-cluster-test", data.KubeConfig.Clusters[0].Name)
+			assert.Equal(t, "https://kube-cluster-test.domain.com:6443", data.KubeConfig.Clusters[0].Cluster.Server)
+		})
+	}
+
+	s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+	s.Data["value"] = []byte(validJSON)
+	c := NewCapiCluster(name, namespace)
+	assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+	assert.Equal(t, KubeConfigFormatJSON, c.KubeConfigFormat)
+	assert.Equal(t, "ZQ==", *c.KubeConfig.Users[0].User.Token)
+}
+
+func TestKubeConfigKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testDataKey        string
+		testFlagKey        string
+		testAnnotationKey  string
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test default key", "value", DefaultKubeConfigKey, "", false, "value"},
+		{"test flag override", "kubeconfig", "kubeconfig", "", false, "kubeconfig"},
+		{"test annotation override", "admin-kubeconfig", DefaultKubeConfigKey, "admin-kubeconfig", false, "admin-kubeconfig"},
+		{"test annotation overriding flag", "admin-kubeconfig", "kubeconfig", "admin-kubeconfig", false, "admin-kubeconfig"},
+		{"test missing key", "value", "kubeconfig", "", true, "kubeconfig"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+			s.Data = map[string][]byte{tt.testDataKey: s.Data["value"]}
+			if tt.testAnnotationKey != "" {
+				s.Annotations = map[string]string{"capi-to-argocd/kubeconfig-secret-key": tt.testAnnotationKey}
+			}
+			key := KubeConfigKey(s, tt.testFlagKey)
+			assert.Equal(t, tt.testExpectedValues, key)
+
+			c := NewCapiCluster(name, namespace)
+			err := c.Unmarshal(s, key)
+			if tt.testExpectedError {
+				assert.EqualError(t, err, "wrong secret key: "+tt.testExpectedValues+" not found in secret data")
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, "kube-cluster-test", c.KubeConfig.Clusters[0].Name)
+			}
+		})
+	}
+}
+
 func TestNewCapiCluster(t *testing.T) {
 	c := NewCapiCluster("test", "test")
 	assert.IsType(t, &CapiCluster{}, c)
@@ -120,7 +232,7 @@ func TestValidateCapiSecret(t *testing.T) {
 		{"test type with valid fields", MockCapiSecret(validMock, validType, validKey, name, namespace), false, nil},
 		{"test type with wrong secret.Data[key]", MockCapiSecret(validMock, validType, !validKey, name, namespace), true,
 			map[string]string{
-				"ErrorMsg": "wrong secret key",
+				"ErrorMsg": "wrong secret key: value not found in secret data",
 			},
 		},
 		{"test type with wrong secret.Type", MockCapiSecret(validMock, !validType, validKey, name, namespace), true,
@@ -133,7 +245,7 @@ func TestValidateCapiSecret(t *testing.T) {
 		tt := tt
 		t.Run(tt.testName, func(t *testing.T) {
 			t.Parallel()
-			err := ValidateCapiSecret(tt.testMock)
+			err := ValidateCapiSecret(tt.testMock, DefaultKubeConfigKey)
 			if !tt.testExpectedError {
 				assert.Nil(t, err)
 			} else {
@@ -147,3 +259,182 @@ func TestValidateCapiSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalExecConfig(t *testing.T) {
+	t.Parallel()
+	kubeConfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: kube-cluster-test
+  cluster:
+    server: https://kube-cluster-test.domain.com:6443
+    certificate-authority-data: Y2E=
+users:
+- name: kube-cluster-test-admin
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: [eks, get-token, --cluster-name, kube-cluster-test]
+      env:
+      - name: AWS_PROFILE
+        value: prod
+      installHint: Install the aws cli
+`
+	s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+	s.Data["value"] = []byte(kubeConfig)
+	c := NewCapiCluster(name, namespace)
+	assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+	assert.Equal(t, &ExecConfig{
+		APIVersion:  "client.authentication.k8s.io/v1beta1",
+		Command:     "aws",
+		Args:        []string{"eks", "get-token", "--cluster-name", "kube-cluster-test"},
+		Env:         []ExecEnvVar{{Name: "AWS_PROFILE", Value: "prod"}},
+		InstallHint: "Install the aws cli",
+	}, c.KubeConfig.Users[0].User.Exec)
+
+	a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, nil)
+	assert.Nil(t, err)
+	argoSecret, err := a.ConvertToSecret()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"tlsClientConfig":{"caData":"Y2E="},"execProviderConfig":{"command":"aws","args":["eks","get-token","--cluster-name","kube-cluster-test"],"env":{"AWS_PROFILE":"prod"},"apiVersion":"client.authentication.k8s.io/v1beta1","installHint":"Install the aws cli"}}`, string(argoSecret.Data["config"]))
+}
+
+func TestUnmarshalCurrentContext(t *testing.T) {
+	t.Parallel()
+	clusters := `apiVersion: v1
+kind: Config
+clusters:
+- name: other
+  cluster:
+    server: https://other.domain.com:6443
+- name: default
+  cluster:
+    server: https://rke2.domain.com:9345
+users:
+- name: other
+  user:
+    token: other
+- name: default
+  user:
+    token: rke2
+`
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedError  bool
+		testExpectedValues map[string]string
+	}{
+		{"test without current-context", clusters, false,
+			map[string]string{"ClusterName": "other", "Server": "https://other.domain.com:6443", "Token": "other"}},
+		{"test current-context", clusters + "contexts:\n- name: default\n  context:\n    cluster: default\n    user: default\ncurrent-context: default\n", false,
+			map[string]string{"ClusterName": name, "Server": "https://rke2.domain.com:9345", "Token": "rke2"}},
+		{"test unknown current-context", clusters + "current-context: default\n", true,
+			map[string]string{"ErrorMsg": "invalid KubeConfig: current-context default not found"}},
+		{"test unknown user of current-context", clusters + "contexts:\n- name: default\n  context:\n    cluster: default\n    user: admin\ncurrent-context: default\n", true,
+			map[string]string{"ErrorMsg": "invalid KubeConfig: cluster default or user admin of current-context default not found"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+			s.Data["value"] = []byte(tt.testMock)
+			c := NewCapiCluster(name, namespace)
+			err := c.Unmarshal(s, DefaultKubeConfigKey)
+			if tt.testExpectedError {
+				assert.EqualError(t, err, tt.testExpectedValues["ErrorMsg"])
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues["ClusterName"], c.KubeConfig.Clusters[0].Name)
+			assert.Equal(t, tt.testExpectedValues["Server"], c.KubeConfig.Clusters[0].Cluster.Server)
+			assert.Equal(t, tt.testExpectedValues["Token"], *c.KubeConfig.Users[0].User.Token)
+		})
+	}
+}
+
+func TestUnmarshalProxyURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues string
+	}{
+		{"test proxy-url", "    proxy-url: http://proxy.example.com:3128\n", `{"tlsClientConfig":{"caData":"Y2E="},"bearerToken":"dG9rZW4=","proxyUrl":"http://proxy.example.com:3128"}`},
+		{"test no proxy-url", "", `{"tlsClientConfig":{"caData":"Y2E="},"bearerToken":"dG9rZW4="}`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: kube-cluster-test\n  cluster:\n    server: https://kube-cluster-test.domain.com:6443\n    certificate-authority-data: Y2E=\n" +
+				tt.testMock + "users:\n- name: kube-cluster-test-admin\n  user:\n    token: dG9rZW4=\n"
+			s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+			s.Data["value"] = []byte(kubeConfig)
+			c := NewCapiCluster(name, namespace)
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, nil)
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.testExpectedValues, string(argoSecret.Data["config"]))
+		})
+	}
+}
+
+func TestInsecureSkipTLSVerify(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testAllowed        bool
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test insecure allowed", "", true, false, `{"tlsClientConfig":{"insecure":true},"bearerToken":"dG9rZW4="}`},
+		{"test insecure with CA allowed", "    certificate-authority-data: Y2E=\n", true, false, `{"tlsClientConfig":{"insecure":true,"caData":"Y2E="},"bearerToken":"dG9rZW4="}`},
+		{"test insecure forbidden", "", false, true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			kubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: kube-cluster-test\n  cluster:\n    server: https://kube-cluster-test.domain.com:6443\n    insecure-skip-tls-verify: true\n" +
+				tt.testMock + "users:\n- name: kube-cluster-test-admin\n  user:\n    token: dG9rZW4=\n"
+			s := MockCapiSecret(validMock, validType, validKey, name, namespace)
+			s.Data["value"] = []byte(kubeConfig)
+			c := NewCapiCluster(name, namespace)
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			config := MockOperatorConfig()
+			config.AllowInsecureClusters = tt.testAllowed
+			a, err := NewArgoCluster(MockReconcileContext(config), c, s, nil)
+			if tt.testExpectedError {
+				assert.ErrorIs(t, err, ErrInsecureCluster)
+				return
+			}
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.testExpectedValues, string(argoSecret.Data["config"]))
+		})
+	}
+}
+
+func TestReconcileInsecureCluster(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "insecure-kubeconfig", TestNamespace)
+	capiSecret.Data["value"] = []byte("apiVersion: v1\nkind: Config\nclusters:\n- name: insecure\n  cluster:\n    server: https://insecure.domain.com:6443\n    insecure-skip-tls-verify: true\nusers:\n- name: insecure-admin\n  user:\n    token: dG9rZW4=\n")
+	c := MockClient(capiSecret)
+	recorder := &MockRecorder{}
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder}
+
+	// Rejected clusters are not retried, as only a change of the kubeconfig helps.
+	result, err := r.Reconcile(ctx, MockReconcileReq("insecure-kubeconfig", TestNamespace))
+	assert.Nil(t, err)
+	assert.Zero(t, result)
+	assert.Equal(t, []string{"InsecureClusterRejected"}, recorder.ForObject("Secret", "insecure-kubeconfig"))
+	var argoSecret corev1.Secret
+	assert.True(t, errors.IsNotFound(c.Get(ctx, r.Config.BuildNamespacedName("insecure-kubeconfig", TestNamespace), &argoSecret)))
+}
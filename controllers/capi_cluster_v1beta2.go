@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterV1Beta2GVK returns the GroupVersionKind of v1beta2 Clusters, which are read unstructured
+// as the vendored cluster-api release predates them.
+func ClusterV1Beta2GVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: clusterv1.GroupVersion.Group, Version: ClusterAPIVersionV1Beta2, Kind: "Cluster"}
+}
+
+// V1Beta2ClusterAdapter adapts an unstructured v1beta2 Cluster to CAPICluster.
+type V1Beta2ClusterAdapter struct {
+	*unstructured.Unstructured
+}
+
+// GetPhase returns the Cluster phase.
+func (a *V1Beta2ClusterAdapter) GetPhase() string {
+	phase, _, _ := unstructured.NestedString(a.Unstructured.Object, "status", "phase")
+	return phase
+}
+
+// GetTopologyVersion returns the ClusterClass topology version.
+func (a *V1Beta2ClusterAdapter) GetTopologyVersion() string {
+	version, _, _ := unstructured.NestedString(a.Unstructured.Object, "spec", "topology", "version")
+	return version
+}
+
+// GetTopologyVariables returns the ClusterClass topology variables.
+func (a *V1Beta2ClusterAdapter) GetTopologyVariables() map[string][]byte {
+	list, found, _ := unstructured.NestedSlice(a.Unstructured.Object, "spec", "topology", "variables")
+	if !found {
+		return nil
+	}
+	variables := map[string][]byte{}
+	for _, item := range list {
+		v, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := v["name"].(string)
+		raw, err := json.Marshal(v["value"])
+		if name == "" || err != nil {
+			continue
+		}
+		variables[name] = raw
+	}
+	return variables
+}
+
+// IsPaused returns true if spec.paused is set.
+func (a *V1Beta2ClusterAdapter) IsPaused() bool {
+	paused, _, _ := unstructured.NestedBool(a.Unstructured.Object, "spec", "paused")
+	return paused
+}
+
+// GetInfrastructureRef returns the reference to the infrastructure provider object.
+func (a *V1Beta2ClusterAdapter) GetInfrastructureRef() *corev1.ObjectReference {
+	return a.contractReference("infrastructureRef")
+}
+
+// GetControlPlaneRef returns the reference to the control plane provider object.
+func (a *V1Beta2ClusterAdapter) GetControlPlaneRef() *corev1.ObjectReference {
+	return a.contractReference("controlPlaneRef")
+}
+
+// GetControlPlaneEndpoint returns the host and port of the API server.
+func (a *V1Beta2ClusterAdapter) GetControlPlaneEndpoint() (string, int32) {
+	host, _, _ := unstructured.NestedString(a.Unstructured.Object, "spec", "controlPlaneEndpoint", "host")
+	port, _, _ := unstructured.NestedInt64(a.Unstructured.Object, "spec", "controlPlaneEndpoint", "port")
+	return host, int32(port)
+}
+
+// contractReference converts a v1beta2 contract reference, holding the API group but no version,
+// to an ObjectReference. Its version is left empty, to be resolved by GetReferencedObject.
+func (a *V1Beta2ClusterAdapter) contractReference(field string) *corev1.ObjectReference {
+	ref, found, _ := unstructured.NestedStringMap(a.Unstructured.Object, "spec", field)
+	if !found || ref["name"] == "" {
+		return nil
+	}
+	return &corev1.ObjectReference{
+		APIVersion: schema.GroupVersion{Group: ref["apiGroup"]}.String(),
+		Kind:       ref["kind"],
+		Name:       ref["name"],
+		Namespace:  a.GetNamespace(),
+	}
+}
+
+// GetConditionStatus returns the status of a status condition. Conditions that were dropped by
+// v1beta2, like ControlPlaneReady, are read from the deprecated v1beta1 conditions.
+func (a *V1Beta2ClusterAdapter) GetConditionStatus(conditionType string) corev1.ConditionStatus {
+	for _, path := range [][]string{{"status", "conditions"}, {"status", "deprecated", "v1beta1", "conditions"}} {
+		conditions, _, _ := unstructured.NestedSlice(a.Unstructured.Object, path...)
+		if i := indexCondition(conditions, conditionType); i >= 0 {
+			status, _ := conditions[i].(map[string]interface{})["status"].(string)
+			return corev1.ConditionStatus(status)
+		}
+	}
+	return ""
+}
+
+// SetCondition sets a status condition.
+func (a *V1Beta2ClusterAdapter) SetCondition(conditionType string, status corev1.ConditionStatus, reason, message string) bool {
+	conditions, _, _ := unstructured.NestedSlice(a.Unstructured.Object, "status", "conditions")
+	condition := map[string]interface{}{
+		"type":               conditionType,
+		"status":             string(status),
+		"reason":             reason,
+		"message":            message,
+		"observedGeneration": a.GetGeneration(),
+		"lastTransitionTime": metav1.Now().UTC().Format(metav1.RFC3339Micro),
+	}
+	if i := indexCondition(conditions, conditionType); i >= 0 {
+		existing := conditions[i].(map[string]interface{})
+		if existing["status"] == string(status) && existing["reason"] == reason && existing["message"] == message {
+			return false
+		}
+		if existing["status"] == string(status) {
+			condition["lastTransitionTime"] = existing["lastTransitionTime"]
+		}
+		conditions[i] = condition
+	} else {
+		conditions = append(conditions, condition)
+	}
+	return unstructured.SetNestedSlice(a.Unstructured.Object, conditions, "status", "conditions") == nil
+}
+
+// RemoveCondition removes a status condition.
+func (a *V1Beta2ClusterAdapter) RemoveCondition(conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(a.Unstructured.Object, "status", "conditions")
+	i := indexCondition(conditions, conditionType)
+	if i < 0 {
+		return false
+	}
+	return unstructured.SetNestedSlice(a.Unstructured.Object, slices.Delete(conditions, i, i+1), "status", "conditions") == nil
+}
+
+// Object returns the underlying Cluster object.
+func (a *V1Beta2ClusterAdapter) Object() client.Object {
+	return a.Unstructured
+}
+
+// indexCondition returns the index of a condition in an unstructured condition list, -1 if absent.
+func indexCondition(conditions []interface{}, conditionType string) int {
+	return slices.IndexFunc(conditions, func(c interface{}) bool {
+		m, ok := c.(map[string]interface{})
+		return ok && m["type"] == conditionType
+	})
+}
+
+// DetectClusterAPIVersion returns the preferred version of the Cluster API served by the
+// management cluster.
+func DetectClusterAPIVersion(mapper meta.RESTMapper) (string, error) {
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: clusterv1.GroupVersion.Group, Kind: "Cluster"})
+	if err != nil {
+		return "", err
+	}
+	version := mapping.GroupVersionKind.Version
+	if err := ValidateClusterAPIVersion(version); err != nil {
+		return "", fmt.Errorf("preferred cluster-api version is not supported: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func MockV1Beta2Scheme() *runtime.Scheme {
+	s := MockScheme()
+	s.AddKnownTypeWithName(ClusterV1Beta2GVK(), &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(kubeadmControlPlaneGVK, &unstructured.Unstructured{})
+	return s
+}
+
+func MockV1Beta2Cluster(name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"paused":          true,
+			"controlPlaneRef": map[string]interface{}{"apiGroup": kubeadmControlPlaneGVK.Group, "kind": kubeadmControlPlaneGVK.Kind, "name": name + "-cp"},
+			"topology": map[string]interface{}{
+				"version":   "v1.31.0",
+				"variables": []interface{}{map[string]interface{}{"name": "env", "value": map[string]interface{}{"tier": "gold"}}},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase":      "Provisioned",
+			"conditions": []interface{}{map[string]interface{}{"type": "Available", "status": "True", "reason": "Available"}},
+			"deprecated": map[string]interface{}{"v1beta1": map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "ControlPlaneReady", "status": "True"}},
+			}},
+		},
+	}}
+	u.SetGroupVersionKind(ClusterV1Beta2GVK())
+	u.SetName(name)
+	u.SetNamespace(TestNamespace)
+	return u
+}
+
+func TestV1Beta2ClusterAdapter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	scheme := MockV1Beta2Scheme()
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{kubeadmControlPlaneGVK.GroupVersion()})
+	mapper.Add(kubeadmControlPlaneGVK, meta.RESTScopeNamespace)
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithObjects(MockV1Beta2Cluster("beta2"), MockControlPlane("beta2-cp", nil)).
+		WithStatusSubresource(MockV1Beta2Cluster("beta2")).
+		Build()
+
+	cluster, err := GetCAPICluster(ctx, c, ClusterAPIVersionV1Beta2, types.NamespacedName{Name: "beta2", Namespace: TestNamespace})
+	assert.Nil(t, err)
+	assert.IsType(t, &V1Beta2ClusterAdapter{}, cluster)
+	assert.Equal(t, "beta2", cluster.GetName())
+	assert.Equal(t, "Provisioned", cluster.GetPhase())
+	assert.Equal(t, "v1.31.0", cluster.GetTopologyVersion())
+	assert.Equal(t, map[string][]byte{"env": []byte(`{"tier":"gold"}`)}, cluster.GetTopologyVariables())
+	assert.True(t, cluster.IsPaused())
+	assert.Nil(t, cluster.GetInfrastructureRef())
+	assert.Equal(t, corev1.ConditionTrue, cluster.GetConditionStatus("Available"))
+	assert.Equal(t, corev1.ConditionTrue, cluster.GetConditionStatus(string(clusterv1.ControlPlaneReadyCondition)))
+	assert.Equal(t, corev1.ConditionStatus(""), cluster.GetConditionStatus("InfrastructureReady"))
+
+	cp, err := GetReferencedObject(ctx, c, cluster.GetNamespace(), cluster.GetControlPlaneRef())
+	assert.Nil(t, err)
+	assert.Equal(t, kubeadmControlPlaneGVK, cp.GroupVersionKind())
+	assert.Equal(t, "beta2-cp", cp.GetName())
+
+	assert.True(t, cluster.SetCondition("Blocked", corev1.ConditionTrue, "Reason", "message"))
+	assert.False(t, cluster.SetCondition("Blocked", corev1.ConditionTrue, "Reason", "message"))
+	assert.Equal(t, corev1.ConditionTrue, cluster.GetConditionStatus("Blocked"))
+	assert.Nil(t, c.Status().Update(ctx, cluster.Object()))
+	assert.True(t, cluster.RemoveCondition("Blocked"))
+	assert.False(t, cluster.RemoveCondition("Blocked"))
+	assert.Equal(t, corev1.ConditionStatus(""), cluster.GetConditionStatus("Blocked"))
+}
+
+func TestDetectClusterAPIVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          []schema.GroupVersion
+		testExpectedError bool
+		testExpectedValue string
+	}{
+		{"test v1beta2", []schema.GroupVersion{{Group: clusterv1.GroupVersion.Group, Version: ClusterAPIVersionV1Beta2}, clusterv1.GroupVersion}, false, ClusterAPIVersionV1Beta2},
+		{"test v1beta1", []schema.GroupVersion{clusterv1.GroupVersion}, false, ClusterAPIVersionV1Beta1},
+		{"test unsupported version", []schema.GroupVersion{{Group: clusterv1.GroupVersion.Group, Version: "v1alpha3"}}, true, ""},
+		{"test not installed", nil, true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			mapper := meta.NewDefaultRESTMapper(tt.testMock)
+			for _, gv := range tt.testMock {
+				mapper.Add(gv.WithKind("Cluster"), meta.RESTScopeNamespace)
+			}
+			version, err := DetectClusterAPIVersion(mapper)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.testExpectedValue, version)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DefaultChangelogMaxEntries is the default number of changes kept per cluster.
+	DefaultChangelogMaxEntries = 10
+	// ChangelogPathPrefix is the HTTP path prefix the changelog is served on, as
+	// `/clusters/<namespace>/<name>/changelog`.
+	ChangelogPathPrefix = "/clusters/"
+)
+
+// ChangeEntry is a single change applied to an ArgoSecret. It only lists the names of
+// the fields that changed, never their values.
+type ChangeEntry struct {
+	Timestamp     time.Time   `json:"timestamp"`
+	Action        AuditAction `json:"action"`
+	FieldsChanged []string    `json:"fields-changed"`
+	// TriggeredBy is the resourceVersion of the CAPI Secret that triggered the change.
+	TriggeredBy string `json:"triggered-by"`
+}
+
+// ChangelogResponse is the JSON body served for the changelog of a cluster.
+type ChangelogResponse struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Entries   []ChangeEntry `json:"entries"`
+}
+
+// ChangelogRecorder keeps the last changes applied to every ArgoSecret in memory, keyed by
+// the ArgoSecret NamespacedName. Every cluster holds a ring buffer of maxEntries entries,
+// so the oldest entry gets evicted once it is full.
+type ChangelogRecorder struct {
+	mu         sync.RWMutex
+	maxEntries int
+	rings      map[types.NamespacedName]*changelogRing
+	now        func() time.Time
+}
+
+// changelogRing is a fixed size ring buffer of ChangeEntries.
+type changelogRing struct {
+	entries []ChangeEntry
+	next    int
+}
+
+// NewChangelogRecorder returns a ChangelogRecorder keeping up to maxEntries changes per cluster.
+// A non-positive maxEntries falls back to DefaultChangelogMaxEntries.
+func NewChangelogRecorder(maxEntries int) *ChangelogRecorder {
+	if maxEntries <= 0 {
+		maxEntries = DefaultChangelogMaxEntries
+	}
+	return &ChangelogRecorder{
+		maxEntries: maxEntries,
+		rings:      map[types.NamespacedName]*changelogRing{},
+		now:        time.Now,
+	}
+}
+
+// Record appends a change to the changelog of a cluster, evicting its oldest change when full.
+func (c *ChangelogRecorder) Record(nn types.NamespacedName, entry ChangeEntry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = c.now().UTC()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ring, ok := c.rings[nn]
+	if !ok {
+		ring = &changelogRing{entries: make([]ChangeEntry, 0, c.maxEntries)}
+		c.rings[nn] = ring
+	}
+	if len(ring.entries) < c.maxEntries {
+		ring.entries = append(ring.entries, entry)
+		return
+	}
+	ring.entries[ring.next] = entry
+	ring.next = (ring.next + 1) % c.maxEntries
+}
+
+// Entries returns the changes of a cluster from oldest to newest.
+func (c *ChangelogRecorder) Entries(nn types.NamespacedName) []ChangeEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ring, ok := c.rings[nn]
+	if !ok {
+		return nil
+	}
+	entries := make([]ChangeEntry, 0, len(ring.entries))
+	entries = append(entries, ring.entries[ring.next:]...)
+	return append(entries, ring.entries[:ring.next]...)
+}
+
+// ServeHTTP serves the changelog of a cluster as JSON on `/clusters/<namespace>/<name>/changelog`.
+func (c *ChangelogRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, ChangelogPathPrefix), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] != "changelog" {
+		http.NotFound(w, req)
+		return
+	}
+	nn := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+	entries := c.Entries(nn)
+	if entries == nil {
+		http.Error(w, "no changelog for cluster "+nn.String(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ChangelogResponse{Namespace: nn.Namespace, Name: nn.Name, Entries: entries})
+}
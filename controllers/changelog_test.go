@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestChangelogRecorder(t *testing.T) {
+	t.Parallel()
+	c := NewChangelogRecorder(3)
+	nn := types.NamespacedName{Namespace: "argocd", Name: "cluster-test"}
+	assert.Nil(t, c.Entries(nn))
+
+	for i := 1; i <= 5; i++ {
+		c.Record(nn, ChangeEntry{Action: AuditActionUpdate, TriggeredBy: fmt.Sprint(i)})
+	}
+	entries := c.Entries(nn)
+	assert.Len(t, entries, 3)
+	// The oldest entries got evicted, the rest stays ordered.
+	for i, e := range entries {
+		assert.Equal(t, fmt.Sprint(i+3), e.TriggeredBy)
+		assert.False(t, e.Timestamp.IsZero())
+	}
+	assert.Nil(t, c.Entries(types.NamespacedName{Namespace: "argocd", Name: "cluster-other"}))
+	assert.Equal(t, DefaultChangelogMaxEntries, NewChangelogRecorder(0).maxEntries)
+}
+
+func TestChangelogRecorderConcurrency(t *testing.T) {
+	t.Parallel()
+	c := NewChangelogRecorder(DefaultChangelogMaxEntries)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		nn := types.NamespacedName{Namespace: "argocd", Name: fmt.Sprintf("cluster-%d", i%4)}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Record(nn, ChangeEntry{Action: AuditActionUpdate})
+				_ = c.Entries(nn)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 4; i++ {
+		assert.Len(t, c.Entries(types.NamespacedName{Namespace: "argocd", Name: fmt.Sprintf("cluster-%d", i)}), DefaultChangelogMaxEntries)
+	}
+}
+
+func TestChangelogRecorderServeHTTP(t *testing.T) {
+	t.Parallel()
+	c := NewChangelogRecorder(DefaultChangelogMaxEntries)
+	c.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	c.Record(types.NamespacedName{Namespace: "argocd", Name: "cluster-test"}, ChangeEntry{
+		Action:        AuditActionUpdate,
+		FieldsChanged: []string{"data.server"},
+		TriggeredBy:   "42",
+	})
+
+	tests := []struct {
+		testName           string
+		testMethod         string
+		testPath           string
+		testExpectedStatus int
+		testExpectedValues string
+	}{
+		{"test known cluster", http.MethodGet, "/clusters/argocd/cluster-test/changelog", http.StatusOK,
+			`{"namespace":"argocd","name":"cluster-test","entries":[{"timestamp":"2024-01-02T03:04:05Z","action":"Update","fields-changed":["data.server"],"triggered-by":"42"}]}` + "\n"},
+		{"test unknown cluster", http.MethodGet, "/clusters/argocd/cluster-other/changelog", http.StatusNotFound, ""},
+		{"test invalid path", http.MethodGet, "/clusters/argocd/changelog", http.StatusNotFound, ""},
+		{"test invalid method", http.MethodPost, "/clusters/argocd/cluster-test/changelog", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			c.ServeHTTP(w, httptest.NewRequest(tt.testMethod, tt.testPath, nil))
+			assert.Equal(t, tt.testExpectedStatus, w.Code)
+			if tt.testExpectedValues != "" {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				assert.Equal(t, tt.testExpectedValues, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReconcileChangelog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "changelog-kubeconfig", TestNamespace)
+	c := MockClient(capiSecret)
+	changelog := NewChangelogRecorder(DefaultChangelogMaxEntries)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Changelog: changelog}
+	req := MockReconcileReq("changelog-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("changelog-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	entries := changelog.Entries(nn)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, AuditActionCreate, entries[0].Action)
+		assert.Contains(t, entries[0].FieldsChanged, "data.config")
+		assert.NotEmpty(t, entries[0].TriggeredBy)
+	}
+
+	w := httptest.NewRecorder()
+	changelog.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ChangelogPathPrefix+nn.Namespace+"/"+nn.Name+"/changelog", nil))
+	var resp ChangelogResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, entries, resp.Entries)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ArgoClusterDefaultsGVK returns the GroupVersionKind of ArgoClusterDefaults.
+func ArgoClusterDefaultsGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "capi2argo.dntosas.io", Version: "v1alpha1", Kind: "ArgoClusterDefaults"}
+}
+
+// ArgoClusterDefaultsListGVK returns the GroupVersionKind of ArgoClusterDefaults lists.
+func ArgoClusterDefaultsListGVK() schema.GroupVersionKind {
+	gvk := ArgoClusterDefaultsGVK()
+	gvk.Kind += "List"
+	return gvk
+}
+
+// ClusterDefaults is the spec of an ArgoClusterDefaults, holding the defaults of the clusters
+// converted from its namespace. Annotations of a CAPI Cluster take precedence.
+type ClusterDefaults struct {
+	Project          string            `json:"project,omitempty"`
+	Namespaces       []string          `json:"namespaces,omitempty"`
+	ClusterResources *bool             `json:"clusterResources,omitempty"`
+	Shard            *int              `json:"shard,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+}
+
+// Apply layers the defaults on top of the operator configuration of a reconcile.
+func (d *ClusterDefaults) Apply(rc *ReconcileContext) {
+	if len(d.Namespaces) > 0 {
+		rc.Config.ClusterNamespaces = strings.Join(d.Namespaces, ",")
+	}
+	if d.ClusterResources != nil {
+		rc.Config.ClusterResources = strconv.FormatBool(*d.ClusterResources)
+	}
+	if d.Shard != nil {
+		rc.Config.ClusterShard = *d.Shard
+	}
+	rc.Defaults = d
+}
+
+// readClusterDefaults returns the ArgoClusterDefaults of a namespace, nil when there are none or
+// the CRD is not installed. With several of them, the first by name wins.
+func (r *Capi2Argo) readClusterDefaults(ctx context.Context, log logr.Logger, namespace string) (*ClusterDefaults, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(ArgoClusterDefaultsListGVK())
+	if err := r.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+	for _, ignored := range list.Items[1:] {
+		log.Info("Warning: multiple ArgoClusterDefaults in namespace. Ignoring", "name", ignored.GetName(), "using", list.Items[0].GetName())
+	}
+	spec, _, err := unstructured.NestedMap(list.Items[0].Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	defaults := &ClusterDefaults{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, defaults); err != nil {
+		return nil, err
+	}
+	for _, k := range DropReservedLabels(defaults.Labels) {
+		log.Info("Warning: label of ArgoClusterDefaults is reserved by the operator. Ignoring", "name", list.Items[0].GetName(), "label", k)
+	}
+	return defaults, nil
+}
+
+// mapClusterDefaults maps ArgoClusterDefaults to a reconcile of every CAPI Secret in their namespace.
+func (r *Capi2Argo) mapClusterDefaults(ctx context.Context, obj client.Object) []ctrl.Request {
+	return r.capiSecretRequests(ctx, obj.GetNamespace())
+}
+
+// capiSecretRequests returns a reconcile request for every CAPI Secret of a namespace.
+func (r *Capi2Argo) capiSecretRequests(ctx context.Context, namespace string) []ctrl.Request {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(namespace)); err != nil {
+		r.Log.Error(err, "Failed to list CapiSecrets", "namespace", namespace)
+		return nil
+	}
+	requests := []ctrl.Request{}
+	for _, s := range secrets.Items {
+		if s.Type != CapiClusterSecretType && !IsHostedControlPlaneSecret(&s) {
+			continue
+		}
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: s.Name, Namespace: s.Namespace}})
+	}
+	return requests
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileClusterDefaults(t *testing.T) {
+	t.Parallel()
+	defaults := MockUnstructured(ArgoClusterDefaultsGVK(), "defaults", TestNamespace, map[string]interface{}{
+		"project":          "team-a",
+		"namespaces":       []interface{}{"apps", "infra"},
+		"clusterResources": true,
+		"shard":            int64(2),
+		"labels":           map[string]interface{}{"env": "prod", "tier": "gold"},
+	})
+	tests := []struct {
+		testName           string
+		testCRDInstalled   bool
+		testDefaults       []client.Object
+		testAnnotations    map[string]string
+		testLabels         map[string]string
+		testExpectedData   map[string]string
+		testExpectedLabels map[string]string
+	}{
+		{"test CRD not installed", false, nil, nil, nil,
+			map[string]string{"project": "", "namespaces": "", "clusterResources": "", "shard": ""}, map[string]string{"env": ""}},
+		{"test no defaults", true, nil, nil, nil,
+			map[string]string{"project": "", "namespaces": "", "clusterResources": "", "shard": ""}, map[string]string{"env": ""}},
+		{"test defaults", true, []client.Object{defaults}, nil, nil,
+			map[string]string{"project": "team-a", "namespaces": "apps,infra", "clusterResources": "true", "shard": "2"},
+			map[string]string{"env": "prod", "tier": "gold"}},
+		{"test annotations override defaults", true, []client.Object{defaults},
+			map[string]string{projectAnnotation: "team-b", namespacesAnnotation: "web", clusterResourcesAnnotation: "false", shardAnnotation: "5"},
+			map[string]string{"env": "stage", clusterTakeAlongKey + "env": ""},
+			map[string]string{"project": "team-b", "namespaces": "web", "clusterResources": "false", "shard": "5"},
+			map[string]string{"env": "stage", "tier": "gold"}},
+		{"test reserved labels dropped", true, []client.Object{MockUnstructured(ArgoClusterDefaultsGVK(), "reserved", TestNamespace, map[string]interface{}{
+			"labels": map[string]interface{}{"env": "prod", argoSecretTypeLabel: "repository", ownedLabel: "false"},
+		})}, nil, nil, nil, map[string]string{"env": "prod", argoSecretTypeLabel: "cluster", ownedLabel: "true"}},
+		{"test first defaults by name win", true, []client.Object{defaults, MockUnstructured(ArgoClusterDefaultsGVK(), "other", TestNamespace, map[string]interface{}{"project": "team-c"})}, nil, nil,
+			map[string]string{"project": "team-a"}, map[string]string{"env": "prod"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			scheme := MockScheme()
+			if tt.testCRDInstalled {
+				scheme = MockUnstructuredScheme(ArgoClusterDefaultsGVK(), ArgoClusterDefaultsListGVK())
+			}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Annotations: tt.testAnnotations, Labels: tt.testLabels}, Status: MockReadyClusterStatus()}
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(MockCapiSecret(validMock, validType, validKey, "defaults-kubeconfig", TestNamespace), cluster).
+				WithObjects(tt.testDefaults...).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+			r.Config.EnableClusterDefaults = true
+			req := MockReconcileReq("defaults-kubeconfig", TestNamespace)
+
+			_, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("defaults-kubeconfig", TestNamespace), &argoSecret))
+			for k, v := range tt.testExpectedData {
+				assert.Equal(t, v, string(argoSecret.Data[k]), k)
+			}
+			for k, v := range tt.testExpectedLabels {
+				assert.Equal(t, v, argoSecret.Labels[k], k)
+			}
+		})
+	}
+}
+
+func TestMapClusterDefaults(t *testing.T) {
+	t.Parallel()
+	c := MockClient(
+		MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", TestNamespace),
+		MockCapiSecret(validMock, false, validKey, "unrelated", TestNamespace),
+		MockCapiSecret(validMock, validType, validKey, "other-kubeconfig", "other"),
+	)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace}}},
+		r.mapClusterDefaults(context.Background(), MockUnstructured(ArgoClusterDefaultsGVK(), "defaults", TestNamespace, nil)))
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// argoClusterStatusAnnotation is the ArgoCD managed connection status of a cluster secret.
+	argoClusterStatusAnnotation = "argocd.argoproj.io/cluster-status"
+	// argoConnectionStatusAnnotation feeds the ArgoCD connection status back on the CAPI Secret.
+	argoConnectionStatusAnnotation = "capi-to-argocd/argo-connection-status"
+	// argoConnectionStatusError is the fed back status of unhealthy connections.
+	argoConnectionStatusError = "Error"
+)
+
+// ClusterProbe watches ArgoSecrets for the connection status ArgoCD reports and feeds
+// failures back on the CAPI Secret they were generated from.
+type ClusterProbe struct {
+	// Client reads ArgoSecrets.
+	client.Client
+	// PlatformCluster is the remote cluster running ArgoCD, watched for ArgoSecrets.
+	// When nil, ArgoSecrets are watched on the manager cluster.
+	PlatformCluster cluster.Cluster
+	// SourceClient reads and annotates CAPI objects, defaults to Client.
+	SourceClient client.Client
+	Log          logr.Logger
+	Config       OperatorConfig
+	// Recorder emits events on CAPI Cluster objects.
+	Recorder record.EventRecorder
+}
+
+// Reconcile syncs the ArgoCD connection status of an ArgoSecret to its CAPI Secret.
+func (p *ClusterProbe) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := p.Log.WithValues("cluster", req.NamespacedName)
+
+	var argoSecret corev1.Secret
+	if err := p.Get(ctx, req.NamespacedName, &argoSecret); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if p.Config.ValidateOwner(argoSecret) != nil {
+		return ctrl.Result{}, nil
+	}
+
+	nn := types.NamespacedName{
+		Name:      argoSecret.Labels[clusterSecretNameLabel],
+		Namespace: argoSecret.Labels[clusterNamespaceLabel],
+	}
+	if nn.Name == "" || nn.Namespace == "" {
+		log.Info("ArgoSecret is missing its CAPI Secret back-reference, skipping...")
+		return ctrl.Result{}, nil
+	}
+	var capiSecret corev1.Secret
+	if err := p.sourceClient().Get(ctx, nn, &capiSecret); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	failing := IsArgoConnectionFailing(argoSecret.Annotations[argoClusterStatusAnnotation])
+	_, annotated := capiSecret.Annotations[argoConnectionStatusAnnotation]
+	if failing == annotated {
+		return ctrl.Result{}, nil
+	}
+
+	if failing {
+		if capiSecret.Annotations == nil {
+			capiSecret.Annotations = map[string]string{}
+		}
+		capiSecret.Annotations[argoConnectionStatusAnnotation] = argoConnectionStatusError
+	} else {
+		delete(capiSecret.Annotations, argoConnectionStatusAnnotation)
+	}
+	if err := p.sourceClient().Update(ctx, &capiSecret); err != nil {
+		log.Error(err, "Failed to update ArgoCD connection status of CapiSecret")
+		return ctrl.Result{}, err
+	}
+	log.Info("Updated ArgoCD connection status of CapiSecret", "failing", failing)
+
+	if failing && p.Recorder != nil {
+		cluster, err := GetCAPICluster(ctx, p.sourceClient(), p.Config.ClusterAPIVersion, types.NamespacedName{Name: capiSecret.Labels[clusterv1.ClusterNameLabel], Namespace: capiSecret.Namespace})
+		if err != nil {
+			log.Info("Failed to get Cluster object", "error", err)
+			return ctrl.Result{}, nil
+		}
+		p.Recorder.Event(cluster.Object(), corev1.EventTypeWarning, "ArgoConnectionFailed", "ArgoCD reports connection status "+argoSecret.Annotations[argoClusterStatusAnnotation]+" for "+req.NamespacedName.String())
+	}
+	return ctrl.Result{}, nil
+}
+
+// IsArgoConnectionFailing returns true if an ArgoCD cluster status reports an unhealthy connection.
+func IsArgoConnectionFailing(status string) bool {
+	return status == "Unknown" || status == "Error"
+}
+
+// SetupWithManager registers the ClusterProbe, watching ArgoSecrets of the Argo namespaces only.
+func (p *ClusterProbe) SetupWithManager(mgr ctrl.Manager) error {
+	inArgoNamespace := predicate.NewPredicateFuncs(p.isProbed)
+	if p.PlatformCluster != nil {
+		return ctrl.NewControllerManagedBy(mgr).
+			Named("clusterprobe").
+			WatchesRawSource(source.Kind(p.PlatformCluster.GetCache(), &corev1.Secret{}), &handler.EnqueueRequestForObject{}, builder.WithPredicates(inArgoNamespace)).
+			Complete(p)
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("clusterprobe").
+		For(&corev1.Secret{}, builder.WithPredicates(inArgoNamespace)).
+		Complete(p)
+}
+
+// isProbed returns true for the ArgoSecrets owned by the operator instance in its Argo namespaces.
+// Referenced token Secrets carry no cluster secret-type and are left out.
+func (p *ClusterProbe) isProbed(obj client.Object) bool {
+	return slices.Contains(p.Config.ArgoNamespaces(), obj.GetNamespace()) && obj.GetLabels()[ownedLabel] == "true" && p.Config.IsOwnInstance(obj) &&
+		obj.GetLabels()[argoSecretTypeLabel] == reservedLabels[argoSecretTypeLabel]
+}
+
+// sourceClient returns the client that owns CAPI objects.
+func (p *ClusterProbe) sourceClient() client.Client {
+	if p.SourceClient != nil {
+		return p.SourceClient
+	}
+	return p.Client
+}
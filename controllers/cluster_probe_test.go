@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestIsArgoConnectionFailing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues bool
+	}{
+		{"test Successful", "Successful", false},
+		{"test Unknown", "Unknown", true},
+		{"test Error", "Error", true},
+		{"test unset", "", false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, IsArgoConnectionFailing(tt.testMock))
+		})
+	}
+}
+
+func TestClusterProbe(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "probe-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "probe"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	_, err := r.Reconcile(ctx, MockReconcileReq("probe-kubeconfig", TestNamespace))
+	assert.Nil(t, err)
+
+	recorder := &MockRecorder{}
+	p := &ClusterProbe{Client: c, Log: TestLog, Config: MockOperatorConfig(), Recorder: recorder}
+	nn := r.Config.BuildNamespacedName("probe-kubeconfig", TestNamespace)
+	setStatus := func(status string) {
+		var argoSecret corev1.Secret
+		assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+		if argoSecret.Annotations == nil {
+			argoSecret.Annotations = map[string]string{}
+		}
+		argoSecret.Annotations["argocd.argoproj.io/cluster-status"] = status
+		assert.Nil(t, c.Update(ctx, &argoSecret))
+		_, err := p.Reconcile(ctx, MockReconcileReq(nn.Name, nn.Namespace))
+		assert.Nil(t, err)
+		assert.Nil(t, c.Get(ctx, MockReconcileReq("probe-kubeconfig", TestNamespace).NamespacedName, capiSecret))
+	}
+
+	// Healthy connections leave the CAPI Secret alone.
+	setStatus("Successful")
+	assert.NotContains(t, capiSecret.Annotations, "capi-to-argocd/argo-connection-status")
+
+	// Failures are fed back once.
+	setStatus("Unknown")
+	assert.Equal(t, "Error", capiSecret.Annotations["capi-to-argocd/argo-connection-status"])
+	setStatus("Error")
+	assert.Equal(t, "Error", capiSecret.Annotations["capi-to-argocd/argo-connection-status"])
+	assert.Equal(t, []string{"ArgoConnectionFailed"}, recorder.ForObject("Cluster", "probe"))
+
+	// Recovery clears the fed back status.
+	setStatus("Successful")
+	assert.NotContains(t, capiSecret.Annotations, "capi-to-argocd/argo-connection-status")
+	assert.Equal(t, []string{"ArgoConnectionFailed"}, recorder.ForObject("Cluster", "probe"))
+
+	// Secrets not managed by the controller are ignored.
+	unowned := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "cluster-unowned",
+		Namespace:   TestArgoNamespace,
+		Annotations: map[string]string{"argocd.argoproj.io/cluster-status": "Error"},
+		Labels:      map[string]string{clusterSecretNameLabel: "probe-kubeconfig", clusterNamespaceLabel: TestNamespace},
+	}}
+	assert.Nil(t, c.Create(ctx, unowned))
+	_, err = p.Reconcile(ctx, MockReconcileReq(unowned.Name, unowned.Namespace))
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, MockReconcileReq("probe-kubeconfig", TestNamespace).NamespacedName, capiSecret))
+	assert.NotContains(t, capiSecret.Annotations, "capi-to-argocd/argo-connection-status")
+}
+
+func TestClusterProbeIsProbed(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testNamespace      string
+		testLabels         map[string]string
+		testExpectedValues bool
+	}{
+		{"test ArgoNamespace", TestArgoNamespace, GetArgoCommonLabels().ToMap(), true},
+		{"test additional Argo namespace", "tenant-argocd", GetArgoCommonLabels().ToMap(), true},
+		{"test other namespace", TestNamespace, GetArgoCommonLabels().ToMap(), false},
+		{"test token Secret", TestArgoNamespace, map[string]string{ownedLabel: "true"}, false},
+		{"test unowned", TestArgoNamespace, map[string]string{argoSecretTypeLabel: "cluster"}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			p := &ClusterProbe{Config: MockOperatorConfig()}
+			p.Config.AdditionalArgoNamespaces = "tenant-argocd"
+			s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-test", Namespace: tt.testNamespace, Labels: tt.testLabels}}
+			assert.Equal(t, tt.testExpectedValues, p.isProbed(s))
+		})
+	}
+}
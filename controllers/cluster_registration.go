@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RegisteredToArgoCDCondition is set on CAPI Clusters once their ArgoSecret got written.
+	RegisteredToArgoCDCondition = "RegisteredToArgoCD"
+	// registeredToArgoCDReason is the reason of the RegisteredToArgoCD condition.
+	registeredToArgoCDReason = "ArgoSecretWritten"
+	// argoSecretAnnotation references the ArgoSecret of a CAPI Cluster as <namespace>/<name>.
+	argoSecretAnnotation = "capi-to-argocd/argo-secret"
+)
+
+// recordRegistration writes the registration of a CAPI Cluster back onto it, once its ArgoSecret
+// got written.
+func (r *Capi2Argo) recordRegistration(ctx context.Context, log logr.Logger, cluster CAPICluster, argoSecret types.NamespacedName) error {
+	if cluster == nil {
+		return nil
+	}
+	if err := r.annotateArgoSecret(ctx, log, cluster, argoSecret); err != nil {
+		return err
+	}
+	return r.setRegisteredCondition(ctx, log, cluster, argoSecret)
+}
+
+// annotateArgoSecret annotates a CAPI Cluster with the reference of its ArgoSecret, so users can
+// trace a Cluster to its registration without knowing the naming rules.
+func (r *Capi2Argo) annotateArgoSecret(ctx context.Context, log logr.Logger, cluster CAPICluster, argoSecret types.NamespacedName) error {
+	if !r.Config.AnnotateClusterArgoSecret || cluster.GetAnnotations()[argoSecretAnnotation] == argoSecret.String() {
+		return nil
+	}
+	obj := cluster.Object()
+	original := obj.DeepCopyObject().(client.Object)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[argoSecretAnnotation] = argoSecret.String()
+	obj.SetAnnotations(annotations)
+	if err := r.Patch(ctx, obj, client.MergeFrom(original)); err != nil {
+		log.Error(err, "Failed to annotate Cluster with ArgoSecret")
+		return err
+	}
+	return nil
+}
+
+// setRegisteredCondition sets the RegisteredToArgoCD condition of a CAPI Cluster, naming the
+// ArgoSecret it is registered as, so lifecycle tooling can gate on the registration.
+func (r *Capi2Argo) setRegisteredCondition(ctx context.Context, log logr.Logger, cluster CAPICluster, argoSecret types.NamespacedName) error {
+	if !r.Config.SetRegisteredCondition {
+		return nil
+	}
+	if !cluster.SetCondition(RegisteredToArgoCDCondition, corev1.ConditionTrue, registeredToArgoCDReason, "Registered as ArgoSecret "+argoSecret.String()) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, cluster.Object()); err != nil {
+		log.Error(err, "Failed to set RegisteredToArgoCD condition of Cluster")
+		return err
+	}
+	return nil
+}
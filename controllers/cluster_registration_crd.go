@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ArgoClusterRegistrationGVK returns the GroupVersionKind of ArgoClusterRegistrations.
+func ArgoClusterRegistrationGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "capi2argo.dntosas.io", Version: "v1alpha1", Kind: "ArgoClusterRegistration"}
+}
+
+// ArgoClusterRegistrationListGVK returns the GroupVersionKind of ArgoClusterRegistration lists.
+func ArgoClusterRegistrationListGVK() schema.GroupVersionKind {
+	gvk := ArgoClusterRegistrationGVK()
+	gvk.Kind += "List"
+	return gvk
+}
+
+// ClusterRegistration is the spec of an ArgoClusterRegistration, declaring a CAPI Cluster of its
+// namespace to register and the overrides of its ArgoSecret. Overrides take precedence over
+// annotations of the Cluster and ArgoClusterDefaults.
+type ClusterRegistration struct {
+	// ClusterName is the name of the CAPI Cluster, or hosted control plane, to register.
+	ClusterName string `json:"clusterName"`
+	// Name overrides the Argo cluster name, like the capi-to-argocd/cluster-name annotation.
+	Name    string `json:"name,omitempty"`
+	Project string `json:"project,omitempty"`
+	// Labels are extra labels of the ArgoSecret, on top of those of ArgoClusterDefaults.
+	Labels map[string]string `json:"labels,omitempty"`
+	// ArgoNamespace is the namespace the ArgoSecret is written to, one of
+	// OperatorConfig.ArgoNamespaces.
+	ArgoNamespace string `json:"argoNamespace,omitempty"`
+}
+
+// Apply layers the registration on top of the operator configuration of a reconcile. An invalid
+// name, reserved labels, or an ArgoNamespace the operator does not manage, are skipped and returned
+// as errors.
+func (cr ClusterRegistration) Apply(rc *ReconcileContext) []string {
+	errors := []string{}
+	if cr.Name != "" {
+		if errs := validation.IsDNS1123Subdomain(cr.Name); len(errs) > 0 {
+			errors = append(errors, fmt.Sprintf("Warning: invalid name %q of ArgoClusterRegistration for cluster %s: %s. Ignoring", cr.Name, cr.ClusterName, strings.Join(errs, "; ")))
+			cr.Name = ""
+		}
+	}
+	for _, k := range DropReservedLabels(cr.Labels) {
+		errors = append(errors, fmt.Sprintf("Warning: label %q of ArgoClusterRegistration for cluster %s is reserved by the operator. Ignoring", k, cr.ClusterName))
+	}
+	if cr.ArgoNamespace != "" {
+		if slices.Contains(rc.Config.ArgoNamespaces(), cr.ArgoNamespace) {
+			rc.Config.ArgoNamespace = cr.ArgoNamespace
+		} else {
+			errors = append(errors, fmt.Sprintf("Warning: argoNamespace %q of ArgoClusterRegistration for cluster %s is not one of %s. Ignoring", cr.ArgoNamespace, cr.ClusterName, strings.Join(rc.Config.ArgoNamespaces(), ", ")))
+			cr.ArgoNamespace = ""
+		}
+	}
+	rc.Registration = &cr
+	return errors
+}
+
+// ValidateArgoNamespaces returns an error for invalid namespaces of
+// OperatorConfig.AdditionalArgoNamespaces and OperatorConfig.FanOutArgoNamespaces.
+func (c OperatorConfig) ValidateArgoNamespaces() error {
+	for _, ns := range c.ArgoNamespaces()[1:] {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("invalid argo namespace %q: %s", ns, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// readClusterRegistration returns the ArgoClusterRegistration of a cluster in a namespace, nil
+// when there is none or the CRD is not installed. With several of them, the first by name wins.
+func (r *Capi2Argo) readClusterRegistration(ctx context.Context, log logr.Logger, namespace, clusterName string) (*ClusterRegistration, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(ArgoClusterRegistrationListGVK())
+	if err := r.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+	var registration *ClusterRegistration
+	for _, item := range list.Items {
+		spec, _, err := unstructured.NestedMap(item.Object, "spec")
+		if err != nil {
+			return nil, err
+		}
+		cr := &ClusterRegistration{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, cr); err != nil {
+			return nil, err
+		}
+		if cr.ClusterName != clusterName {
+			continue
+		}
+		if registration != nil {
+			log.Info("Warning: multiple ArgoClusterRegistrations for cluster. Ignoring", "name", item.GetName())
+			continue
+		}
+		registration = cr
+	}
+	return registration, nil
+}
+
+// mapClusterRegistration maps ArgoClusterRegistrations to a reconcile of every CAPI Secret in their
+// namespace, as the cluster of a registration may have changed.
+func (r *Capi2Argo) mapClusterRegistration(ctx context.Context, obj client.Object) []ctrl.Request {
+	return r.capiSecretRequests(ctx, obj.GetNamespace())
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestArgoNamespaces(t *testing.T) {
+	t.Parallel()
+	c := MockOperatorConfig()
+	assert.Equal(t, []string{c.ArgoNamespace}, c.ArgoNamespaces())
+	c.AdditionalArgoNamespaces = "argocd-prod, " + c.ArgoNamespace + ",argocd-dev"
+	assert.Equal(t, []string{c.ArgoNamespace, "argocd-prod", "argocd-dev"}, c.ArgoNamespaces())
+	assert.Nil(t, c.ValidateArgoNamespaces())
+	c.AdditionalArgoNamespaces = "Argo_CD"
+	assert.NotNil(t, c.ValidateArgoNamespaces())
+}
+
+func TestReconcileClusterRegistration(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testCRDInstalled   bool
+		testRegistrations  []client.Object
+		testExpectedName   types.NamespacedName
+		testExpectedData   map[string]string
+		testExpectedLabels map[string]string
+	}{
+		{"test CRD not installed", false, nil, types.NamespacedName{}, nil, nil},
+		{"test no registration", true, nil, types.NamespacedName{}, nil, nil},
+		{"test registration of other cluster", true, []client.Object{MockUnstructured(ArgoClusterRegistrationGVK(), "other", TestNamespace, map[string]interface{}{"clusterName": "other"})},
+			types.NamespacedName{}, nil, nil},
+		{"test registration", true, []client.Object{MockUnstructured(ArgoClusterRegistrationGVK(), "registered", TestNamespace, map[string]interface{}{"clusterName": "registered"})},
+			MockOperatorConfig().BuildNamespacedName("registered-kubeconfig", TestNamespace), map[string]string{"name": "kube-cluster-test", "project": ""}, nil},
+		{"test registration overrides", true, []client.Object{MockUnstructured(ArgoClusterRegistrationGVK(), "registered", TestNamespace, map[string]interface{}{
+			"clusterName":   "registered",
+			"name":          "platform",
+			"project":       "team-a",
+			"labels":        map[string]interface{}{"env": "prod"},
+			"argoNamespace": "argocd-prod",
+		})}, types.NamespacedName{Name: MockOperatorConfig().BuildArgoSecretName("platform"), Namespace: "argocd-prod"},
+			map[string]string{"name": "platform", "project": "team-a"}, map[string]string{"env": "prod"}},
+		{"test invalid registration overrides", true, []client.Object{MockUnstructured(ArgoClusterRegistrationGVK(), "registered", TestNamespace, map[string]interface{}{
+			"clusterName":   "registered",
+			"name":          "Platform_1",
+			"labels":        map[string]interface{}{"env": "prod", ownedLabel: "false"},
+			"argoNamespace": "kube-system",
+		})}, MockOperatorConfig().BuildNamespacedName("registered-kubeconfig", TestNamespace), map[string]string{"name": "kube-cluster-test"},
+			map[string]string{"env": "prod", ownedLabel: "true"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			scheme := MockScheme()
+			if tt.testCRDInstalled {
+				scheme = MockUnstructuredScheme(ArgoClusterRegistrationGVK(), ArgoClusterRegistrationListGVK())
+			}
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "registered-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "registered"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "registered", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+			config := MockOperatorConfig()
+			config.EnableClusterRegistrations = true
+			config.AdditionalArgoNamespaces = "argocd-prod"
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(capiSecret, cluster).
+				WithObjects(tt.testRegistrations...).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, config.ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, config.ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: config}
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("registered-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			secrets, err := r.listArgoSecrets(ctx, capiSecret.Name, capiSecret.Namespace)
+			assert.Nil(t, err)
+			if tt.testExpectedName.Name == "" {
+				assert.Empty(t, secrets)
+				return
+			}
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, tt.testExpectedName, &argoSecret))
+			assert.Len(t, secrets, 1)
+			for k, v := range tt.testExpectedData {
+				assert.Equal(t, v, string(argoSecret.Data[k]), k)
+			}
+			for k, v := range tt.testExpectedLabels {
+				assert.Equal(t, v, argoSecret.Labels[k], k)
+			}
+		})
+	}
+}
+
+func TestReconcileClusterRegistrationRemoved(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	scheme := MockUnstructuredScheme(ArgoClusterRegistrationGVK(), ArgoClusterRegistrationListGVK())
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "registered-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "registered"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "registered", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	registration := MockUnstructured(ArgoClusterRegistrationGVK(), "registered", TestNamespace, map[string]interface{}{"clusterName": "registered"})
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(capiSecret, cluster, registration).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+	r.Config.EnableClusterRegistrations = true
+	req := MockReconcileReq("registered-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("registered-kubeconfig", TestNamespace)
+
+	var argoSecret corev1.Secret
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+
+	// Removing the registration deregisters the cluster.
+	assert.Nil(t, c.Delete(ctx, registration))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+}
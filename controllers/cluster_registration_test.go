@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileRegisteredCondition(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testEnabled        bool
+		testExpectedStatus corev1.ConditionStatus
+	}{
+		{"test registered condition", true, corev1.ConditionTrue},
+		{"test disabled", false, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "registered-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "registered"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "registered", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+			c := fake.NewClientBuilder().
+				WithScheme(MockScheme()).
+				WithObjects(capiSecret, cluster).
+				WithStatusSubresource(cluster).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			r.Config.SetRegisteredCondition = tt.testEnabled
+			req := MockReconcileReq("registered-kubeconfig", TestNamespace)
+
+			// Reconcile twice, the in-sync ArgoSecret keeps the condition.
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(ctx, req)
+				assert.Nil(t, err)
+			}
+			assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+			adapter := &V1Beta1ClusterAdapter{cluster}
+			assert.Equal(t, tt.testExpectedStatus, adapter.GetConditionStatus(RegisteredToArgoCDCondition))
+			if tt.testExpectedStatus == "" {
+				return
+			}
+			condition := cluster.Status.Conditions[len(cluster.Status.Conditions)-1]
+			assert.Equal(t, registeredToArgoCDReason, condition.Reason)
+			assert.Equal(t, "Registered as ArgoSecret "+r.Config.BuildNamespacedName("registered-kubeconfig", TestNamespace).String(), condition.Message)
+		})
+	}
+}
+
+func TestReconcileArgoSecretAnnotation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testEnabled       bool
+		testAnnotations   map[string]string
+		testExpectedValue string
+	}{
+		{"test annotation", true, nil, "argocd/cluster-registered"},
+		{"test stale annotation", true, map[string]string{argoSecretAnnotation: "argocd/other", "foo": "bar"}, "argocd/cluster-registered"},
+		{"test disabled", false, nil, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "registered-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "registered"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "registered", Namespace: TestNamespace, Annotations: tt.testAnnotations}, Status: MockReadyClusterStatus()}
+			c := fake.NewClientBuilder().
+				WithScheme(MockScheme()).
+				WithObjects(capiSecret, cluster).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			r.Config.AnnotateClusterArgoSecret = tt.testEnabled
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("registered-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+			assert.Equal(t, tt.testExpectedValue, cluster.Annotations[argoSecretAnnotation])
+			for k, v := range tt.testAnnotations {
+				if k != argoSecretAnnotation {
+					assert.Equal(t, v, cluster.Annotations[k], k)
+				}
+			}
+		})
+	}
+}
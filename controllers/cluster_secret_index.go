@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// clusterSecretNameLabel references the CAPI Secret an ArgoSecret got generated from.
+	clusterSecretNameLabel = "capi-to-argocd/cluster-secret-name"
+	// clusterNamespaceLabel references the namespace of the CAPI Secret an ArgoSecret got generated from.
+	clusterNamespaceLabel = "capi-to-argocd/cluster-namespace"
+
+	// clusterSecretNameIndex is the field index of ArgoSecrets by CAPI Secret name.
+	clusterSecretNameIndex = "capi-to-argocd.clusterSecretName"
+	// clusterNameIndex is the field index of ArgoSecrets by Argo cluster name.
+	clusterNameIndex = "capi-to-argocd.clusterName"
+)
+
+// ClusterSecretIndex indexes ArgoSecrets in ArgoNamespaces of the operator instance by the CAPI
+// Secret they belong to. Cache indexers get re-evaluated on every Secret update, so label changes
+// are picked up.
+func (c OperatorConfig) ClusterSecretIndex(obj client.Object) []string {
+	if !slices.Contains(c.ArgoNamespaces(), obj.GetNamespace()) || !c.IsOwnInstance(obj) {
+		return nil
+	}
+	name := obj.GetLabels()[clusterSecretNameLabel]
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+// ClusterNameIndex indexes ArgoSecrets in ArgoNamespaces owned by the operator instance by the
+// Argo cluster name they produce.
+func (c OperatorConfig) ClusterNameIndex(obj client.Object) []string {
+	s, ok := obj.(*corev1.Secret)
+	if !ok || !slices.Contains(c.ArgoNamespaces(), s.Namespace) || c.ValidateOwner(*s) != nil {
+		return nil
+	}
+	name := string(s.Data["name"])
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+// listArgoSecretsByClusterName returns the ArgoSecrets of a namespace producing the Argo cluster
+// name, using the ClusterNameIndex.
+func (r *Capi2Argo) listArgoSecretsByClusterName(ctx context.Context, namespace, clusterName string) ([]corev1.Secret, error) {
+	secretList := &corev1.SecretList{}
+	err := r.argoClient().List(ctx, secretList,
+		client.InNamespace(namespace),
+		client.MatchingFields{clusterNameIndex: clusterName},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return secretList.Items, nil
+}
+
+// listArgoSecrets returns the ArgoSecrets generated from the given CAPI Secret, using the ClusterSecretIndex.
+// The index only holds ArgoNamespaces, so every namespace is listed.
+func (r *Capi2Argo) listArgoSecrets(ctx context.Context, secretName, namespace string) ([]corev1.Secret, error) {
+	timer := prometheus.NewTimer(IndexLookupDuration)
+	defer timer.ObserveDuration()
+
+	secretList := &corev1.SecretList{}
+	err := r.argoClient().List(ctx, secretList,
+		client.MatchingFields{clusterSecretNameIndex: secretName},
+		client.MatchingLabels{clusterNamespaceLabel: namespace},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return secretList.Items, nil
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func MockIndexedArgoSecret(name, secretName, namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: TestArgoNamespace,
+			Labels: map[string]string{
+				clusterSecretNameLabel: secretName,
+				clusterNamespaceLabel:  namespace,
+			},
+		},
+	}
+}
+
+func TestClusterSecretIndex(t *testing.T) {
+	t.Parallel()
+	outside := MockIndexedArgoSecret("cluster-test", "test-kubeconfig", "test")
+	outside.Namespace = "other"
+	tests := []struct {
+		testName           string
+		testMock           *corev1.Secret
+		testExpectedValues []string
+	}{
+		{"test labeled ArgoSecret", MockIndexedArgoSecret("cluster-test", "test-kubeconfig", "test"), []string{"test-kubeconfig"}},
+		{"test unlabeled ArgoSecret", &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-test", Namespace: TestArgoNamespace}}, nil},
+		{"test Secret outside ArgoNamespace", outside, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, MockOperatorConfig().ClusterSecretIndex(tt.testMock))
+		})
+	}
+}
+
+func TestClusterNameIndex(t *testing.T) {
+	t.Parallel()
+	owned := func(name string) *corev1.Secret {
+		s := MockIndexedArgoSecret("cluster-test", "test-kubeconfig", "test")
+		s.Labels["capi-to-argocd/owned"] = "true"
+		s.Data = map[string][]byte{"name": []byte(name)}
+		return s
+	}
+	outside := owned("test")
+	outside.Namespace = "other"
+	tests := []struct {
+		testName           string
+		testMock           *corev1.Secret
+		testExpectedValues []string
+	}{
+		{"test owned ArgoSecret", owned("test"), []string{"test"}},
+		{"test ArgoSecret without name", owned(""), nil},
+		{"test foreign ArgoSecret", MockIndexedArgoSecret("cluster-test", "test-kubeconfig", "test"), nil},
+		{"test Secret outside ArgoNamespace", outside, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, MockOperatorConfig().ClusterNameIndex(tt.testMock))
+		})
+	}
+}
+
+func TestListArgoSecrets(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// Empty index returns no results, not an error.
+	r := &Capi2Argo{Client: MockClient(), Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	secrets, err := r.listArgoSecrets(ctx, "test-kubeconfig", "test")
+	assert.Nil(t, err)
+	assert.Empty(t, secrets)
+
+	c := MockClient(
+		MockIndexedArgoSecret("cluster-test", "test-kubeconfig", "test"),
+		MockIndexedArgoSecret("cluster-other-test", "test-kubeconfig", "other"),
+		MockIndexedArgoSecret("cluster-foo", "foo-kubeconfig", "test"),
+	)
+	r = &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	secrets, err = r.listArgoSecrets(ctx, "test-kubeconfig", "test")
+	assert.Nil(t, err)
+	if assert.Len(t, secrets, 1) {
+		assert.Equal(t, "cluster-test", secrets[0].Name)
+	}
+
+	// Updated label values get re-indexed.
+	var s corev1.Secret
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "cluster-foo", Namespace: TestArgoNamespace}, &s))
+	s.Labels[clusterSecretNameLabel] = "bar-kubeconfig"
+	assert.Nil(t, c.Update(ctx, &s))
+	secrets, err = r.listArgoSecrets(ctx, "foo-kubeconfig", "test")
+	assert.Nil(t, err)
+	assert.Empty(t, secrets)
+	secrets, err = r.listArgoSecrets(ctx, "bar-kubeconfig", "test")
+	assert.Nil(t, err)
+	if assert.Len(t, secrets, 1) {
+		assert.Equal(t, "cluster-foo", secrets[0].Name)
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// clusterTagsConfigMapSuffix names the ConfigMap holding the tags of a cluster: <cluster-name>-tags.
+	clusterTagsConfigMapSuffix = "-tags"
+	// clusterTagsDocumentKey is the ConfigMap key of an optional YAML map of typed tags.
+	clusterTagsDocumentKey = "tags.yaml"
+	// clusterTagAnnotationPrefix prefixes the ArgoSecret annotations tags are stored in.
+	clusterTagAnnotationPrefix = "capi-to-argocd/tag-"
+	// clusterTagValueMaxLength is the length tag values get truncated to.
+	clusterTagValueMaxLength = 253
+)
+
+// ClusterTagsConfigMapName returns the name of the tags ConfigMap of a cluster.
+func ClusterTagsConfigMapName(clusterName string) string {
+	return clusterName + clusterTagsConfigMapSuffix
+}
+
+// BuildClusterTagAnnotations converts the tags of a ConfigMap to ArgoSecret annotations. Plain
+// data entries are taken verbatim, while the tags.yaml entry may hold a map of typed values that
+// get converted to strings, structured ones as JSON. Invalid keys are dropped and overlong values
+// truncated, both reported as warnings.
+func BuildClusterTagAnnotations(cm *corev1.ConfigMap) (map[string]string, []string) {
+	tags := map[string]string{}
+	warnings := []string{}
+	for k, v := range cm.Data {
+		if k != clusterTagsDocumentKey {
+			tags[k] = v
+		}
+	}
+	if doc, ok := cm.Data[clusterTagsDocumentKey]; ok {
+		typed, err := parseTypedTags(doc)
+		if err != nil {
+			warnings = append(warnings, "Warning: failed to parse "+clusterTagsDocumentKey+" of ConfigMap "+cm.Name+": "+err.Error()+". Ignoring")
+		}
+		for k, v := range typed {
+			tags[k] = v
+		}
+	}
+
+	annotations := make(map[string]string, len(tags))
+	for k, v := range tags {
+		key := clusterTagAnnotationPrefix + k
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			warnings = append(warnings, "Warning: invalid tag '"+k+"' in ConfigMap "+cm.Name+": "+strings.Join(errs, "; ")+". Ignoring")
+			continue
+		}
+		if len(v) > clusterTagValueMaxLength {
+			warnings = append(warnings, "Warning: value of tag '"+k+"' in ConfigMap "+cm.Name+" exceeds "+strconv.Itoa(clusterTagValueMaxLength)+" characters. Truncating")
+			v = strings.ToValidUTF8(v[:clusterTagValueMaxLength], "")
+		}
+		annotations[key] = v
+	}
+	sort.Strings(warnings)
+	return annotations, warnings
+}
+
+// parseTypedTags parses a YAML map of tags, converting non-string values to strings.
+func parseTypedTags(doc string) (map[string]string, error) {
+	b, err := yaml.YAMLToJSON([]byte(doc))
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	// Keep numbers as written instead of converting them to float64.
+	d.UseNumber()
+	if err := d.Decode(&raw); err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case nil:
+			tags[k] = ""
+		case string:
+			tags[k] = v
+		case json.Number:
+			tags[k] = v.String()
+		case bool:
+			tags[k] = strconv.FormatBool(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			tags[k] = string(b)
+		}
+	}
+	return tags, nil
+}
+
+// readClusterTags returns the tag annotations of a cluster, from its tags ConfigMap in the
+// namespace of the CAPI Secret. No annotations are returned when the ConfigMap does not exist.
+func (r *Capi2Argo) readClusterTags(ctx context.Context, log logr.Logger, clusterName, namespace string) (map[string]string, error) {
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: ClusterTagsConfigMapName(clusterName), Namespace: namespace}, cm)
+	if err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	annotations, warnings := BuildClusterTagAnnotations(cm)
+	for _, w := range warnings {
+		log.Info(w)
+	}
+	return annotations, nil
+}
+
+// mapClusterTagsConfigMap maps tags ConfigMaps to a reconcile of the CAPI Secret of their cluster.
+func mapClusterTagsConfigMap(_ context.Context, obj client.Object) []ctrl.Request {
+	name, ok := strings.CutSuffix(obj.GetName(), clusterTagsConfigMapSuffix)
+	if !ok || name == "" {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: name + "-kubeconfig", Namespace: obj.GetNamespace()}}}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func MockClusterTagsConfigMap(clusterName string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterTagsConfigMapName(clusterName), Namespace: TestNamespace},
+		Data:       data,
+	}
+}
+
+func TestBuildClusterTagAnnotations(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           map[string]string
+		testExpectedError  bool
+		testExpectedValues map[string]string
+	}{
+		{"test no tags", nil, false, map[string]string{}},
+		{"test plain tags", map[string]string{"team": "platform", "cost-center": "4711"}, false, map[string]string{
+			"capi-to-argocd/tag-team":        "platform",
+			"capi-to-argocd/tag-cost-center": "4711",
+		}},
+		{"test typed tags", map[string]string{clusterTagsDocumentKey: "cost-center: 4711\nratio: 0.25\nbig: 10000000\nproduction: true\nowner: null\nregion: {lat: 52.52, lon: 13.405}\nzones: [a, b]\n"}, false, map[string]string{
+			"capi-to-argocd/tag-cost-center": "4711",
+			"capi-to-argocd/tag-ratio":       "0.25",
+			"capi-to-argocd/tag-big":         "10000000",
+			"capi-to-argocd/tag-production":  "true",
+			"capi-to-argocd/tag-owner":       "",
+			"capi-to-argocd/tag-region":      `{"lat":52.52,"lon":13.405}`,
+			"capi-to-argocd/tag-zones":       `["a","b"]`,
+		}},
+		{"test truncated value", map[string]string{"description": strings.Repeat("a", 300)}, true, map[string]string{
+			"capi-to-argocd/tag-description": strings.Repeat("a", clusterTagValueMaxLength),
+		}},
+		{"test invalid key", map[string]string{"team": "platform", "invalid key": "value"}, true, map[string]string{
+			"capi-to-argocd/tag-team": "platform",
+		}},
+		{"test invalid typed tags", map[string]string{"team": "platform", clusterTagsDocumentKey: "[a, b]"}, true, map[string]string{
+			"capi-to-argocd/tag-team": "platform",
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v, warnings := BuildClusterTagAnnotations(MockClusterTagsConfigMap("test", tt.testMock))
+			assert.Equal(t, tt.testExpectedError, len(warnings) > 0)
+			assert.Equal(t, tt.testExpectedValues, v)
+		})
+	}
+}
+
+func TestMapClusterTagsConfigMap(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace}}},
+		mapClusterTagsConfigMap(context.Background(), MockClusterTagsConfigMap("test", nil)))
+	assert.Empty(t, mapClusterTagsConfigMap(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace}}))
+	assert.Empty(t, mapClusterTagsConfigMap(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "-tags", Namespace: TestNamespace}}))
+}
+
+func TestReconcileClusterTags(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(validMock, validType, validKey, "tagged-kubeconfig", TestNamespace))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.ReadClusterTags = true
+	req := MockReconcileReq("tagged-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("tagged-kubeconfig", TestNamespace)
+
+	// Without tags ConfigMap, no tag annotations get written.
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	for k := range argoSecret.Annotations {
+		assert.False(t, strings.HasPrefix(k, clusterTagAnnotationPrefix), k)
+	}
+
+	cm := MockClusterTagsConfigMap("tagged", map[string]string{"team": "platform", clusterTagsDocumentKey: "cost-center: 4711"})
+	assert.Nil(t, c.Create(ctx, cm))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "platform", argoSecret.Annotations["capi-to-argocd/tag-team"])
+	assert.Equal(t, "4711", argoSecret.Annotations["capi-to-argocd/tag-cost-center"])
+
+	// Tags removed from the ConfigMap get removed from the ArgoSecret.
+	delete(cm.Data, "team")
+	assert.Nil(t, c.Update(ctx, cm))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.NotContains(t, argoSecret.Annotations, "capi-to-argocd/tag-team")
+	assert.Equal(t, "4711", argoSecret.Annotations["capi-to-argocd/tag-cost-center"])
+}
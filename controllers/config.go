@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+)
+
+// OperatorConfig holds the operator wide configuration.
+type OperatorConfig struct {
+	// ArgoNamespace represents the Namespace that hold ArgoCluster secrets.
+	ArgoNamespace string
+	// AdditionalArgoNamespaces is a comma separated list of namespaces ArgoClusterRegistrations
+	// may write ArgoSecrets to, besides ArgoNamespace.
+	AdditionalArgoNamespaces string
+	// FanOutArgoNamespaces is a comma separated list of namespaces every ArgoSecret gets copied
+	// to, for several ArgoCD instances.
+	FanOutArgoNamespaces string
+	// InstanceID identifies the operator instance among several sharing a management cluster.
+	// Secrets get labeled with it and those of other instances are left alone.
+	InstanceID string
+	// EnableGarbageCollection enables experimental GC feature.
+	EnableGarbageCollection bool
+	// EnableNamespacedNames represents a mode where the cluster name is always
+	// prepended by the cluster namespace in all generated secrets.
+	EnableNamespacedNames bool
+	// AutoNamespaceSuffixOnCollision represents a mode where colliding cluster names
+	// from different namespaces get disambiguated with a namespace suffix.
+	AutoNamespaceSuffixOnCollision bool
+	// EmitClusterEvents represents a mode where events are emitted on the CAPI Cluster
+	// object in addition to the CAPI Secret.
+	EmitClusterEvents bool
+	// ArgoClusterNameMaxLength is the maximum length of generated cluster names.
+	// Longer names get truncated and suffixed with a hash. Disabled when 0.
+	ArgoClusterNameMaxLength int
+	// MaxOwnerTakeAlongDepth is the deepest chain of take-along directives processed,
+	// where 1 only takes along labels referenced from the top level.
+	MaxOwnerTakeAlongDepth int
+	// ClusterAPIVersion represents the CAPI API version used to read Cluster objects.
+	ClusterAPIVersion string
+	// KubeConfigKey is the CAPI Secret data key holding the kubeconfig.
+	KubeConfigKey string
+	// ArgoSecretImmutable represents a mode where ArgoSecrets are created immutable,
+	// so changes get applied by deleting and recreating them.
+	ArgoSecretImmutable bool
+	// SkipTLSRotationIfMatching represents a mode where ArgoSecret credentials are only
+	// compared and rewritten when the hash of the rendered config changed.
+	SkipTLSRotationIfMatching bool
+	// UseTokenReference represents a mode where bearer tokens are stored in a Secret of
+	// their own, referenced from the ArgoSecret config instead of embedded in it.
+	UseTokenReference bool
+	// RequireTopologyVersionAnnotation represents a mode where ArgoSecrets are only written while
+	// the topology version of the CAPI Cluster matches its approved-topology-version annotation.
+	RequireTopologyVersionAnnotation bool
+	// RequireControlPlaneReady represents a mode where ArgoSecrets are only created once the CAPI
+	// Cluster reports ControlPlaneReady=True.
+	RequireControlPlaneReady bool
+	// ReadinessGates is the comma separated list of further CAPI Cluster conditions that must be
+	// True before ArgoSecrets get created.
+	ReadinessGates string
+	// MinClusterAge is the minimum age of CAPI Clusters before ArgoSecrets get written for them.
+	// Disabled when 0.
+	MinClusterAge time.Duration
+	// EnableDeletionProtection represents a mode where CAPI Secrets get a finalizer, and their
+	// ArgoSecrets are only deleted once no ArgoCD Application targets the cluster anymore.
+	EnableDeletionProtection bool
+	// ReadClusterTags represents a mode where the <cluster-name>-tags ConfigMap of a cluster is
+	// read, and its entries stored as tag annotations on the ArgoSecret.
+	ReadClusterTags bool
+	// EnableClusterDefaults represents a mode where the ArgoClusterDefaults of the namespace of a
+	// CAPI Secret provide the defaults of its ArgoSecret.
+	EnableClusterDefaults bool
+	// EnableClusterRegistrations represents a mode where only CAPI Clusters declared by an
+	// ArgoClusterRegistration of their namespace get registered, with its overrides.
+	EnableClusterRegistrations bool
+	// EnableTenantMappings represents a mode where the ArgoTenantMapping matching a CAPI Cluster
+	// routes its ArgoSecret to an Argo namespace and project.
+	EnableTenantMappings bool
+	// ReadInfrastructureTopology represents a mode where the region and failure domains of the
+	// infrastructure object of a CAPI Cluster are stored as labels on the ArgoSecret.
+	ReadInfrastructureTopology bool
+	// SetRegisteredCondition represents a mode where the CAPI Cluster gets a RegisteredToArgoCD
+	// condition once its ArgoSecret got written.
+	SetRegisteredCondition bool
+	// AnnotateClusterArgoSecret represents a mode where the CAPI Cluster gets annotated with the
+	// reference of its ArgoSecret.
+	AnnotateClusterArgoSecret bool
+	// UseControlPlaneEndpoint represents a mode where the ArgoSecret server is built from the
+	// spec.controlPlaneEndpoint of the CAPI Cluster instead of the kubeconfig server.
+	UseControlPlaneEndpoint bool
+	// TopologyVariableLabels is the comma separated list of ClusterClass topology variables of a
+	// CAPI Cluster that are flattened into labels on the ArgoSecret.
+	TopologyVariableLabels string
+	// ClusterNamespaces is the comma separated list of namespaces ArgoSecrets are restricted to,
+	// unless overridden on the CAPI Cluster. Empty grants access to the whole cluster.
+	ClusterNamespaces string
+	// ClusterResources is "true" or "false" to allow or deny ArgoCD to manage cluster-scoped
+	// resources, unless overridden on the CAPI Cluster. Left unset in ArgoSecrets when empty.
+	ClusterResources string
+	// ClusterShard is the argocd-application-controller shard ArgoSecrets are assigned to,
+	// unless overridden on the CAPI Cluster. Left unset in ArgoSecrets when negative.
+	ClusterShard int
+	// EnableAWSAuthConfig represents a mode where CAPA managed EKS clusters authenticate through
+	// an awsAuthConfig in place of the static credentials of their kubeconfig.
+	EnableAWSAuthConfig bool
+	// AllowInsecureClusters represents a mode where kubeconfigs skipping TLS verification are
+	// registered as insecure clusters, instead of being rejected.
+	AllowInsecureClusters bool
+	// DisableCompression disables response compression for all clusters, unless overridden on
+	// the CAPI Cluster.
+	DisableCompression bool
+	// AuthPreference selects the credential type kept for kubeconfigs holding both a bearer
+	// token and a client certificate, unless overridden on the CAPI Cluster.
+	AuthPreference string
+	// KubeconfigSource selects the Secret kubeconfigs are read from, one of KubeconfigSourceAdmin
+	// or KubeconfigSourceUser.
+	KubeconfigSource string
+	// AzureLoginMode is the kubelogin login mode AKS kubeconfigs with an azure auth provider or an
+	// interactive kubelogin are converted to, like workloadidentity, msi or spn.
+	AzureLoginMode string
+	// TakeAllLabels represents a mode where every non-system label of CAPI Clusters is taken along,
+	// unless overridden by the capi-to-argocd/take-all-labels Cluster annotation.
+	TakeAllLabels bool
+	// ArgoSecretNamePrefix and ArgoSecretNameSuffix wrap cluster names into ArgoSecret names.
+	ArgoSecretNamePrefix string
+	ArgoSecretNameSuffix string
+	// ClusterNameTemplate is a Go template rendering Argo cluster names from NameTemplateData,
+	// replacing the [<namespace>-]<name> scheme when set.
+	ClusterNameTemplate string
+	// ArgoSecretNameTemplate is a Go template rendering ArgoSecret names from NameTemplateData,
+	// replacing the cluster-<cluster name> scheme when set.
+	ArgoSecretNameTemplate string
+	// IncludeNamespaces and ExcludeNamespaces are comma separated lists of glob patterns of the
+	// namespaces CAPI Secrets get converted in, all namespaces when IncludeNamespaces is empty.
+	// ExcludeNamespaces wins.
+	IncludeNamespaces string
+	ExcludeNamespaces string
+	// WatchNamespaces is a comma separated list of the namespaces CAPI Secrets and Clusters are
+	// read from, restricting the cache to them and the ArgoNamespace. All namespaces when empty.
+	WatchNamespaces string
+	// ManagementCluster is the <namespace>/<name> of the CAPI Cluster ArgoCD runs on, registered
+	// according to SelfRegistration.
+	ManagementCluster string
+	// SelfRegistration is how the ManagementCluster gets registered, one of
+	// SelfRegistrationExternal, SelfRegistrationInCluster or SelfRegistrationSkip.
+	SelfRegistration string
+	// InfrastructureKinds is a comma separated list of the infrastructureRef kinds CAPI Clusters
+	// must have to get registered, e.g. AWSCluster. All kinds when empty.
+	InfrastructureKinds string
+	// OptInRegistration represents a mode where only CAPI Clusters labeled with
+	// capi-to-argocd/enabled=true, or their kubeconfig Secrets, get registered.
+	OptInRegistration bool
+	// ClusterSelector is a label selector CAPI Clusters must match to get registered, all clusters
+	// get registered when empty.
+	ClusterSelector string
+	// MigrateSecretNames represents a mode where ArgoSecrets left under previous names by a change
+	// of the naming scheme get replaced by ArgoSecrets under the current names.
+	MigrateSecretNames bool
+	// TakeAlongDomain is the domain of take-along-label.<domain>.<key> directives on CAPI Clusters.
+	TakeAlongDomain string
+	// TakeAllLabelsExclude is the comma separated list of label key prefixes never taken along
+	// when taking all labels.
+	TakeAllLabelsExclude string
+	// ReplicaCountLabels represents a mode where the control plane and worker replicas of CAPI
+	// Clusters are stored as labels on ArgoSecrets.
+	ReplicaCountLabels bool
+	// TakeAlongNamespaceLabels represents a mode where labels of the namespace of CAPI Secrets,
+	// selected by take-along-from-namespace-label directives, are taken along to their ArgoSecrets.
+	TakeAlongNamespaceLabels bool
+	// EnableHyperShift represents a mode where the <name>-admin-kubeconfig Secrets of HyperShift
+	// HostedClusters are converted along CAPI Secrets.
+	EnableHyperShift bool
+	// EnableKamaji represents a mode where the <name>-admin-kubeconfig Secrets of Kamaji
+	// TenantControlPlanes are converted along CAPI Secrets.
+	EnableKamaji bool
+	// EnableK0smotron represents a mode where the <name>-kubeconfig Secrets of standalone k0smotron
+	// Clusters are converted along CAPI Secrets.
+	EnableK0smotron bool
+	// OutputFormat selects the kind of object the controller writes for every ArgoCluster.
+	OutputFormat string
+	// DryRun represents a mode where no changes should be applied.
+	DryRun bool
+	// Version is the operator version stamped on written ArgoSecrets.
+	Version string
+}
+
+// NewOperatorConfig returns an OperatorConfig with defaults, overridden by environment variables.
+func NewOperatorConfig() OperatorConfig {
+	c := OperatorConfig{
+		ArgoNamespace:            os.Getenv("ARGOCD_NAMESPACE"),
+		ArgoClusterNameMaxLength: 63,
+		MaxOwnerTakeAlongDepth:   1,
+		ClusterShard:             -1,
+		RequireControlPlaneReady: true,
+		AuthPreference:           AuthPreferenceBoth,
+		KubeconfigSource:         KubeconfigSourceAdmin,
+		TakeAllLabelsExclude:     "cluster.x-k8s.io/,topology.cluster.x-k8s.io/",
+		TakeAlongDomain:          DefaultTakeAlongDomain,
+		ArgoSecretNamePrefix:     DefaultArgoSecretNamePrefix,
+		AzureLoginMode:           AzureLoginModeWorkloadIdentity,
+		ClusterAPIVersion:        ClusterAPIVersionV1Beta1,
+		KubeConfigKey:            DefaultKubeConfigKey,
+		OutputFormat:             OutputFormatSecret,
+		SelfRegistration:         SelfRegistrationExternal,
+		Version:                  os.Getenv("VERSION"),
+		WatchNamespaces:          os.Getenv("WATCH_NAMESPACE"),
+	}
+	if c.ArgoNamespace == "" {
+		c.ArgoNamespace = "argocd"
+	}
+	c.EnableGarbageCollection, _ = strconv.ParseBool(os.Getenv("ENABLE_GARBAGE_COLLECTION"))
+	c.EnableNamespacedNames, _ = strconv.ParseBool(os.Getenv("ENABLE_NAMESPACED_NAMES"))
+	return c
+}
+
+// ArgoNamespaces returns the namespaces holding ArgoSecrets or their copies, ArgoNamespace first.
+func (c OperatorConfig) ArgoNamespaces() []string {
+	namespaces := []string{c.ArgoNamespace}
+	for _, ns := range ParseNamespaces(c.AdditionalArgoNamespaces + "," + c.FanOutArgoNamespaces) {
+		if !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// TakeAlongLabelKey returns the prefix of take-along-label directives on CAPI Clusters.
+func (c OperatorConfig) TakeAlongLabelKey() string {
+	return "take-along-label." + c.TakeAlongDomain + "."
+}
+
+// ClusterLabelSelector returns the parsed ClusterSelector, matching everything when unset.
+func (c OperatorConfig) ClusterLabelSelector() (labels.Selector, error) {
+	if c.ClusterSelector == "" {
+		return labels.Everything(), nil
+	}
+	return labels.Parse(c.ClusterSelector)
+}
+
+// MatchesClusterSelector reports whether a CAPI Cluster matches ClusterSelector. Without Cluster
+// object, only an unset ClusterSelector matches.
+func (c OperatorConfig) MatchesClusterSelector(cluster CAPICluster) (bool, error) {
+	if c.ClusterSelector == "" {
+		return true, nil
+	}
+	selector, err := c.ClusterLabelSelector()
+	if err != nil || cluster == nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(cluster.GetLabels())), nil
+}
+
+// ValidateTakeAlongDomain returns an error when domain is no valid DNS subdomain.
+func ValidateTakeAlongDomain(domain string) error {
+	if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+		return fmt.Errorf("invalid take-along domain %q: %s", domain, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateArgoSecretNameAffixes returns an error when ArgoSecretNamePrefix or ArgoSecretNameSuffix
+// make ArgoSecret names invalid.
+func (c OperatorConfig) ValidateArgoSecretNameAffixes() error {
+	if errs := validation.IsDNS1123Subdomain(c.ArgoSecretNamePrefix + "name" + c.ArgoSecretNameSuffix); len(errs) > 0 {
+		return fmt.Errorf("invalid argo secret name prefix %q or suffix %q: %s", c.ArgoSecretNamePrefix, c.ArgoSecretNameSuffix, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ReconcileContext holds the request-scoped data threaded through the reconcile call chain.
+type ReconcileContext struct {
+	Context  context.Context
+	Logger   logr.Logger
+	DryRun   bool
+	Config   OperatorConfig
+	Recorder record.EventRecorder
+	// Pipeline transforms take-along label values, see Capi2Argo.Pipeline.
+	Pipeline []ValueTransformer
+	// Defaults holds the ArgoClusterDefaults of the namespace of the CAPI Secret, if any.
+	Defaults *ClusterDefaults
+	// Registration holds the ArgoClusterRegistration of the cluster, if any.
+	Registration *ClusterRegistration
+	// Tenant holds the ArgoTenantMapping routing the cluster, if any.
+	Tenant *TenantMapping
+}
+
+// NewReconcileContext returns a ReconcileContext for a single reconcile request.
+func NewReconcileContext(ctx context.Context, log logr.Logger, config OperatorConfig, recorder record.EventRecorder) *ReconcileContext {
+	return &ReconcileContext{
+		Context:  ctx,
+		Logger:   log,
+		DryRun:   config.DryRun,
+		Config:   config,
+		Recorder: recorder,
+	}
+}
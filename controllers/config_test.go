@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestNewOperatorConfig(t *testing.T) {
+	t.Setenv("ARGOCD_NAMESPACE", "")
+	t.Setenv("ENABLE_GARBAGE_COLLECTION", "true")
+	t.Setenv("ENABLE_NAMESPACED_NAMES", "")
+	c := NewOperatorConfig()
+	assert.Equal(t, "argocd", c.ArgoNamespace)
+	assert.True(t, c.EnableGarbageCollection)
+	assert.False(t, c.EnableNamespacedNames)
+	assert.Equal(t, 63, c.ArgoClusterNameMaxLength)
+	assert.Equal(t, 1, c.MaxOwnerTakeAlongDepth)
+	assert.Equal(t, -1, c.ClusterShard)
+	assert.True(t, c.RequireControlPlaneReady)
+	assert.Equal(t, AuthPreferenceBoth, c.AuthPreference)
+	assert.Equal(t, KubeconfigSourceAdmin, c.KubeconfigSource)
+	assert.Equal(t, AzureLoginModeWorkloadIdentity, c.AzureLoginMode)
+	assert.Equal(t, []string{"cluster.x-k8s.io/", "topology.cluster.x-k8s.io/"}, c.TakeAllLabelsExclusions())
+	assert.Equal(t, clusterTakeAlongKey, c.TakeAlongLabelKey())
+	assert.Equal(t, "cluster-test", c.BuildArgoSecretName("test"))
+	assert.Equal(t, ClusterAPIVersionV1Beta1, c.ClusterAPIVersion)
+	assert.Equal(t, OutputFormatSecret, c.OutputFormat)
+
+	t.Setenv("ARGOCD_NAMESPACE", "gitops")
+	t.Setenv("ENABLE_NAMESPACED_NAMES", "true")
+	c = NewOperatorConfig()
+	assert.Equal(t, "gitops", c.ArgoNamespace)
+	assert.True(t, c.EnableNamespacedNames)
+}
+
+func TestNewReconcileContext(t *testing.T) {
+	t.Parallel()
+	config := MockOperatorConfig()
+	config.DryRun = true
+	rc := NewReconcileContext(context.Background(), TestLog, config, &MockRecorder{})
+	assert.True(t, rc.DryRun)
+	assert.Equal(t, config, rc.Config)
+	assert.NotNil(t, rc.Recorder)
+}
+
+func TestReconcileContextConfig(t *testing.T) {
+	t.Parallel()
+	s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "team-a")
+	c := NewCapiCluster("test", "team-a")
+	assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+
+	config := MockOperatorConfig()
+	config.ArgoNamespace = "gitops"
+	config.EnableNamespacedNames = true
+	a, err := NewArgoCluster(MockReconcileContext(config), c, s, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "gitops", a.NamespacedName.Namespace)
+	assert.Equal(t, "cluster-team-a-test", a.NamespacedName.Name)
+	assert.Equal(t, "team-a-kube-cluster-test", a.ClusterName)
+}
+
+// TestNoConfigGlobals guards against package-level configuration state. Only sentinel
+// errors, metrics and constant-like values may be declared as package variables.
+func TestNoConfigGlobals(t *testing.T) {
+	t.Parallel()
+	allowed := map[string]bool{
+		"IndexLookupDuration":        true,
+		"ClusterNameTruncatedTotal":  true,
+		"ClusterNameCollisionsTotal": true,
+		"EndpointUnreachableTotal":   true,
+		"SealedSecretGroupVersion":   true,
+		"reservedLabels":             true,
+	}
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	assert.Nil(t, err)
+	for _, pkg := range pkgs {
+		for fileName, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					for _, n := range spec.(*ast.ValueSpec).Names {
+						if !allowed[n.Name] && !strings.HasPrefix(n.Name, "Err") {
+							t.Errorf("package-level variable %s declared in %s, move it into OperatorConfig", n.Name, fileName)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateTakeAlongDomain(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ValidateTakeAlongDomain(DefaultTakeAlongDomain))
+	assert.Nil(t, ValidateTakeAlongDomain("example.com"))
+	assert.NotNil(t, ValidateTakeAlongDomain(""))
+	assert.NotNil(t, ValidateTakeAlongDomain("example.com/"))
+}
+
+func TestArgoSecretNameAffixes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testPrefix         string
+		testSuffix         string
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test default", DefaultArgoSecretNamePrefix, "", false, "cluster-test"},
+		{"test no prefix", "", "", false, "test"},
+		{"test prefix and suffix", "argo-", "-capi", false, "argo-test-capi"},
+		{"test invalid prefix", "Argo_", "", true, "argo-test-" + nameHash("Argo_test")},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.ArgoSecretNamePrefix = tt.testPrefix
+			c.ArgoSecretNameSuffix = tt.testSuffix
+			assert.Equal(t, tt.testExpectedError, c.ValidateArgoSecretNameAffixes() != nil)
+			assert.Equal(t, tt.testExpectedValues, c.BuildArgoSecretName("test"))
+			if !tt.testExpectedError {
+				assert.Equal(t, "test", c.TrimArgoSecretName(c.BuildArgoSecretName("test")))
+			}
+		})
+	}
+}
+
+func TestMatchesClusterSelector(t *testing.T) {
+	t.Parallel()
+	enabled := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Labels: map[string]string{"argocd": "enabled"}}}}
+	tests := []struct {
+		testName           string
+		testSelector       string
+		testMock           CAPICluster
+		testExpectedError  bool
+		testExpectedValues bool
+	}{
+		{"test unset", "", nil, false, true},
+		{"test matching", "argocd=enabled", enabled, false, true},
+		{"test set based matching", "argocd in (enabled, true),!legacy", enabled, false, true},
+		{"test not matching", "argocd=disabled", enabled, false, false},
+		{"test missing cluster", "argocd=enabled", nil, false, false},
+		{"test invalid selector", "argocd==enabled=", enabled, true, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.ClusterSelector = tt.testSelector
+			matches, err := c.MatchesClusterSelector(tt.testMock)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+			assert.Equal(t, tt.testExpectedValues, matches)
+		})
+	}
+}
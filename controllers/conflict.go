@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConflictResolutionStrategy decides which value wins when a take-along key is already
+// set on the ArgoSecret with another value, by something else than the operator.
+type ConflictResolutionStrategy string
+
+const (
+	// CAPIWins overwrites the ArgoSecret value with the CAPI Cluster value.
+	CAPIWins ConflictResolutionStrategy = "CAPIWins"
+	// ArgoWins keeps the ArgoSecret value.
+	ArgoWins ConflictResolutionStrategy = "ArgoWins"
+	// ConflictError reports the conflict and leaves the key untouched.
+	ConflictError ConflictResolutionStrategy = "Error"
+
+	// conflictStrategyAnnotationPrefix configures the strategy of a single key on the CAPI Cluster.
+	conflictStrategyAnnotationPrefix = "capi-to-argocd/conflict-strategy-"
+)
+
+// ResolveConflict returns the value to apply for key according to strategy.
+func ResolveConflict(key, capiValue, argoValue string, strategy ConflictResolutionStrategy) (string, error) {
+	switch strategy {
+	case CAPIWins:
+		return capiValue, nil
+	case ArgoWins:
+		return argoValue, nil
+	case ConflictError:
+		return "", fmt.Errorf("conflicting values for key '%s': cluster has '%s', ArgoSecret has '%s'", key, capiValue, argoValue)
+	}
+	return "", fmt.Errorf("unknown conflict resolution strategy '%s' for key '%s'", strategy, key)
+}
+
+// conflictStrategyFor returns the strategy configured for key on a CAPI Cluster, defaulting to CAPIWins.
+func conflictStrategyFor(cluster CAPICluster, key string) ConflictResolutionStrategy {
+	if cluster == nil {
+		return CAPIWins
+	}
+	if s, ok := cluster.GetAnnotations()[conflictStrategyAnnotationPrefix+key]; ok {
+		return ConflictResolutionStrategy(s)
+	}
+	return CAPIWins
+}
+
+// resolveTakeAlongConflicts returns the take-along labels to apply on an existing ArgoSecret.
+// Labels already set on the ArgoSecret by someone else are resolved with their strategy, and
+// skipped, along with their taken-from marker, when the ArgoSecret value wins or on error.
+func (r *Capi2Argo) resolveTakeAlongConflicts(capiSecret *corev1.Secret, cluster CAPICluster, takeAlong, existing map[string]string) map[string]string {
+	resolved := make(map[string]string, len(takeAlong))
+	for k, v := range takeAlong {
+		resolved[k] = v
+	}
+	for k, v := range takeAlong {
+		marker := clusterTakenFromClusterKey + k
+		if _, ok := takeAlong[marker]; !ok {
+			continue
+		}
+		argoValue, exists := existing[k]
+		if _, owned := existing[marker]; owned || !exists || argoValue == v {
+			continue
+		}
+		value, err := ResolveConflict(k, v, argoValue, conflictStrategyFor(cluster, k))
+		if err != nil {
+			r.Log.Info("Skipping conflicting take-along label", "error", err.Error())
+			r.sourceEvent(capiSecret, cluster, corev1.EventTypeWarning, "TakeAlongConflict", err.Error())
+		}
+		if err != nil || value == argoValue {
+			delete(resolved, k)
+			delete(resolved, marker)
+			continue
+		}
+		resolved[k] = value
+	}
+	return resolved
+}
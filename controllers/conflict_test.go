@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestResolveConflict(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           ConflictResolutionStrategy
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test CAPIWins", CAPIWins, false, "capi"},
+		{"test ArgoWins", ArgoWins, false, "argocd"},
+		{"test Error", ConflictError, true, ""},
+		{"test unknown strategy", "Merge", true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v, err := ResolveConflict("managed-by", "capi", "argocd", tt.testMock)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.testExpectedValues, v)
+		})
+	}
+}
+
+func TestReconcileTakeAlongConflicts(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testExpectedValues map[string]string
+		testExpectedEvents []string
+	}{
+		{"test default strategy", nil,
+			map[string]string{"managed-by": "capi", "team": "platform"}, []string{EventReasonUpdated}},
+		{"test ArgoWins per-key override", map[string]string{"capi-to-argocd/conflict-strategy-managed-by": "ArgoWins"},
+			map[string]string{"managed-by": "argocd", "team": "platform"}, []string{EventReasonUpdated}},
+		{"test Error strategy", map[string]string{"capi-to-argocd/conflict-strategy-managed-by": "Error"},
+			map[string]string{"managed-by": "argocd", "team": "platform"}, []string{"TakeAlongConflict", EventReasonUpdated}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "conflict-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "conflict"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "conflict", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+			c := MockClient(capiSecret, cluster)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			req := MockReconcileReq("conflict-kubeconfig", TestNamespace)
+			nn := r.Config.BuildNamespacedName("conflict-kubeconfig", TestNamespace)
+
+			_, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+
+			// ArgoCD sets its own label on the ArgoSecret.
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+			argoSecret.Labels["managed-by"] = "argocd"
+			assert.Nil(t, c.Update(ctx, &argoSecret))
+
+			// The CAPI Cluster starts taking along the same key.
+			assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+			cluster.Annotations = tt.testAnnotations
+			cluster.Labels = map[string]string{
+				"managed-by":                       "capi",
+				"team":                             "platform",
+				clusterTakeAlongKey + "managed-by": "",
+				clusterTakeAlongKey + "team":       "",
+			}
+			assert.Nil(t, c.Update(ctx, cluster))
+
+			recorder := &MockRecorder{}
+			r.Recorder = recorder
+			_, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+			for k, v := range tt.testExpectedValues {
+				assert.Equal(t, v, argoSecret.Labels[k], k)
+			}
+			_, marked := argoSecret.Labels[clusterTakenFromClusterKey+"managed-by"]
+			assert.Equal(t, tt.testExpectedValues["managed-by"] == "capi", marked)
+			assert.Equal(t, tt.testExpectedEvents, recorder.ForObject("Secret", "conflict-kubeconfig"))
+		})
+	}
+}
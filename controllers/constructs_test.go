@@ -1,14 +1,37 @@
 package controllers
 
 import (
+	"context"
 	b64 "encoding/base64"
 	"log"
 	"os"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1alpha4 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
+// TestArgoNamespace is the ArgoNamespace of MockOperatorConfig.
+var TestArgoNamespace = MockOperatorConfig().ArgoNamespace
+
+// MockOperatorConfig returns the default OperatorConfig.
+func MockOperatorConfig() OperatorConfig {
+	return NewOperatorConfig()
+}
+
+// MockReconcileContext returns a ReconcileContext holding the given OperatorConfig.
+func MockReconcileContext(config OperatorConfig) *ReconcileContext {
+	return NewReconcileContext(context.Background(), ctrl.Log.WithName("test"), config, nil)
+}
+
 // MockCapiKubeConfig returns a based64-encoded string that
 // represents a valid KubeConfig definition.
 func MockCapiKubeConfig() string {
@@ -78,7 +101,7 @@ func MockArgoCluster(validMock bool) *ArgoCluster {
 	}
 
 	a := &ArgoCluster{
-		NamespacedName: BuildNamespacedName("test", "test"),
+		NamespacedName: MockOperatorConfig().BuildNamespacedName("test", "test"),
 		ClusterName:    "test",
 		ClusterServer:  "server",
 		ClusterLabels: map[string]string{
@@ -109,3 +132,47 @@ func IsBase64(s string) bool {
 	_, err := b64.StdEncoding.DecodeString(s)
 	return err == nil
 }
+
+// MockScheme returns a scheme holding all types the controller works with.
+func MockScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(s)
+	_ = clusterv1.AddToScheme(s)
+	_ = clusterv1alpha4.AddToScheme(s)
+	_ = AddSealedSecretToScheme(s)
+	return s
+}
+
+// MockUnstructuredScheme returns MockScheme with a custom resource kind and its list kind
+// registered as unstructured objects.
+func MockUnstructuredScheme(gvk, listGVK schema.GroupVersionKind) *runtime.Scheme {
+	s := MockScheme()
+	s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(listGVK, &unstructured.UnstructuredList{})
+	return s
+}
+
+// MockUnstructured returns a custom resource of the given kind and spec, cluster-scoped when
+// namespace is empty.
+func MockUnstructured(gvk schema.GroupVersionKind, name, namespace string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	return u
+}
+
+// MockReadyClusterStatus returns the status of a Cluster with a ready control plane.
+func MockReadyClusterStatus() clusterv1.ClusterStatus {
+	return clusterv1.ClusterStatus{Conditions: clusterv1.Conditions{{Type: clusterv1.ControlPlaneReadyCondition, Status: corev1.ConditionTrue}}}
+}
+
+// MockClient returns a fake client pre-populated with the given objects.
+func MockClient(objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(objs...).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		Build()
+}
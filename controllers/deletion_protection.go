@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ErrDeletionBlocked is returned when ArgoCD Applications still target a cluster being deleted.
+var ErrDeletionBlocked = errors.New("deletion blocked by ArgoCD Applications")
+
+const (
+	// deletionProtectionFinalizer holds CAPI Secrets until their ArgoSecrets got deleted.
+	deletionProtectionFinalizer = "capi-to-argocd/deletion-protection"
+	// deletionBlockedAnnotation is set on CAPI Secrets whose deletion is blocked, as Secrets have
+	// no status conditions. It holds the blocking Applications.
+	deletionBlockedAnnotation = "capi-to-argocd/deletion-blocked"
+	// DeletionBlockedCondition is the reason of events emitted for blocked deletions.
+	DeletionBlockedCondition = "DeletionBlocked"
+	// deletionBlockedRequeueAfter is how often blocked deletions get checked again.
+	deletionBlockedRequeueAfter = time.Minute
+)
+
+// ArgoApplicationListGVK returns the GroupVersionKind of ArgoCD Application lists.
+func ArgoApplicationListGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "ApplicationList"}
+}
+
+// finalizeCapiSecret deletes the ArgoSecrets of a CAPI Secret being deleted, unless moved by
+// clusterctl, and removes the deletion protection finalizer. With Config.EnableDeletionProtection, deletion is blocked and
+// requeued while ArgoCD Applications still target the cluster.
+func (r *Capi2Argo) finalizeCapiSecret(ctx context.Context, log logr.Logger, capiSecret *corev1.Secret) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(capiSecret, deletionProtectionFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if r.Config.EnableDeletionProtection {
+		apps, err := r.listTargetingApplications(ctx, log, capiSecret)
+		if err != nil {
+			log.Error(err, "Failed to list ArgoCD Applications")
+			return ctrl.Result{}, err
+		}
+		if len(apps) > 0 {
+			message := fmt.Sprintf("Applications still target the cluster: %s", strings.Join(apps, ", "))
+			log.Error(ErrDeletionBlocked, message)
+			if capiSecret.Annotations[deletionBlockedAnnotation] != message {
+				if capiSecret.Annotations == nil {
+					capiSecret.Annotations = map[string]string{}
+				}
+				capiSecret.Annotations[deletionBlockedAnnotation] = message
+				r.sourceEvent(capiSecret, nil, corev1.EventTypeWarning, DeletionBlockedCondition, message)
+				if err := r.Update(ctx, capiSecret); err != nil {
+					log.Error(err, "Failed to mark CapiSecret deletion as blocked")
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: deletionBlockedRequeueAfter}, nil
+		}
+	}
+	moved, err := r.isMovedCapiSecret(ctx, log, client.ObjectKeyFromObject(capiSecret), capiSecret.Annotations)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !moved {
+		if result, err := r.deleteArgoSecrets(ctx, log, client.ObjectKeyFromObject(capiSecret)); err != nil {
+			return result, err
+		}
+	}
+	controllerutil.RemoveFinalizer(capiSecret, deletionProtectionFinalizer)
+	if err := r.Update(ctx, capiSecret); err != nil {
+		log.Error(err, "Failed to remove deletion protection finalizer from CapiSecret")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// listTargetingApplications returns the `<namespace>/<name>` of the ArgoCD Applications whose
+// destination matches the server or name of an ArgoSecret of the CAPI Secret. No Applications
+// are returned when the Application CRD is not installed, or Applications may not be listed, so
+// deletions do not get stuck.
+func (r *Capi2Argo) listTargetingApplications(ctx context.Context, log logr.Logger, capiSecret *corev1.Secret) ([]string, error) {
+	secrets, err := r.listArgoSecrets(ctx, capiSecret.Name, capiSecret.Namespace)
+	if err != nil || len(secrets) == 0 {
+		return nil, err
+	}
+	servers, names := map[string]bool{}, map[string]bool{}
+	for _, s := range secrets {
+		if server := string(s.Data["server"]); server != "" {
+			servers[server] = true
+		}
+		if name := string(s.Data["name"]); name != "" {
+			names[name] = true
+		}
+	}
+
+	appList := &unstructured.UnstructuredList{}
+	appList.SetGroupVersionKind(ArgoApplicationListGVK())
+	if err := r.argoClient().List(ctx, appList); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		if apierrors.IsForbidden(err) {
+			log.Info("Warning: not allowed to list ArgoCD Applications, deletion protection is skipped", "error", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	apps := []string{}
+	for _, app := range appList.Items {
+		server, _, _ := unstructured.NestedString(app.Object, "spec", "destination", "server")
+		name, _, _ := unstructured.NestedString(app.Object, "spec", "destination", "name")
+		if (server != "" && servers[server]) || (name != "" && names[name]) {
+			apps = append(apps, app.GetNamespace()+"/"+app.GetName())
+		}
+	}
+	return apps, nil
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func MockArgoApplication(name, server, clusterName string) *unstructured.Unstructured {
+	app := &unstructured.Unstructured{}
+	app.SetGroupVersionKind(ArgoApplicationListGVK().GroupVersion().WithKind("Application"))
+	app.SetName(name)
+	app.SetNamespace(TestArgoNamespace)
+	destination := map[string]interface{}{}
+	if server != "" {
+		destination["server"] = server
+	}
+	if clusterName != "" {
+		destination["name"] = clusterName
+	}
+	_ = unstructured.SetNestedMap(app.Object, destination, "spec", "destination")
+	return app
+}
+
+// MockApplicationScheme returns MockScheme with the ArgoCD Application CRD installed.
+func MockApplicationScheme() *runtime.Scheme {
+	s := MockScheme()
+	gv := ArgoApplicationListGVK().GroupVersion()
+	s.AddKnownTypeWithName(gv.WithKind("Application"), &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(ArgoApplicationListGVK(), &unstructured.UnstructuredList{})
+	return s
+}
+
+func TestReconcileDeletionProtection(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testCRDInstalled  bool
+		testForbidden     bool
+		testServer        bool
+		testClusterName   bool
+		testExpectedError bool
+	}{
+		{"test application targeting server", true, false, true, false, true},
+		{"test application targeting name", true, false, false, true, true},
+		{"test unrelated application", true, false, false, false, false},
+		{"test application CRD not installed", false, false, false, false, false},
+		{"test applications forbidden", true, true, true, false, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			scheme := MockScheme()
+			if tt.testCRDInstalled {
+				scheme = MockApplicationScheme()
+			}
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "protected-kubeconfig", TestNamespace)
+			b := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(capiSecret).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex)
+			if tt.testForbidden {
+				b = b.WithInterceptorFuncs(interceptor.Funcs{List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					if _, ok := list.(*unstructured.UnstructuredList); ok {
+						return errors.NewForbidden(ArgoApplicationListGVK().GroupVersion().WithResource("applications").GroupResource(), "", nil)
+					}
+					return c.List(ctx, list, opts...)
+				}})
+			}
+			c := b.Build()
+			recorder := &MockRecorder{}
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig(), Recorder: recorder}
+			r.Config.EnableGarbageCollection = true
+			r.Config.EnableDeletionProtection = true
+			req := MockReconcileReq("protected-kubeconfig", TestNamespace)
+			nn := r.Config.BuildNamespacedName("protected-kubeconfig", TestNamespace)
+
+			_, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+			assert.True(t, controllerutil.ContainsFinalizer(capiSecret, deletionProtectionFinalizer))
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+
+			app := MockArgoApplication("guestbook", "https://unrelated.example.com", "unrelated")
+			if tt.testServer {
+				app = MockArgoApplication("guestbook", string(argoSecret.Data["server"]), "")
+			}
+			if tt.testClusterName {
+				app = MockArgoApplication("guestbook", "", string(argoSecret.Data["name"]))
+			}
+			if tt.testCRDInstalled {
+				assert.Nil(t, c.Create(ctx, app))
+			}
+
+			// The finalizer holds the CAPI Secret until its ArgoSecret got deleted.
+			assert.Nil(t, c.Delete(ctx, capiSecret))
+			result, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			if !tt.testExpectedError {
+				assert.Zero(t, result.RequeueAfter)
+				assert.True(t, errors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+				assert.True(t, errors.IsNotFound(c.Get(ctx, req.NamespacedName, capiSecret)))
+				return
+			}
+			assert.Equal(t, deletionBlockedRequeueAfter, result.RequeueAfter)
+			assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+			assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+			assert.Contains(t, capiSecret.Annotations[deletionBlockedAnnotation], TestArgoNamespace+"/guestbook")
+			assert.Contains(t, recorder.ForObject("Secret", "protected-kubeconfig"), DeletionBlockedCondition)
+
+			// Still blocked requeues do not emit events again.
+			_, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Len(t, recorder.ForObject("Secret", "protected-kubeconfig"), 2)
+
+			// Deletion proceeds once no Application targets the cluster anymore.
+			assert.Nil(t, c.Delete(ctx, app))
+			result, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Zero(t, result.RequeueAfter)
+			assert.True(t, errors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+			assert.True(t, errors.IsNotFound(c.Get(ctx, req.NamespacedName, capiSecret)))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const (
+	// DefaultEndpointHealthCheckInterval is how often ArgoSecret servers get checked by default.
+	DefaultEndpointHealthCheckInterval = 15 * time.Minute
+	// endpointHealthCheckTimeout bounds a single reachability check.
+	endpointHealthCheckTimeout = 5 * time.Second
+	// endpointHealthCheckPath is the API server path requested by reachability checks.
+	endpointHealthCheckPath = "/healthz"
+)
+
+// HTTPDoer sends HTTP requests, as http.Client does.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// EndpointHealthChecker periodically checks that the servers of the ArgoSecrets owned by the
+// operator instance are reachable. Unreachable servers trigger a reconcile of the CAPI Secret they were
+// generated from, which may hold a fresh server URL.
+type EndpointHealthChecker struct {
+	// Client reads ArgoSecrets.
+	Client client.Client
+	// HTTPClient sends the reachability checks.
+	HTTPClient HTTPDoer
+	Log        logr.Logger
+	// Config provides the namespaces holding ArgoSecrets and the operator instance owning them.
+	Config OperatorConfig
+	// Interval is how often the check runs.
+	Interval time.Duration
+	// Recorder emits events on ArgoSecrets with unreachable servers. No events are emitted when nil.
+	Recorder record.EventRecorder
+	// Triggers receives the CAPI Secrets to reconcile, see Capi2Argo.Triggers.
+	Triggers chan<- event.GenericEvent
+}
+
+// NewEndpointHealthChecker returns an EndpointHealthChecker for the ArgoSecrets of the Argo
+// namespaces of config, sending CAPI Secrets of unreachable servers to triggers.
+func NewEndpointHealthChecker(c client.Client, log logr.Logger, config OperatorConfig, interval time.Duration, recorder record.EventRecorder, triggers chan<- event.GenericEvent) *EndpointHealthChecker {
+	return &EndpointHealthChecker{
+		Client: c,
+		// Only reachability is checked and no credentials are sent, so the server certificate is not verified.
+		HTTPClient: &http.Client{
+			Timeout:   endpointHealthCheckTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, //nolint:gosec
+		},
+		Log:      log,
+		Config:   config,
+		Interval: interval,
+		Recorder: recorder,
+		Triggers: triggers,
+	}
+}
+
+// Check verifies the servers of all ArgoSecrets and returns the number of unreachable ones.
+func (h *EndpointHealthChecker) Check(ctx context.Context) (int, error) {
+	secrets := []corev1.Secret{}
+	for _, ns := range h.Config.ArgoNamespaces() {
+		list := &corev1.SecretList{}
+		if err := h.Client.List(ctx, list, client.InNamespace(ns), client.MatchingLabels(GetArgoCommonLabels().ToMap())); err != nil {
+			return 0, err
+		}
+		for _, s := range list.Items {
+			if h.Config.IsOwnInstance(&s) {
+				secrets = append(secrets, s)
+			}
+		}
+	}
+	unreachable := 0
+	for i := range secrets {
+		s := &secrets[i]
+		server := string(s.Data["server"])
+		if server == "" || h.reachable(ctx, server) {
+			continue
+		}
+		unreachable++
+		EndpointUnreachableTotal.Inc()
+		h.Log.Info("Warning: ArgoSecret server is unreachable", "cluster", client.ObjectKeyFromObject(s), "server", server)
+		if h.Recorder != nil {
+			h.Recorder.Event(s, corev1.EventTypeWarning, "EndpointUnreachable", "Server "+server+" is unreachable")
+		}
+		if err := h.trigger(ctx, s); err != nil {
+			return unreachable, err
+		}
+	}
+	return unreachable, nil
+}
+
+// Start runs the check until ctx is done. It implements manager.Runnable.
+func (h *EndpointHealthChecker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if _, err := h.Check(ctx); err != nil {
+			h.Log.Error(err, "Failed to check ArgoSecret servers")
+		}
+	}
+}
+
+// reachable returns true if server answers a HEAD request on its health endpoint.
+// Any response below 500, unauthorized ones included, proves reachability.
+func (h *EndpointHealthChecker) reachable(ctx context.Context, server string) bool {
+	ctx, cancel := context.WithTimeout(ctx, endpointHealthCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, strings.TrimSuffix(server, "/")+endpointHealthCheckPath, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// trigger enqueues a reconcile of the CAPI Secret an ArgoSecret was generated from.
+func (h *EndpointHealthChecker) trigger(ctx context.Context, s *corev1.Secret) error {
+	name, namespace := s.Labels[clusterSecretNameLabel], s.Labels[clusterNamespaceLabel]
+	if h.Triggers == nil || name == "" || namespace == "" {
+		return nil
+	}
+	capiSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	select {
+	case h.Triggers <- event.GenericEvent{Object: capiSecret}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
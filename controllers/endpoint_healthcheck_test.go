@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// MockHTTPDoer answers requests with the status of their host, failing for unknown hosts.
+type MockHTTPDoer struct {
+	mu       sync.Mutex
+	statuses map[string]int
+	requests []*http.Request
+}
+
+func (m *MockHTTPDoer) Do(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+	status, ok := m.statuses[req.URL.Host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func MockEndpointArgoSecret(name, server string) *corev1.Secret {
+	s := MockIndexedArgoSecret(name, name+"-kubeconfig", "test")
+	for k, v := range GetArgoCommonLabels().ToMap() {
+		s.Labels[k] = v
+	}
+	s.Data = map[string][]byte{"server": []byte(server)}
+	return s
+}
+
+func endpointUnreachableTotal(t *testing.T) float64 {
+	m := &dto.Metric{}
+	assert.Nil(t, EndpointUnreachableTotal.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestEndpointHealthChecker(t *testing.T) {
+	ctx := context.Background()
+	// ArgoSecrets of every Argo namespace get checked.
+	tenant := MockEndpointArgoSecret("cluster-tenant", "https://tenant.example.com")
+	tenant.Namespace = "tenant-argocd"
+	outside := MockEndpointArgoSecret("cluster-outside", "https://outside.example.com")
+	outside.Namespace = "outside"
+	// ArgoSecrets of other operator instances are left alone.
+	other := MockEndpointArgoSecret("cluster-other", "https://other.example.com")
+	other.Labels[instanceLabel] = "staging"
+	c := MockClient(
+		MockEndpointArgoSecret("cluster-up", "https://up.example.com/"),
+		MockEndpointArgoSecret("cluster-unauthorized", "https://unauthorized.example.com"),
+		MockEndpointArgoSecret("cluster-failing", "https://failing.example.com"),
+		MockEndpointArgoSecret("cluster-down", "https://down.example.com"),
+		tenant,
+		outside,
+		other,
+	)
+	doer := &MockHTTPDoer{statuses: map[string]int{
+		"up.example.com":           http.StatusOK,
+		"unauthorized.example.com": http.StatusUnauthorized,
+		"failing.example.com":      http.StatusServiceUnavailable,
+	}}
+	recorder := record.NewFakeRecorder(10)
+	triggers := make(chan event.GenericEvent, 10)
+	config := MockOperatorConfig()
+	config.AdditionalArgoNamespaces = "tenant-argocd"
+	h := NewEndpointHealthChecker(c, TestLog, config, time.Minute, recorder, triggers)
+	h.HTTPClient = doer
+
+	before := endpointUnreachableTotal(t)
+	unreachable, err := h.Check(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, unreachable)
+	assert.Equal(t, before+3, endpointUnreachableTotal(t))
+
+	assert.Len(t, doer.requests, 5)
+	for _, req := range doer.requests {
+		assert.Equal(t, http.MethodHead, req.Method)
+		assert.Equal(t, endpointHealthCheckPath, req.URL.Path)
+	}
+
+	close(triggers)
+	var triggered []string
+	for e := range triggers {
+		assert.Equal(t, "test", e.Object.GetNamespace())
+		triggered = append(triggered, e.Object.GetName())
+	}
+	assert.ElementsMatch(t, []string{"cluster-failing-kubeconfig", "cluster-down-kubeconfig", "cluster-tenant-kubeconfig"}, triggered)
+	assert.Len(t, recorder.Events, 3)
+	assert.Contains(t, <-recorder.Events, "Warning EndpointUnreachable")
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Reasons of the events emitted along the registration lifecycle of a cluster.
+const (
+	// EventReasonRegistered is emitted once the ArgoSecret of a cluster got created.
+	EventReasonRegistered = "Registered"
+	// EventReasonUpdated is emitted once an out-of-sync ArgoSecret got updated.
+	EventReasonUpdated = "Updated"
+	// EventReasonSkippedNotReady is emitted while the readiness gates of a cluster hold back its registration.
+	EventReasonSkippedNotReady = "SkippedNotReady"
+	// EventReasonConversionFailed is emitted when a CAPI Secret cannot be converted to an ArgoSecret.
+	EventReasonConversionFailed = "ConversionFailed"
+	// EventReasonDeregistered is emitted once the ArgoSecret of a deleted CAPI Secret got deleted.
+	EventReasonDeregistered = "Deregistered"
+	// EventReasonNameCollision is emitted when the names of a cluster are taken by another CAPI Secret.
+	EventReasonNameCollision = "NameCollision"
+)
+
+// registrationEvent emits a registration lifecycle event on the CAPI Secret, the CAPI Cluster as
+// of sourceEvent, and the ArgoSecret when given.
+func (r *Capi2Argo) registrationEvent(capiSecret *corev1.Secret, cluster CAPICluster, argoSecret *corev1.Secret, eventType, reason, message string) {
+	r.sourceEvent(capiSecret, cluster, eventType, reason, message)
+	if argoSecret != nil {
+		r.event(argoSecret, eventType, reason, message)
+	}
+}
+
+// deregistrationEvent emits a Deregistered event on a deleted ArgoSecret and, with
+// Config.EmitClusterEvents enabled, on the CAPI Cluster of its deleted CAPI Secret if still present.
+func (r *Capi2Argo) deregistrationEvent(ctx context.Context, capiSecret types.NamespacedName, argoSecret *corev1.Secret) {
+	message := "Deregistered ArgoSecret " + types.NamespacedName{Name: argoSecret.Name, Namespace: argoSecret.Namespace}.String()
+	r.event(argoSecret, corev1.EventTypeNormal, EventReasonDeregistered, message)
+	if !r.Config.EmitClusterEvents {
+		return
+	}
+	name := strings.TrimSuffix(capiSecret.Name, "-kubeconfig")
+	if cluster, err := GetCAPICluster(ctx, r.Client, r.Config.ClusterAPIVersion, types.NamespacedName{Name: name, Namespace: capiSecret.Namespace}); err == nil {
+		r.sourceEvent(nil, cluster, corev1.EventTypeNormal, EventReasonDeregistered, message)
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MockEvent is an event captured by MockRecorder.
+type MockEvent struct {
+	Object  runtime.Object
+	Type    string
+	Reason  string
+	Message string
+}
+
+// MockRecorder keeps emitted events along with their involved object.
+type MockRecorder struct {
+	mu     sync.Mutex
+	Events []MockEvent
+}
+
+// Event implements record.EventRecorder.
+func (m *MockRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Events = append(m.Events, MockEvent{object, eventtype, reason, message})
+}
+
+// Eventf implements record.EventRecorder.
+func (m *MockRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	m.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf implements record.EventRecorder.
+func (m *MockRecorder) AnnotatedEventf(object runtime.Object, _ map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	m.Eventf(object, eventtype, reason, messageFmt, args...)
+}
+
+// ForObject returns the reasons of the events emitted on the given object kind and name.
+func (m *MockRecorder) ForObject(kind, name string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reasons := []string{}
+	for _, e := range m.Events {
+		o := e.Object.(client.Object)
+		if strings.HasSuffix(fmt.Sprintf("%T", o), "."+kind) && o.GetName() == name {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	return reasons
+}
+
+func TestReconcileClusterEvents(t *testing.T) {
+	tests := []struct {
+		testName              string
+		testEmitClusterEvents bool
+		testCluster           bool
+		testExpectedValues    []string
+	}{
+		{"test cluster events disabled", false, true, []string{}},
+		{"test cluster events enabled", true, true, []string{EventReasonRegistered, EventReasonUpdated}},
+		{"test cluster events enabled without Cluster object", true, false, []string{}},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		capiSecret := MockCapiSecret(validMock, validType, validKey, "events-kubeconfig", TestNamespace)
+		capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "events"}
+		objs := []client.Object{capiSecret}
+		if tt.testCluster {
+			objs = append(objs, &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "events", Namespace: TestNamespace}, Status: MockReadyClusterStatus()})
+		}
+		c := MockClient(objs...)
+		recorder := &MockRecorder{}
+		r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder}
+		r.Config.EmitClusterEvents = tt.testEmitClusterEvents
+
+		_, err := r.Reconcile(ctx, MockReconcileReq("events-kubeconfig", TestNamespace))
+		assert.Nil(t, err, tt.testName)
+		var argoSecret corev1.Secret
+		nn := MockOperatorConfig().BuildNamespacedName("events-kubeconfig", TestNamespace)
+		assert.Nil(t, c.Get(ctx, nn, &argoSecret), tt.testName)
+		argoSecret.Data["server"] = []byte("https://stale")
+		assert.Nil(t, c.Update(ctx, &argoSecret), tt.testName)
+		_, err = r.Reconcile(ctx, MockReconcileReq("events-kubeconfig", TestNamespace))
+		assert.Nil(t, err, tt.testName)
+
+		assert.Equal(t, []string{EventReasonRegistered, EventReasonUpdated}, recorder.ForObject("Secret", "events-kubeconfig"), tt.testName)
+		assert.Equal(t, tt.testExpectedValues, recorder.ForObject("Cluster", "events"), tt.testName)
+		for _, e := range recorder.Events {
+			assert.Contains(t, e.Message, nn.String(), tt.testName)
+		}
+	}
+}
+
+func TestReconcileRegistrationEvents(t *testing.T) {
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "lifecycle-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "lifecycle"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "lifecycle", Namespace: TestNamespace}}
+	c := MockClient(capiSecret, cluster)
+	recorder := &MockRecorder{}
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder}
+	r.Config.EmitClusterEvents = true
+	r.Config.EnableGarbageCollection = true
+	req := MockReconcileReq("lifecycle-kubeconfig", TestNamespace)
+	nn := MockOperatorConfig().BuildNamespacedName("lifecycle-kubeconfig", TestNamespace)
+
+	// Held back by the ControlPlaneReady readiness gate.
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{EventReasonSkippedNotReady}, recorder.ForObject("Cluster", "lifecycle"))
+
+	cluster.Status = MockReadyClusterStatus()
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.Delete(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{EventReasonSkippedNotReady, EventReasonRegistered, EventReasonUpdated}, recorder.ForObject("Secret", "lifecycle-kubeconfig"))
+	assert.Equal(t, []string{EventReasonRegistered, EventReasonUpdated, EventReasonDeregistered}, recorder.ForObject("Secret", nn.Name))
+	assert.Equal(t, []string{EventReasonSkippedNotReady, EventReasonRegistered, EventReasonUpdated, EventReasonDeregistered}, recorder.ForObject("Cluster", "lifecycle"))
+}
+
+func TestReconcileConversionFailedEvent(t *testing.T) {
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(false, validType, validKey, "broken-kubeconfig", TestNamespace))
+	recorder := &MockRecorder{}
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder}
+
+	_, err := r.Reconcile(ctx, MockReconcileReq("broken-kubeconfig", TestNamespace))
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{EventReasonConversionFailed}, recorder.ForObject("Secret", "broken-kubeconfig"))
+	assert.Equal(t, corev1.EventTypeWarning, recorder.Events[0].Type)
+}
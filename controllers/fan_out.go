@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"maps"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// completeRegistration mirrors a written ArgoSecret into the fan-out namespaces, then records the
+// registration on the CAPI Cluster.
+func (r *Capi2Argo) completeRegistration(ctx context.Context, log logr.Logger, cluster CAPICluster, a *ArgoCluster, written *corev1.Secret) error {
+	if err := r.fanOutArgoSecret(ctx, log, a, written); err != nil {
+		return err
+	}
+	return r.recordRegistration(ctx, log, cluster, a.NamespacedName)
+}
+
+// fanOutArgoSecret reconciles an identical copy of a written ArgoSecret, and of its token Secret,
+// in every OperatorConfig.FanOutArgoNamespaces besides its own namespace.
+func (r *Capi2Argo) fanOutArgoSecret(ctx context.Context, log logr.Logger, a *ArgoCluster, written *corev1.Secret) error {
+	sources := []*corev1.Secret{written}
+	if tokenSecret := a.ConvertToTokenSecret(); tokenSecret != nil {
+		sources = append(sources, tokenSecret)
+	}
+	for _, ns := range ParseNamespaces(r.Config.FanOutArgoNamespaces) {
+		if ns == written.Namespace {
+			continue
+		}
+		for _, source := range sources {
+			if err := r.reconcileSecretCopy(ctx, log, a, CopySecret(source, ns)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CopySecret returns a copy of the name, labels, annotations and content of a Secret in another
+// namespace.
+func CopySecret(s *corev1.Secret, namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.Name,
+			Namespace:   namespace,
+			Labels:      maps.Clone(s.Labels),
+			Annotations: maps.Clone(s.Annotations),
+		},
+		Type:      s.Type,
+		Data:      maps.Clone(s.Data),
+		Immutable: s.Immutable,
+	}
+}
+
+// reconcileSecretCopy creates or updates a copy of a Secret, leaving Secrets not managed by the
+// operator alone.
+func (r *Capi2Argo) reconcileSecretCopy(ctx context.Context, log logr.Logger, a *ArgoCluster, copied *corev1.Secret) error {
+	log = log.WithValues("copy", client.ObjectKeyFromObject(copied))
+	var existing corev1.Secret
+	err := r.argoClient().Get(ctx, client.ObjectKeyFromObject(copied), &existing)
+	if errors.IsNotFound(err) {
+		err = r.argoClient().Create(ctx, copied)
+		r.auditRecord(AuditActionCreate, copied, a.ClusterName, secretFieldNames(copied), err)
+		if err != nil {
+			log.Error(err, "Failed to create copy of ArgoSecret")
+			return err
+		}
+		log.Info("Created copy of ArgoSecret")
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to fetch copy of ArgoSecret")
+		return err
+	}
+	if r.Config.ValidateOwner(existing) != nil {
+		log.Info("Copy of ArgoSecret is not managed by Controller, skipping...")
+		return nil
+	}
+	if isSecretCopy(&existing, copied) {
+		return nil
+	}
+	// Like ArgoSecrets, immutable copies get replaced.
+	replace := isImmutable(&existing)
+	existing.Labels, existing.Annotations, existing.Data = copied.Labels, copied.Annotations, copied.Data
+	existing.Immutable = copied.Immutable
+	if replace {
+		err = r.replaceArgoSecret(ctx, log, &existing)
+	} else if err = r.argoClient().Update(ctx, &existing); err != nil {
+		log.Error(err, "Failed to update copy of ArgoSecret")
+	}
+	r.auditRecord(AuditActionUpdate, &existing, a.ClusterName, secretFieldNames(&existing), err)
+	if err != nil {
+		return err
+	}
+	log.Info("Updated copy of ArgoSecret")
+	return nil
+}
+
+// isSecretCopy returns true if a Secret holds the same labels, annotations and content as copied.
+func isSecretCopy(s, copied *corev1.Secret) bool {
+	if !maps.Equal(s.Labels, copied.Labels) || !maps.Equal(s.Annotations, copied.Annotations) {
+		return false
+	}
+	if !maps.EqualFunc(s.Data, copied.Data, bytes.Equal) {
+		return false
+	}
+	return isImmutable(s) == isImmutable(copied)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCopySecret(t *testing.T) {
+	t.Parallel()
+	immutable := true
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-test", Namespace: "argocd", ResourceVersion: "42", Labels: map[string]string{"env": "prod"}, Annotations: map[string]string{"note": "x"}},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"name": []byte("test")},
+		Immutable:  &immutable,
+	}
+	copied := CopySecret(s, "argocd-prod")
+	assert.Equal(t, "cluster-test", copied.Name)
+	assert.Equal(t, "argocd-prod", copied.Namespace)
+	assert.Empty(t, copied.ResourceVersion)
+	assert.True(t, isSecretCopy(s, copied))
+
+	copied.Labels["env"] = "dev"
+	assert.Equal(t, "prod", s.Labels["env"])
+	assert.False(t, isSecretCopy(s, copied))
+}
+
+func TestReconcileFanOut(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config := MockOperatorConfig()
+	config.FanOutArgoNamespaces = "argocd-a,argocd-b"
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "fanout-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "fanout"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "fanout", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	nn := config.BuildNamespacedName("fanout-kubeconfig", TestNamespace)
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: "argocd-b"}, Data: map[string][]byte{"name": []byte("foreign")}}
+	c := fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(capiSecret, cluster, foreign).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, config.ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, config.ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: config}
+	req := MockReconcileReq("fanout-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret, copied corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "argocd-a"}, &copied))
+	assert.True(t, isSecretCopy(&argoSecret, &copied))
+
+	// Secrets not managed by the operator are left alone.
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "argocd-b"}, &copied))
+	assert.Equal(t, "foreign", string(copied.Data["name"]))
+
+	// Out-of-sync copies get restored.
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "argocd-a"}, &copied))
+	copied.Data["server"] = []byte("https://tampered")
+	assert.Nil(t, c.Update(ctx, &copied))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "argocd-a"}, &copied))
+	assert.True(t, isSecretCopy(&argoSecret, &copied))
+
+	// Deregistering removes the copies along with the ArgoSecret.
+	cluster.Annotations = map[string]string{skipAnnotation: "true"}
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "argocd-a"}, &copied)))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: "argocd-b"}, &copied))
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"path"
+	"slices"
+)
+
+const (
+	// gcpAuthProviderName is the legacy auth provider of GKE kubeconfigs.
+	gcpAuthProviderName = "gcp"
+	// gkeAuthPluginCommand is the GKE credential plugin replacing the gcp auth provider.
+	gkeAuthPluginCommand = "gke-gcloud-auth-plugin"
+	// gkeApplicationDefaultCredentialsArg makes the GKE plugin authenticate with the application
+	// default credentials, i.e. the Workload Identity of ArgoCD, instead of a gcloud login.
+	gkeApplicationDefaultCredentialsArg = "--use_application_default_credentials"
+)
+
+// buildGKEExecConfig returns the gke-gcloud-auth-plugin exec plugin of a kubeconfig user
+// authenticating through the gcp auth provider or the plugin itself, false for other users.
+// The plugin is looked up in the PATH of ArgoCD and runs with the application default credentials.
+func buildGKEExecConfig(u UserInfo) (*ExecConfig, bool) {
+	exec := &ExecConfig{}
+	switch {
+	case u.Exec != nil && path.Base(u.Exec.Command) == gkeAuthPluginCommand:
+		*exec = *u.Exec
+		exec.Args = slices.Clone(u.Exec.Args)
+	case u.AuthProvider != nil && u.AuthProvider.Name == gcpAuthProviderName:
+	default:
+		return nil, false
+	}
+	exec.Command = gkeAuthPluginCommand
+	if exec.APIVersion == "" {
+		exec.APIVersion = "client.authentication.k8s.io/v1beta1"
+	}
+	if exec.InstallHint == "" {
+		exec.InstallHint = "gke-gcloud-auth-plugin is required in the ArgoCD image, see https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-access-for-kubectl#install_plugin"
+	}
+	if !slices.Contains(exec.Args, gkeApplicationDefaultCredentialsArg) {
+		exec.Args = append(exec.Args, gkeApplicationDefaultCredentialsArg)
+	}
+	return exec, true
+}
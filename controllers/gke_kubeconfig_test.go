@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildGKEExecConfig(t *testing.T) {
+	t.Parallel()
+	hint := "gke-gcloud-auth-plugin is required in the ArgoCD image, see https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-access-for-kubectl#install_plugin"
+	tests := []struct {
+		testName           string
+		testMock           UserInfo
+		testExpectedValues *ExecConfig
+	}{
+		{"test gke plugin exec", UserInfo{Exec: &ExecConfig{APIVersion: "client.authentication.k8s.io/v1beta1", Command: "/usr/lib/google-cloud-sdk/bin/gke-gcloud-auth-plugin", InstallHint: "install it"}},
+			&ExecConfig{APIVersion: "client.authentication.k8s.io/v1beta1", Command: "gke-gcloud-auth-plugin", Args: []string{"--use_application_default_credentials"}, InstallHint: "install it"}},
+		{"test gke plugin exec with application default credentials", UserInfo{Exec: &ExecConfig{Command: "gke-gcloud-auth-plugin", Args: []string{"--use_application_default_credentials"}, Env: []ExecEnvVar{{Name: "CLOUDSDK_CORE_PROJECT", Value: "test"}}}},
+			&ExecConfig{APIVersion: "client.authentication.k8s.io/v1beta1", Command: "gke-gcloud-auth-plugin", Args: []string{"--use_application_default_credentials"}, Env: []ExecEnvVar{{Name: "CLOUDSDK_CORE_PROJECT", Value: "test"}}, InstallHint: hint}},
+		{"test gcp auth-provider", UserInfo{AuthProvider: &AuthProviderConfig{Name: "gcp", Config: map[string]string{"cmd-path": "/usr/bin/gcloud"}}},
+			&ExecConfig{APIVersion: "client.authentication.k8s.io/v1beta1", Command: "gke-gcloud-auth-plugin", Args: []string{"--use_application_default_credentials"}, InstallHint: hint}},
+		{"test other exec", UserInfo{Exec: &ExecConfig{Command: "kubelogin"}}, nil},
+		{"test client certificate", UserInfo{}, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			exec, ok := buildGKEExecConfig(tt.testMock)
+			assert.Equal(t, tt.testExpectedValues != nil, ok)
+			assert.Equal(t, tt.testExpectedValues, exec)
+		})
+	}
+}
+
+func TestGKEKubeConfig(t *testing.T) {
+	t.Parallel()
+	s := MockCapiSecret(validMock, validType, validKey, "gke-kubeconfig", "test")
+	s.Data["value"] = []byte("apiVersion: v1\nkind: Config\nclusters:\n- name: gke\n  cluster:\n    server: https://34.1.2.3\n    certificate-authority-data: Y2E=\n" +
+		"users:\n- name: gke\n  user:\n    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: gke-gcloud-auth-plugin\n      provideClusterInfo: true\n")
+	c := NewCapiCluster("gke", "test")
+	assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+	a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "gke-gcloud-auth-plugin", a.ClusterConfig.ExecProviderConfig.Command)
+	assert.Equal(t, []string{"--use_application_default_credentials"}, a.ClusterConfig.ExecProviderConfig.Args)
+	assert.Nil(t, a.ClusterConfig.BearerToken)
+}
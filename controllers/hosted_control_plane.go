@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// HostedControlPlaneHyperShift marks the admin kubeconfigs of HyperShift HostedClusters.
+	HostedControlPlaneHyperShift = "hypershift"
+	// HostedControlPlaneKamaji marks the admin kubeconfigs of Kamaji TenantControlPlanes.
+	HostedControlPlaneKamaji = "kamaji"
+	// HostedControlPlaneK0smotron marks the kubeconfigs of standalone k0smotron Clusters.
+	HostedControlPlaneK0smotron = "k0smotron"
+)
+
+// HostedControlPlaneProvider describes the kubeconfig Secrets a control plane provider writes for
+// the clusters it manages outside of CAPI. Of the several kubeconfigs of a control plane, only the
+// <name><Suffix> Secret owned by its <Group> <Kind> is converted.
+type HostedControlPlaneProvider struct {
+	Name  string
+	Group string
+	Kind  string
+	// Suffix is the name suffix of the kubeconfig Secret converted.
+	Suffix string
+	// Key is the data key holding the kubeconfig.
+	Key string
+}
+
+// HostedControlPlaneProviders returns the supported hosted control plane providers.
+func HostedControlPlaneProviders() []HostedControlPlaneProvider {
+	return []HostedControlPlaneProvider{
+		{Name: HostedControlPlaneHyperShift, Group: "hypershift.openshift.io", Kind: "HostedCluster", Suffix: "-admin-kubeconfig", Key: "kubeconfig"},
+		{Name: HostedControlPlaneKamaji, Group: "kamaji.clastix.io", Kind: "TenantControlPlane", Suffix: "-admin-kubeconfig", Key: "admin.conf"},
+		{Name: HostedControlPlaneK0smotron, Group: "k0smotron.io", Kind: "Cluster", Suffix: "-kubeconfig", Key: "value"},
+	}
+}
+
+// HostedControlPlane is the control plane a hosted kubeconfig Secret belongs to.
+type HostedControlPlane struct {
+	// Name is the name of the control plane object, the cluster gets named after.
+	Name     string
+	Provider HostedControlPlaneProvider
+}
+
+// ParseHostedControlPlane returns the hosted control plane owning a kubeconfig Secret, false for
+// Secrets of no supported provider.
+func ParseHostedControlPlane(s *corev1.Secret) (*HostedControlPlane, bool) {
+	for _, p := range HostedControlPlaneProviders() {
+		name, ok := strings.CutSuffix(s.Name, p.Suffix)
+		if !ok || name == "" {
+			continue
+		}
+		for _, ref := range s.OwnerReferences {
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err == nil && gv.Group == p.Group && ref.Kind == p.Kind && ref.Name == name {
+				return &HostedControlPlane{Name: name, Provider: p}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// IsHostedControlPlaneSecret reports whether a Secret is the kubeconfig of a hosted control plane.
+func IsHostedControlPlaneSecret(s *corev1.Secret) bool {
+	_, ok := ParseHostedControlPlane(s)
+	return ok
+}
+
+// HostedControlPlaneEnabled reports whether the kubeconfigs of a hosted control plane provider get converted.
+func (c OperatorConfig) HostedControlPlaneEnabled(provider string) bool {
+	switch provider {
+	case HostedControlPlaneHyperShift:
+		return c.EnableHyperShift
+	case HostedControlPlaneKamaji:
+		return c.EnableKamaji
+	case HostedControlPlaneK0smotron:
+		return c.EnableK0smotron
+	}
+	return false
+}
+
+// shadowedByCapiSecret reports whether the kubeconfig of a hosted control plane is shadowed by the
+// <name>-kubeconfig CAPI Secret of the same cluster, like Kamaji TenantControlPlanes of a
+// KamajiControlPlane. Such clusters are converted from their CAPI Secret only.
+func (r *Capi2Argo) shadowedByCapiSecret(ctx context.Context, s *corev1.Secret, hosted *HostedControlPlane) (bool, error) {
+	name := hosted.Name + "-kubeconfig"
+	if name == s.Name {
+		return false, nil
+	}
+	capiSecret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: s.Namespace}, capiSecret); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return capiSecret.Type == CapiClusterSecretType, nil
+}
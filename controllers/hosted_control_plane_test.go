@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func MockHostedControlPlaneSecret(name string, key string, ownerAPIVersion string, ownerKind string, owner string) *corev1.Secret {
+	s := MockCapiSecret(validMock, false, validKey, name, TestNamespace)
+	s.Type = corev1.SecretTypeOpaque
+	s.Data = map[string][]byte{key: s.Data["value"]}
+	if owner != "" {
+		s.OwnerReferences = []metav1.OwnerReference{{APIVersion: ownerAPIVersion, Kind: ownerKind, Name: owner, UID: "uid"}}
+	}
+	return s
+}
+
+func MockHostedClusterSecret(name string, owner string) *corev1.Secret {
+	return MockHostedControlPlaneSecret(name, "kubeconfig", "hypershift.openshift.io/v1beta1", "HostedCluster", owner)
+}
+
+func MockTenantControlPlaneSecret(name string, owner string) *corev1.Secret {
+	return MockHostedControlPlaneSecret(name, "admin.conf", "kamaji.clastix.io/v1alpha1", "TenantControlPlane", owner)
+}
+
+func MockK0smotronClusterSecret(name string, owner string) *corev1.Secret {
+	return MockHostedControlPlaneSecret(name, "value", "k0smotron.io/v1beta1", "Cluster", owner)
+}
+
+func TestParseHostedControlPlane(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName             string
+		testMock             *corev1.Secret
+		testExpectedName     string
+		testExpectedProvider string
+	}{
+		{"test hosted cluster admin kubeconfig", MockHostedClusterSecret("hosted-admin-kubeconfig", "hosted"), "hosted", HostedControlPlaneHyperShift},
+		{"test tenant control plane admin kubeconfig", MockTenantControlPlaneSecret("tenant-admin-kubeconfig", "tenant"), "tenant", HostedControlPlaneKamaji},
+		{"test tenant control plane scheduler kubeconfig", MockTenantControlPlaneSecret("tenant-scheduler-kubeconfig", "tenant"), "", ""},
+		{"test k0smotron cluster kubeconfig", MockK0smotronClusterSecret("k0s-kubeconfig", "k0s"), "k0s", HostedControlPlaneK0smotron},
+		{"test without owner", MockHostedClusterSecret("hosted-admin-kubeconfig", ""), "", ""},
+		{"test other owner name", MockHostedClusterSecret("hosted-admin-kubeconfig", "other"), "", ""},
+		{"test other name", MockHostedClusterSecret("hosted-kubeconfig", "hosted"), "", ""},
+		{"test capi secret", MockCapiSecret(validMock, validType, validKey, "test-admin-kubeconfig", TestNamespace), "", ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			hosted, ok := ParseHostedControlPlane(tt.testMock)
+			assert.Equal(t, tt.testExpectedName != "", ok)
+			assert.Equal(t, ok, IsHostedControlPlaneSecret(tt.testMock))
+			if ok {
+				assert.Equal(t, tt.testExpectedName, hosted.Name)
+				assert.Equal(t, tt.testExpectedProvider, hosted.Provider.Name)
+			}
+		})
+	}
+}
+
+func TestReconcileHostedControlPlane(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          []client.Object
+		testConfig        func(*OperatorConfig)
+		testExpectedValue bool
+	}{
+		{"test hypershift enabled", []client.Object{MockHostedClusterSecret("hosted-admin-kubeconfig", "hosted")},
+			func(c *OperatorConfig) { c.EnableHyperShift = true }, true},
+		{"test hypershift disabled", []client.Object{MockHostedClusterSecret("hosted-admin-kubeconfig", "hosted")},
+			func(c *OperatorConfig) { c.EnableKamaji = true }, false},
+		{"test kamaji enabled", []client.Object{MockTenantControlPlaneSecret("hosted-admin-kubeconfig", "hosted")},
+			func(c *OperatorConfig) { c.EnableKamaji = true }, true},
+		{"test kamaji shadowed by capi secret", []client.Object{MockTenantControlPlaneSecret("hosted-admin-kubeconfig", "hosted"), MockCapiSecret(validMock, validType, validKey, "hosted-kubeconfig", TestNamespace)},
+			func(c *OperatorConfig) { c.EnableKamaji = true }, false},
+		{"test k0smotron enabled", []client.Object{MockK0smotronClusterSecret("hosted-kubeconfig", "hosted")},
+			func(c *OperatorConfig) { c.EnableK0smotron = true }, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			c := MockClient(tt.testMock...)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			tt.testConfig(&r.Config)
+			source := tt.testMock[0].(*corev1.Secret)
+
+			_, err := r.Reconcile(ctx, MockReconcileReq(source.Name, TestNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			err = c.Get(ctx, r.Config.BuildNamespacedName("hosted", TestNamespace), &argoSecret)
+			assert.Equal(t, tt.testExpectedValue, err == nil)
+			if tt.testExpectedValue {
+				assert.Equal(t, r.Config.BuildClusterName("hosted", TestNamespace), string(argoSecret.Data["name"]))
+				assert.Equal(t, source.Name, argoSecret.Labels[clusterSecretNameLabel])
+
+				// Deleting the hosted kubeconfig Secret deletes its ArgoSecret.
+				r.Config.EnableGarbageCollection = true
+				assert.Nil(t, c.Delete(ctx, source))
+				_, err = r.Reconcile(ctx, MockReconcileReq(source.Name, TestNamespace))
+				assert.Nil(t, err)
+				assert.NotNil(t, c.Get(ctx, r.Config.BuildNamespacedName("hosted", TestNamespace), &corev1.Secret{}))
+			}
+		})
+	}
+}
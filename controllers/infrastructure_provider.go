@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// infrastructureProviderLabel holds the infrastructure provider of a cluster.
+const infrastructureProviderLabel = "capi-to-argocd/infrastructure-provider"
+
+// InfrastructureProvider returns the provider of a CAPI infrastructure kind, like aws for
+// AWSCluster or AWSManagedCluster. An empty provider is returned for unknown kinds.
+func InfrastructureProvider(kind string) string {
+	provider, ok := strings.CutSuffix(kind, "Cluster")
+	if !ok {
+		return ""
+	}
+	// Providers whose name cannot be derived from the kind as is.
+	switch {
+	case strings.HasPrefix(provider, "IBMPowerVS"), strings.HasPrefix(provider, "IBMVPC"):
+		return "ibmcloud"
+	case strings.HasPrefix(provider, "DO"):
+		return "digitalocean"
+	case strings.HasPrefix(provider, "OCI"):
+		return "oci"
+	case strings.HasPrefix(provider, "Packet"):
+		return "equinix"
+	}
+	provider = strings.ToLower(strings.TrimSuffix(provider, "Managed"))
+	if provider == "" || len(validation.IsValidLabelValue(provider)) > 0 {
+		return ""
+	}
+	return provider
+}
+
+// InfrastructureKindsList returns the infrastructure kinds CAPI Clusters get registered for, all
+// kinds when empty.
+func (c OperatorConfig) InfrastructureKindsList() []string {
+	kinds := []string{}
+	for _, kind := range strings.Split(c.InfrastructureKinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" && !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// MatchesInfrastructureKind reports whether the infrastructureRef kind of a CAPI Cluster is one of
+// InfrastructureKinds. Without Cluster object or infrastructureRef, only unset kinds match.
+func (c OperatorConfig) MatchesInfrastructureKind(cluster CAPICluster) bool {
+	kinds := c.InfrastructureKindsList()
+	if len(kinds) == 0 {
+		return true
+	}
+	if cluster == nil || cluster.GetInfrastructureRef() == nil {
+		return false
+	}
+	return slices.Contains(kinds, cluster.GetInfrastructureRef().Kind)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestInfrastructureProvider(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          string
+		testExpectedValue string
+	}{
+		{"test aws", "AWSCluster", "aws"},
+		{"test aws managed", "AWSManagedCluster", "aws"},
+		{"test azure managed", "AzureManagedCluster", "azure"},
+		{"test gcp", "GCPCluster", "gcp"},
+		{"test vsphere", "VSphereCluster", "vsphere"},
+		{"test docker", "DockerCluster", "docker"},
+		{"test openstack", "OpenStackCluster", "openstack"},
+		{"test digitalocean", "DOCluster", "digitalocean"},
+		{"test ibmcloud", "IBMPowerVSCluster", "ibmcloud"},
+		{"test oci managed", "OCIManagedCluster", "oci"},
+		{"test unknown kind", "Machine", ""},
+		{"test bare suffix", "Cluster", ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValue, InfrastructureProvider(tt.testMock))
+		})
+	}
+}
+
+func TestInfrastructureProviderLabel(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          *corev1.ObjectReference
+		testExpectedValue string
+	}{
+		{"test infrastructure ref", &corev1.ObjectReference{Kind: "AWSManagedCluster"}, "aws"},
+		{"test unknown infrastructure ref", &corev1.ObjectReference{Kind: "Unknown"}, ""},
+		{"test without infrastructure ref", nil, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}, Spec: clusterv1.ClusterSpec{InfrastructureRef: tt.testMock}}
+			c := NewCapiCluster("test", "test")
+			s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+			assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+			a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, &V1Beta1ClusterAdapter{cluster})
+			assert.Nil(t, err)
+			argoSecret, err := a.ConvertToSecret()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValue, argoSecret.Labels[infrastructureProviderLabel])
+		})
+	}
+}
+
+func TestMatchesInfrastructureKind(t *testing.T) {
+	t.Parallel()
+	cluster := func(kind string) CAPICluster {
+		c := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace}}
+		if kind != "" {
+			c.Spec.InfrastructureRef = &corev1.ObjectReference{Kind: kind, Name: "test"}
+		}
+		return &V1Beta1ClusterAdapter{c}
+	}
+	tests := []struct {
+		testName           string
+		testMock           string
+		testCluster        CAPICluster
+		testExpectedValues bool
+	}{
+		{"test all kinds", "", cluster("DockerCluster"), true},
+		{"test all kinds without cluster", "", nil, true},
+		{"test allowed kind", "AWSCluster, AzureCluster", cluster("AzureCluster"), true},
+		{"test other kind", "AWSCluster,AzureCluster", cluster("DockerCluster"), false},
+		{"test without infrastructureRef", "AWSCluster", cluster(""), false},
+		{"test without cluster", "AWSCluster", nil, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.InfrastructureKinds = tt.testMock
+			assert.Equal(t, tt.testExpectedValues, c.MatchesInfrastructureKind(tt.testCluster))
+		})
+	}
+}
+
+func TestReconcileInfrastructureKinds(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "docker-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "docker"}
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "docker", Namespace: TestNamespace},
+		Spec:       clusterv1.ClusterSpec{InfrastructureRef: &corev1.ObjectReference{Kind: "DockerCluster", Name: "docker"}},
+		Status:     MockReadyClusterStatus(),
+	}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.InfrastructureKinds = "AWSCluster,AzureCluster"
+	req := MockReconcileReq("docker-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("docker-kubeconfig", TestNamespace)
+
+	var argoSecret corev1.Secret
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+	r.Config.InfrastructureKinds = "AWSCluster,DockerCluster"
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+}
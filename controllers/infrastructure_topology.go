@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// regionLabel holds the region or location of the infrastructure of a cluster.
+	regionLabel = "capi-to-argocd/region"
+	// failureDomainLabelPrefix prefixes the labels marking the failure domains of a cluster:
+	// failure-domain.capi-to-argocd/<name>: "true".
+	failureDomainLabelPrefix = "failure-domain.capi-to-argocd/"
+)
+
+// BuildInfrastructureTopologyLabels returns the region and failure domain labels of a CAPI
+// infrastructure object. The region is read from spec.region, or spec.location on Azure, and the
+// failure domains from status.failureDomains. Values that are no valid labels are reported as warnings.
+func BuildInfrastructureTopologyLabels(infra *unstructured.Unstructured) (map[string]string, []string) {
+	labels := map[string]string{}
+	warnings := []string{}
+	region, _, _ := unstructured.NestedString(infra.Object, "spec", "region")
+	if region == "" {
+		region, _, _ = unstructured.NestedString(infra.Object, "spec", "location")
+	}
+	if region != "" {
+		if errs := validation.IsValidLabelValue(region); len(errs) > 0 {
+			warnings = append(warnings, "Warning: invalid region '"+region+"' of "+infra.GetKind()+" "+infra.GetName()+": "+strings.Join(errs, "; ")+". Ignoring")
+		} else {
+			labels[regionLabel] = region
+		}
+	}
+	failureDomains, _, _ := unstructured.NestedMap(infra.Object, "status", "failureDomains")
+	for name := range failureDomains {
+		key := failureDomainLabelPrefix + name
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			warnings = append(warnings, "Warning: invalid failure domain '"+name+"' of "+infra.GetKind()+" "+infra.GetName()+": "+strings.Join(errs, "; ")+". Ignoring")
+			continue
+		}
+		labels[key] = "true"
+	}
+	return labels, warnings
+}
+
+// readInfrastructureTopology returns the region and failure domain labels of the infrastructure
+// object of a CAPI Cluster. No labels are returned when the object cannot be read.
+func (r *Capi2Argo) readInfrastructureTopology(ctx context.Context, log logr.Logger, cluster CAPICluster) map[string]string {
+	if cluster == nil || cluster.GetInfrastructureRef() == nil {
+		return nil
+	}
+	ref := cluster.GetInfrastructureRef()
+	infra, err := GetReferencedObject(ctx, r.Client, cluster.GetNamespace(), ref)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			log.Info("Warning: failed to get infrastructure of Cluster", "kind", ref.Kind, "name", ref.Name, "error", err)
+		}
+		return nil
+	}
+	labels, warnings := BuildInfrastructureTopologyLabels(infra)
+	for _, w := range warnings {
+		log.Info(w)
+	}
+	return labels
+}
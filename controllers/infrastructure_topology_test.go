@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var awsClusterGVK = schema.GroupVersionKind{Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta2", Kind: "AWSCluster"}
+
+func MockInfrastructure(name string, spec, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec, "status": status}}
+	u.SetGroupVersionKind(awsClusterGVK)
+	u.SetName(name)
+	u.SetNamespace(TestNamespace)
+	return u
+}
+
+func TestBuildInfrastructureTopologyLabels(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName             string
+		testSpec             map[string]interface{}
+		testStatus           map[string]interface{}
+		testExpectedValues   map[string]string
+		testExpectedWarnings int
+	}{
+		{"test region", map[string]interface{}{"region": "eu-west-1"}, nil, map[string]string{regionLabel: "eu-west-1"}, 0},
+		{"test location", map[string]interface{}{"location": "westeurope"}, nil, map[string]string{regionLabel: "westeurope"}, 0},
+		{"test failure domains", map[string]interface{}{"region": "eu-west-1"},
+			map[string]interface{}{"failureDomains": map[string]interface{}{"eu-west-1a": map[string]interface{}{}, "eu-west-1b": map[string]interface{}{}}},
+			map[string]string{regionLabel: "eu-west-1", failureDomainLabelPrefix + "eu-west-1a": "true", failureDomainLabelPrefix + "eu-west-1b": "true"}, 0},
+		{"test invalid values", map[string]interface{}{"region": "eu west"},
+			map[string]interface{}{"failureDomains": map[string]interface{}{"zone/a": map[string]interface{}{}}},
+			map[string]string{}, 2},
+		{"test without topology", nil, nil, map[string]string{}, 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			labels, warnings := BuildInfrastructureTopologyLabels(MockInfrastructure("topology", tt.testSpec, tt.testStatus))
+			assert.Equal(t, tt.testExpectedValues, labels)
+			assert.Len(t, warnings, tt.testExpectedWarnings)
+		})
+	}
+}
+
+func TestReconcileInfrastructureTopology(t *testing.T) {
+	t.Parallel()
+	infrastructureRef := &corev1.ObjectReference{APIVersion: awsClusterGVK.GroupVersion().String(), Kind: awsClusterGVK.Kind, Name: "topology-infra"}
+	failureDomains := map[string]interface{}{"failureDomains": map[string]interface{}{"eu-west-1a": map[string]interface{}{}}}
+	tests := []struct {
+		testName           string
+		testEnabled        bool
+		testCRDInstalled   bool
+		testInfrastructure client.Object
+		testExpectedValues map[string]string
+	}{
+		{"test topology labels", true, true, MockInfrastructure("topology-infra", map[string]interface{}{"region": "eu-west-1"}, failureDomains),
+			map[string]string{regionLabel: "eu-west-1", failureDomainLabelPrefix + "eu-west-1a": "true"}},
+		{"test disabled", false, true, MockInfrastructure("topology-infra", map[string]interface{}{"region": "eu-west-1"}, failureDomains),
+			map[string]string{regionLabel: "", failureDomainLabelPrefix + "eu-west-1a": ""}},
+		{"test missing infrastructure", true, true, nil, map[string]string{regionLabel: ""}},
+		{"test infrastructure CRD not installed", true, false, nil, map[string]string{regionLabel: ""}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			scheme := MockScheme()
+			if tt.testCRDInstalled {
+				scheme.AddKnownTypeWithName(awsClusterGVK, &unstructured.Unstructured{})
+			}
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "topology-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "topology"}
+			objs := []client.Object{capiSecret, &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "topology", Namespace: TestNamespace},
+				Spec:       clusterv1.ClusterSpec{InfrastructureRef: infrastructureRef},
+				Status:     MockReadyClusterStatus(),
+			}}
+			if tt.testInfrastructure != nil {
+				objs = append(objs, tt.testInfrastructure)
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objs...).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+			r.Config.ReadInfrastructureTopology = tt.testEnabled
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("topology-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("topology-kubeconfig", TestNamespace), &argoSecret))
+			for k, v := range tt.testExpectedValues {
+				assert.Equal(t, v, argoSecret.Labels[k], k)
+			}
+		})
+	}
+}
+
+func TestReconcileRemovesStaleFailureDomains(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	scheme := MockScheme()
+	scheme.AddKnownTypeWithName(awsClusterGVK, &unstructured.Unstructured{})
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "topology-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "topology"}
+	infra := MockInfrastructure("topology-infra", nil, map[string]interface{}{"failureDomains": map[string]interface{}{"a": map[string]interface{}{}, "b": map[string]interface{}{}}})
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(capiSecret, infra, &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "topology", Namespace: TestNamespace},
+			Spec:       clusterv1.ClusterSpec{InfrastructureRef: &corev1.ObjectReference{APIVersion: awsClusterGVK.GroupVersion().String(), Kind: awsClusterGVK.Kind, Name: "topology-infra"}},
+			Status:     MockReadyClusterStatus(),
+		}).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+	r.Config.ReadInfrastructureTopology = true
+	req := MockReconcileReq("topology-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(infra), infra))
+	assert.Nil(t, unstructured.SetNestedMap(infra.Object, map[string]interface{}{"a": map[string]interface{}{}}, "status", "failureDomains"))
+	assert.Nil(t, c.Update(ctx, infra))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("topology-kubeconfig", TestNamespace), &argoSecret))
+	assert.Equal(t, "true", argoSecret.Labels[failureDomainLabelPrefix+"a"])
+	assert.NotContains(t, argoSecret.Labels, failureDomainLabelPrefix+"b")
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// instanceLabel holds the OperatorConfig.InstanceID of the operator instance managing a Secret.
+const instanceLabel = "capi-to-argocd/instance"
+
+// ValidateInstanceID returns an error when an instance ID is set but not a DNS-1123 label, as it
+// is used as label value and in the leader election ID.
+func ValidateInstanceID(id string) error {
+	if id == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(id); len(errs) > 0 {
+		return fmt.Errorf("invalid instance ID %q: %s", id, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// IsOwnInstance returns true if an object belongs to the operator instance of c. Objects without
+// instance label belong to the instance running without InstanceID.
+func (c OperatorConfig) IsOwnInstance(obj client.Object) bool {
+	return obj.GetLabels()[instanceLabel] == c.InstanceID
+}
+
+// ValidateOwner checks whether a Secret is managed by CACO, and by the operator instance of c.
+func (c OperatorConfig) ValidateOwner(s corev1.Secret) error {
+	if err := ValidateObjectOwner(s); err != nil {
+		return err
+	}
+	if !c.IsOwnInstance(&s) {
+		return fmt.Errorf("owned by CACO instance %q", s.Labels[instanceLabel])
+	}
+	return nil
+}
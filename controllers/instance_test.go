@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateInstanceID(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ValidateInstanceID(""))
+	assert.Nil(t, ValidateInstanceID("prod"))
+	assert.NotNil(t, ValidateInstanceID("Prod"))
+	assert.NotNil(t, ValidateInstanceID("prod/eu"))
+}
+
+func TestValidateOwner(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testLabels         map[string]string
+		testExpectedError  bool
+		testExpectedValues []string
+	}{
+		{"test unlabeled default instance", "", map[string]string{ownedLabel: "true", clusterSecretNameLabel: "a"}, false, []string{"a"}},
+		{"test unlabeled named instance", "prod", map[string]string{ownedLabel: "true", clusterSecretNameLabel: "a"}, true, nil},
+		{"test own instance", "prod", map[string]string{ownedLabel: "true", instanceLabel: "prod", clusterSecretNameLabel: "a"}, false, []string{"a"}},
+		{"test other instance", "prod", map[string]string{ownedLabel: "true", instanceLabel: "staging", clusterSecretNameLabel: "a"}, true, nil},
+		{"test labeled default instance", "", map[string]string{ownedLabel: "true", instanceLabel: "staging", clusterSecretNameLabel: "a"}, true, nil},
+		{"test not owned", "prod", map[string]string{instanceLabel: "prod"}, true, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.InstanceID = tt.testMock
+			s := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: TestArgoNamespace, Labels: tt.testLabels}}
+			if tt.testExpectedError {
+				assert.NotNil(t, c.ValidateOwner(s))
+			} else {
+				assert.Nil(t, c.ValidateOwner(s))
+			}
+			if s.Labels[ownedLabel] == "true" {
+				assert.Equal(t, tt.testExpectedValues, c.ClusterSecretIndex(&s))
+			}
+		})
+	}
+}
+
+func TestReconcileInstanceID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config := MockOperatorConfig()
+	config.InstanceID = "prod"
+	capiSecret := MockCapiSecret(true, true, true, "instance-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "instance"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	c := fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(capiSecret, cluster).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, config.ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, config.ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: config}
+	req := MockReconcileReq("instance-kubeconfig", TestNamespace)
+	nn := config.BuildNamespacedName("instance-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "prod", argoSecret.Labels[instanceLabel])
+
+	// ArgoSecrets of other instances are left alone.
+	argoSecret.Labels[instanceLabel] = "staging"
+	argoSecret.Data["server"] = []byte("https://staging.example.com")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "https://staging.example.com", string(argoSecret.Data["server"]))
+
+	// and survive the garbage collection of their CAPI Secret.
+	r.Config.EnableGarbageCollection = true
+	assert.Nil(t, c.Delete(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.False(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kubernetesVersionLabel holds the Kubernetes version of the control plane of a cluster.
+const kubernetesVersionLabel = "capi-to-argocd/kubernetes-version"
+
+// clusterKubernetesVersion returns the Kubernetes version of the control plane of a CAPI Cluster,
+// from its ClusterClass topology or else the status of its control plane object. An empty version
+// is returned when it cannot be determined.
+func (r *Capi2Argo) clusterKubernetesVersion(ctx context.Context, log logr.Logger, cluster CAPICluster) string {
+	if cluster == nil {
+		return ""
+	}
+	version := cluster.GetTopologyVersion()
+	if ref := cluster.GetControlPlaneRef(); version == "" && ref != nil {
+		cp, err := GetReferencedObject(ctx, r.Client, cluster.GetNamespace(), ref)
+		if err != nil {
+			if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+				log.Info("Warning: failed to get control plane of Cluster", "kind", ref.Kind, "name", ref.Name, "error", err)
+			}
+			return ""
+		}
+		version, _, _ = unstructured.NestedString(cp.Object, "status", "version")
+		if version == "" {
+			version, _, _ = unstructured.NestedString(cp.Object, "spec", "version")
+		}
+	}
+	if errs := validation.IsValidLabelValue(version); len(errs) > 0 {
+		log.Info("Warning: invalid Kubernetes version of Cluster: "+strings.Join(errs, "; ")+". Ignoring", "version", version)
+		return ""
+	}
+	return version
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var kubeadmControlPlaneGVK = schema.GroupVersionKind{Group: "controlplane.cluster.x-k8s.io", Version: "v1beta1", Kind: "KubeadmControlPlane"}
+
+func MockControlPlane(name string, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"version": "v1.27.0"}, "status": status}}
+	u.SetGroupVersionKind(kubeadmControlPlaneGVK)
+	u.SetName(name)
+	u.SetNamespace(TestNamespace)
+	return u
+}
+
+func TestReconcileKubernetesVersion(t *testing.T) {
+	t.Parallel()
+	controlPlaneRef := &corev1.ObjectReference{APIVersion: kubeadmControlPlaneGVK.GroupVersion().String(), Kind: kubeadmControlPlaneGVK.Kind, Name: "version-cp"}
+	tests := []struct {
+		testName          string
+		testCRDInstalled  bool
+		testControlPlane  client.Object
+		testSpec          clusterv1.ClusterSpec
+		testExpectedValue string
+	}{
+		{"test topology version", true, nil, clusterv1.ClusterSpec{Topology: &clusterv1.Topology{Class: "default", Version: "v1.28.0"}, ControlPlaneRef: controlPlaneRef}, "v1.28.0"},
+		{"test control plane status version", true, MockControlPlane("version-cp", map[string]interface{}{"version": "v1.27.3"}), clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef}, "v1.27.3"},
+		{"test control plane spec version", true, MockControlPlane("version-cp", nil), clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef}, "v1.27.0"},
+		{"test invalid version", true, MockControlPlane("version-cp", map[string]interface{}{"version": "v1.27.3+rke2r1"}), clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef}, ""},
+		{"test missing control plane", true, nil, clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef}, ""},
+		{"test control plane CRD not installed", false, nil, clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef}, ""},
+		{"test without control plane", true, nil, clusterv1.ClusterSpec{}, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			scheme := MockScheme()
+			if tt.testCRDInstalled {
+				scheme.AddKnownTypeWithName(kubeadmControlPlaneGVK, &unstructured.Unstructured{})
+			}
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "version-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "version"}
+			objs := []client.Object{capiSecret, &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "version", Namespace: TestNamespace},
+				Spec:       tt.testSpec,
+				Status:     MockReadyClusterStatus(),
+			}}
+			if tt.testControlPlane != nil {
+				objs = append(objs, tt.testControlPlane)
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objs...).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("version-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("version-kubeconfig", TestNamespace), &argoSecret))
+			assert.Equal(t, tt.testExpectedValue, argoSecret.Labels[kubernetesVersionLabel])
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	// argoSecretTypeLabel marks a Secret as an ArgoCD cluster definition.
+	argoSecretTypeLabel = "argocd.argoproj.io/secret-type"
+	// ownedLabel marks a Secret as managed by the operator.
+	ownedLabel = "capi-to-argocd/owned"
+)
+
+// reservedLabels holds the operator-reserved label keys and their values.
+var reservedLabels = map[string]string{
+	ownedLabel:          "true",
+	argoSecretTypeLabel: "cluster",
+}
+
+// LabelMap is a set of labels that protects operator-reserved keys from being shadowed.
+type LabelMap struct {
+	m map[string]string
+}
+
+// NewLabelMap returns an empty LabelMap.
+func NewLabelMap() LabelMap {
+	return LabelMap{m: map[string]string{}}
+}
+
+// IsReservedLabel returns true if key is reserved by the operator.
+func IsReservedLabel(key string) bool {
+	_, ok := reservedLabels[key]
+	return ok
+}
+
+// Set adds a label to the map. Operator-reserved keys are immutable and are rejected.
+func (l *LabelMap) Set(key, value string) error {
+	if IsReservedLabel(key) {
+		return fmt.Errorf("label '%s' is reserved by the operator", key)
+	}
+	if l.m == nil {
+		l.m = map[string]string{}
+	}
+	l.m[key] = value
+	return nil
+}
+
+// Get returns the value of a label and whether it exists.
+func (l LabelMap) Get(key string) (string, bool) {
+	v, ok := l.m[key]
+	return v, ok
+}
+
+// Merge returns a new LabelMap holding both label sets. On conflict, the receiver wins.
+func (l LabelMap) Merge(other LabelMap) LabelMap {
+	merged := LabelMap{m: make(map[string]string, len(l.m)+len(other.m))}
+	for k, v := range other.m {
+		merged.m[k] = v
+	}
+	for k, v := range l.m {
+		merged.m[k] = v
+	}
+	return merged
+}
+
+// ToMap returns a copy of the labels as a plain map.
+func (l LabelMap) ToMap() map[string]string {
+	out := make(map[string]string, len(l.m))
+	for k, v := range l.m {
+		out[k] = v
+	}
+	return out
+}
+
+// MergeOwnedLabels sets the operator-owned labels on existing, keeping the labels added by others.
+// It returns the sorted keys that changed.
+func MergeOwnedLabels(existing, owned map[string]string) []string {
+	changed := []string{}
+	for k, v := range owned {
+		if val, ok := existing[k]; !ok || val != v {
+			existing[k] = v
+			changed = append(changed, k)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// DropReservedLabels deletes the operator-reserved keys from labels set by users, so they cannot
+// fail the conversion. It returns the sorted keys deleted.
+func DropReservedLabels(labels map[string]string) []string {
+	dropped := []string{}
+	for k := range labels {
+		if IsReservedLabel(k) {
+			delete(labels, k)
+			dropped = append(dropped, k)
+		}
+	}
+	slices.Sort(dropped)
+	return dropped
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelMapSet(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          string
+		testExpectedError bool
+	}{
+		{"test with regular label", "foo", false},
+		{"test with domain label", "my.mydomain.com/env", false},
+		{"test with unreserved argocd label", "argocd.argoproj.io/other", false},
+		{"test with argo secret-type label", "argocd.argoproj.io/secret-type", true},
+		{"test with operator owned label", "capi-to-argocd/owned", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			var l LabelMap
+			err := l.Set(tt.testMock, "value")
+			v, ok := l.Get(tt.testMock)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+				assert.False(t, ok)
+			} else {
+				assert.Nil(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, "value", v)
+			}
+		})
+	}
+}
+
+func TestGetArgoCommonLabels(t *testing.T) {
+	t.Parallel()
+	l := GetArgoCommonLabels()
+	v, ok := l.Get("argocd.argoproj.io/secret-type")
+	assert.True(t, ok)
+	assert.Equal(t, "cluster", v)
+	v, ok = l.Get("capi-to-argocd/owned")
+	assert.True(t, ok)
+	assert.Equal(t, "true", v)
+
+	assert.NotNil(t, l.Set("argocd.argoproj.io/secret-type", "repository"))
+	assert.NotNil(t, l.Set("capi-to-argocd/owned", "false"))
+	v, _ = l.Get("argocd.argoproj.io/secret-type")
+	assert.Equal(t, "cluster", v)
+}
+
+func TestLabelMapMerge(t *testing.T) {
+	t.Parallel()
+	receiver := NewLabelMap()
+	assert.Nil(t, receiver.Set("foo", "receiver"))
+	assert.Nil(t, receiver.Set("only-receiver", "yes"))
+	other := NewLabelMap()
+	assert.Nil(t, other.Set("foo", "other"))
+	assert.Nil(t, other.Set("only-other", "yes"))
+
+	merged := receiver.Merge(other)
+	assert.Equal(t, map[string]string{
+		"foo":           "receiver",
+		"only-receiver": "yes",
+		"only-other":    "yes",
+	}, merged.ToMap())
+
+	// Merging must not mutate its inputs.
+	assert.Equal(t, map[string]string{"foo": "receiver", "only-receiver": "yes"}, receiver.ToMap())
+	assert.Equal(t, map[string]string{"foo": "other", "only-other": "yes"}, other.ToMap())
+
+	// Common labels always win over merged ones.
+	merged = GetArgoCommonLabels().Merge(other)
+	v, _ := merged.Get("argocd.argoproj.io/secret-type")
+	assert.Equal(t, "cluster", v)
+}
+
+func TestLabelMapToMap(t *testing.T) {
+	t.Parallel()
+	l := GetArgoCommonLabels()
+	m := l.ToMap()
+	m["argocd.argoproj.io/secret-type"] = "repository"
+	m["foo"] = "bar"
+
+	v, _ := l.Get("argocd.argoproj.io/secret-type")
+	assert.Equal(t, "cluster", v)
+	_, ok := l.Get("foo")
+	assert.False(t, ok)
+	assert.Equal(t, "cluster", GetArgoCommonLabels().ToMap()["argocd.argoproj.io/secret-type"])
+}
+
+func TestMergeOwnedLabels(t *testing.T) {
+	t.Parallel()
+	owned := map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default"}
+	tests := []struct {
+		testName           string
+		testMock           map[string]string
+		testExpectedChange []string
+		testExpectedValues map[string]string
+	}{
+		{"test in sync", map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default"}, []string{},
+			map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default"}},
+		{"test user labels kept", map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default", "appset": "prod"}, []string{},
+			map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default", "appset": "prod"}},
+		{"test owned labels restored", map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "other", "appset": "prod"}, []string{"capi-to-argocd/cluster-namespace"},
+			map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default", "appset": "prod"}},
+		{"test owned labels added", map[string]string{"appset": "prod"}, []string{"capi-to-argocd/cluster-namespace", "capi-to-argocd/owned"},
+			map[string]string{"capi-to-argocd/owned": "true", "capi-to-argocd/cluster-namespace": "default", "appset": "prod"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedChange, MergeOwnedLabels(tt.testMock, owned))
+			assert.Equal(t, tt.testExpectedValues, tt.testMock)
+		})
+	}
+}
+
+func TestDropReservedLabels(t *testing.T) {
+	t.Parallel()
+	labels := map[string]string{"env": "prod", ownedLabel: "false", argoSecretTypeLabel: "repository"}
+	assert.Equal(t, []string{argoSecretTypeLabel, ownedLabel}, DropReservedLabels(labels))
+	assert.Equal(t, map[string]string{"env": "prod"}, labels)
+	assert.Equal(t, []string{}, DropReservedLabels(nil))
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// IndexLookupDuration observes lookups of ArgoSecrets through the ClusterSecretIndex.
+	IndexLookupDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "capi2argo_index_lookup_duration_seconds",
+		Help:    "Duration of ArgoSecret lookups by CAPI Secret name.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// ClusterNameTruncatedTotal counts ArgoSecrets created under a name truncated to ArgoClusterNameMaxLength.
+	ClusterNameTruncatedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "capi2argo_cluster_name_truncated_total",
+		Help: "Number of ArgoCD cluster secrets created under a name truncated to the maximum ArgoCD cluster name length.",
+	})
+
+	// ClusterNameCollisionsTotal counts ArgoSecrets not written because their names are taken by
+	// another CAPI Secret.
+	ClusterNameCollisionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "capi2argo_cluster_name_collisions_total",
+		Help: "Number of ArgoCD clusters not registered because their name is taken by another CAPI Secret.",
+	})
+
+	// EndpointUnreachableTotal counts failed reachability checks of ArgoSecret servers.
+	EndpointUnreachableTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "capi2argo_endpoint_unreachable_total",
+		Help: "Number of failed reachability checks of ArgoCD cluster servers.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(IndexLookupDuration, ClusterNameTruncatedTotal, ClusterNameCollisionsTotal, EndpointUnreachableTotal)
+}
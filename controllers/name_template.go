@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"text/template"
+)
+
+// NameTemplateData holds the fields Argo cluster and ArgoSecret name templates are rendered with.
+type NameTemplateData struct {
+	ClusterName      string
+	ClusterNamespace string
+}
+
+// RenderNameTemplate renders a name template, with the same functions as take-along templates.
+func RenderNameTemplate(text string, data NameTemplateData) (string, error) {
+	tmpl, err := template.New("name").Funcs(templateFuncs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ValidateNameTemplate returns an error when a name template does not parse, refers to unknown
+// fields or renders to an empty name.
+func ValidateNameTemplate(text string) error {
+	name, err := RenderNameTemplate(text, NameTemplateData{ClusterName: "name", ClusterNamespace: "namespace"})
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("name template renders to an empty name")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestValidateNameTemplate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          string
+		testExpectedError bool
+	}{
+		{"test valid template", "{{ .ClusterNamespace }}.{{ .ClusterName | lower }}", false},
+		{"test parse error", "{{ .ClusterName", true},
+		{"test unknown field", "{{ .Name }}", true},
+		{"test empty name", "{{ if false }}x{{ end }}", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateNameTemplate(tt.testMock)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+		})
+	}
+}
+
+func TestBuildNamesFromTemplates(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName                   string
+		testClusterNameTemplate    string
+		testSecretNameTemplate     string
+		testExpectedClusterName    string
+		testExpectedNamespacedName types.NamespacedName
+	}{
+		{"test default scheme", "", "", "test", types.NamespacedName{Name: "cluster-test", Namespace: "argocd"}},
+		{"test cluster name template", "{{ .ClusterNamespace }}.{{ .ClusterName }}", "",
+			"team-a.test", types.NamespacedName{Name: "cluster-team-a.test", Namespace: "argocd"}},
+		{"test secret name template", "", "{{ .ClusterNamespace }}.{{ .ClusterName }}",
+			"test", types.NamespacedName{Name: "team-a.test", Namespace: "argocd"}},
+		{"test failing template", "{{ .Unknown }}", "{{ .Unknown }}", "test", types.NamespacedName{Name: "cluster-test", Namespace: "argocd"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.ArgoNamespace = "argocd"
+			c.EnableNamespacedNames = false
+			c.ClusterNameTemplate = tt.testClusterNameTemplate
+			c.ArgoSecretNameTemplate = tt.testSecretNameTemplate
+			assert.Equal(t, tt.testExpectedClusterName, c.BuildClusterName("test", "team-a"))
+			assert.Equal(t, tt.testExpectedNamespacedName, c.BuildNamespacedName("test-kubeconfig", "team-a"))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"fmt"
+	"path"
+)
+
+// WatchesNamespace reports whether CAPI Secrets of a namespace get converted, as it matches a glob
+// pattern of OperatorConfig.IncludeNamespaces, if any, and none of
+// OperatorConfig.ExcludeNamespaces.
+func (c OperatorConfig) WatchesNamespace(namespace string) bool {
+	if include := ParseNamespaces(c.IncludeNamespaces); len(include) > 0 && !matchesAnyPattern(include, namespace) {
+		return false
+	}
+	return !matchesAnyPattern(ParseNamespaces(c.ExcludeNamespaces), namespace)
+}
+
+// ValidateNamespacePatterns returns an error for malformed patterns of
+// OperatorConfig.IncludeNamespaces or OperatorConfig.ExcludeNamespaces.
+func (c OperatorConfig) ValidateNamespacePatterns() error {
+	for _, pattern := range append(ParseNamespaces(c.IncludeNamespaces), ParseNamespaces(c.ExcludeNamespaces)...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesAnyPattern reports whether a namespace matches one of the glob patterns.
+func matchesAnyPattern(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
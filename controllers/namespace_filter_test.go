@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestWatchesNamespace(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testInclude        string
+		testExclude        string
+		testMock           string
+		testExpectedValues bool
+	}{
+		{"test no filters", "", "", "team-a", true},
+		{"test included", "team-*,platform", "", "team-a", true},
+		{"test not included", "team-*,platform", "", "sandbox", false},
+		{"test excluded", "", "kube-*, sandbox", "kube-system", false},
+		{"test not excluded", "", "kube-*, sandbox", "team-a", true},
+		{"test exclude wins", "team-*", "team-legacy-?", "team-legacy-1", false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.IncludeNamespaces = tt.testInclude
+			c.ExcludeNamespaces = tt.testExclude
+			assert.Equal(t, tt.testExpectedValues, c.WatchesNamespace(tt.testMock))
+		})
+	}
+}
+
+func TestValidateNamespacePatterns(t *testing.T) {
+	t.Parallel()
+	c := MockOperatorConfig()
+	c.IncludeNamespaces = "team-*,platform"
+	c.ExcludeNamespaces = "team-[ab]"
+	assert.Nil(t, c.ValidateNamespacePatterns())
+	c.ExcludeNamespaces = "team-["
+	assert.NotNil(t, c.ValidateNamespacePatterns())
+}
+
+func TestReconcileExcludedNamespace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(validMock, validType, validKey, "excluded-kubeconfig", TestNamespace))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.ExcludeNamespaces = TestNamespace
+	_, err := r.Reconcile(ctx, MockReconcileReq("excluded-kubeconfig", TestNamespace))
+	assert.Nil(t, err)
+	var secrets corev1.SecretList
+	assert.Nil(t, c.List(ctx, &secrets))
+	assert.Len(t, secrets.Items, 1)
+}
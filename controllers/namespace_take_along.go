@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// clusterTakeAlongFromNamespaceKey prefixes the Namespace labels selecting labels of the
+	// Namespace to take along to every cluster of it, and clusterTakenFromNamespaceKey the markers
+	// of labels taken along from a Namespace.
+	clusterTakeAlongFromNamespaceKey = "take-along-from-namespace-label.capi-to-argocd."
+	clusterTakenFromNamespaceKey     = "taken-from-namespace-label.capi-to-argocd."
+)
+
+// BuildNamespaceTakeAlongLabels returns the labels of a Namespace selected by its
+// take-along-from-namespace-label directives, each with its taken-from-namespace-label marker.
+func BuildNamespaceTakeAlongLabels(rc *ReconcileContext, ns *corev1.Namespace) (map[string]string, []string) {
+	keys := []string{}
+	for k := range ns.Labels {
+		if label := strings.TrimPrefix(k, clusterTakeAlongFromNamespaceKey); label != k {
+			keys = append(keys, label)
+		}
+	}
+	slices.Sort(keys)
+
+	labels := map[string]string{}
+	errors := []string{}
+	for _, label := range keys {
+		if label == "" {
+			errors = append(errors, fmt.Sprintf("Warning: invalid take-along namespace label. missing key after '%s' on namespace: %s. Ignoring", clusterTakeAlongFromNamespaceKey, ns.Name))
+			continue
+		}
+		if takeAlongDepth(label) > 1 || IsReservedLabel(label) || strings.HasPrefix(label, clusterTakenFromClusterKey) || strings.HasPrefix(label, clusterTakenFromNamespaceKey) {
+			errors = append(errors, fmt.Sprintf("Warning: take-along namespace label '%s' is reserved by the operator on namespace: %s. Ignoring", label, ns.Name))
+			continue
+		}
+		if _, ok := ns.Labels[label]; !ok {
+			errors = append(errors, fmt.Sprintf("take-along namespace label '%s' not found on namespace: %s. Ignoring", label, ns.Name))
+			continue
+		}
+		value, err := ApplyValuePipeline(rc.Pipeline, label, ns.Labels[label])
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Warning: failed to transform value of take-along namespace label '%s' on namespace: %s: %v. Ignoring", label, ns.Name, err))
+			continue
+		}
+		labels[label] = value
+		labels[clusterTakenFromNamespaceKey+label] = ""
+	}
+	return labels, errors
+}
+
+// readNamespaceTakeAlongLabels returns the labels taken along from the namespace of a CAPI
+// Secret. No labels are returned when the Namespace cannot be read.
+func (r *Capi2Argo) readNamespaceTakeAlongLabels(ctx context.Context, rc *ReconcileContext, namespace string) map[string]string {
+	log := rc.Logger
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		log.Info("Warning: failed to get Namespace", "namespace", namespace, "error", err)
+		return nil
+	}
+	labels, warnings := BuildNamespaceTakeAlongLabels(rc, ns)
+	for _, w := range warnings {
+		log.Info(w)
+	}
+	return labels
+}
+
+// mergeTakeAlong adds the keys taken along from another source than the cluster, each with its
+// marker, to those taken along from the cluster, which take precedence.
+func mergeTakeAlong(log logr.Logger, takeAlong, from map[string]string, markerPrefix string) {
+	for k, v := range from {
+		if strings.HasPrefix(k, markerPrefix) {
+			continue
+		}
+		if _, ok := takeAlong[k]; ok {
+			log.Info("Warning: key is taken along from the cluster already. Ignoring", "key", k, "marker", markerPrefix)
+			continue
+		}
+		takeAlong[k] = v
+		takeAlong[markerPrefix+k] = ""
+	}
+}
+
+// mapNamespace maps Namespaces to a reconcile of every CAPI Secret in them.
+func (r *Capi2Argo) mapNamespace(ctx context.Context, obj client.Object) []ctrl.Request {
+	return r.capiSecretRequests(ctx, obj.GetName())
+}
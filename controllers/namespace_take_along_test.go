@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBuildNamespaceTakeAlongLabels(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           map[string]string
+		testExpectedError  bool
+		testExpectedValues map[string]string
+	}{
+		{"test take along", map[string]string{"team": "a", "example.com/tenant": "b", clusterTakeAlongFromNamespaceKey + "team": "", clusterTakeAlongFromNamespaceKey + "example.com/tenant": ""}, false,
+			map[string]string{"team": "a", "example.com/tenant": "b", clusterTakenFromNamespaceKey + "team": "", clusterTakenFromNamespaceKey + "example.com/tenant": ""}},
+		{"test without directives", map[string]string{"team": "a"}, false, map[string]string{}},
+		{"test missing label", map[string]string{clusterTakeAlongFromNamespaceKey + "team": ""}, true, map[string]string{}},
+		{"test reserved label", map[string]string{argoSecretTypeLabel: "cluster", clusterTakeAlongFromNamespaceKey + argoSecretTypeLabel: ""}, true, map[string]string{}},
+		{"test take-along directive", map[string]string{clusterTakeAlongKey + "team": "", clusterTakeAlongFromNamespaceKey + clusterTakeAlongKey + "team": ""}, true, map[string]string{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			rc := MockReconcileContext(MockOperatorConfig())
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: TestNamespace, Labels: tt.testMock}}
+			labels, errs := BuildNamespaceTakeAlongLabels(rc, ns)
+			assert.Equal(t, tt.testExpectedError, len(errs) > 0)
+			assert.Equal(t, tt.testExpectedValues, labels)
+		})
+	}
+}
+
+func TestReconcileNamespaceTakeAlong(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: TestNamespace, Labels: map[string]string{
+		"team": "a", clusterTakeAlongFromNamespaceKey + "team": "",
+		"env": "prod", clusterTakeAlongFromNamespaceKey + "env": "",
+	}}}
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "ns-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "ns"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "ns", Namespace: TestNamespace,
+		Labels: map[string]string{"env": "stage", clusterTakeAlongKey + "env": ""},
+	}, Status: MockReadyClusterStatus()}
+	c := MockClient(ns, capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.TakeAlongNamespaceLabels = true
+	req := MockReconcileReq("ns-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("ns-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "a", argoSecret.Labels["team"])
+	assert.Contains(t, argoSecret.Labels, clusterTakenFromNamespaceKey+"team")
+	// Labels taken along from the cluster win.
+	assert.Equal(t, "stage", argoSecret.Labels["env"])
+	assert.NotContains(t, argoSecret.Labels, clusterTakenFromNamespaceKey+"env")
+
+	// Labels no longer taken along from the namespace are removed, along their marker.
+	assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(ns), ns))
+	delete(ns.Labels, clusterTakeAlongFromNamespaceKey+"team")
+	assert.Nil(t, c.Update(ctx, ns))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.NotContains(t, argoSecret.Labels, "team")
+	assert.NotContains(t, argoSecret.Labels, clusterTakenFromNamespaceKey+"team")
+	assert.Equal(t, "stage", argoSecret.Labels["env"])
+}
+
+func TestMapNamespace(t *testing.T) {
+	t.Parallel()
+	c := MockClient(
+		MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", TestNamespace),
+		MockCapiSecret(validMock, false, validKey, "unrelated", TestNamespace),
+		MockCapiSecret(validMock, validType, validKey, "other-kubeconfig", "other"),
+	)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: TestNamespace}}
+	assert.Equal(t, []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace}}},
+		r.mapNamespace(context.Background(), ns))
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// namespacedNamesAnnotation overrides OperatorConfig.EnableNamespacedNames for a CAPI Cluster, or
+// for every CAPI Cluster of a Namespace. The annotation of the Cluster wins.
+const namespacedNamesAnnotation = "capi-to-argocd/namespaced-names"
+
+// BuildNamespacedNames returns whether the names of a cluster get prefixed with its namespace,
+// from the namespaced names annotation of the cluster, else of its namespace, else
+// OperatorConfig.EnableNamespacedNames. Invalid annotations are skipped and returned as errors.
+func BuildNamespacedNames(rc *ReconcileContext, cluster CAPICluster, ns *corev1.Namespace) (bool, []string) {
+	errors := []string{}
+	sources := map[string]map[string]string{}
+	order := []string{}
+	if cluster != nil {
+		source := fmt.Sprintf("cluster resource: %s, namespace: %s", cluster.GetName(), cluster.GetNamespace())
+		sources[source] = cluster.GetAnnotations()
+		order = append(order, source)
+	}
+	if ns != nil {
+		source := "namespace: " + ns.Name
+		sources[source] = ns.Annotations
+		order = append(order, source)
+	}
+	for _, source := range order {
+		value, ok := sources[source][namespacedNamesAnnotation]
+		if !ok {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Warning: invalid %s annotation %q on %s. Ignoring", namespacedNamesAnnotation, value, source))
+			continue
+		}
+		return enabled, errors
+	}
+	return rc.Config.EnableNamespacedNames, errors
+}
+
+// applyNamespacedNames sets the namespaced names setting of a CAPI Cluster on the
+// ReconcileContext. The Namespace is skipped when it cannot be read.
+func (r *Capi2Argo) applyNamespacedNames(ctx context.Context, rc *ReconcileContext, cluster CAPICluster, namespace string) {
+	log := rc.Logger
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Info("Warning: failed to get Namespace", "namespace", namespace, "error", err)
+		}
+		ns = nil
+	}
+	enabled, warnings := BuildNamespacedNames(rc, cluster, ns)
+	for _, w := range warnings {
+		log.Info(w)
+	}
+	rc.Config.EnableNamespacedNames = enabled
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestBuildNamespacedNames(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName              string
+		testConfig            bool
+		testClusterAnnotation map[string]string
+		testNamespace         map[string]string
+		testExpectedError     bool
+		testExpectedValues    bool
+	}{
+		{"test operator default", true, nil, nil, false, true},
+		{"test cluster annotation", false, map[string]string{namespacedNamesAnnotation: "true"}, nil, false, true},
+		{"test namespace annotation", true, nil, map[string]string{namespacedNamesAnnotation: "false"}, false, false},
+		{"test cluster annotation wins", false, map[string]string{namespacedNamesAnnotation: "false"}, map[string]string{namespacedNamesAnnotation: "true"}, false, false},
+		{"test invalid cluster annotation falls back to namespace", false, map[string]string{namespacedNamesAnnotation: "yes"}, map[string]string{namespacedNamesAnnotation: "true"}, true, true},
+		{"test invalid namespace annotation falls back to operator default", true, nil, map[string]string{namespacedNamesAnnotation: "no"}, true, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			config := MockOperatorConfig()
+			config.EnableNamespacedNames = tt.testConfig
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Annotations: tt.testClusterAnnotation}}}
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: TestNamespace, Annotations: tt.testNamespace}}
+			enabled, errs := BuildNamespacedNames(MockReconcileContext(config), cluster, ns)
+			assert.Equal(t, tt.testExpectedError, len(errs) > 0)
+			assert.Equal(t, tt.testExpectedValues, enabled)
+		})
+	}
+}
+
+func TestReconcileNamespacedNames(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenants", Annotations: map[string]string{namespacedNamesAnnotation: "true"}}}
+	shared := MockCapiSecret(validMock, validType, validKey, "shared-kubeconfig", "tenants")
+	short := MockCapiSecret(validMock, validType, validKey, "short-kubeconfig", "tenants")
+	short.Labels = map[string]string{clusterv1.ClusterNameLabel: "short"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "short", Namespace: "tenants",
+		Annotations: map[string]string{namespacedNamesAnnotation: "false"},
+	}, Status: MockReadyClusterStatus()}
+	c := MockClient(ns, shared, short, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+
+	for _, name := range []string{"shared-kubeconfig", "short-kubeconfig"} {
+		_, err := r.Reconcile(ctx, MockReconcileReq(name, "tenants"))
+		assert.Nil(t, err)
+	}
+	var argoSecret corev1.Secret
+	// The namespace annotation prefixes names, unless the cluster annotation opts out.
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "cluster-tenants-shared", Namespace: TestArgoNamespace}, &argoSecret))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "cluster-short", Namespace: TestArgoNamespace}, &argoSecret))
+}
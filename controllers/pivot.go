@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deleteForMoveAnnotation is set by clusterctl move on the objects it deletes from the source
+// management cluster, once they got re-created on the target one.
+const deleteForMoveAnnotation = "clusterctl.cluster.x-k8s.io/delete-for-move"
+
+// IsMovingCluster returns true if a CAPI Cluster is being moved by clusterctl, i.e. it is annotated
+// for deletion by move without being deleted itself. Merely paused clusters are not being moved.
+func IsMovingCluster(cluster CAPICluster) bool {
+	if cluster == nil || !cluster.Object().GetDeletionTimestamp().IsZero() {
+		return false
+	}
+	_, moving := cluster.GetAnnotations()[deleteForMoveAnnotation]
+	return moving
+}
+
+// isMovedCapiSecret reports whether a CAPI Secret got deleted by clusterctl move rather than along
+// its cluster, in which case its ArgoSecrets are kept for the target management cluster to take over.
+// Secrets are moved when annotated for deletion by move, or when their Cluster is being moved.
+func (r *Capi2Argo) isMovedCapiSecret(ctx context.Context, log logr.Logger, capiSecret types.NamespacedName, annotations map[string]string) (bool, error) {
+	if _, ok := annotations[deleteForMoveAnnotation]; ok {
+		log.Info("CapiSecret got deleted by clusterctl move, keeping ArgoSecret")
+		return true, nil
+	}
+	name := strings.TrimSuffix(capiSecret.Name, "-kubeconfig")
+	cluster, err := GetCAPICluster(ctx, r.Client, r.Config.ClusterAPIVersion, types.NamespacedName{Name: name, Namespace: capiSecret.Namespace})
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	if IsMovingCluster(cluster) {
+		log.Info("Cluster of deleted CapiSecret is being moved, keeping ArgoSecret")
+		return true, nil
+	}
+	return false, nil
+}
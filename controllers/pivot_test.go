@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestIsMovingCluster(t *testing.T) {
+	t.Parallel()
+	now := metav1.Now()
+	tests := []struct {
+		testName           string
+		testMock           CAPICluster
+		testExpectedValues bool
+	}{
+		{"test nil cluster", nil, false},
+		{"test running cluster", &V1Beta1ClusterAdapter{&clusterv1.Cluster{}}, false},
+		{"test paused cluster", &V1Beta1ClusterAdapter{&clusterv1.Cluster{Spec: clusterv1.ClusterSpec{Paused: true}}}, false},
+		{"test cluster deleted for move", &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{deleteForMoveAnnotation: ""}}}}, true},
+		{"test paused cluster deleted for move", &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{deleteForMoveAnnotation: ""}}, Spec: clusterv1.ClusterSpec{Paused: true}}}, true},
+		{"test cluster being deleted for move", &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now, Annotations: map[string]string{deleteForMoveAnnotation: ""}}}}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.testExpectedValues, IsMovingCluster(tt.testMock))
+		})
+	}
+}
+
+func TestReconcileClusterctlMove(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMove          func(context.Context, client.Client, *clusterv1.Cluster, *corev1.Secret)
+		testFinalizer     bool
+		testExpectedValue bool
+	}{
+		{"test secret deleted along cluster", func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, _ *corev1.Secret) {
+			assert.Nil(t, c.Delete(ctx, cluster))
+		}, false, false},
+		{"test secret deleted of running cluster", func(context.Context, client.Client, *clusterv1.Cluster, *corev1.Secret) {}, false, false},
+		{"test secret deleted of paused cluster", func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, _ *corev1.Secret) {
+			cluster.Spec.Paused = true
+			assert.Nil(t, c.Update(ctx, cluster))
+		}, false, false},
+		{"test secret deleted of paused cluster deleted for move", func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, _ *corev1.Secret) {
+			cluster.Spec.Paused = true
+			cluster.Annotations = map[string]string{deleteForMoveAnnotation: ""}
+			assert.Nil(t, c.Update(ctx, cluster))
+		}, false, true},
+		{"test secret deleted of cluster deleted for move", func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, _ *corev1.Secret) {
+			cluster.Annotations = map[string]string{deleteForMoveAnnotation: ""}
+			assert.Nil(t, c.Update(ctx, cluster))
+		}, false, true},
+		{"test protected secret deleted for move", func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, s *corev1.Secret) {
+			assert.Nil(t, c.Delete(ctx, cluster))
+			s.Annotations = map[string]string{deleteForMoveAnnotation: ""}
+			assert.Nil(t, c.Update(ctx, s))
+		}, true, true},
+		{"test protected secret deleted along cluster", func(ctx context.Context, c client.Client, cluster *clusterv1.Cluster, _ *corev1.Secret) {
+			assert.Nil(t, c.Delete(ctx, cluster))
+		}, true, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "moved-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "moved"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "moved", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+			c := MockClient(capiSecret, cluster)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			r.Config.EnableGarbageCollection = true
+			if tt.testFinalizer {
+				controllerutil.AddFinalizer(capiSecret, deletionProtectionFinalizer)
+				assert.Nil(t, c.Update(ctx, capiSecret))
+			}
+			req := MockReconcileReq("moved-kubeconfig", TestNamespace)
+			nn := r.Config.BuildNamespacedName("moved-kubeconfig", TestNamespace)
+
+			_, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Nil(t, c.Get(ctx, nn, &corev1.Secret{}))
+
+			assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+			tt.testMove(ctx, c, cluster, capiSecret)
+			assert.Nil(t, c.Delete(ctx, capiSecret))
+			_, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.True(t, errors.IsNotFound(c.Get(ctx, req.NamespacedName, &corev1.Secret{})))
+			err = c.Get(ctx, nn, &corev1.Secret{})
+			assert.Equal(t, tt.testExpectedValue, err == nil, err)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// clusterTakeAlongInfraLabelKey and clusterTakeAlongInfraAnnotationKey prefix the CAPI Cluster
+	// annotations selecting labels and annotations of its infrastructure object to take along.
+	clusterTakeAlongInfraLabelKey      = "take-along-infra-label.capi-to-argocd."
+	clusterTakeAlongInfraAnnotationKey = "take-along-infra-annotation.capi-to-argocd."
+	// clusterTakenFromInfraLabelKey and clusterTakenFromInfraAnnotationKey prefix the markers of
+	// labels and annotations taken along from an infrastructure object.
+	clusterTakenFromInfraLabelKey      = "taken-from-infra-label.capi-to-argocd."
+	clusterTakenFromInfraAnnotationKey = "taken-from-infra-annotation.capi-to-argocd."
+
+	// clusterTakeAlongCPLabelKey and clusterTakeAlongCPAnnotationKey prefix the CAPI Cluster
+	// annotations selecting labels and annotations of its control plane object to take along.
+	clusterTakeAlongCPLabelKey      = "take-along-cp-label.capi-to-argocd."
+	clusterTakeAlongCPAnnotationKey = "take-along-cp-annotation.capi-to-argocd."
+	// clusterTakenFromCPLabelKey and clusterTakenFromCPAnnotationKey prefix the markers of labels
+	// and annotations taken along from a control plane object.
+	clusterTakenFromCPLabelKey      = "taken-from-cp-label.capi-to-argocd."
+	clusterTakenFromCPAnnotationKey = "taken-from-cp-annotation.capi-to-argocd."
+)
+
+// ReferencedTakeAlong describes taking along labels and annotations from an object referenced by
+// a CAPI Cluster, selected by annotations of the Cluster.
+type ReferencedTakeAlong struct {
+	// Source names the referenced object in warnings.
+	Source string
+	// LabelKey and AnnotationKey prefix the Cluster annotations selecting labels and annotations.
+	LabelKey      string
+	AnnotationKey string
+	// TakenLabelKey and TakenAnnotationKey prefix the markers of keys taken along.
+	TakenLabelKey      string
+	TakenAnnotationKey string
+	// Ref returns the reference of the object on a Cluster.
+	Ref func(cluster CAPICluster) *corev1.ObjectReference
+}
+
+// InfrastructureTakeAlong takes along metadata of the spec.infrastructureRef object of a Cluster.
+func InfrastructureTakeAlong() ReferencedTakeAlong {
+	return ReferencedTakeAlong{
+		Source:             "infrastructure",
+		LabelKey:           clusterTakeAlongInfraLabelKey,
+		AnnotationKey:      clusterTakeAlongInfraAnnotationKey,
+		TakenLabelKey:      clusterTakenFromInfraLabelKey,
+		TakenAnnotationKey: clusterTakenFromInfraAnnotationKey,
+		Ref:                func(cluster CAPICluster) *corev1.ObjectReference { return cluster.GetInfrastructureRef() },
+	}
+}
+
+// ControlPlaneTakeAlong takes along metadata of the spec.controlPlaneRef object of a Cluster.
+func ControlPlaneTakeAlong() ReferencedTakeAlong {
+	return ReferencedTakeAlong{
+		Source:             "control plane",
+		LabelKey:           clusterTakeAlongCPLabelKey,
+		AnnotationKey:      clusterTakeAlongCPAnnotationKey,
+		TakenLabelKey:      clusterTakenFromCPLabelKey,
+		TakenAnnotationKey: clusterTakenFromCPAnnotationKey,
+		Ref:                func(cluster CAPICluster) *corev1.ObjectReference { return cluster.GetControlPlaneRef() },
+	}
+}
+
+// ReferencedTakeAlongs returns every ReferencedTakeAlong, in order of precedence.
+func ReferencedTakeAlongs() []ReferencedTakeAlong {
+	return []ReferencedTakeAlong{InfrastructureTakeAlong(), ControlPlaneTakeAlong()}
+}
+
+// selectedKeys returns the sorted keys selected by the annotations of a cluster with prefix.
+func selectedKeys(cluster CAPICluster, prefix string) []string {
+	keys := []string{}
+	for k := range cluster.GetAnnotations() {
+		if key := strings.TrimPrefix(k, prefix); key != k {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Has reports whether a cluster takes along metadata of the referenced object.
+func (t ReferencedTakeAlong) Has(cluster CAPICluster) bool {
+	return len(selectedKeys(cluster, t.LabelKey)) > 0 || len(selectedKeys(cluster, t.AnnotationKey)) > 0
+}
+
+// Build returns the labels and annotations of the referenced object of a cluster selected by its
+// annotations, each with its taken-from marker.
+func (t ReferencedTakeAlong) Build(rc *ReconcileContext, cluster CAPICluster, obj *unstructured.Unstructured) (map[string]string, map[string]string, []string) {
+	name := cluster.GetName()
+	namespace := cluster.GetNamespace()
+
+	labels := map[string]string{}
+	errors := []string{}
+	for _, label := range selectedKeys(cluster, t.LabelKey) {
+		if label == "" {
+			errors = append(errors, fmt.Sprintf("Warning: invalid take-along %s label. missing key after '%s' on cluster resource: %s, namespace: %s. Ignoring", t.Source, t.LabelKey, name, namespace))
+			continue
+		}
+		if takeAlongDepth(label) > 1 || IsReservedLabel(label) || strings.HasPrefix(label, "taken-from-") {
+			errors = append(errors, fmt.Sprintf("Warning: take-along %s label '%s' is reserved by the operator on cluster resource: %s, namespace: %s. Ignoring", t.Source, label, name, namespace))
+			continue
+		}
+		value, ok := obj.GetLabels()[label]
+		if !ok {
+			errors = append(errors, fmt.Sprintf("take-along %s label '%s' not found on %s %s, namespace: %s. Ignoring", t.Source, label, obj.GetKind(), obj.GetName(), namespace))
+			continue
+		}
+		value, err := ApplyValuePipeline(rc.Pipeline, label, value)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Warning: failed to transform value of take-along %s label '%s' on cluster resource: %s, namespace: %s: %v. Ignoring", t.Source, label, name, namespace, err))
+			continue
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			errors = append(errors, fmt.Sprintf("Warning: take-along %s label '%s' has invalid value '%s' on cluster resource: %s, namespace: %s: %s. Ignoring", t.Source, label, value, name, namespace, strings.Join(errs, "; ")))
+			continue
+		}
+		labels[label] = value
+		labels[t.TakenLabelKey+label] = ""
+	}
+
+	annotations := map[string]string{}
+	for _, annotation := range selectedKeys(cluster, t.AnnotationKey) {
+		if annotation == "" {
+			errors = append(errors, fmt.Sprintf("Warning: invalid take-along %s annotation. missing key after '%s' on cluster resource: %s, namespace: %s. Ignoring", t.Source, t.AnnotationKey, name, namespace))
+			continue
+		}
+		if strings.HasPrefix(annotation, operatorAnnotationPrefix) || strings.HasPrefix(annotation, "taken-from-") {
+			errors = append(errors, fmt.Sprintf("Warning: take-along %s annotation '%s' is reserved by the operator on cluster resource: %s, namespace: %s. Ignoring", t.Source, annotation, name, namespace))
+			continue
+		}
+		value, ok := obj.GetAnnotations()[annotation]
+		if !ok {
+			errors = append(errors, fmt.Sprintf("take-along %s annotation '%s' not found on %s %s, namespace: %s. Ignoring", t.Source, annotation, obj.GetKind(), obj.GetName(), namespace))
+			continue
+		}
+		annotations[annotation] = value
+		annotations[t.TakenAnnotationKey+annotation] = ""
+	}
+	return labels, annotations, errors
+}
+
+// readReferencedTakeAlong returns the labels and annotations taken along from the referenced
+// object of a CAPI Cluster. Nothing is returned when the cluster takes none along or the object
+// cannot be read.
+func (r *Capi2Argo) readReferencedTakeAlong(ctx context.Context, rc *ReconcileContext, cluster CAPICluster, t ReferencedTakeAlong) (map[string]string, map[string]string) {
+	if cluster == nil || t.Ref(cluster) == nil || !t.Has(cluster) {
+		return nil, nil
+	}
+	log := rc.Logger
+	ref := t.Ref(cluster)
+	obj, err := GetReferencedObject(ctx, r.Client, cluster.GetNamespace(), ref)
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			log.Info("Warning: failed to get "+t.Source+" of Cluster", "kind", ref.Kind, "name", ref.Name, "error", err)
+		}
+		return nil, nil
+	}
+	labels, annotations, warnings := t.Build(rc, cluster, obj)
+	for _, w := range warnings {
+		log.Info(w)
+	}
+	return labels, annotations
+}
+
+// takenFromLabelPrefixes returns the prefixes of the markers of labels taken along from elsewhere
+// than the cluster itself.
+func takenFromLabelPrefixes() []string {
+	prefixes := []string{clusterTakenFromNamespaceKey}
+	for _, t := range ReferencedTakeAlongs() {
+		prefixes = append(prefixes, t.TakenLabelKey)
+	}
+	return prefixes
+}
+
+// takenFromAnnotationPrefixes returns the prefixes of the markers of annotations taken along.
+func takenFromAnnotationPrefixes() []string {
+	prefixes := []string{clusterTakenFromClusterAnnotationKey}
+	for _, t := range ReferencedTakeAlongs() {
+		prefixes = append(prefixes, t.TakenAnnotationKey)
+	}
+	return prefixes
+}
+
+// trimTakenFromPrefix returns the key a marker is set for, and whether k is a marker of prefixes.
+func trimTakenFromPrefix(k string, prefixes []string) (string, bool) {
+	for _, p := range prefixes {
+		if key := strings.TrimPrefix(k, p); key != k {
+			return key, true
+		}
+	}
+	return k, false
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBuildReferencedTakeAlong(t *testing.T) {
+	t.Parallel()
+	infra := MockInfrastructure("infra", nil, nil)
+	infra.SetLabels(map[string]string{"example.com/account": "1234"})
+	infra.SetAnnotations(map[string]string{"example.com/cost-center": "cc-42", operatorAnnotationPrefix + "project": "x"})
+	tests := []struct {
+		testName                string
+		testMock                map[string]string
+		testExpectedError       bool
+		testExpectedLabels      map[string]string
+		testExpectedAnnotations map[string]string
+	}{
+		{"test take along", map[string]string{clusterTakeAlongInfraLabelKey + "example.com/account": "", clusterTakeAlongInfraAnnotationKey + "example.com/cost-center": ""}, false,
+			map[string]string{"example.com/account": "1234", clusterTakenFromInfraLabelKey + "example.com/account": ""},
+			map[string]string{"example.com/cost-center": "cc-42", clusterTakenFromInfraAnnotationKey + "example.com/cost-center": ""}},
+		{"test other source", map[string]string{clusterTakeAlongCPLabelKey + "example.com/account": ""}, false, map[string]string{}, map[string]string{}},
+		{"test without directives", map[string]string{}, false, map[string]string{}, map[string]string{}},
+		{"test missing keys", map[string]string{clusterTakeAlongInfraLabelKey + "missing": "", clusterTakeAlongInfraAnnotationKey + "missing": ""}, true, map[string]string{}, map[string]string{}},
+		{"test reserved keys", map[string]string{clusterTakeAlongInfraLabelKey + argoSecretTypeLabel: "", clusterTakeAlongInfraAnnotationKey + operatorAnnotationPrefix + "project": ""}, true, map[string]string{}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			rc := MockReconcileContext(MockOperatorConfig())
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Annotations: tt.testMock}}}
+			assert.Equal(t, len(tt.testExpectedLabels) > 0 || tt.testExpectedError, InfrastructureTakeAlong().Has(cluster))
+			labels, annotations, errs := InfrastructureTakeAlong().Build(rc, cluster, infra)
+			assert.Equal(t, tt.testExpectedError, len(errs) > 0)
+			assert.Equal(t, tt.testExpectedLabels, labels)
+			assert.Equal(t, tt.testExpectedAnnotations, annotations)
+		})
+	}
+}
+
+func TestReconcileReferencedTakeAlong(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	scheme := MockScheme()
+	scheme.AddKnownTypeWithName(awsClusterGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(kubeadmControlPlaneGVK, &unstructured.Unstructured{})
+	controlPlane := MockControlPlane("take-along-cp", nil)
+	controlPlane.SetLabels(map[string]string{"channel": "stable"})
+	infra := MockInfrastructure("take-along-infra", nil, nil)
+	infra.SetLabels(map[string]string{"account": "1234"})
+	infra.SetAnnotations(map[string]string{"cost-center": "cc-42"})
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "infra-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "infra"}
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "infra", Namespace: TestNamespace, Annotations: map[string]string{
+			clusterTakeAlongInfraLabelKey + "account":          "",
+			clusterTakeAlongInfraAnnotationKey + "cost-center": "",
+			clusterTakeAlongCPLabelKey + "channel":             "",
+		}},
+		Spec: clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{APIVersion: awsClusterGVK.GroupVersion().String(), Kind: awsClusterGVK.Kind, Name: "take-along-infra"},
+			ControlPlaneRef:   &corev1.ObjectReference{APIVersion: kubeadmControlPlaneGVK.GroupVersion().String(), Kind: kubeadmControlPlaneGVK.Kind, Name: "take-along-cp"},
+		},
+		Status: MockReadyClusterStatus(),
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(capiSecret, cluster, infra, controlPlane).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+	req := MockReconcileReq("infra-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("infra-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "1234", argoSecret.Labels["account"])
+	assert.Contains(t, argoSecret.Labels, clusterTakenFromInfraLabelKey+"account")
+	assert.Equal(t, "cc-42", argoSecret.Annotations["cost-center"])
+	assert.Contains(t, argoSecret.Annotations, clusterTakenFromInfraAnnotationKey+"cost-center")
+	assert.Equal(t, "stable", argoSecret.Labels["channel"])
+	assert.Contains(t, argoSecret.Labels, clusterTakenFromCPLabelKey+"channel")
+
+	// Keys no longer taken along are removed, along their marker.
+	assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+	cluster.Annotations = nil
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.NotContains(t, argoSecret.Labels, "account")
+	assert.NotContains(t, argoSecret.Labels, clusterTakenFromInfraLabelKey+"account")
+	assert.NotContains(t, argoSecret.Annotations, "cost-center")
+	assert.NotContains(t, argoSecret.Annotations, clusterTakenFromInfraAnnotationKey+"cost-center")
+	assert.NotContains(t, argoSecret.Labels, "channel")
+	assert.NotContains(t, argoSecret.Labels, clusterTakenFromCPLabelKey+"channel")
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// skipAnnotation opts a CAPI Cluster, or a kubeconfig Secret, out of registration when "true".
+	skipAnnotation = "capi-to-argocd/skip"
+	// enabledLabel opts a CAPI Cluster, or a kubeconfig Secret, into registration when "true",
+	// with OperatorConfig.OptInRegistration.
+	enabledLabel = "capi-to-argocd/enabled"
+)
+
+// IsRegistrationSkipped reports whether the kubeconfig Secret or the CAPI Cluster of a cluster
+// opts out of registration. Values other than booleans are ignored.
+func IsRegistrationSkipped(capiSecret *corev1.Secret, cluster CAPICluster) bool {
+	if skip, _ := strconv.ParseBool(capiSecret.Annotations[skipAnnotation]); skip {
+		return true
+	}
+	if cluster == nil {
+		return false
+	}
+	skip, _ := strconv.ParseBool(cluster.GetAnnotations()[skipAnnotation])
+	return skip
+}
+
+// IsRegistrationEnabled reports whether the kubeconfig Secret or the CAPI Cluster of a cluster
+// opts into registration.
+func IsRegistrationEnabled(capiSecret *corev1.Secret, cluster CAPICluster) bool {
+	if enabled, _ := strconv.ParseBool(capiSecret.Labels[enabledLabel]); enabled {
+		return true
+	}
+	if cluster == nil {
+		return false
+	}
+	enabled, _ := strconv.ParseBool(cluster.GetLabels()[enabledLabel])
+	return enabled
+}
+
+// deregisterSkippedCluster deletes the ArgoSecrets of a cluster opted out of registration. With
+// Config.EnableDeletionProtection, they are kept and requeued while ArgoCD Applications still
+// target the cluster.
+func (r *Capi2Argo) deregisterSkippedCluster(ctx context.Context, log logr.Logger, capiSecret *corev1.Secret) (ctrl.Result, error) {
+	if r.Config.EnableDeletionProtection {
+		apps, err := r.listTargetingApplications(ctx, log, capiSecret)
+		if err != nil {
+			log.Error(err, "Failed to list ArgoCD Applications")
+			return ctrl.Result{}, err
+		}
+		if len(apps) > 0 {
+			message := fmt.Sprintf("Applications still target the opted out cluster: %s", strings.Join(apps, ", "))
+			log.Error(ErrDeletionBlocked, message)
+			r.sourceEvent(capiSecret, nil, corev1.EventTypeWarning, DeletionBlockedCondition, message)
+			return ctrl.Result{RequeueAfter: deletionBlockedRequeueAfter}, nil
+		}
+	}
+	return r.deleteArgoSecrets(ctx, log, client.ObjectKeyFromObject(capiSecret))
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestIsRegistrationSkipped(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName               string
+		testSecretAnnotations  map[string]string
+		testClusterAnnotations map[string]string
+		testExpectedValues     bool
+	}{
+		{"test no annotations", nil, nil, false},
+		{"test secret annotation", map[string]string{skipAnnotation: "true"}, nil, true},
+		{"test cluster annotation", nil, map[string]string{skipAnnotation: "true"}, true},
+		{"test disabled annotation", map[string]string{skipAnnotation: "false"}, map[string]string{skipAnnotation: "false"}, false},
+		{"test invalid annotation", nil, map[string]string{skipAnnotation: "yes"}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: tt.testSecretAnnotations}}
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Annotations: tt.testClusterAnnotations}}}
+			assert.Equal(t, tt.testExpectedValues, IsRegistrationSkipped(s, cluster))
+		})
+	}
+	assert.False(t, IsRegistrationSkipped(&corev1.Secret{}, nil))
+}
+
+func TestIsRegistrationEnabled(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testSecretLabels   map[string]string
+		testClusterLabels  map[string]string
+		testExpectedValues bool
+	}{
+		{"test no labels", nil, nil, false},
+		{"test secret label", map[string]string{enabledLabel: "true"}, nil, true},
+		{"test cluster label", nil, map[string]string{enabledLabel: "true"}, true},
+		{"test disabled label", nil, map[string]string{enabledLabel: "false"}, false},
+		{"test invalid label", nil, map[string]string{enabledLabel: "yes"}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: tt.testSecretLabels}}
+			cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Labels: tt.testClusterLabels}}}
+			assert.Equal(t, tt.testExpectedValues, IsRegistrationEnabled(s, cluster))
+		})
+	}
+	assert.False(t, IsRegistrationEnabled(&corev1.Secret{}, nil))
+}
+
+func TestReconcileOptInRegistration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "optin-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "optin"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "optin", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.OptInRegistration = true
+	req := MockReconcileReq("optin-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("optin-kubeconfig", TestNamespace)
+
+	var argoSecret corev1.Secret
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+	cluster.Labels = map[string]string{enabledLabel: "true"}
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+}
+
+func TestReconcileSkippedCluster(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "skipped-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "skipped"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "skipped", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	c := MockClient(capiSecret, cluster)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("skipped-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("skipped-kubeconfig", TestNamespace)
+
+	var argoSecret corev1.Secret
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+
+	// Opting out removes the previously created ArgoSecret.
+	cluster.Annotations = map[string]string{skipAnnotation: "true"}
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &argoSecret)))
+
+	// Opting back in registers the cluster again.
+	cluster.Annotations = nil
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+}
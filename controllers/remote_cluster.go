@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// remoteSecretRefPrefix marks a remote cluster reference as a Secret instead of a kubeconfig path.
+const remoteSecretRefPrefix = "secret:"
+
+// LoadRemoteConfig builds a rest.Config for a remote cluster from a reference that is either
+// a kubeconfig file path or a Secret reference in the form `secret:<namespace>/<name>`.
+// Referenced Secrets are read with the given reader and must hold the kubeconfig under
+// the `value` key, same as CAPI kubeconfig Secrets.
+func LoadRemoteConfig(ctx context.Context, c client.Reader, ref string) (*rest.Config, error) {
+	if !strings.HasPrefix(ref, remoteSecretRefPrefix) {
+		return clientcmd.BuildConfigFromFlags("", ref)
+	}
+
+	nn, err := parseSecretRef(strings.TrimPrefix(ref, remoteSecretRefPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, errors.New("a client is required to read remote cluster secret references")
+	}
+	var s corev1.Secret
+	if err := c.Get(ctx, nn, &s); err != nil {
+		return nil, err
+	}
+	raw, ok := s.Data["value"]
+	if !ok {
+		return nil, fmt.Errorf("secret %s is missing the 'value' key", nn)
+	}
+	return clientcmd.RESTConfigFromKubeConfig(raw)
+}
+
+// parseSecretRef parses a `<namespace>/<name>` Secret reference.
+func parseSecretRef(ref string) (types.NamespacedName, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid secret reference %q, expected <namespace>/<name>", ref)
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nil
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestLoadRemoteConfig(t *testing.T) {
+	t.Parallel()
+	raw, err := os.ReadFile("../tests/remote-kubeconfig.yaml")
+	assert.Nil(t, err)
+	remoteSecret := MockCapiSecret(validMock, validType, validKey, "remote-kubeconfig", "capi")
+	remoteSecret.Data["value"] = raw
+	remote := MockClient(
+		remoteSecret,
+		MockCapiSecret(validMock, validType, !validKey, "broken-kubeconfig", "capi"),
+	)
+
+	tests := []struct {
+		testName          string
+		testMock          string
+		testExpectedError bool
+		testExpectedHost  string
+	}{
+		{"test kubeconfig path", "../tests/remote-kubeconfig.yaml", false, "https://remote-management.domain.com:6443"},
+		{"test secret reference", "secret:capi/remote-kubeconfig", false, "https://remote-management.domain.com:6443"},
+		{"test secret reference with missing key", "secret:capi/broken-kubeconfig", true, ""},
+		{"test missing secret reference", "secret:capi/missing-kubeconfig", true, ""},
+		{"test malformed secret reference", "secret:missing-kubeconfig", true, ""},
+		{"test missing kubeconfig path", "../tests/missing.yaml", true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := LoadRemoteConfig(context.Background(), remote, tt.testMock)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else if assert.Nil(t, err) {
+				assert.Equal(t, tt.testExpectedHost, cfg.Host)
+			}
+		})
+	}
+}
+
+func TestReconcileRemoteManagementCluster(t *testing.T) {
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "remote-kubeconfig", TestNamespace)
+	remote := MockClient(capiSecret)
+	local := MockClient()
+	r := &Capi2Argo{
+		Client:     remote,
+		ArgoClient: local,
+		Log:        TestLog,
+		Scheme:     MockScheme(),
+		Config:     MockOperatorConfig(),
+	}
+	r.Config.EnableGarbageCollection = true
+
+	_, err := r.Reconcile(ctx, MockReconcileReq("remote-kubeconfig", TestNamespace))
+	assert.Nil(t, err)
+
+	// ArgoSecret must be written to the local cluster only.
+	nn := MockOperatorConfig().BuildNamespacedName("remote-kubeconfig", TestNamespace)
+	var argoSecret corev1.Secret
+	assert.Nil(t, local.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, "remote-kubeconfig", argoSecret.Labels["capi-to-argocd/cluster-secret-name"])
+	assert.True(t, apierrors.IsNotFound(remote.Get(ctx, nn, &corev1.Secret{})))
+
+	// Removing the CAPI Secret from the remote cluster garbage collects the local ArgoSecret.
+	assert.Nil(t, remote.Delete(ctx, capiSecret))
+	_, err = r.Reconcile(ctx, MockReconcileReq("remote-kubeconfig", TestNamespace))
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(local.Get(ctx, nn, &corev1.Secret{})))
+
+	// Further reconciles with nothing left to collect are a no-op.
+	_, err = r.Reconcile(ctx, MockReconcileReq("remote-kubeconfig", TestNamespace))
+	assert.Nil(t, err)
+}
+
+// MockRecordingClient returns a fake client pre-populated with the given objects, that
+// appends the name of every method called to calls.
+func MockRecordingClient(calls *[]string, objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(objs...).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				*calls = append(*calls, "Get")
+				return c.Get(ctx, key, obj, opts...)
+			},
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				*calls = append(*calls, "List")
+				return c.List(ctx, list, opts...)
+			},
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				*calls = append(*calls, "Create")
+				return c.Create(ctx, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				*calls = append(*calls, "Update")
+				return c.Update(ctx, obj, opts...)
+			},
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				*calls = append(*calls, "Delete")
+				return c.Delete(ctx, obj, opts...)
+			},
+		}).
+		Build()
+}
+
+// mutatingCalls filters the write calls recorded by MockRecordingClient.
+func mutatingCalls(calls []string) []string {
+	writes := []string{}
+	for _, c := range calls {
+		if c != "Get" && c != "List" {
+			writes = append(writes, c)
+		}
+	}
+	return writes
+}
+
+func TestReconcilePlatformCluster(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var localCalls, platformCalls []string
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "platform-kubeconfig", TestNamespace)
+	local := MockRecordingClient(&localCalls, capiSecret)
+	platform := MockRecordingClient(&platformCalls)
+	r := &Capi2Argo{
+		Client:     local,
+		ArgoClient: platform,
+		Log:        TestLog,
+		Scheme:     MockScheme(),
+		Config:     MockOperatorConfig(),
+	}
+	r.Config.EnableGarbageCollection = true
+	req := MockReconcileReq("platform-kubeconfig", TestNamespace)
+	nn := MockOperatorConfig().BuildNamespacedName("platform-kubeconfig", TestNamespace)
+
+	// CAPI objects are read from the local cluster, ArgoSecrets are written to the platform cluster.
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Empty(t, mutatingCalls(localCalls))
+	assert.Contains(t, localCalls, "Get")
+	assert.Equal(t, []string{"Create"}, mutatingCalls(platformCalls))
+	var argoSecret corev1.Secret
+	assert.Nil(t, platform.Get(ctx, nn, &argoSecret))
+	assert.True(t, apierrors.IsNotFound(local.Get(ctx, nn, &corev1.Secret{})))
+
+	// Updates go to the platform cluster only.
+	argoSecret.Data["server"] = []byte("https://stale")
+	assert.Nil(t, platform.Update(ctx, &argoSecret))
+	localCalls, platformCalls = nil, nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Empty(t, mutatingCalls(localCalls))
+	assert.Equal(t, []string{"Update"}, mutatingCalls(platformCalls))
+
+	// Garbage collection lists and deletes on the platform cluster only, after checking the
+	// Cluster of the CAPI Secret is not being moved.
+	assert.Nil(t, local.Delete(ctx, capiSecret))
+	localCalls, platformCalls = nil, nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Get", "Get"}, localCalls)
+	assert.Equal(t, []string{"List", "Delete"}, platformCalls)
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// controlPlaneReplicasLabel holds the desired replicas of the control plane of a cluster.
+	controlPlaneReplicasLabel = "capi-to-argocd/control-plane-replicas"
+	// workerReplicasLabel holds the desired replicas of the MachineDeployments and MachinePools of a cluster.
+	workerReplicasLabel = "capi-to-argocd/worker-replicas"
+)
+
+// workerKinds returns the kinds of the CAPI objects whose replicas are counted as workers.
+func workerKinds() []string {
+	return []string{"MachineDeployment", "MachinePool"}
+}
+
+// clusterReplicaCounts returns the replica count labels of a CAPI Cluster: the spec.replicas of
+// its control plane object, and the sum of those of its MachineDeployments and MachinePools.
+// Counts that cannot be read are left out.
+func (r *Capi2Argo) clusterReplicaCounts(ctx context.Context, log logr.Logger, cluster CAPICluster) map[string]string {
+	labels := map[string]string{}
+	if cluster == nil {
+		return labels
+	}
+	if ref := cluster.GetControlPlaneRef(); ref != nil {
+		cp, err := GetReferencedObject(ctx, r.Client, cluster.GetNamespace(), ref)
+		if err != nil {
+			if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+				log.Info("Warning: failed to get control plane of Cluster", "kind", ref.Kind, "name", ref.Name, "error", err)
+			}
+		} else if replicas, ok, _ := unstructured.NestedInt64(cp.Object, "spec", "replicas"); ok {
+			labels[controlPlaneReplicasLabel] = strconv.FormatInt(replicas, 10)
+		}
+	}
+
+	var workers int64
+	for _, kind := range workerKinds() {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: clusterv1.GroupVersion.Group, Version: r.Config.ClusterAPIVersion, Kind: kind + "List"})
+		err := r.List(ctx, list, client.InNamespace(cluster.GetNamespace()), client.MatchingLabels{clusterv1.ClusterNameLabel: cluster.GetName()})
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			log.Info("Warning: failed to list workers of Cluster. Ignoring", "kind", kind, "error", err)
+			return labels
+		}
+		for _, item := range list.Items {
+			replicas, _, _ := unstructured.NestedInt64(item.Object, "spec", "replicas")
+			workers += replicas
+		}
+	}
+	labels[workerReplicasLabel] = strconv.FormatInt(workers, 10)
+	return labels
+}
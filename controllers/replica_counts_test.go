@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var machinePoolGVK = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "MachinePool"}
+
+func MockMachinePool(name string, cluster string, replicas int64) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}}}
+	u.SetGroupVersionKind(machinePoolGVK)
+	u.SetName(name)
+	u.SetNamespace(TestNamespace)
+	u.SetLabels(map[string]string{clusterv1.ClusterNameLabel: cluster})
+	return u
+}
+
+func MockMachineDeployment(name string, cluster string, replicas int32) *clusterv1.MachineDeployment {
+	return &clusterv1.MachineDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: TestNamespace, Labels: map[string]string{clusterv1.ClusterNameLabel: cluster}},
+		Spec:       clusterv1.MachineDeploymentSpec{ClusterName: cluster, Replicas: &replicas},
+	}
+}
+
+func TestReconcileReplicaCounts(t *testing.T) {
+	t.Parallel()
+	controlPlaneRef := &corev1.ObjectReference{APIVersion: kubeadmControlPlaneGVK.GroupVersion().String(), Kind: kubeadmControlPlaneGVK.Kind, Name: "replicas-cp"}
+	controlPlane := MockControlPlane("replicas-cp", nil)
+	_ = unstructured.SetNestedField(controlPlane.Object, int64(3), "spec", "replicas")
+	tests := []struct {
+		testName           string
+		testMachinePools   bool
+		testObjects        []client.Object
+		testSpec           clusterv1.ClusterSpec
+		testExpectedValues map[string]string
+	}{
+		{"test control plane and machine deployments", false,
+			[]client.Object{controlPlane, MockMachineDeployment("md-0", "replicas", 2), MockMachineDeployment("md-1", "replicas", 4), MockMachineDeployment("other", "other", 8)},
+			clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef},
+			map[string]string{controlPlaneReplicasLabel: "3", workerReplicasLabel: "6"}},
+		{"test machine pools", true,
+			[]client.Object{MockMachineDeployment("md-0", "replicas", 2), MockMachinePool("mp-0", "replicas", 5)},
+			clusterv1.ClusterSpec{},
+			map[string]string{controlPlaneReplicasLabel: "", workerReplicasLabel: "7"}},
+		{"test missing control plane without workers", false, nil,
+			clusterv1.ClusterSpec{ControlPlaneRef: controlPlaneRef},
+			map[string]string{controlPlaneReplicasLabel: "", workerReplicasLabel: "0"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			scheme := MockScheme()
+			scheme.AddKnownTypeWithName(kubeadmControlPlaneGVK, &unstructured.Unstructured{})
+			if tt.testMachinePools {
+				scheme.AddKnownTypeWithName(machinePoolGVK, &unstructured.Unstructured{})
+				scheme.AddKnownTypeWithName(machinePoolGVK.GroupVersion().WithKind("MachinePoolList"), &unstructured.UnstructuredList{})
+			}
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "replicas-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "replicas"}
+			objs := append([]client.Object{capiSecret, &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicas", Namespace: TestNamespace},
+				Spec:       tt.testSpec,
+				Status:     MockReadyClusterStatus(),
+			}}, tt.testObjects...)
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objs...).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: scheme, Config: MockOperatorConfig()}
+			r.Config.ReplicaCountLabels = true
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("replicas-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("replicas-kubeconfig", TestNamespace), &argoSecret))
+			for k, v := range tt.testExpectedValues {
+				assert.Equal(t, v, argoSecret.Labels[k], k)
+			}
+		})
+	}
+}
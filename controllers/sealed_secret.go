@@ -0,0 +1,489 @@
+package controllers
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// OutputFormatSecret makes the controller write plain ArgoCD cluster Secrets.
+	OutputFormatSecret = "secret"
+	// OutputFormatSealedSecret makes the controller write Bitnami SealedSecrets
+	// that the sealed-secrets controller unseals into ArgoCD cluster Secrets.
+	OutputFormatSealedSecret = "sealed-secret"
+
+	// sealedSecretHashAnnotation holds the hash of the plaintext data that was sealed.
+	// Sealing is non-deterministic, so this is what we compare to detect drift.
+	sealedSecretHashAnnotation = "capi-to-argocd/plaintext-hash"
+
+	sealedSecretSessionKeyBytes = 32
+)
+
+// SealedSecretGroupVersion is the API group of Bitnami SealedSecrets.
+var SealedSecretGroupVersion = schema.GroupVersion{Group: "bitnami.com", Version: "v1alpha1"}
+
+// SealedSecret is a local representation of bitnami.com/v1alpha1.SealedSecret.
+// It is modeled here instead of importing sealed-secrets to avoid coupling on its dependencies.
+type SealedSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SealedSecretSpec `json:"spec"`
+}
+
+// SealedSecretSpec represents SealedSecret.spec.
+type SealedSecretSpec struct {
+	Template      SealedSecretTemplate `json:"template,omitempty"`
+	EncryptedData map[string]string    `json:"encryptedData"`
+}
+
+// SealedSecretTemplate represents SealedSecret.spec.template, the metadata of the unsealed Secret.
+type SealedSecretTemplate struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Type              corev1.SecretType `json:"type,omitempty"`
+}
+
+// SealedSecretList is a list of SealedSecrets.
+type SealedSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []SealedSecret `json:"items"`
+}
+
+// DeepCopyObject implements runtime.Object.
+func (s *SealedSecret) DeepCopyObject() runtime.Object {
+	return s.DeepCopy()
+}
+
+// DeepCopy returns a deep copy of the SealedSecret.
+func (s *SealedSecret) DeepCopy() *SealedSecret {
+	if s == nil {
+		return nil
+	}
+	out := &SealedSecret{
+		TypeMeta:   s.TypeMeta,
+		ObjectMeta: *s.ObjectMeta.DeepCopy(),
+		Spec: SealedSecretSpec{
+			Template: SealedSecretTemplate{
+				ObjectMeta: *s.Spec.Template.ObjectMeta.DeepCopy(),
+				Type:       s.Spec.Template.Type,
+			},
+		},
+	}
+	if s.Spec.EncryptedData != nil {
+		out.Spec.EncryptedData = make(map[string]string, len(s.Spec.EncryptedData))
+		for k, v := range s.Spec.EncryptedData {
+			out.Spec.EncryptedData[k] = v
+		}
+	}
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (l *SealedSecretList) DeepCopyObject() runtime.Object {
+	if l == nil {
+		return nil
+	}
+	out := &SealedSecretList{
+		TypeMeta: l.TypeMeta,
+		ListMeta: *l.ListMeta.DeepCopy(),
+	}
+	if l.Items != nil {
+		out.Items = make([]SealedSecret, len(l.Items))
+		for i := range l.Items {
+			out.Items[i] = *l.Items[i].DeepCopy()
+		}
+	}
+	return out
+}
+
+// AddSealedSecretToScheme registers the local SealedSecret types to the given scheme.
+func AddSealedSecretToScheme(s *runtime.Scheme) error {
+	s.AddKnownTypes(SealedSecretGroupVersion, &SealedSecret{}, &SealedSecretList{})
+	metav1.AddToGroupVersion(s, SealedSecretGroupVersion)
+	return nil
+}
+
+// PublicKeyFetcher returns the public key of the sealed-secrets controller.
+type PublicKeyFetcher func(ctx context.Context) (*rsa.PublicKey, error)
+
+// NewControllerPublicKeyFetcher returns a PublicKeyFetcher that reads the sealing certificate
+// from the sealed-secrets controller through the API server service proxy.
+func NewControllerPublicKeyFetcher(cs kubernetes.Interface, namespace, service string) PublicKeyFetcher {
+	return func(ctx context.Context) (*rsa.PublicKey, error) {
+		raw, err := cs.CoreV1().Services(namespace).ProxyGet("http", service, "", "/v1/cert.pem", nil).DoRaw(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ParseSealingPublicKey(raw)
+	}
+}
+
+// ParseSealingPublicKey parses a PEM encoded certificate and returns its RSA public key.
+func ParseSealingPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in sealing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("sealing certificate does not hold an RSA public key")
+	}
+	return key, nil
+}
+
+// SealedSecretKeyCache caches the sealed-secrets controller public key and refreshes it
+// once it gets older than the refresh interval.
+type SealedSecretKeyCache struct {
+	fetch   PublicKeyFetcher
+	refresh time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	key       *rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewSealedSecretKeyCache returns a new SealedSecretKeyCache.
+func NewSealedSecretKeyCache(fetch PublicKeyFetcher, refresh time.Duration) *SealedSecretKeyCache {
+	return &SealedSecretKeyCache{
+		fetch:   fetch,
+		refresh: refresh,
+		now:     time.Now,
+	}
+}
+
+// PublicKey returns the cached public key, fetching it when missing or expired.
+// If a refresh fails while a key is cached, the cached key keeps being served.
+func (c *SealedSecretKeyCache) PublicKey(ctx context.Context) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.key != nil && c.now().Sub(c.fetchedAt) < c.refresh {
+		return c.key, nil
+	}
+	key, err := c.fetch(ctx)
+	if err != nil {
+		if c.key != nil {
+			return c.key, nil
+		}
+		return nil, err
+	}
+	c.key = key
+	c.fetchedAt = c.now()
+	return c.key, nil
+}
+
+// SealSecret encrypts a Secret into a strict-scoped SealedSecret using the given public key.
+func SealSecret(s *corev1.Secret, key *rsa.PublicKey) (*SealedSecret, error) {
+	return sealSecret(rand.Reader, s, key)
+}
+
+func sealSecret(rnd io.Reader, s *corev1.Secret, key *rsa.PublicKey) (*SealedSecret, error) {
+	// Strict scope binds the ciphertext to the Secret namespace and name.
+	label := []byte(fmt.Sprintf("%s/%s", s.Namespace, s.Name))
+
+	encrypted := make(map[string]string, len(s.Data))
+	for k, v := range s.Data {
+		c, err := hybridEncrypt(rnd, key, v, label)
+		if err != nil {
+			return nil, err
+		}
+		encrypted[k] = b64.StdEncoding.EncodeToString(c)
+	}
+
+	annotations := map[string]string{
+		sealedSecretHashAnnotation: HashSecretData(s.Data),
+	}
+	for k, v := range s.Annotations {
+		annotations[k] = v
+	}
+
+	return &SealedSecret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "SealedSecret",
+			APIVersion: SealedSecretGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.Name,
+			Namespace:   s.Namespace,
+			Labels:      s.Labels,
+			Annotations: annotations,
+		},
+		Spec: SealedSecretSpec{
+			Template: SealedSecretTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        s.Name,
+					Namespace:   s.Namespace,
+					Labels:      s.Labels,
+					Annotations: s.Annotations,
+				},
+				Type: s.Type,
+			},
+			EncryptedData: encrypted,
+		},
+	}, nil
+}
+
+// hybridEncrypt mirrors the sealed-secrets wire format: a random AES-256-GCM session key
+// encrypted with RSA-OAEP, prefixed by its big-endian uint16 length, followed by the sealed payload.
+func hybridEncrypt(rnd io.Reader, key *rsa.PublicKey, plaintext, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, sealedSecretSessionKeyBytes)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rnd, key, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+
+	// Session keys are single-use, so a zero nonce is safe.
+	zeroNonce := make([]byte, aead.NonceSize())
+	return aead.Seal(ciphertext, zeroNonce, plaintext, nil), nil
+}
+
+// HashSecretData returns a stable hex encoded sha256 of a Secret data map.
+func HashSecretData(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reconcileSealedSecret seals the ArgoSecret of a and creates or updates the matching SealedSecret,
+// stamped and recorded like ArgoSecrets.
+func (r *Capi2Argo) reconcileSealedSecret(ctx context.Context, log logr.Logger, capiSecret *corev1.Secret, cluster CAPICluster, a *ArgoCluster, argoSecret *corev1.Secret) (ctrl.Result, error) {
+	if r.SealedSecretKeys == nil {
+		return ctrl.Result{}, errors.New("sealed-secret output format requires a sealing key cache")
+	}
+	key, err := r.SealedSecretKeys.PublicKey(ctx)
+	if err != nil {
+		log.Error(err, "Failed to fetch sealed-secrets public key")
+		return ctrl.Result{}, err
+	}
+
+	existing := &SealedSecret{}
+	err = r.argoClient().Get(ctx, types.NamespacedName{Name: argoSecret.Name, Namespace: argoSecret.Namespace}, existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Failed to fetch ArgoSealedSecret to check if exists")
+		return ctrl.Result{}, err
+	}
+
+	setSourceGeneration(argoSecret, capiSecret)
+	r.setSourceHash(argoSecret, SourceHash(capiSecret))
+
+	if apierrors.IsNotFound(err) {
+		log.Info("ArgoSealedSecret does not exists, creating..")
+		r.setLifecycleAnnotations(argoSecret, true)
+		sealed, err := SealSecret(argoSecret, key)
+		if err != nil {
+			log.Error(err, "Failed to seal ArgoSecret")
+			return ctrl.Result{}, err
+		}
+		err = r.argoClient().Create(ctx, sealed)
+		r.auditRecord(AuditActionCreate, sealed, string(argoSecret.Data["name"]), secretFieldNames(argoSecret), err)
+		r.changelogRecord(AuditActionCreate, sealed, secretFieldNames(argoSecret), capiSecret.ResourceVersion, err)
+		if err != nil {
+			log.Error(err, "Failed to create ArgoSealedSecret")
+			return ctrl.Result{}, err
+		}
+		log.Info("Created new ArgoSealedSecret")
+		if a.NameTruncated {
+			ClusterNameTruncatedTotal.Inc()
+		}
+		r.registrationEvent(capiSecret, cluster, nil, corev1.EventTypeNormal, EventReasonRegistered, "Registered as ArgoSealedSecret "+a.NamespacedName.String())
+		return ctrl.Result{}, r.recordSealedRegistration(ctx, log, cluster, a.NamespacedName)
+	}
+
+	if err := r.Config.ValidateOwner(corev1.Secret{ObjectMeta: existing.ObjectMeta}); err != nil {
+		log.Info("Not managed by Controller, skipping...")
+		return ctrl.Result{}, nil
+	}
+
+	diff := sealedSecretDiff(existing, argoSecret)
+	if len(diff) == 0 {
+		log.Info("ArgoSealedSecret is in-sync with CapiCluster, skipping...")
+		return ctrl.Result{}, r.recordSealedRegistration(ctx, log, cluster, a.NamespacedName)
+	}
+
+	if v, ok := existing.Annotations[firstSyncedAtAnnotation]; ok {
+		argoSecret.Annotations[firstSyncedAtAnnotation] = v
+	}
+	r.setLifecycleAnnotations(argoSecret, false)
+	sealed, err := SealSecret(argoSecret, key)
+	if err != nil {
+		log.Error(err, "Failed to seal ArgoSecret")
+		return ctrl.Result{}, err
+	}
+	// Labels and annotations added by other tools are kept, those previously written by the
+	// operator are known from the template and removed when no longer set.
+	for k := range existing.Spec.Template.Labels {
+		if _, ok := sealed.Labels[k]; !ok {
+			delete(existing.Labels, k)
+		}
+	}
+	for k := range existing.Spec.Template.Annotations {
+		if _, ok := sealed.Annotations[k]; !ok {
+			delete(existing.Annotations, k)
+		}
+	}
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	if existing.Annotations == nil {
+		existing.Annotations = map[string]string{}
+	}
+	MergeOwnedLabels(existing.Labels, sealed.Labels)
+	maps.Copy(existing.Annotations, sealed.Annotations)
+	existing.Spec = sealed.Spec
+
+	log.Info("Updating out-of-sync ArgoSealedSecret")
+	err = r.argoClient().Update(ctx, existing)
+	r.auditRecord(AuditActionUpdate, existing, string(argoSecret.Data["name"]), diff, err)
+	r.changelogRecord(AuditActionUpdate, existing, diff, capiSecret.ResourceVersion, err)
+	if err != nil {
+		log.Error(err, "Failed to update ArgoSealedSecret")
+		return ctrl.Result{}, err
+	}
+	log.Info("Updated successfully of ArgoSealedSecret")
+	r.registrationEvent(capiSecret, cluster, nil, corev1.EventTypeNormal, EventReasonUpdated, "Updated ArgoSealedSecret "+a.NamespacedName.String())
+	return ctrl.Result{}, r.recordSealedRegistration(ctx, log, cluster, a.NamespacedName)
+}
+
+// sealedSecretDiff returns the sorted fields of a SealedSecret out-of-sync with the ArgoSecret it
+// gets sealed from. Lifecycle annotations, stamped on every write, and metadata added by other
+// tools are left out.
+func sealedSecretDiff(existing *SealedSecret, s *corev1.Secret) []string {
+	diff := []string{}
+	if existing.Annotations[sealedSecretHashAnnotation] != HashSecretData(s.Data) {
+		diff = append(diff, "spec.encryptedData")
+	}
+	if existing.Spec.Template.Type != s.Type {
+		diff = append(diff, "type")
+	}
+	for _, k := range changedKeys(existing.Spec.Template.Labels, s.Labels) {
+		diff = append(diff, "labels."+k)
+	}
+	for k, v := range s.Labels {
+		if existing.Labels[k] != v {
+			diff = append(diff, "labels."+k)
+		}
+	}
+	withoutLifecycle := func(annotations map[string]string) map[string]string {
+		out := maps.Clone(annotations)
+		for _, k := range []string{firstSyncedAtAnnotation, lastSyncedAtAnnotation, lastUpdatedAtAnnotation, syncedByVersionAnnotation} {
+			delete(out, k)
+		}
+		return out
+	}
+	for _, k := range changedKeys(withoutLifecycle(existing.Spec.Template.Annotations), withoutLifecycle(s.Annotations)) {
+		diff = append(diff, "annotations."+k)
+	}
+	slices.Sort(diff)
+	return slices.Compact(diff)
+}
+
+// changedKeys returns the keys added, removed or changed between two maps.
+func changedKeys(a, b map[string]string) []string {
+	changed := []string{}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	return changed
+}
+
+// recordSealedRegistration annotates a CAPI Cluster with the reference of its SealedSecret. The
+// RegisteredToArgoCD condition is left out, as SealedSecrets are only registered once unsealed.
+func (r *Capi2Argo) recordSealedRegistration(ctx context.Context, log logr.Logger, cluster CAPICluster, sealedSecret types.NamespacedName) error {
+	if cluster == nil {
+		return nil
+	}
+	return r.annotateArgoSecret(ctx, log, cluster, sealedSecret)
+}
+
+// deleteSealedSecrets removes the SealedSecrets matching the given list options, owned by the
+// operator instance.
+func (r *Capi2Argo) deleteSealedSecrets(ctx context.Context, log logr.Logger, opts ...client.ListOption) (ctrl.Result, error) {
+	sealedList := &SealedSecretList{}
+	if err := r.argoClient().List(ctx, sealedList, opts...); err != nil {
+		log.Error(err, "Failed to list Cluster SealedSecrets")
+		return ctrl.Result{}, err
+	}
+	for i := range sealedList.Items {
+		if !r.Config.IsOwnInstance(&sealedList.Items[i]) {
+			continue
+		}
+		err := r.argoClient().Delete(ctx, &sealedList.Items[i])
+		clusterName := strings.TrimSuffix(sealedList.Items[i].Labels["capi-to-argocd/cluster-secret-name"], "-kubeconfig")
+		r.auditRecord(AuditActionDelete, &sealedList.Items[i], clusterName, nil, err)
+		if err != nil {
+			log.Error(err, "Failed to delete ArgoSealedSecret")
+			return ctrl.Result{}, err
+		}
+	}
+	log.Info("Deleted successfully of ArgoSealedSecret")
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestSealSecret(t *testing.T) {
+	t.Parallel()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	s := MockArgoSecret()
+	sealed, err := SealSecret(s, &key.PublicKey)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SealedSecret", sealed.Kind)
+	assert.Equal(t, "bitnami.com/v1alpha1", sealed.APIVersion)
+	assert.Equal(t, s.Name, sealed.Name)
+	assert.Equal(t, s.Namespace, sealed.Namespace)
+	assert.Equal(t, s.Name, sealed.Spec.Template.Name)
+	assert.Equal(t, s.Labels, sealed.Spec.Template.Labels)
+	assert.Equal(t, "cluster", sealed.Spec.Template.Labels["argocd.argoproj.io/secret-type"])
+	assert.Equal(t, HashSecretData(s.Data), sealed.Annotations[sealedSecretHashAnnotation])
+	assert.Len(t, sealed.Spec.EncryptedData, len(s.Data))
+
+	label := []byte(s.Namespace + "/" + s.Name)
+	for k, v := range sealed.Spec.EncryptedData {
+		raw, err := b64.StdEncoding.DecodeString(v)
+		assert.Nil(t, err)
+		plain, err := mockHybridDecrypt(key, raw, label)
+		assert.Nil(t, err)
+		assert.Equal(t, s.Data[k], plain)
+	}
+
+	// Encryption must be bound to the Secret namespace/name.
+	raw, _ := b64.StdEncoding.DecodeString(sealed.Spec.EncryptedData["name"])
+	_, err = mockHybridDecrypt(key, raw, []byte("other/name"))
+	assert.NotNil(t, err)
+}
+
+func TestSealedSecretKeyCache(t *testing.T) {
+	t.Parallel()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	calls := 0
+	failing := false
+	c := NewSealedSecretKeyCache(func(_ context.Context) (*rsa.PublicKey, error) {
+		calls++
+		if failing {
+			return nil, errors.New("controller unavailable")
+		}
+		return &key.PublicKey, nil
+	}, time.Hour)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	k, err := c.PublicKey(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, k)
+	_, _ = c.PublicKey(context.Background())
+	assert.Equal(t, 1, calls)
+
+	// Refresh after expiry, but keep serving the cached key on failures.
+	now = now.Add(2 * time.Hour)
+	failing = true
+	k, err = c.PublicKey(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, k)
+	assert.Equal(t, 2, calls)
+
+	empty := NewSealedSecretKeyCache(func(_ context.Context) (*rsa.PublicKey, error) {
+		return nil, errors.New("controller unavailable")
+	}, time.Hour)
+	_, err = empty.PublicKey(context.Background())
+	assert.NotNil(t, err)
+}
+
+func TestReconcileSealedSecret(t *testing.T) {
+	t.Parallel()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	r := &Capi2Argo{
+		Client: MockClient(),
+		Log:    TestLog,
+		Scheme: MockScheme(),
+		Config: MockOperatorConfig(),
+		SealedSecretKeys: NewSealedSecretKeyCache(func(_ context.Context) (*rsa.PublicKey, error) {
+			return &key.PublicKey, nil
+		}, time.Hour),
+	}
+
+	capiSecret := MockCapiSecret(true, true, true, "test-kubeconfig", TestNamespace)
+	argoSecret := MockArgoSecret()
+	nn := types.NamespacedName{Name: argoSecret.Name, Namespace: argoSecret.Namespace}
+	a := &ArgoCluster{NamespacedName: nn}
+	_, err = r.reconcileSealedSecret(context.Background(), r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+
+	created := &SealedSecret{}
+	assert.Nil(t, r.Get(context.Background(), nn, created))
+	assert.Equal(t, "true", created.Labels["capi-to-argocd/owned"])
+	assert.NotEmpty(t, created.Spec.Template.Annotations[firstSyncedAtAnnotation])
+	assert.Equal(t, SourceHash(capiSecret), created.Spec.Template.Annotations[sourceHashAnnotation])
+
+	// Unchanged data must not be re-sealed.
+	_, err = r.reconcileSealedSecret(context.Background(), r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+	current := &SealedSecret{}
+	assert.Nil(t, r.Get(context.Background(), nn, current))
+	assert.Equal(t, created.Spec.EncryptedData, current.Spec.EncryptedData)
+
+	argoSecret.Data["server"] = []byte("https://changed")
+	_, err = r.reconcileSealedSecret(context.Background(), r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Get(context.Background(), nn, current))
+	assert.NotEqual(t, created.Spec.EncryptedData["server"], current.Spec.EncryptedData["server"])
+	assert.Equal(t, HashSecretData(argoSecret.Data), current.Annotations[sealedSecretHashAnnotation])
+
+	// SealedSecrets of other operator instances are left alone.
+	other := &SealedSecret{}
+	assert.Nil(t, r.Get(context.Background(), nn, other))
+	other.Labels[instanceLabel] = "staging"
+	assert.Nil(t, r.Update(context.Background(), other))
+	argoSecret.Data["server"] = []byte("https://other")
+	_, err = r.reconcileSealedSecret(context.Background(), r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Get(context.Background(), nn, current))
+	assert.Equal(t, other.Spec.EncryptedData, current.Spec.EncryptedData)
+	assert.Equal(t, "staging", current.Labels[instanceLabel])
+
+	_, err = (&Capi2Argo{Log: TestLog}).reconcileSealedSecret(context.Background(), ctrl.Log, capiSecret, nil, a, argoSecret)
+	assert.NotNil(t, err)
+}
+
+func TestReconcileSealedSecretMetadata(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	changelog := NewChangelogRecorder(DefaultChangelogMaxEntries)
+	r := &Capi2Argo{
+		Client:    MockClient(),
+		Log:       TestLog,
+		Scheme:    MockScheme(),
+		Config:    MockOperatorConfig(),
+		Changelog: changelog,
+		SealedSecretKeys: NewSealedSecretKeyCache(func(_ context.Context) (*rsa.PublicKey, error) {
+			return &key.PublicKey, nil
+		}, time.Hour),
+	}
+	capiSecret := MockCapiSecret(true, true, true, "test-kubeconfig", TestNamespace)
+	argoSecret := MockArgoSecret()
+	argoSecret.Annotations = map[string]string{"team": "a"}
+	nn := types.NamespacedName{Name: argoSecret.Name, Namespace: argoSecret.Namespace}
+	a := &ArgoCluster{NamespacedName: nn}
+	_, err = r.reconcileSealedSecret(ctx, r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+
+	// Metadata added by other tools is kept.
+	created := &SealedSecret{}
+	assert.Nil(t, r.Get(ctx, nn, created))
+	created.Labels["backup"] = "daily"
+	created.Annotations["note"] = "kept"
+	assert.Nil(t, r.Update(ctx, created))
+
+	// Annotation-only changes reach the template, stale annotations get removed.
+	argoSecret = MockArgoSecret()
+	argoSecret.Annotations = map[string]string{"owner": "b"}
+	_, err = r.reconcileSealedSecret(ctx, r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+	current := &SealedSecret{}
+	assert.Nil(t, r.Get(ctx, nn, current))
+	assert.Equal(t, "b", current.Spec.Template.Annotations["owner"])
+	assert.NotContains(t, current.Spec.Template.Annotations, "team")
+	assert.NotContains(t, current.Annotations, "team")
+	assert.Equal(t, "b", current.Annotations["owner"])
+	assert.Equal(t, "kept", current.Annotations["note"])
+	assert.Equal(t, "daily", current.Labels["backup"])
+	assert.Equal(t, created.Spec.Template.Annotations[firstSyncedAtAnnotation], current.Spec.Template.Annotations[firstSyncedAtAnnotation])
+
+	// In-sync reconciles do not write, whatever the lifecycle annotations.
+	_, err = r.reconcileSealedSecret(ctx, r.Log, capiSecret, nil, a, argoSecret)
+	assert.Nil(t, err)
+	entries := changelog.Entries(nn)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, AuditActionCreate, entries[0].Action)
+		assert.Equal(t, AuditActionUpdate, entries[1].Action)
+		assert.Equal(t, []string{"annotations.owner", "annotations.team"}, entries[1].FieldsChanged)
+	}
+}
+
+func TestDeleteSealedSecrets(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	mock := func(name, instance string) *SealedSecret {
+		labels := map[string]string{clusterSecretNameLabel: "test-kubeconfig", clusterNamespaceLabel: TestNamespace}
+		if instance != "" {
+			labels[instanceLabel] = instance
+		}
+		return &SealedSecret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: TestArgoNamespace, Labels: labels}}
+	}
+	c := MockClient(mock("own", ""), mock("staging", "staging"))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.OutputFormat = OutputFormatSealedSecret
+
+	// Without an instance ID, SealedSecrets labeled by other instances are kept.
+	_, err := r.deleteArgoSecrets(ctx, r.Log, types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace})
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: "own", Namespace: TestArgoNamespace}, &SealedSecret{})))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "staging", Namespace: TestArgoNamespace}, &SealedSecret{}))
+}
+
+func TestReconcileSealedSecretReadinessGates(t *testing.T) {
+	t.Parallel()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(true, true, true, "sealed-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "sealed"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sealed", Namespace: TestNamespace}}
+	c := MockClient(capiSecret, cluster)
+	recorder := &MockRecorder{}
+	r := &Capi2Argo{
+		Client:   c,
+		Log:      TestLog,
+		Scheme:   MockScheme(),
+		Config:   MockOperatorConfig(),
+		Recorder: recorder,
+		SealedSecretKeys: NewSealedSecretKeyCache(func(_ context.Context) (*rsa.PublicKey, error) {
+			return &key.PublicKey, nil
+		}, time.Hour),
+	}
+	r.Config.OutputFormat = OutputFormatSealedSecret
+	req := MockReconcileReq("sealed-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("sealed-kubeconfig", TestNamespace)
+
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, readinessGatesRequeueAfter, result.RequeueAfter)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, nn, &SealedSecret{})))
+
+	// The SealedSecret gets created once the control plane is ready.
+	cluster.Status = MockReadyClusterStatus()
+	assert.Nil(t, c.Update(ctx, cluster))
+	result, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Zero(t, result.RequeueAfter)
+	assert.Nil(t, c.Get(ctx, nn, &SealedSecret{}))
+	assert.Equal(t, []string{EventReasonSkippedNotReady, EventReasonRegistered}, recorder.ForObject("Secret", "sealed-kubeconfig"))
+}
+
+// mockHybridDecrypt reverses hybridEncrypt the way the sealed-secrets controller does.
+func mockHybridDecrypt(key *rsa.PrivateKey, ciphertext, label []byte) ([]byte, error) {
+	if len(ciphertext) < 2 {
+		return nil, errors.New("ciphertext too short")
+	}
+	rsaLen := int(binary.BigEndian.Uint16(ciphertext))
+	if len(ciphertext) < rsaLen+2 {
+		return nil, errors.New("ciphertext too short")
+	}
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), nil, key, ciphertext[2:rsaLen+2], label)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[rsaLen+2:], nil)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// metadataHashAnnotation holds the hash of the CAPI Secret labels and annotations.
+	metadataHashAnnotation = "capi-to-argocd/metadata-hash"
+	// credentialsHashAnnotation holds the hash of the config the ArgoSecret got written with.
+	credentialsHashAnnotation = "capi-to-argocd/credentials-hash"
+	// sourceHashAnnotation holds the hash of the CAPI Secret content the ArgoSecret got synced from.
+	sourceHashAnnotation = "capi-to-argocd/source-hash"
+)
+
+// SourceHash returns the hash of the content of a CAPI Secret.
+func SourceHash(s *corev1.Secret) string {
+	return HashSecretData(s.Data)
+}
+
+// MetadataHash returns the hash of the labels and annotations of a CAPI Secret.
+func MetadataHash(s *corev1.Secret) string {
+	fields := make(map[string][]byte, len(s.Labels)+len(s.Annotations))
+	for k, v := range s.Labels {
+		fields["labels."+k] = []byte(v)
+	}
+	for k, v := range s.Annotations {
+		fields["annotations."+k] = []byte(v)
+	}
+	return HashSecretData(fields)
+}
+
+// CredentialsHash returns the hash of the rendered config of an ArgoSecret, so it changes with the
+// kubeconfig credentials as well as with every annotation, registration or flag the config depends on.
+func CredentialsHash(config []byte) string {
+	return HashSecretData(map[string][]byte{"config": config})
+}
+
+// setSecretHashes stamps an ArgoSecret with the metadata and credentials hashes it got built from.
+// It returns the names of the annotations that changed.
+func setSecretHashes(s *corev1.Secret, metadataHash, credentialsHash string) []string {
+	changed := []string{}
+	if s.Annotations == nil {
+		s.Annotations = map[string]string{}
+	}
+	for k, v := range map[string]string{metadataHashAnnotation: metadataHash, credentialsHashAnnotation: credentialsHash} {
+		if s.Annotations[k] != v {
+			s.Annotations[k] = v
+			changed = append(changed, "annotations."+k)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
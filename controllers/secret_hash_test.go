@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestSecretHashes(t *testing.T) {
+	t.Parallel()
+	s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"a": "b"}}}
+	metadataHash := MetadataHash(s)
+	assert.Equal(t, metadataHash, MetadataHash(s.DeepCopy()))
+	s.Annotations = map[string]string{"a": "b"}
+	assert.NotEqual(t, metadataHash, MetadataHash(s))
+
+	credentialsHash := CredentialsHash([]byte(`{"bearerToken":"token"}`))
+	assert.Equal(t, credentialsHash, CredentialsHash([]byte(`{"bearerToken":"token"}`)))
+	assert.NotEqual(t, credentialsHash, CredentialsHash([]byte(`{"bearerToken":"other"}`)))
+
+	argoSecret := &corev1.Secret{}
+	assert.Equal(t, []string{"annotations.capi-to-argocd/credentials-hash", "annotations.capi-to-argocd/metadata-hash"},
+		setSecretHashes(argoSecret, metadataHash, credentialsHash))
+	assert.Empty(t, setSecretHashes(argoSecret, metadataHash, credentialsHash))
+}
+
+func TestReconcileSkipTLSRotationIfMatching(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(true, true, true, "hashes-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "hashes"}
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "hashes", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+	var calls []string
+	c := fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(capiSecret, cluster).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if obj.GetNamespace() == TestArgoNamespace {
+					calls = append(calls, "Update")
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				calls = append(calls, "Patch")
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.SkipTLSRotationIfMatching = true
+	req := MockReconcileReq("hashes-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("hashes-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	metadataHash := argoSecret.Annotations["capi-to-argocd/metadata-hash"]
+	credentialsHash := argoSecret.Annotations["capi-to-argocd/credentials-hash"]
+	assert.NotEmpty(t, metadataHash)
+	assert.NotEmpty(t, credentialsHash)
+	config := argoSecret.Data["config"]
+
+	// Metadata changes only patch metadata, credentials are not compared.
+	argoSecret.Data["config"] = []byte("{}")
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+	assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+	capiSecret.Annotations = map[string]string{"churn": "1"}
+	assert.Nil(t, c.Update(ctx, capiSecret))
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Patch"}, calls)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.NotEqual(t, metadataHash, argoSecret.Annotations["capi-to-argocd/metadata-hash"])
+	assert.Equal(t, credentialsHash, argoSecret.Annotations["capi-to-argocd/credentials-hash"])
+	assert.Equal(t, "{}", string(argoSecret.Data["config"]))
+
+	// Data changes outside the config do a full update, leaving the config alone.
+	assert.Nil(t, c.Get(ctx, req.NamespacedName, capiSecret))
+	capiSecret.Data["value"] = []byte(strings.Replace(string(capiSecret.Data["value"]), "server: https://", "server: https://rotated.", 1))
+	assert.Nil(t, c.Update(ctx, capiSecret))
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Update"}, calls)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.Equal(t, credentialsHash, argoSecret.Annotations["capi-to-argocd/credentials-hash"])
+	assert.Equal(t, "{}", string(argoSecret.Data["config"]))
+	assert.Contains(t, string(argoSecret.Data["server"]), "https://rotated.")
+
+	// Config changes from Cluster annotations rewrite the config.
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "hashes", Namespace: TestNamespace}, cluster))
+	cluster.Annotations = map[string]string{tlsServerNameAnnotation: "hashes.example.com"}
+	assert.Nil(t, c.Update(ctx, cluster))
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Update"}, calls)
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	assert.NotEqual(t, credentialsHash, argoSecret.Annotations["capi-to-argocd/credentials-hash"])
+	assert.NotEqual(t, config, argoSecret.Data["config"])
+	assert.Contains(t, string(argoSecret.Data["config"]), "hashes.example.com")
+
+	// In-sync reconciles do not write.
+	calls = nil
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Empty(t, calls)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// operatorKeyDomain is part of every label and annotation key owned by the operator.
+const operatorKeyDomain = "capi-to-argocd"
+
+// staleArgoSecrets returns the ArgoSecrets and token Secrets generated from a CAPI Secret under
+// other names, or in other namespaces, than the current ones of a, left behind by a change of
+// the naming scheme.
+func (r *Capi2Argo) staleArgoSecrets(ctx context.Context, capiSecret *corev1.Secret, a *ArgoCluster) ([]corev1.Secret, error) {
+	secrets, err := r.listArgoSecrets(ctx, capiSecret.Name, capiSecret.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	stale := []corev1.Secret{}
+	for _, s := range secrets {
+		if r.Config.ValidateOwner(s) != nil {
+			continue
+		}
+		// Copies in fan-out namespaces are current as well.
+		current := s.Namespace == a.NamespacedName.Namespace || slices.Contains(ParseNamespaces(r.Config.FanOutArgoNamespaces), s.Namespace)
+		if current && s.Name == a.NamespacedName.Name {
+			continue
+		}
+		if ref := a.ClusterConfig.BearerTokenSecret; ref != nil && current && s.Name == ref.SecretName {
+			continue
+		}
+		stale = append(stale, s)
+	}
+	return stale, nil
+}
+
+// CarryForeignMetadata copies the labels and annotations added to a previous ArgoSecret by other
+// tools onto the ArgoSecret replacing it, along with its first-synced-at annotation. Keys owned
+// by the operator, including those taken along, are left out as they get rebuilt.
+func CarryForeignMetadata(from, to *corev1.Secret) {
+	if to.Labels == nil {
+		to.Labels = map[string]string{}
+	}
+	if to.Annotations == nil {
+		to.Annotations = map[string]string{}
+	}
+	labelMarkers := append([]string{clusterTakenFromClusterKey}, takenFromLabelPrefixes()...)
+	for k, v := range foreignKeys(from.Labels, labelMarkers) {
+		if _, ok := to.Labels[k]; !ok && k != argoSecretTypeLabel {
+			to.Labels[k] = v
+		}
+	}
+	for k, v := range foreignKeys(from.Annotations, takenFromAnnotationPrefixes()) {
+		if _, ok := to.Annotations[k]; !ok {
+			to.Annotations[k] = v
+		}
+	}
+	if v, ok := from.Annotations[firstSyncedAtAnnotation]; ok {
+		to.Annotations[firstSyncedAtAnnotation] = v
+	}
+}
+
+// foreignKeys returns the keys not owned by the operator, skipping those marked as taken along by
+// a marker of prefixes.
+func foreignKeys(keys map[string]string, prefixes []string) map[string]string {
+	marked := map[string]bool{}
+	for k := range keys {
+		if key, ok := trimTakenFromPrefix(k, prefixes); ok {
+			marked[key] = true
+		}
+	}
+	foreign := map[string]string{}
+	for k, v := range keys {
+		if !marked[k] && !strings.Contains(k, operatorKeyDomain) {
+			foreign[k] = v
+		}
+	}
+	return foreign
+}
+
+// deleteStaleArgoSecrets deletes the ArgoSecrets of a cluster replaced under a new name.
+func (r *Capi2Argo) deleteStaleArgoSecrets(ctx context.Context, log logr.Logger, a *ArgoCluster, stale []corev1.Secret) error {
+	for i := range stale {
+		err := r.argoClient().Delete(ctx, &stale[i])
+		if errors.IsNotFound(err) {
+			continue
+		}
+		r.auditRecord(AuditActionDelete, &stale[i], a.ClusterName, nil, err)
+		r.changelogRecord(AuditActionDelete, &stale[i], nil, "", err)
+		if err != nil {
+			log.Error(err, "Failed to delete ArgoSecret of previous naming scheme", "name", stale[i].Name)
+			return err
+		}
+		log.Info("Migrated ArgoSecret of previous naming scheme", "name", stale[i].Name)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCarryForeignMetadata(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName                string
+		testLabels              map[string]string
+		testAnnotations         map[string]string
+		testExpectedLabels      map[string]string
+		testExpectedAnnotations map[string]string
+	}{
+		{"test foreign metadata",
+			map[string]string{"appset.example.com/env": "prod"}, map[string]string{"example.com/contact": "team-a"},
+			map[string]string{"appset.example.com/env": "prod"}, map[string]string{"example.com/contact": "team-a"}},
+		{"test operator-owned metadata",
+			map[string]string{argoSecretTypeLabel: "cluster", ownedLabel: "true", clusterSecretNameLabel: "old", kubernetesVersionLabel: "v1.29.0"},
+			map[string]string{lastUpdatedAtAnnotation: "2024-01-01T00:00:00Z", projectAnnotation: "old"},
+			map[string]string{}, map[string]string{}},
+		{"test taken along metadata",
+			map[string]string{"env": "prod", clusterTakenFromClusterKey + "env": "", "team": "a", clusterTakenFromNamespaceKey + "team": ""},
+			map[string]string{"owner": "a", clusterTakenFromClusterAnnotationKey + "owner": ""},
+			map[string]string{}, map[string]string{}},
+		{"test first synced at", nil, map[string]string{firstSyncedAtAnnotation: "2024-01-01T00:00:00Z"},
+			map[string]string{}, map[string]string{firstSyncedAtAnnotation: "2024-01-01T00:00:00Z"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			from := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: tt.testLabels, Annotations: tt.testAnnotations}}
+			to := &corev1.Secret{}
+			CarryForeignMetadata(from, to)
+			assert.Equal(t, tt.testExpectedLabels, to.Labels)
+			assert.Equal(t, tt.testExpectedAnnotations, to.Annotations)
+		})
+	}
+
+	// Keys of the new ArgoSecret are never overwritten, except the first sync time.
+	from := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"env": "stage"}, Annotations: map[string]string{firstSyncedAtAnnotation: "2024-01-01T00:00:00Z"}}}
+	to := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"env": "prod"}, Annotations: map[string]string{firstSyncedAtAnnotation: "2024-02-01T00:00:00Z"}}}
+	CarryForeignMetadata(from, to)
+	assert.Equal(t, "prod", to.Labels["env"])
+	assert.Equal(t, "2024-01-01T00:00:00Z", to.Annotations[firstSyncedAtAnnotation])
+}
+
+func TestReconcileMigrateSecretNames(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(validMock, validType, validKey, "migrated-kubeconfig", TestNamespace))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	req := MockReconcileReq("migrated-kubeconfig", TestNamespace)
+	old := types.NamespacedName{Name: "cluster-migrated", Namespace: TestArgoNamespace}
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, old, &argoSecret))
+	firstSyncedAt := argoSecret.Annotations[firstSyncedAtAnnotation]
+	argoSecret.Labels["appset.example.com/env"] = "prod"
+	assert.Nil(t, c.Update(ctx, &argoSecret))
+
+	// Without migration, the ArgoSecret under the previous name stays behind.
+	r.Config.ArgoSecretNamePrefix = "argo-"
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, old, &argoSecret))
+
+	r.Config.ArgoSecretNamePrefix = "capi-"
+	r.Config.MigrateSecretNames = true
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, old, &argoSecret)))
+	assert.True(t, apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Name: "argo-migrated", Namespace: TestArgoNamespace}, &argoSecret)))
+	assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: "capi-migrated", Namespace: TestArgoNamespace}, &argoSecret))
+	assert.Equal(t, "prod", argoSecret.Labels["appset.example.com/env"])
+	assert.Equal(t, firstSyncedAt, argoSecret.Annotations[firstSyncedAtAnnotation])
+}
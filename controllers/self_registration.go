@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// SelfRegistrationExternal registers the management cluster like any other cluster, through
+	// the endpoint of its kubeconfig.
+	SelfRegistrationExternal = "external"
+	// SelfRegistrationInCluster registers the management cluster as the in-cluster of ArgoCD,
+	// reached through the Kubernetes service with the credentials of ArgoCD itself.
+	SelfRegistrationInCluster = "in-cluster"
+	// SelfRegistrationSkip leaves the management cluster out of ArgoCD.
+	SelfRegistrationSkip = "skip"
+
+	// InClusterServer is the server of the cluster ArgoCD runs on.
+	InClusterServer = "https://kubernetes.default.svc"
+	// inClusterName is the name ArgoCD gives the cluster it runs on.
+	inClusterName = "in-cluster"
+)
+
+// ValidateSelfRegistration returns an error for unknown self-registration modes.
+func ValidateSelfRegistration(mode string) error {
+	switch mode {
+	case SelfRegistrationExternal, SelfRegistrationInCluster, SelfRegistrationSkip:
+		return nil
+	}
+	return fmt.Errorf("unknown self-registration mode %q, must be one of: %s, %s, %s", mode, SelfRegistrationExternal, SelfRegistrationInCluster, SelfRegistrationSkip)
+}
+
+// ValidateManagementCluster returns an error when OperatorConfig.ManagementCluster is set but
+// not in the form <namespace>/<name>.
+func (c OperatorConfig) ValidateManagementCluster() error {
+	if c.ManagementCluster == "" {
+		return nil
+	}
+	namespace, name, ok := strings.Cut(c.ManagementCluster, "/")
+	if !ok || len(validation.IsDNS1123Label(namespace)) > 0 || len(validation.IsDNS1123Subdomain(name)) > 0 {
+		return fmt.Errorf("invalid management cluster %q, must be <namespace>/<name>", c.ManagementCluster)
+	}
+	return nil
+}
+
+// IsManagementCluster reports whether a cluster is the management cluster ArgoCD runs on.
+func (c OperatorConfig) IsManagementCluster(namespace, name string) bool {
+	return c.ManagementCluster != "" && c.ManagementCluster == namespace+"/"+name
+}
+
+// SetInCluster turns an ArgoCluster into the in-cluster of ArgoCD: its server becomes the
+// Kubernetes service and credentials are dropped, as ArgoCD uses its own service account. The
+// name is kept when overridden by annotation.
+func (a *ArgoCluster) SetInCluster() {
+	a.ClusterServer = InClusterServer
+	a.ClusterConfig = ArgoConfig{TLSClientConfig: &ArgoTLS{}}
+	a.ReferencedToken = nil
+	if !a.NameOverridden {
+		a.ClusterName = inClusterName
+	}
+}
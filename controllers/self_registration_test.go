@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestValidateSelfRegistration(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ValidateSelfRegistration(SelfRegistrationExternal))
+	assert.Nil(t, ValidateSelfRegistration(SelfRegistrationInCluster))
+	assert.Nil(t, ValidateSelfRegistration(SelfRegistrationSkip))
+	assert.NotNil(t, ValidateSelfRegistration("local"))
+}
+
+func TestManagementCluster(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedError  bool
+		testExpectedValues bool
+	}{
+		{"test unset", "", false, false},
+		{"test management cluster", TestNamespace + "/mgmt", false, true},
+		{"test other cluster", TestNamespace + "/other", false, false},
+		{"test missing namespace", "mgmt", true, false},
+		{"test invalid name", TestNamespace + "/Mgmt_1", true, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.ManagementCluster = tt.testMock
+			err := c.ValidateManagementCluster()
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.testExpectedValues, c.IsManagementCluster(TestNamespace, "mgmt"))
+		})
+	}
+}
+
+func TestSetInCluster(t *testing.T) {
+	t.Parallel()
+	token := "token"
+	a := &ArgoCluster{
+		ClusterName:     "mgmt",
+		ClusterServer:   "https://mgmt.example.com:6443",
+		ClusterConfig:   ArgoConfig{BearerTokenSecret: &ArgoTokenRef{SecretName: "mgmt-token", Key: "token"}},
+		ReferencedToken: &token,
+	}
+	a.SetInCluster()
+	assert.Equal(t, inClusterName, a.ClusterName)
+	assert.Equal(t, InClusterServer, a.ClusterServer)
+	assert.Equal(t, ArgoConfig{TLSClientConfig: &ArgoTLS{}}, a.ClusterConfig)
+	assert.Nil(t, a.ReferencedToken)
+
+	a = &ArgoCluster{ClusterName: "platform", NameOverridden: true}
+	a.SetInCluster()
+	assert.Equal(t, "platform", a.ClusterName)
+}
+
+func TestReconcileSelfRegistration(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues bool
+	}{
+		{"test external", SelfRegistrationExternal, true},
+		{"test in-cluster", SelfRegistrationInCluster, true},
+		{"test skip", SelfRegistrationSkip, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "mgmt-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "mgmt"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "mgmt", Namespace: TestNamespace}, Status: MockReadyClusterStatus()}
+			c := MockClient(capiSecret, cluster)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			r.Config.ManagementCluster = TestNamespace + "/mgmt"
+			r.Config.SelfRegistration = tt.testMock
+			nn := r.Config.BuildNamespacedName("mgmt-kubeconfig", TestNamespace)
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("mgmt-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			err = c.Get(ctx, nn, &argoSecret)
+			if !tt.testExpectedValues {
+				assert.True(t, apierrors.IsNotFound(err))
+				return
+			}
+			assert.Nil(t, err)
+			if tt.testMock == SelfRegistrationInCluster {
+				assert.Equal(t, InClusterServer, string(argoSecret.Data["server"]))
+				assert.Equal(t, inClusterName, string(argoSecret.Data["name"]))
+				assert.NotContains(t, string(argoSecret.Data["config"]), "bearerToken")
+			} else {
+				assert.NotEqual(t, InClusterServer, string(argoSecret.Data["server"]))
+			}
+		})
+	}
+}
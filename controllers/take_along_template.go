@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// clusterTakeAlongTemplateKey prefixes the CAPI Cluster annotations holding a Go template whose
+// rendering is taken along as label: take-along-template.capi-to-argocd.<label-key>: <template>.
+const clusterTakeAlongTemplateKey = "take-along-template.capi-to-argocd."
+
+// TemplateData holds the CAPI Cluster fields take-along templates are rendered with.
+type TemplateData struct {
+	Name               string
+	Namespace          string
+	TopologyVersion    string
+	InfrastructureKind string
+	ControlPlaneKind   string
+	Labels             map[string]string
+	Annotations        map[string]string
+}
+
+// NewTemplateData returns the TemplateData of a CAPI Cluster.
+func NewTemplateData(cluster CAPICluster) TemplateData {
+	data := TemplateData{
+		Name:            cluster.GetName(),
+		Namespace:       cluster.GetNamespace(),
+		TopologyVersion: cluster.GetTopologyVersion(),
+		Labels:          cluster.GetLabels(),
+		Annotations:     cluster.GetAnnotations(),
+	}
+	if ref := cluster.GetInfrastructureRef(); ref != nil {
+		data.InfrastructureKind = ref.Kind
+	}
+	if ref := cluster.GetControlPlaneRef(); ref != nil {
+		data.ControlPlaneKind = ref.Kind
+	}
+	return data
+}
+
+// templateFuncs returns the functions available to take-along templates, on top of the builtin ones.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	}
+}
+
+// RenderTakeAlongTemplate renders a take-along template with the fields of a CAPI Cluster.
+// Missing map keys are errors rather than rendering as "<no value>".
+func RenderTakeAlongTemplate(text string, data TemplateData) (string, error) {
+	tmpl, err := template.New("take-along").Funcs(templateFuncs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// buildTemplateLabels returns the labels rendered from the take-along-template annotations of a
+// cluster, along with a taken-from marker for each of them. Value transformers apply to rendered
+// values like to copied ones.
+func buildTemplateLabels(rc *ReconcileContext, cluster CAPICluster) (map[string]string, []string) {
+	name := cluster.GetName()
+	namespace := cluster.GetNamespace()
+	data := NewTemplateData(cluster)
+
+	keys := []string{}
+	for k := range cluster.GetAnnotations() {
+		if strings.HasPrefix(k, clusterTakeAlongTemplateKey) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	labels := map[string]string{}
+	errors := []string{}
+	for _, k := range keys {
+		label := strings.TrimPrefix(k, clusterTakeAlongTemplateKey)
+		if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+			errors = append(errors, fmt.Sprintf("Warning: invalid take-along template label '%s' on cluster resource: %s, namespace: %s: %s. Ignoring", label, name, namespace, strings.Join(errs, "; ")))
+			continue
+		}
+		if takeAlongDepth(label) > 1 || IsReservedLabel(label) || strings.HasPrefix(label, clusterTakenFromClusterKey) {
+			errors = append(errors, fmt.Sprintf("Warning: take-along template label '%s' is reserved by the operator on cluster resource: %s, namespace: %s. Ignoring", label, name, namespace))
+			continue
+		}
+		value, err := RenderTakeAlongTemplate(cluster.GetAnnotations()[k], data)
+		if err == nil {
+			value, err = ApplyValuePipeline(rc.Pipeline, label, value)
+		}
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Warning: failed to render take-along template label '%s' on cluster resource: %s, namespace: %s: %v. Ignoring", label, name, namespace, err))
+			continue
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			errors = append(errors, fmt.Sprintf("Warning: take-along template label '%s' rendered to invalid value '%s' on cluster resource: %s, namespace: %s: %s. Ignoring", label, value, name, namespace, strings.Join(errs, "; ")))
+			continue
+		}
+		labels[label] = value
+		labels[clusterTakenFromClusterKey+label] = ""
+	}
+	return labels, errors
+}
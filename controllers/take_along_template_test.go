@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestBuildTemplateLabels(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           map[string]string
+		testExpectedError  bool
+		testExpectedValues map[string]string
+	}{
+		{"test no templates", map[string]string{"foo": "bar"}, false, map[string]string{}},
+		{"test cluster fields", map[string]string{
+			clusterTakeAlongTemplateKey + "cluster":             "{{ .Namespace }}.{{ .Name }}",
+			clusterTakeAlongTemplateKey + "example.com/version": "{{ .TopologyVersion }}",
+			clusterTakeAlongTemplateKey + "provider":            `{{ .InfrastructureKind | lower | trimSuffix "cluster" }}`,
+			clusterTakeAlongTemplateKey + "tier":                `{{ index .Labels "tier" | upper }}-{{ .ControlPlaneKind }}`,
+		}, false, map[string]string{
+			"cluster":                              "test.test",
+			"example.com/version":                  "v1.28.0",
+			"provider":                             "aws",
+			"tier":                                 "GOLD-KubeadmControlPlane",
+			clusterTakenFromClusterKey + "cluster": "",
+			clusterTakenFromClusterKey + "example.com/version": "",
+			clusterTakenFromClusterKey + "provider":            "",
+			clusterTakenFromClusterKey + "tier":                "",
+		}},
+		{"test missing key", map[string]string{clusterTakeAlongTemplateKey + "env": `{{ .Labels.env }}`}, true, map[string]string{}},
+		{"test invalid template", map[string]string{clusterTakeAlongTemplateKey + "env": `{{ .Name `}, true, map[string]string{}},
+		{"test invalid value", map[string]string{clusterTakeAlongTemplateKey + "env": `{{ .Name }}/{{ .Namespace }}`}, true, map[string]string{}},
+		{"test reserved label", map[string]string{clusterTakeAlongTemplateKey + "argocd.argoproj.io/secret-type": "{{ .Name }}"}, true, map[string]string{}},
+		{"test invalid label", map[string]string{clusterTakeAlongTemplateKey + "in valid": "{{ .Name }}"}, true, map[string]string{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Labels: map[string]string{"tier": "gold"}, Annotations: tt.testMock},
+				Spec: clusterv1.ClusterSpec{
+					Topology:          &clusterv1.Topology{Class: "default", Version: "v1.28.0"},
+					InfrastructureRef: &corev1.ObjectReference{Kind: "AWSCluster"},
+					ControlPlaneRef:   &corev1.ObjectReference{Kind: "KubeadmControlPlane"},
+				},
+			}
+			v, errors := buildTemplateLabels(MockReconcileContext(MockOperatorConfig()), &V1Beta1ClusterAdapter{cluster})
+			if tt.testExpectedError {
+				assert.NotEmpty(t, errors)
+			} else {
+				assert.Empty(t, errors)
+			}
+			assert.Equal(t, tt.testExpectedValues, v)
+		})
+	}
+}
+
+func TestNewArgoClusterTemplateLabels(t *testing.T) {
+	t.Parallel()
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+		Labels: map[string]string{"env": "prod", clusterTakeAlongKey + "env": ""},
+		Annotations: map[string]string{
+			clusterTakeAlongTemplateKey + "env":     "{{ .Name }}",
+			clusterTakeAlongTemplateKey + "cluster": "{{ .Namespace }}-{{ .Name }}",
+		},
+	}}
+	s := MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", "test")
+	c := NewCapiCluster("test", "test")
+	assert.Nil(t, c.Unmarshal(s, DefaultKubeConfigKey))
+	a, err := NewArgoCluster(MockReconcileContext(MockOperatorConfig()), c, s, &V1Beta1ClusterAdapter{cluster})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"env":                                  "prod",
+		"cluster":                              "test-test",
+		clusterTakenFromClusterKey + "env":     "",
+		clusterTakenFromClusterKey + "cluster": "",
+	}, a.TakeAlongLabels)
+}
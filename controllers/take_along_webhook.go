@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// TakeAlongWebhookPath is the path the take-along validating webhook is served on.
+	TakeAlongWebhookPath = "/validate-take-along"
+
+	// TakeAlongWebhookWarn admits Clusters with malformed take-along keys, returning warnings to
+	// the client, and TakeAlongWebhookReject denies them.
+	TakeAlongWebhookWarn   = "warn"
+	TakeAlongWebhookReject = "reject"
+)
+
+// ValidateTakeAlongWebhookMode returns an error for unknown take-along webhook modes.
+func ValidateTakeAlongWebhookMode(mode string) error {
+	switch mode {
+	case TakeAlongWebhookWarn, TakeAlongWebhookReject:
+		return nil
+	}
+	return fmt.Errorf("unknown take-along webhook mode %q, must be one of: %s, %s", mode, TakeAlongWebhookWarn, TakeAlongWebhookReject)
+}
+
+// ValidateTakeAlongKeys returns the syntax errors of the take-along directives in the labels and
+// annotations of a CAPI Cluster, with take-along-label directives prefixed with labelKey. Whether
+// selected keys exist is not checked, as they may be added later.
+func ValidateTakeAlongKeys(labelKey string, labels, annotations map[string]string) []string {
+	errors := []string{}
+	for k, v := range labels {
+		label, ok := strings.CutPrefix(k, labelKey)
+		if !ok {
+			continue
+		}
+		if label == "" {
+			errors = append(errors, fmt.Sprintf("label %s: missing key after '%s'", k, labelKey))
+			continue
+		}
+		if IsReservedLabel(label) {
+			errors = append(errors, fmt.Sprintf("label %s: '%s' is reserved by the operator", k, label))
+		}
+		if v != "" {
+			if errs := validation.IsQualifiedName(v); len(errs) > 0 {
+				errors = append(errors, fmt.Sprintf("label %s: invalid target '%s': %s", k, v, strings.Join(errs, "; ")))
+			} else if takeAlongDepth(v) > 1 || IsReservedLabel(v) || strings.HasPrefix(v, "taken-from-") {
+				errors = append(errors, fmt.Sprintf("label %s: target '%s' is reserved by the operator", k, v))
+			}
+		}
+	}
+
+	referenced := []string{clusterTakeAlongAnnotationKey}
+	for _, t := range ReferencedTakeAlongs() {
+		referenced = append(referenced, t.LabelKey, t.AnnotationKey)
+	}
+	for k, v := range annotations {
+		if key, ok := strings.CutPrefix(k, clusterTakeAlongTemplateKey); ok {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				errors = append(errors, fmt.Sprintf("annotation %s: invalid label '%s': %s", k, key, strings.Join(errs, "; ")))
+			} else if takeAlongDepth(key) > 1 || IsReservedLabel(key) || strings.HasPrefix(key, "taken-from-") {
+				errors = append(errors, fmt.Sprintf("annotation %s: '%s' is reserved by the operator", k, key))
+			}
+			if _, err := template.New(k).Funcs(templateFuncs()).Parse(v); err != nil {
+				errors = append(errors, fmt.Sprintf("annotation %s: invalid template: %v", k, err))
+			}
+			continue
+		}
+		if strategy, ok := strings.CutPrefix(k, conflictStrategyAnnotationPrefix); ok && strategy != "" {
+			if s := ConflictResolutionStrategy(v); s != CAPIWins && s != ArgoWins && s != ConflictError {
+				errors = append(errors, fmt.Sprintf("annotation %s: unknown conflict strategy '%s'", k, v))
+			}
+			continue
+		}
+		if k == takeAllLabelsAnnotation {
+			if _, err := strconv.ParseBool(v); err != nil {
+				errors = append(errors, fmt.Sprintf("annotation %s: invalid boolean '%s'", k, v))
+			}
+			continue
+		}
+		for _, prefix := range referenced {
+			key, ok := strings.CutPrefix(k, prefix)
+			if !ok {
+				continue
+			}
+			if key == "" {
+				errors = append(errors, fmt.Sprintf("annotation %s: missing key after '%s'", k, prefix))
+			} else if IsReservedLabel(key) || strings.HasPrefix(key, operatorAnnotationPrefix) || strings.HasPrefix(key, "taken-from-") {
+				errors = append(errors, fmt.Sprintf("annotation %s: '%s' is reserved by the operator", k, key))
+			}
+		}
+	}
+	slices.Sort(errors)
+	return errors
+}
+
+// TakeAlongWebhook validates the take-along directives of CAPI Clusters at admission time. As it
+// only reads metadata, it serves every Cluster API version.
+type TakeAlongWebhook struct {
+	// Mode is either TakeAlongWebhookWarn or TakeAlongWebhookReject.
+	Mode string
+	// LabelKey prefixes take-along-label directives, see OperatorConfig.TakeAlongLabelKey.
+	LabelKey string
+}
+
+// Handle admits a Cluster, warning on or denying malformed take-along keys depending on the mode.
+func (w *TakeAlongWebhook) Handle(_ context.Context, req admission.Request) admission.Response {
+	if len(req.Object.Raw) == 0 {
+		return admission.Allowed("")
+	}
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	errors := ValidateTakeAlongKeys(w.LabelKey, obj.Labels, obj.Annotations)
+	if len(errors) == 0 {
+		return admission.Allowed("")
+	}
+	if w.Mode == TakeAlongWebhookReject {
+		return admission.Denied("malformed take-along keys: " + strings.Join(errors, ", "))
+	}
+	warnings := make([]string, 0, len(errors))
+	for _, e := range errors {
+		warnings = append(warnings, "malformed take-along key: "+e)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
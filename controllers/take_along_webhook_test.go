@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidateTakeAlongKeys(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testLabels        map[string]string
+		testAnnotations   map[string]string
+		testExpectedError int
+	}{
+		{"test valid keys",
+			map[string]string{clusterTakeAlongKey + "env": "", clusterTakeAlongKey + "example.com/team": "team", "env": "prod"},
+			map[string]string{clusterTakeAlongAnnotationKey + "owner": "", clusterTakeAlongTemplateKey + "cluster": "{{ .Name | lower }}", takeAllLabelsAnnotation: "true",
+				conflictStrategyAnnotationPrefix + "env": "ArgoWins", clusterTakeAlongInfraLabelKey + "account": "", clusterTakeAlongCPAnnotationKey + "channel": ""}, 0},
+		{"test missing keys", map[string]string{clusterTakeAlongKey: ""}, map[string]string{clusterTakeAlongAnnotationKey: "", clusterTakeAlongInfraLabelKey: ""}, 3},
+		{"test reserved keys", map[string]string{clusterTakeAlongKey + argoSecretTypeLabel: ""},
+			map[string]string{clusterTakeAlongAnnotationKey + operatorAnnotationPrefix + "project": "", clusterTakeAlongTemplateKey + clusterTakenFromClusterKey + "env": "x"}, 3},
+		{"test invalid target", map[string]string{clusterTakeAlongKey + "env": "taken-from-cluster-label.capi-to-argocd.env", clusterTakeAlongKey + "team": "-team"}, nil, 2},
+		{"test invalid values", nil, map[string]string{clusterTakeAlongTemplateKey + "cluster": "{{ .Name", takeAllLabelsAnnotation: "yes", conflictStrategyAnnotationPrefix + "env": "Nobody"}, 3},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			assert.Len(t, ValidateTakeAlongKeys(clusterTakeAlongKey, tt.testLabels, tt.testAnnotations), tt.testExpectedError)
+		})
+	}
+}
+
+func TestTakeAlongWebhook(t *testing.T) {
+	t.Parallel()
+	request := func(labels map[string]string) admission.Request {
+		raw, _ := json.Marshal(&clusterv1.Cluster{
+			TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: TestNamespace, Labels: labels},
+		})
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create, Object: runtime.RawExtension{Raw: raw}}}
+	}
+	malformed := map[string]string{clusterTakeAlongKey: ""}
+	tests := []struct {
+		testName            string
+		testMode            string
+		testRequest         admission.Request
+		testExpectedAllowed bool
+		testExpectedWarning bool
+	}{
+		{"test valid cluster", TakeAlongWebhookReject, request(map[string]string{clusterTakeAlongKey + "env": ""}), true, false},
+		{"test warn", TakeAlongWebhookWarn, request(malformed), true, true},
+		{"test reject", TakeAlongWebhookReject, request(malformed), false, false},
+		{"test delete", TakeAlongWebhookReject, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Delete}}, true, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			w := &TakeAlongWebhook{Mode: tt.testMode, LabelKey: clusterTakeAlongKey}
+			resp := w.Handle(context.Background(), tt.testRequest)
+			assert.Equal(t, tt.testExpectedAllowed, resp.Allowed)
+			assert.Equal(t, tt.testExpectedWarning, len(resp.Warnings) > 0)
+		})
+	}
+}
+
+func TestValidateTakeAlongWebhookMode(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ValidateTakeAlongWebhookMode(TakeAlongWebhookWarn))
+	assert.Nil(t, ValidateTakeAlongWebhookMode(TakeAlongWebhookReject))
+	assert.NotNil(t, ValidateTakeAlongWebhookMode("deny"))
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ArgoTenantMappingGVK returns the GroupVersionKind of ArgoTenantMappings.
+func ArgoTenantMappingGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "capi2argo.dntosas.io", Version: "v1alpha1", Kind: "ArgoTenantMapping"}
+}
+
+// ArgoTenantMappingListGVK returns the GroupVersionKind of ArgoTenantMapping lists.
+func ArgoTenantMappingListGVK() schema.GroupVersionKind {
+	gvk := ArgoTenantMappingGVK()
+	gvk.Kind += "List"
+	return gvk
+}
+
+// TenantMapping is the spec of a cluster-scoped ArgoTenantMapping, routing the CAPI Clusters it
+// matches to an Argo namespace and project. Annotations of a Cluster and ArgoClusterRegistrations
+// take precedence.
+type TenantMapping struct {
+	// Name is the name of the ArgoTenantMapping.
+	Name string `json:"-"`
+	// Namespaces are glob patterns of the namespaces of matched clusters, all when empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ClusterSelector selects matched clusters by label, all when unset.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// ArgoNamespace is the namespace ArgoSecrets are written to, one of
+	// OperatorConfig.ArgoNamespaces.
+	ArgoNamespace string `json:"argoNamespace,omitempty"`
+	Project       string `json:"project,omitempty"`
+}
+
+// Matches reports whether a cluster of a namespace is routed by the mapping. Without Cluster
+// object, as for hosted control planes, only mappings without selector match.
+func (m *TenantMapping) Matches(namespace string, cluster CAPICluster) (bool, error) {
+	if len(m.Namespaces) > 0 && !matchesAnyPattern(m.Namespaces, namespace) {
+		return false, nil
+	}
+	if m.ClusterSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(m.ClusterSelector)
+	if err != nil || cluster == nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(cluster.GetLabels())), nil
+}
+
+// readTenantMapping returns the ArgoTenantMapping routing a cluster of a namespace, nil when none
+// matches or the CRD is not installed. With several matching, the first by name wins. Mappings
+// with an invalid selector are skipped with a warning.
+func (r *Capi2Argo) readTenantMapping(ctx context.Context, log logr.Logger, namespace string, cluster CAPICluster) (*TenantMapping, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(ArgoTenantMappingListGVK())
+	if err := r.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+	var mapping *TenantMapping
+	for _, item := range list.Items {
+		spec, _, err := unstructured.NestedMap(item.Object, "spec")
+		if err != nil {
+			return nil, err
+		}
+		m := &TenantMapping{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, m); err != nil {
+			return nil, err
+		}
+		m.Name = item.GetName()
+		matches, err := m.Matches(namespace, cluster)
+		if err != nil {
+			log.Info("Warning: invalid clusterSelector of ArgoTenantMapping. Ignoring", "name", m.Name, "error", err)
+			continue
+		}
+		if !matches {
+			continue
+		}
+		if mapping != nil {
+			log.Info("Warning: multiple ArgoTenantMappings match cluster. Ignoring", "name", m.Name, "using", mapping.Name)
+			continue
+		}
+		mapping = m
+	}
+	return mapping, nil
+}
+
+// mapTenantMapping maps ArgoTenantMappings to a reconcile of every CAPI Secret, as any cluster may
+// be routed differently.
+func (r *Capi2Argo) mapTenantMapping(ctx context.Context, _ client.Object) []ctrl.Request {
+	return r.capiSecretRequests(ctx, "")
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTenantMappingMatches(t *testing.T) {
+	t.Parallel()
+	cluster := &V1Beta1ClusterAdapter{&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "team-a", Labels: map[string]string{"tier": "prod"}}}}
+	tests := []struct {
+		testName           string
+		testMock           TenantMapping
+		testCluster        CAPICluster
+		testExpectedError  bool
+		testExpectedValues bool
+	}{
+		{"test match all", TenantMapping{}, cluster, false, true},
+		{"test namespace pattern", TenantMapping{Namespaces: []string{"team-*"}}, cluster, false, true},
+		{"test other namespace", TenantMapping{Namespaces: []string{"platform"}}, cluster, false, false},
+		{"test selector", TenantMapping{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "prod"}}}, cluster, false, true},
+		{"test selector mismatch", TenantMapping{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "dev"}}}, cluster, false, false},
+		{"test selector without cluster", TenantMapping{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "prod"}}}, nil, false, false},
+		{"test invalid selector", TenantMapping{ClusterSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "Near"}}}}, cluster, true, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			matches, err := tt.testMock.Matches("team-a", tt.testCluster)
+			assert.Equal(t, tt.testExpectedError, err != nil)
+			assert.Equal(t, tt.testExpectedValues, matches)
+		})
+	}
+}
+
+func TestReconcileTenantMapping(t *testing.T) {
+	t.Parallel()
+	mappings := []client.Object{
+		MockUnstructured(ArgoTenantMappingGVK(), "b-teams", "", map[string]interface{}{"namespaces": []interface{}{"team-*"}, "argoNamespace": "teams-argocd", "project": "teams"}),
+		MockUnstructured(ArgoTenantMappingGVK(), "a-prod", "", map[string]interface{}{"clusterSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"tier": "prod"}}, "argoNamespace": "prod-argocd"}),
+		MockUnstructured(ArgoTenantMappingGVK(), "c-unmanaged", "", map[string]interface{}{"namespaces": []interface{}{"rogue"}, "argoNamespace": "kube-system"}),
+	}
+	tests := []struct {
+		testName           string
+		testNamespace      string
+		testLabels         map[string]string
+		testAnnotations    map[string]string
+		testExpectedValues []string
+	}{
+		{"test unmatched", "platform", nil, nil, []string{TestArgoNamespace, ""}},
+		{"test namespace route", "team-a", nil, nil, []string{"teams-argocd", "teams"}},
+		{"test first mapping by name wins", "team-a", map[string]string{"tier": "prod"}, nil, []string{"prod-argocd", ""}},
+		{"test annotations win", "team-a", nil, map[string]string{argoNamespaceAnnotation: "prod-argocd", projectAnnotation: "team-a"}, []string{"prod-argocd", "team-a"}},
+		{"test unmanaged namespace falls back to default", "rogue", nil, nil, []string{TestArgoNamespace, ""}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			config := MockOperatorConfig()
+			config.EnableTenantMappings = true
+			config.AdditionalArgoNamespaces = "teams-argocd,prod-argocd"
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "tenant-kubeconfig", tt.testNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "tenant"}
+			cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: tt.testNamespace, Labels: tt.testLabels, Annotations: tt.testAnnotations}, Status: MockReadyClusterStatus()}
+			c := fake.NewClientBuilder().
+				WithScheme(MockUnstructuredScheme(ArgoTenantMappingGVK(), ArgoTenantMappingListGVK())).
+				WithObjects(capiSecret, cluster).
+				WithObjects(mappings...).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, config.ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, config.ClusterNameIndex).
+				Build()
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockUnstructuredScheme(ArgoTenantMappingGVK(), ArgoTenantMappingListGVK()), Config: config}
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("tenant-kubeconfig", tt.testNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			nn := config.BuildNamespacedName("tenant-kubeconfig", tt.testNamespace)
+			assert.Nil(t, c.Get(ctx, types.NamespacedName{Name: nn.Name, Namespace: tt.testExpectedValues[0]}, &argoSecret))
+			assert.Equal(t, tt.testExpectedValues[1], string(argoSecret.Data["project"]))
+		})
+	}
+}
+
+func TestMapTenantMapping(t *testing.T) {
+	t.Parallel()
+	c := MockClient(
+		MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", TestNamespace),
+		MockCapiSecret(validMock, false, validKey, "unrelated", TestNamespace),
+		MockCapiSecret(validMock, validType, validKey, "other-kubeconfig", "other"),
+	)
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	assert.ElementsMatch(t, []ctrl.Request{
+		{NamespacedName: types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace}},
+		{NamespacedName: types.NamespacedName{Name: "other-kubeconfig", Namespace: "other"}},
+	}, r.mapTenantMapping(context.Background(), MockUnstructured(ArgoTenantMappingGVK(), "teams", "", nil)))
+}
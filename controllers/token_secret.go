@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileTokenSecret creates or updates the Secret holding the bearer token referenced by
+// the config of an ArgoCluster. Nothing is done when the token is embedded in the config.
+func (r *Capi2Argo) reconcileTokenSecret(ctx context.Context, log logr.Logger, a *ArgoCluster) error {
+	tokenSecret := a.ConvertToTokenSecret()
+	if tokenSecret == nil {
+		return nil
+	}
+	log = log.WithValues("tokenSecret", tokenSecret.Name)
+
+	var existing corev1.Secret
+	err := r.argoClient().Get(ctx, client.ObjectKeyFromObject(tokenSecret), &existing)
+	if errors.IsNotFound(err) {
+		err = r.argoClient().Create(ctx, tokenSecret)
+		r.auditRecord(AuditActionCreate, tokenSecret, a.ClusterName, secretFieldNames(tokenSecret), err)
+		if err != nil {
+			log.Error(err, "Failed to create token Secret")
+			return err
+		}
+		log.Info("Created new token Secret")
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to fetch token Secret")
+		return err
+	}
+	if err := r.Config.ValidateOwner(existing); err != nil {
+		return fmt.Errorf("token Secret %s: %w", tokenSecret.Name, err)
+	}
+
+	diff := []string{}
+	for k, v := range tokenSecret.Data {
+		if !bytes.Equal(existing.Data[k], v) {
+			diff = append(diff, "data."+k)
+		}
+	}
+	if !maps.Equal(existing.Labels, tokenSecret.Labels) {
+		diff = append(diff, "labels")
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	existing.Labels = tokenSecret.Labels
+	existing.Data = tokenSecret.Data
+	err = r.argoClient().Update(ctx, &existing)
+	r.auditRecord(AuditActionUpdate, &existing, a.ClusterName, diff, err)
+	if err != nil {
+		log.Error(err, "Failed to update token Secret")
+		return err
+	}
+	log.Info("Updated successfully of token Secret")
+	return nil
+}
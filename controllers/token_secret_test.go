@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestConvertToTokenSecret(t *testing.T) {
+	t.Parallel()
+	a := MockArgoCluster(true)
+	assert.Nil(t, a.ConvertToTokenSecret())
+
+	a.ReferencedToken = a.ClusterConfig.BearerToken
+	a.ClusterConfig.BearerToken = nil
+	a.ClusterConfig.BearerTokenSecret = &ArgoTokenRef{SecretName: BuildTokenSecretName(a.ClusterName), Key: argoTokenSecretKey}
+	a.SetComputedName("test-team-a", "cluster-test-team-a")
+	s := a.ConvertToTokenSecret()
+	assert.Equal(t, "test-team-a-token", s.Name)
+	assert.Equal(t, TestArgoNamespace, s.Namespace)
+	assert.Equal(t, []byte(*a.ReferencedToken), s.Data["token"])
+	assert.Equal(t, "true", s.Labels["capi-to-argocd/owned"])
+	assert.Equal(t, "test-kubeconfig", s.Labels["capi-to-argocd/cluster-secret-name"])
+	assert.NotContains(t, s.Labels, "argocd.argoproj.io/secret-type")
+}
+
+func TestReconcileTokenReference(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := MockClient(MockCapiSecret(true, true, true, "token-kubeconfig", TestNamespace))
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.UseTokenReference = true
+	r.Config.EnableGarbageCollection = true
+	req := MockReconcileReq("token-kubeconfig", TestNamespace)
+	nn := r.Config.BuildNamespacedName("token-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+	var config ArgoConfig
+	assert.Nil(t, json.Unmarshal(argoSecret.Data["config"], &config))
+	assert.Nil(t, config.BearerToken)
+	assert.Equal(t, &ArgoTokenRef{SecretName: "kube-cluster-test-token", Key: "token"}, config.BearerTokenSecret)
+
+	tokenNN := types.NamespacedName{Name: config.BearerTokenSecret.SecretName, Namespace: TestArgoNamespace}
+	var tokenSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, tokenNN, &tokenSecret))
+	assert.Equal(t, "test", string(tokenSecret.Data["token"]))
+
+	// A token changed behind the controller back gets restored.
+	tokenSecret.Data["token"] = []byte("stale")
+	assert.Nil(t, c.Update(ctx, &tokenSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, tokenNN, &tokenSecret))
+	assert.Equal(t, "test", string(tokenSecret.Data["token"]))
+
+	// Deleting the CAPI Secret cleans up both Secrets.
+	var capiSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, req.NamespacedName, &capiSecret))
+	assert.Nil(t, c.Delete(ctx, &capiSecret))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var secrets corev1.SecretList
+	assert.Nil(t, c.List(ctx, &secrets, client.InNamespace(TestArgoNamespace)))
+	assert.Empty(t, secrets.Items)
+}
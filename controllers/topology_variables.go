@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// topologyVariableLabelPrefix prefixes the labels holding ClusterClass topology variables:
+// topology-variable.capi-to-argocd/<name>[.<field>...]: <value>.
+const topologyVariableLabelPrefix = "topology-variable.capi-to-argocd/"
+
+// TopologyVariables returns the names of the topology variables that are flattened into labels.
+func (c OperatorConfig) TopologyVariables() []string {
+	names := []string{}
+	for _, name := range strings.Split(c.TopologyVariableLabels, ",") {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// BuildTopologyVariableLabels flattens the selected topology variables into labels. Fields of
+// object variables are joined with dots, lists and values that are no valid labels are reported
+// as warnings.
+func BuildTopologyVariableLabels(variables map[string][]byte, names []string) (map[string]string, []string) {
+	labels := map[string]string{}
+	warnings := []string{}
+	for _, name := range names {
+		raw, ok := variables[name]
+		if !ok {
+			continue
+		}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		var value interface{}
+		if err := d.Decode(&value); err != nil {
+			warnings = append(warnings, "Warning: invalid topology variable '"+name+"': "+err.Error()+". Ignoring")
+			continue
+		}
+		warnings = append(warnings, flattenTopologyVariable(labels, name, value)...)
+	}
+	return labels, warnings
+}
+
+// flattenTopologyVariable adds the labels of a topology variable value under a path.
+func flattenTopologyVariable(labels map[string]string, path string, value interface{}) []string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		warnings := []string{}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			warnings = append(warnings, flattenTopologyVariable(labels, path+"."+k, v[k])...)
+		}
+		return warnings
+	case []interface{}:
+		return []string{"Warning: topology variable '" + path + "' is a list. Ignoring"}
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+	key := topologyVariableLabelPrefix + path
+	if errs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(s)...); len(errs) > 0 {
+		return []string{"Warning: invalid topology variable '" + path + "': " + strings.Join(errs, "; ") + ". Ignoring"}
+	}
+	labels[key] = s
+	return nil
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTopologyVariables(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues []string
+	}{
+		{"test empty", "", []string{}},
+		{"test list", "region, env ,region,", []string{"region", "env"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.TopologyVariableLabels = tt.testMock
+			assert.Equal(t, tt.testExpectedValues, c.TopologyVariables())
+		})
+	}
+}
+
+func TestBuildTopologyVariableLabels(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName             string
+		testMock             map[string][]byte
+		testExpectedValues   map[string]string
+		testExpectedWarnings int
+	}{
+		{"test scalars", map[string][]byte{"region": []byte(`"eu-west-1"`), "replicas": []byte(`3`), "ha": []byte(`true`)},
+			map[string]string{topologyVariableLabelPrefix + "region": "eu-west-1", topologyVariableLabelPrefix + "replicas": "3", topologyVariableLabelPrefix + "ha": "true"}, 0},
+		{"test object", map[string][]byte{"env": []byte(`{"name":"prod","network":{"cidr":"10.0.0.0"},"empty":null}`)},
+			map[string]string{topologyVariableLabelPrefix + "env.name": "prod", topologyVariableLabelPrefix + "env.network.cidr": "10.0.0.0"}, 0},
+		{"test unselected variable", map[string][]byte{"other": []byte(`"value"`)}, map[string]string{}, 0},
+		{"test list", map[string][]byte{"region": []byte(`["a","b"]`)}, map[string]string{}, 1},
+		{"test invalid value", map[string][]byte{"region": []byte(`"eu west"`)}, map[string]string{}, 1},
+		{"test invalid json", map[string][]byte{"region": []byte(`{`)}, map[string]string{}, 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			labels, warnings := BuildTopologyVariableLabels(tt.testMock, []string{"region", "replicas", "ha", "env"})
+			assert.Equal(t, tt.testExpectedValues, labels)
+			assert.Len(t, warnings, tt.testExpectedWarnings)
+		})
+	}
+}
+
+func TestReconcileTopologyVariableLabels(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capiSecret := MockCapiSecret(validMock, validType, validKey, "variables-kubeconfig", TestNamespace)
+	capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "variables"}
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "variables", Namespace: TestNamespace},
+		Spec: clusterv1.ClusterSpec{Topology: &clusterv1.Topology{Class: "default", Version: "v1.28.0", Variables: []clusterv1.ClusterVariable{
+			{Name: "region", Value: apiextensionsv1.JSON{Raw: []byte(`"eu-west-1"`)}},
+			{Name: "env", Value: apiextensionsv1.JSON{Raw: []byte(`{"tier":"gold","stage":"prod"}`)}},
+		}}},
+		Status: MockReadyClusterStatus(),
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(MockScheme()).
+		WithObjects(capiSecret, cluster).
+		WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+		WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+		Build()
+	r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+	r.Config.TopologyVariableLabels = "region,env"
+	req := MockReconcileReq("variables-kubeconfig", TestNamespace)
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var argoSecret corev1.Secret
+	assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("variables-kubeconfig", TestNamespace), &argoSecret))
+	assert.Equal(t, "eu-west-1", argoSecret.Labels[topologyVariableLabelPrefix+"region"])
+	assert.Equal(t, "gold", argoSecret.Labels[topologyVariableLabelPrefix+"env.tier"])
+	assert.Equal(t, "prod", argoSecret.Labels[topologyVariableLabelPrefix+"env.stage"])
+
+	// Variables removed from the topology are removed from the ArgoSecret.
+	assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+	cluster.Spec.Topology.Variables = cluster.Spec.Topology.Variables[:1]
+	assert.Nil(t, c.Update(ctx, cluster))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("variables-kubeconfig", TestNamespace), &argoSecret))
+	assert.Equal(t, "eu-west-1", argoSecret.Labels[topologyVariableLabelPrefix+"region"])
+	assert.NotContains(t, argoSecret.Labels, topologyVariableLabelPrefix+"env.tier")
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// approvedTopologyVersionAnnotation pins the ClusterClass topology version of a CAPI Cluster
+	// that ArgoSecrets are synced for.
+	approvedTopologyVersionAnnotation = "capi-to-argocd/approved-topology-version"
+	// TopologyVersionMismatchCondition is set on CAPI Clusters whose topology version is not approved.
+	TopologyVersionMismatchCondition = "TopologyVersionMismatch"
+	// topologyVersionRequeueAfter is how often blocked clusters get checked for an updated approval,
+	// as CAPI Cluster changes do not trigger reconciles.
+	topologyVersionRequeueAfter = time.Minute
+)
+
+// checkTopologyVersion reports whether ArgoSecret writes of a cluster are blocked because its
+// topology version does not match the approved one. Clusters without topology or approval
+// annotation are never blocked. The TopologyVersionMismatch condition of the cluster is kept in sync.
+func (r *Capi2Argo) checkTopologyVersion(ctx context.Context, log logr.Logger, capiSecret *corev1.Secret, cluster CAPICluster) (bool, error) {
+	if cluster == nil {
+		return false, nil
+	}
+	approved, ok := cluster.GetAnnotations()[approvedTopologyVersionAnnotation]
+	version := cluster.GetTopologyVersion()
+	if !ok || version == "" || version == approved {
+		if cluster.RemoveCondition(TopologyVersionMismatchCondition) {
+			if err := r.Status().Update(ctx, cluster.Object()); err != nil {
+				log.Error(err, "Failed to clear TopologyVersionMismatch condition of Cluster")
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	message := fmt.Sprintf("Topology version %s does not match approved version %s, blocking ArgoSecret updates", version, approved)
+	log.Info("Warning: "+message, "cluster", cluster.GetName())
+	if cluster.SetCondition(TopologyVersionMismatchCondition, corev1.ConditionTrue, "VersionNotApproved", message) {
+		r.sourceEvent(capiSecret, cluster, corev1.EventTypeWarning, "TopologyVersionMismatch", message)
+		if err := r.Status().Update(ctx, cluster.Object()); err != nil {
+			log.Error(err, "Failed to set TopologyVersionMismatch condition of Cluster")
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileTopologyVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testAnnotations    map[string]string
+		testExpectedError  bool
+		testExpectedEvents []string
+	}{
+		{"test matching version", map[string]string{approvedTopologyVersionAnnotation: "v1.28.0"}, false, []string{EventReasonRegistered}},
+		{"test mismatching version", map[string]string{approvedTopologyVersionAnnotation: "v1.27.0"}, true, []string{"TopologyVersionMismatch"}},
+		{"test annotation absent", nil, false, []string{EventReasonRegistered}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			capiSecret := MockCapiSecret(validMock, validType, validKey, "topology-kubeconfig", TestNamespace)
+			capiSecret.Labels = map[string]string{clusterv1.ClusterNameLabel: "topology"}
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "topology", Namespace: TestNamespace, Annotations: tt.testAnnotations},
+				Spec:       clusterv1.ClusterSpec{Topology: &clusterv1.Topology{Class: "default", Version: "v1.28.0"}},
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(MockScheme()).
+				WithObjects(capiSecret, cluster).
+				WithStatusSubresource(&clusterv1.Cluster{}).
+				WithIndex(&corev1.Secret{}, clusterSecretNameIndex, MockOperatorConfig().ClusterSecretIndex).
+				WithIndex(&corev1.Secret{}, clusterNameIndex, MockOperatorConfig().ClusterNameIndex).
+				Build()
+			recorder := &MockRecorder{}
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig(), Recorder: recorder}
+			r.Config.RequireTopologyVersionAnnotation = true
+			r.Config.RequireControlPlaneReady = false
+			req := MockReconcileReq("topology-kubeconfig", TestNamespace)
+			nn := r.Config.BuildNamespacedName("topology-kubeconfig", TestNamespace)
+
+			result, err := r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			err = c.Get(ctx, nn, &argoSecret)
+			assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+			assert.Equal(t, tt.testExpectedEvents, recorder.ForObject("Secret", "topology-kubeconfig"))
+			if !tt.testExpectedError {
+				assert.Nil(t, err)
+				assert.Zero(t, result.RequeueAfter)
+				assert.Empty(t, cluster.Status.Conditions)
+				return
+			}
+			assert.NotNil(t, err)
+			assert.Equal(t, topologyVersionRequeueAfter, result.RequeueAfter)
+			if assert.Len(t, cluster.Status.Conditions, 1) {
+				assert.Equal(t, clusterv1.ConditionType(TopologyVersionMismatchCondition), cluster.Status.Conditions[0].Type)
+				assert.Equal(t, corev1.ConditionTrue, cluster.Status.Conditions[0].Status)
+			}
+
+			// Still blocked requeues neither emit events nor write the condition again.
+			_, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Len(t, recorder.ForObject("Secret", "topology-kubeconfig"), 1)
+
+			// Approving the current version unblocks the cluster.
+			cluster.Annotations[approvedTopologyVersionAnnotation] = "v1.28.0"
+			assert.Nil(t, c.Update(ctx, cluster))
+			result, err = r.Reconcile(ctx, req)
+			assert.Nil(t, err)
+			assert.Zero(t, result.RequeueAfter)
+			assert.Nil(t, c.Get(ctx, nn, &argoSecret))
+			assert.Nil(t, c.Get(ctx, client.ObjectKeyFromObject(cluster), cluster))
+			assert.Empty(t, cluster.Status.Conditions)
+		})
+	}
+}
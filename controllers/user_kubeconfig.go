@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// KubeconfigSourceAdmin reads kubeconfigs from the <cluster-name>-kubeconfig CAPI Secret.
+	KubeconfigSourceAdmin = "admin"
+	// KubeconfigSourceUser prefers the <cluster-name>-user-kubeconfig Secret CAPA writes for EKS
+	// clusters, whose credentials do not expire like the admin token.
+	KubeconfigSourceUser = "user"
+	// userKubeconfigSuffix is the name suffix of CAPA EKS user kubeconfig Secrets.
+	userKubeconfigSuffix = "-user-kubeconfig"
+)
+
+// ValidateKubeconfigSource checks that the given kubeconfig source is supported.
+func ValidateKubeconfigSource(s string) error {
+	switch s {
+	case KubeconfigSourceAdmin, KubeconfigSourceUser:
+		return nil
+	}
+	return fmt.Errorf("unsupported kubeconfig source: %s", s)
+}
+
+// kubeconfigSource returns the Secret the kubeconfig of a CAPI Secret is read from. With
+// OperatorConfig.KubeconfigSource set to user, this is its user kubeconfig Secret when it exists.
+func (r *Capi2Argo) kubeconfigSource(ctx context.Context, capiSecret *corev1.Secret) (*corev1.Secret, error) {
+	if r.Config.KubeconfigSource != KubeconfigSourceUser {
+		return capiSecret, nil
+	}
+	name := strings.TrimSuffix(capiSecret.Name, "-kubeconfig") + userKubeconfigSuffix
+	userSecret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: capiSecret.Namespace}, userSecret)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return capiSecret, nil
+		}
+		return nil, err
+	}
+	return userSecret, nil
+}
+
+// mapUserKubeconfigSecret maps user kubeconfig Secrets to a reconcile of the CAPI Secret of their cluster.
+func mapUserKubeconfigSecret(_ context.Context, obj client.Object) []ctrl.Request {
+	name, ok := strings.CutSuffix(obj.GetName(), userKubeconfigSuffix)
+	if !ok || name == "" {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: name + "-kubeconfig", Namespace: obj.GetNamespace()}}}
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestValidateKubeconfigSource(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, ValidateKubeconfigSource("admin"))
+	assert.Nil(t, ValidateKubeconfigSource("user"))
+	assert.NotNil(t, ValidateKubeconfigSource("tester"))
+}
+
+func TestMapUserKubeconfigSecret(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedValues []ctrl.Request
+	}{
+		{"test user kubeconfig", "test-user-kubeconfig", []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "test-kubeconfig", Namespace: TestNamespace}}}},
+		{"test admin kubeconfig", "test-kubeconfig", nil},
+		{"test bare suffix", "-user-kubeconfig", nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			s := MockCapiSecret(validMock, validType, validKey, tt.testMock, TestNamespace)
+			assert.Equal(t, tt.testExpectedValues, mapUserKubeconfigSecret(context.Background(), s))
+		})
+	}
+}
+
+func TestReconcileKubeconfigSource(t *testing.T) {
+	t.Parallel()
+	userKubeConfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: test\n  cluster:\n    server: https://user.eks.amazonaws.com\n    certificate-authority-data: Y2E=\n" +
+		"users:\n- name: test-user\n  user:\n    token: dXNlcg==\n"
+	tests := []struct {
+		testName          string
+		testSource        string
+		testUserSecret    bool
+		testExpectedValue string
+	}{
+		{"test admin source", KubeconfigSourceAdmin, true, "https://kube-cluster-test.domain.com:6443"},
+		{"test user source", KubeconfigSourceUser, true, "https://user.eks.amazonaws.com"},
+		{"test user source without user kubeconfig", KubeconfigSourceUser, false, "https://kube-cluster-test.domain.com:6443"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			objs := []client.Object{MockCapiSecret(validMock, validType, validKey, "test-kubeconfig", TestNamespace)}
+			if tt.testUserSecret {
+				userSecret := MockCapiSecret(validMock, validType, validKey, "test-user-kubeconfig", TestNamespace)
+				userSecret.Data["value"] = []byte(userKubeConfig)
+				objs = append(objs, userSecret)
+			}
+			c := MockClient(objs...)
+			r := &Capi2Argo{Client: c, Log: TestLog, Scheme: MockScheme(), Config: MockOperatorConfig()}
+			r.Config.KubeconfigSource = tt.testSource
+
+			_, err := r.Reconcile(ctx, MockReconcileReq("test-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			var argoSecret corev1.Secret
+			assert.Nil(t, c.Get(ctx, r.Config.BuildNamespacedName("test-kubeconfig", TestNamespace), &argoSecret))
+			assert.Equal(t, tt.testExpectedValue, string(argoSecret.Data["server"]))
+
+			// User kubeconfig Secrets are never registered on their own.
+			_, err = r.Reconcile(ctx, MockReconcileReq("test-user-kubeconfig", TestNamespace))
+			assert.Nil(t, err)
+			assert.NotNil(t, c.Get(ctx, r.Config.BuildNamespacedName("test-user-kubeconfig", TestNamespace), &corev1.Secret{}))
+		})
+	}
+}
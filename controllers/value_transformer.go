@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValueTransformer rewrites the value of a take-along label before it is written to the ArgoSecret.
+type ValueTransformer interface {
+	Transform(key, value string) (string, error)
+}
+
+// TruncateTransformer truncates values to MaxLen characters, defaulting to the label value
+// length limit when MaxLen is not positive.
+type TruncateTransformer struct {
+	MaxLen int
+}
+
+// Transform implements ValueTransformer. Trailing characters not allowed at the end of label
+// values are trimmed after truncating.
+func (t TruncateTransformer) Transform(_, value string) (string, error) {
+	maxLen := t.MaxLen
+	if maxLen <= 0 {
+		maxLen = validation.LabelValueMaxLength
+	}
+	if len(value) <= maxLen {
+		return value, nil
+	}
+	return strings.TrimRight(value[:maxLen], "-_."), nil
+}
+
+// LowercaseTransformer lowercases values.
+type LowercaseTransformer struct{}
+
+// Transform implements ValueTransformer.
+func (LowercaseTransformer) Transform(_, value string) (string, error) {
+	return strings.ToLower(value), nil
+}
+
+// RegexpReplaceTransformer replaces all matches of Pattern with Replace, which may hold
+// `$1` style references to submatches.
+type RegexpReplaceTransformer struct {
+	Pattern string
+	Replace string
+}
+
+// Transform implements ValueTransformer.
+func (t RegexpReplaceTransformer) Transform(_, value string) (string, error) {
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(value, t.Replace), nil
+}
+
+// ApplyValuePipeline runs value through the transformers of pipeline in order, and checks
+// that the result is still a valid label value.
+func ApplyValuePipeline(pipeline []ValueTransformer, key, value string) (string, error) {
+	for _, t := range pipeline {
+		v, err := t.Transform(key, value)
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return "", fmt.Errorf("invalid label value %q: %s", value, strings.Join(errs, "; "))
+	}
+	return value, nil
+}
+
+// ParseValueTransformers parses a comma separated list of transformers, in the form
+// `truncate:63,lowercase,replace:s/ /-/g`. The length of `truncate` is optional.
+func ParseValueTransformers(spec string) ([]ValueTransformer, error) {
+	var pipeline []ValueTransformer
+	for _, s := range strings.Split(spec, ",") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(s), ":")
+		switch name {
+		case "truncate":
+			t := TruncateTransformer{}
+			if hasArg {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid truncate length: %s", arg)
+				}
+				t.MaxLen = n
+			}
+			pipeline = append(pipeline, t)
+		case "lowercase":
+			if hasArg {
+				return nil, fmt.Errorf("lowercase takes no argument: %s", s)
+			}
+			pipeline = append(pipeline, LowercaseTransformer{})
+		case "replace":
+			t, err := parseRegexpReplace(arg)
+			if err != nil {
+				return nil, err
+			}
+			pipeline = append(pipeline, t)
+		default:
+			return nil, fmt.Errorf("unknown value transformer: %s", s)
+		}
+	}
+	return pipeline, nil
+}
+
+// parseRegexpReplace parses a sed style `s/<pattern>/<replace>/g` expression, where any
+// character following `s` may be used as the delimiter.
+func parseRegexpReplace(expr string) (RegexpReplaceTransformer, error) {
+	if len(expr) < 2 || expr[0] != 's' {
+		return RegexpReplaceTransformer{}, fmt.Errorf("invalid replace expression, expected s/<pattern>/<replace>/g: %s", expr)
+	}
+	parts := strings.Split(expr[2:], expr[1:2])
+	if len(parts) != 3 || parts[0] == "" || (parts[2] != "" && parts[2] != "g") {
+		return RegexpReplaceTransformer{}, fmt.Errorf("invalid replace expression, expected s/<pattern>/<replace>/g: %s", expr)
+	}
+	if _, err := regexp.Compile(parts[0]); err != nil {
+		return RegexpReplaceTransformer{}, fmt.Errorf("invalid replace pattern %q: %w", parts[0], err)
+	}
+	return RegexpReplaceTransformer{Pattern: parts[0], Replace: parts[1]}, nil
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// MockFailingTransformer fails on values of Key, passing other values through.
+type MockFailingTransformer struct {
+	Key string
+}
+
+func (m MockFailingTransformer) Transform(key, value string) (string, error) {
+	if key == m.Key {
+		return "", errors.New("transform failed")
+	}
+	return value, nil
+}
+
+func TestValueTransformers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           ValueTransformer
+		testValue          string
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test truncate short value", TruncateTransformer{MaxLen: 5}, "abc", false, "abc"},
+		{"test truncate long value", TruncateTransformer{MaxLen: 5}, "abcdefgh", false, "abcde"},
+		{"test truncate trims trailing separators", TruncateTransformer{MaxLen: 5}, "abc--defgh", false, "abc"},
+		{"test truncate default length", TruncateTransformer{}, strings.Repeat("a", 70), false, strings.Repeat("a", 63)},
+		{"test lowercase", LowercaseTransformer{}, "Team-A", false, "team-a"},
+		{"test replace", RegexpReplaceTransformer{Pattern: " +", Replace: "-"}, "team  a b", false, "team-a-b"},
+		{"test replace with submatch", RegexpReplaceTransformer{Pattern: "^v(\\d+)$", Replace: "version-$1"}, "v2", false, "version-2"},
+		{"test replace invalid pattern", RegexpReplaceTransformer{Pattern: "("}, "a", true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v, err := tt.testMock.Transform("key", tt.testValue)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.testExpectedValues, v)
+			}
+		})
+	}
+}
+
+func TestParseValueTransformers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExpectedError  bool
+		testExpectedValues []ValueTransformer
+	}{
+		{"test single", "lowercase", false, []ValueTransformer{LowercaseTransformer{}}},
+		{"test multiple", "truncate:63,lowercase,replace:s/ /-/g", false, []ValueTransformer{
+			TruncateTransformer{MaxLen: 63}, LowercaseTransformer{}, RegexpReplaceTransformer{Pattern: " ", Replace: "-"},
+		}},
+		{"test truncate without length", "truncate", false, []ValueTransformer{TruncateTransformer{}}},
+		{"test replace with other delimiter", "replace:s|a/b|c|", false, []ValueTransformer{RegexpReplaceTransformer{Pattern: "a/b", Replace: "c"}}},
+		{"test unknown transformer", "uppercase", true, nil},
+		{"test invalid truncate length", "truncate:abc", true, nil},
+		{"test invalid replace expression", "replace:s/a/b", true, nil},
+		{"test invalid replace flags", "replace:s/a/b/i", true, nil},
+		{"test invalid replace pattern", "replace:s/(/b/g", true, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v, err := ParseValueTransformers(tt.testMock)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.testExpectedValues, v)
+			}
+		})
+	}
+}
+
+func TestApplyValuePipeline(t *testing.T) {
+	t.Parallel()
+	pipeline, err := ParseValueTransformers("lowercase,replace:s/[ _]+/-/g,truncate:10")
+	assert.Nil(t, err)
+	tests := []struct {
+		testName           string
+		testMock           []ValueTransformer
+		testValue          string
+		testExpectedError  bool
+		testExpectedValues string
+	}{
+		{"test empty pipeline", nil, "Team_A", false, "Team_A"},
+		{"test multi-step pipeline", pipeline, "Team_A Platform_Services", false, "team-a-pla"},
+		{"test transformer error", []ValueTransformer{LowercaseTransformer{}, MockFailingTransformer{Key: "key"}}, "a", true, ""},
+		{"test invalid label value", []ValueTransformer{RegexpReplaceTransformer{Pattern: "-", Replace: " "}}, "a-b", true, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			v, err := ApplyValuePipeline(tt.testMock, "key", tt.testValue)
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.testExpectedValues, v)
+			}
+		})
+	}
+}
+
+func TestBuildTakeAlongLabelsPipeline(t *testing.T) {
+	t.Parallel()
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Labels: map[string]string{
+		"team":                       "Team_A",
+		"env":                        "Stage",
+		clusterTakeAlongKey + "team": "",
+		clusterTakeAlongKey + "env":  "",
+	}}}
+	rc := MockReconcileContext(MockOperatorConfig())
+	rc.Pipeline = []ValueTransformer{LowercaseTransformer{}, RegexpReplaceTransformer{Pattern: "_", Replace: "-"}, MockFailingTransformer{Key: "env"}}
+
+	v, errList := buildTakeAlongLabels(rc, &V1Beta1ClusterAdapter{cluster})
+	// The failing key is dropped, the others are still taken along.
+	assert.Len(t, errList, 1)
+	assert.Contains(t, errList[0], "env")
+	assert.Equal(t, map[string]string{
+		"team":                              "team-a",
+		clusterTakenFromClusterKey + "team": "",
+	}, v)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ValidateWatchNamespaces returns an error for invalid namespaces of OperatorConfig.WatchNamespaces.
+func (c OperatorConfig) ValidateWatchNamespaces() error {
+	for _, ns := range ParseNamespaces(c.WatchNamespaces) {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("invalid watch namespace %q: %s", ns, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// CacheOptions returns the cache options restricting namespaced objects to
+// OperatorConfig.WatchNamespaces and the extra namespaces, e.g. the ArgoNamespace when ArgoSecrets
+// are read through the same cache. The cache spans every namespace when WatchNamespaces is empty.
+func (c OperatorConfig) CacheOptions(extra ...string) cache.Options {
+	namespaces := ParseNamespaces(c.WatchNamespaces)
+	if len(namespaces) == 0 {
+		return cache.Options{}
+	}
+	defaults := map[string]cache.Config{}
+	for _, ns := range append(namespaces, extra...) {
+		if ns != "" {
+			defaults[ns] = cache.Config{}
+		}
+	}
+	return cache.Options{DefaultNamespaces: defaults}
+}
+
+// PlatformCacheOptions returns the cache options of a platform cluster running ArgoCD, restricting
+// Secrets to the ArgoNamespaces so no cluster-wide Secret access is needed there. Other objects,
+// like Applications in any namespace, are cached cluster-wide.
+func (c OperatorConfig) PlatformCacheOptions() cache.Options {
+	namespaces := map[string]cache.Config{}
+	for _, ns := range c.ArgoNamespaces() {
+		namespaces[ns] = cache.Config{}
+	}
+	return cache.Options{ByObject: map[client.Object]cache.ByObject{&corev1.Secret{}: {Namespaces: namespaces}}}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+func TestCacheOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName           string
+		testMock           string
+		testExtra          []string
+		testExpectedValues map[string]cache.Config
+	}{
+		{"test all namespaces", "", []string{"argocd"}, nil},
+		{"test watch namespaces", "team-a, team-b", nil, map[string]cache.Config{"team-a": {}, "team-b": {}}},
+		{"test extra namespaces", "team-a,argocd", []string{"argocd", ""}, map[string]cache.Config{"team-a": {}, "argocd": {}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.WatchNamespaces = tt.testMock
+			assert.Equal(t, tt.testExpectedValues, c.CacheOptions(tt.testExtra...).DefaultNamespaces)
+		})
+	}
+}
+
+func TestValidateWatchNamespaces(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		testName          string
+		testMock          string
+		testExpectedError bool
+	}{
+		{"test empty", "", false},
+		{"test valid", "team-a,team-b", false},
+		{"test invalid", "team-a,Team_B", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.testName, func(t *testing.T) {
+			t.Parallel()
+			c := MockOperatorConfig()
+			c.WatchNamespaces = tt.testMock
+			err := c.ValidateWatchNamespaces()
+			if tt.testExpectedError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestPlatformCacheOptions(t *testing.T) {
+	t.Parallel()
+	c := MockOperatorConfig()
+	c.AdditionalArgoNamespaces = "argocd-prod"
+	// Only set by the namespace-scoped mode of the management cluster.
+	c.WatchNamespaces = "team-a"
+	opts := c.PlatformCacheOptions()
+	assert.Nil(t, opts.DefaultNamespaces)
+	assert.Len(t, opts.ByObject, 1)
+	for obj, byObject := range opts.ByObject {
+		assert.IsType(t, &corev1.Secret{}, obj)
+		assert.Equal(t, map[string]cache.Config{c.ArgoNamespace: {}, "argocd-prod": {}}, byObject.Namespaces)
+	}
+}
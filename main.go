@@ -18,8 +18,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/dntosas/capi2argo-cluster-operator/controllers"
@@ -30,11 +33,18 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1alpha4 "sigs.k8s.io/cluster-api/api/v1alpha4"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,24 +55,119 @@ var (
 
 func init() {
 	utilruntime.Must(clusterv1.AddToScheme(scheme))
+	utilruntime.Must(clusterv1alpha4.AddToScheme(scheme))
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(controllers.AddSealedSecretToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
-	var enableDryRun bool
 	var enableDebugMode bool
 	var probeAddr string
 	var syncDuration time.Duration
+	var sealedSecretsNamespace string
+	var sealedSecretsService string
+	var sealedSecretsKeyRefresh time.Duration
+	var remoteManagementCluster string
+	var platformClusterKubeconfig string
+	var auditLogFile string
+	var auditLogOutput string
+	var validateArgoNamespace bool
+	var enableStatusFeedback bool
+	var changelogMaxEntries int
+	var enableEndpointHealthCheck bool
+	var endpointHealthCheckInterval time.Duration
+	var valuePipeline []controllers.ValueTransformer
+	var takeAlongWebhookMode string
+	var webhookPort int
+	var webhookCertDir string
+	config := controllers.NewOperatorConfig()
 	defaultSyncDuration, _ := time.ParseDuration("45s")
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.DurationVar(&syncDuration, "sync-duration", defaultSyncDuration, "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableDryRun, "dry-run", false, "Run in dry-run mode.")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "Run in dry-run mode.")
 	flag.BoolVar(&enableDebugMode, "debug", false, "Run in debug mode.")
+	flag.StringVar(&config.OutputFormat, "output-format", config.OutputFormat, "Kind of object written for every cluster, one of: secret, sealed-secret.")
+	flag.StringVar(&sealedSecretsNamespace, "sealed-secrets-namespace", "kube-system", "The namespace of the sealed-secrets controller.")
+	flag.StringVar(&sealedSecretsService, "sealed-secrets-service", "sealed-secrets-controller", "The service name of the sealed-secrets controller.")
+	flag.DurationVar(&sealedSecretsKeyRefresh, "sealed-secrets-key-refresh", time.Hour, "How often the sealed-secrets public key is refreshed.")
+	flag.StringVar(&remoteManagementCluster, "remote-management-cluster", "", "Kubeconfig path or Secret reference (secret:<namespace>/<name>) of a remote CAPI management cluster to watch.")
+	flag.StringVar(&platformClusterKubeconfig, "platform-cluster-kubeconfig", "", "Kubeconfig path or Secret reference (secret:<namespace>/<name>) of a remote platform cluster running ArgoCD, that ArgoSecrets are written to.")
+	flag.StringVar(&auditLogFile, "audit-log-file", "", "Path of the file that audit entries of ArgoSecret mutations are appended to.")
+	flag.StringVar(&auditLogOutput, "audit-log-output", "", "Write audit entries to a standard stream instead of a file, one of: stdout.")
+	flag.StringVar(&config.ClusterAPIVersion, "cluster-api-version", config.ClusterAPIVersion, "API version of the CAPI Cluster objects, one of: v1beta2, v1beta1, v1alpha4, or auto to detect the preferred version of the management cluster.")
+	flag.BoolVar(&config.AutoNamespaceSuffixOnCollision, "auto-namespace-suffix-on-collision", false, "Suffix colliding cluster names with the first characters of their namespace.")
+	flag.BoolVar(&validateArgoNamespace, "validate-argo-namespace", false, "Periodically check that the ArgoCD namespace runs an argocd-server Deployment.")
+	flag.BoolVar(&enableEndpointHealthCheck, "enable-endpoint-healthcheck", false, "Periodically check that ArgoSecret servers are reachable, resyncing clusters whose server is not.")
+	flag.DurationVar(&endpointHealthCheckInterval, "endpoint-healthcheck-interval", controllers.DefaultEndpointHealthCheckInterval, "How often ArgoSecret servers are checked for reachability.")
+	flag.BoolVar(&enableStatusFeedback, "enable-status-feedback", false, "Feed the ArgoCD connection status of clusters back on their CAPI Secret and Cluster.")
+	flag.BoolVar(&config.EmitClusterEvents, "emit-cluster-events", false, "Emit events on the CAPI Cluster object in addition to its kubeconfig Secret.")
+	flag.IntVar(&config.ArgoClusterNameMaxLength, "argo-cluster-name-max-length", config.ArgoClusterNameMaxLength, "Maximum length of ArgoCD cluster names, longer names get truncated with a hash suffix. 0 disables truncation.")
+	flag.StringVar(&config.ArgoSecretNamePrefix, "argo-secret-name-prefix", config.ArgoSecretNamePrefix, "Prefix of ArgoSecret names, may be empty. Ignored with --argo-secret-name-template.")
+	flag.StringVar(&config.ArgoSecretNameSuffix, "argo-secret-name-suffix", "", "Suffix of ArgoSecret names. Ignored with --argo-secret-name-template.")
+	flag.StringVar(&config.IncludeNamespaces, "include-namespaces", "", "Comma separated list of glob patterns of the namespaces CAPI Secrets get converted in, e.g. team-*. All namespaces when empty.")
+	flag.StringVar(&config.ExcludeNamespaces, "exclude-namespaces", "", "Comma separated list of glob patterns of namespaces whose CAPI Secrets are ignored, winning over --include-namespaces.")
+	flag.StringVar(&config.ManagementCluster, "management-cluster", "", "The <namespace>/<name> of the CAPI Cluster ArgoCD runs on, registered according to --self-registration.")
+	flag.StringVar(&config.SelfRegistration, "self-registration", controllers.SelfRegistrationExternal, "How the --management-cluster gets registered: external registers its kubeconfig endpoint, in-cluster registers https://kubernetes.default.svc, skip leaves it out.")
+	flag.StringVar(&config.InfrastructureKinds, "infrastructure-kinds", "", "Comma separated list of infrastructureRef kinds CAPI Clusters must have to get registered, e.g. AWSCluster,AzureCluster. All kinds when empty.")
+	flag.BoolVar(&config.OptInRegistration, "opt-in", false, "Only register CAPI Clusters, or kubeconfig Secrets, labeled with capi-to-argocd/enabled=true.")
+	flag.StringVar(&config.ClusterSelector, "cluster-selector", "", "Label selector CAPI Clusters must match to get registered, e.g. argocd=enabled. Hosted control planes are not filtered.")
+	flag.BoolVar(&config.MigrateSecretNames, "migrate-secret-names", false, "Replace ArgoSecrets left under previous names by naming flag changes with ArgoSecrets under the current names, keeping their foreign metadata.")
+	flag.StringVar(&config.ClusterNameTemplate, "cluster-name-template", "", "Go template rendering Argo cluster names from .ClusterName and .ClusterNamespace, e.g. '{{ .ClusterNamespace }}.{{ .ClusterName }}'. Defaults to [<namespace>-]<name>.")
+	flag.StringVar(&config.ArgoSecretNameTemplate, "argo-secret-name-template", "", "Go template rendering ArgoSecret names from .ClusterName and .ClusterNamespace. Defaults to cluster-<cluster name>.")
+	flag.IntVar(&config.MaxOwnerTakeAlongDepth, "max-owner-take-along-depth", config.MaxOwnerTakeAlongDepth, "Deepest chain of take-along directives processed, 1 ignores take-along labels pointing to other take-along keys.")
+	flag.BoolVar(&config.ArgoSecretImmutable, "argo-secret-immutable", false, "Create ArgoSecrets as immutable, replacing them on changes.")
+	flag.BoolVar(&config.SkipTLSRotationIfMatching, "skip-tls-rotation-if-matching", false, "Only update ArgoSecret credentials when the CAPI kubeconfig hash changed, patching metadata otherwise.")
+	flag.BoolVar(&config.UseTokenReference, "use-token-reference", false, "Store bearer tokens in a separate <cluster-name>-token Secret referenced from the ArgoSecret config.")
+	flag.BoolVar(&config.RequireTopologyVersionAnnotation, "require-topology-version-annotation", false, "Block ArgoSecret writes while the CAPI Cluster topology version does not match its capi-to-argocd/approved-topology-version annotation.")
+	flag.BoolVar(&config.EnableDeletionProtection, "enable-deletion-protection", false, "Hold deleted CAPI Secrets with a finalizer until no ArgoCD Application targets their cluster anymore, before deleting ArgoSecrets. Requires ENABLE_GARBAGE_COLLECTION.")
+	flag.BoolVar(&config.ReadClusterTags, "read-cluster-tags", false, "Store the entries of the <cluster-name>-tags ConfigMap of clusters as capi-to-argocd/tag-<key> annotations on ArgoSecrets.")
+	flag.StringVar(&config.TopologyVariableLabels, "topology-variable-labels", "", "Comma separated list of ClusterClass topology variables of CAPI Clusters to flatten into labels on ArgoSecrets.")
+	flag.BoolVar(&config.UseControlPlaneEndpoint, "use-control-plane-endpoint", false, "Build the ArgoSecret server from the spec.controlPlaneEndpoint of CAPI Clusters instead of the kubeconfig server.")
+	flag.BoolVar(&config.AnnotateClusterArgoSecret, "annotate-cluster-argo-secret", false, "Annotate CAPI Clusters with the <namespace>/<name> of their ArgoSecret as capi-to-argocd/argo-secret.")
+	flag.BoolVar(&config.SetRegisteredCondition, "set-registered-condition", false, "Set a RegisteredToArgoCD condition on CAPI Clusters once their ArgoSecret got written.")
+	flag.BoolVar(&config.TakeAllLabels, "take-all-labels", false, "Take along every non-system label of CAPI Clusters, unless overridden by the capi-to-argocd/take-all-labels Cluster annotation.")
+	flag.StringVar(&config.TakeAlongDomain, "take-along-domain", config.TakeAlongDomain, "Domain of the take-along-label.<domain>.<key> directives on CAPI Clusters.")
+	flag.StringVar(&config.TakeAllLabelsExclude, "take-all-labels-exclude", config.TakeAllLabelsExclude, "Comma separated list of label key prefixes never taken along when taking all labels.")
+	flag.BoolVar(&config.ReplicaCountLabels, "replica-count-labels", false, "Store the control plane replicas and the sum of MachineDeployment and MachinePool replicas of CAPI Clusters as labels on ArgoSecrets.")
+	flag.BoolVar(&config.TakeAlongNamespaceLabels, "take-along-namespace-labels", false, "Take along the labels of the namespace of CAPI Secrets selected by take-along-from-namespace-label.capi-to-argocd.<key> Namespace labels.")
+	flag.BoolVar(&config.ReadInfrastructureTopology, "read-infrastructure-topology", false, "Store the region and failure domains of the infrastructure object of CAPI Clusters as labels on ArgoSecrets.")
+	flag.BoolVar(&config.EnableClusterDefaults, "enable-cluster-defaults", false, "Apply the ArgoClusterDefaults of the namespace of CAPI Secrets to their ArgoSecrets. Requires the ArgoClusterDefaults CRD.")
+	flag.BoolVar(&config.EnableClusterRegistrations, "enable-cluster-registrations", false, "Only register CAPI Clusters declared by an ArgoClusterRegistration of their namespace, with its overrides. Requires the ArgoClusterRegistration CRD.")
+	flag.BoolVar(&config.EnableTenantMappings, "enable-tenant-mappings", false, "Route CAPI Clusters to the Argo namespace and project of the ArgoTenantMapping matching them. Requires the ArgoTenantMapping CRD.")
+	flag.StringVar(&config.InstanceID, "instance-id", "", "Identifies the operator instance among several sharing a management cluster, labeling its Secrets and leaving those of other instances alone.")
+	flag.StringVar(&config.FanOutArgoNamespaces, "fan-out-argo-namespaces", "", "Comma separated list of namespaces every ArgoSecret gets copied to, besides ARGOCD_NAMESPACE, for several ArgoCD instances.")
+	flag.StringVar(&config.AdditionalArgoNamespaces, "additional-argo-namespaces", "", "Comma separated list of namespaces ArgoClusterRegistrations may write ArgoSecrets to, besides ARGOCD_NAMESPACE.")
+	flag.StringVar(&config.ClusterNamespaces, "cluster-namespaces", "", "Comma separated list of namespaces ArgoSecrets are scoped to, unless overridden by the capi-to-argocd/namespaces Cluster annotation. Empty grants access to the whole cluster.")
+	flag.StringVar(&config.ClusterResources, "cluster-resources", "", "Set clusterResources of ArgoSecrets to true or false, unless overridden by the capi-to-argocd/cluster-resources Cluster annotation. Left unset when empty.")
+	flag.IntVar(&config.ClusterShard, "cluster-shard", config.ClusterShard, "Application controller shard ArgoSecrets are assigned to, unless overridden by the capi-to-argocd/shard Cluster annotation. Left unset when negative.")
+	flag.BoolVar(&config.EnableAWSAuthConfig, "enable-aws-auth-config", false, "Authenticate CAPA managed EKS clusters through IAM with an awsAuthConfig in place of static kubeconfig credentials.")
+	flag.BoolVar(&config.AllowInsecureClusters, "allow-insecure-clusters", false, "Register clusters whose kubeconfig sets insecure-skip-tls-verify with tlsClientConfig.insecure, instead of rejecting them.")
+	flag.BoolVar(&config.DisableCompression, "disable-compression", false, "Set disableCompression in ArgoSecret configs, unless overridden by the capi-to-argocd/disable-compression Cluster annotation.")
+	flag.StringVar(&config.AuthPreference, "auth-preference", config.AuthPreference, "Credential type kept for kubeconfigs holding both a bearer token and a client certificate, one of: both, token, cert. Overridden by the capi-to-argocd/auth-preference Cluster annotation.")
+	flag.StringVar(&config.AzureLoginMode, "azure-login-mode", config.AzureLoginMode, "kubelogin login mode AKS kubeconfigs with an azure auth-provider or interactive kubelogin are converted to, e.g. workloadidentity, msi, azurecli or spn.")
+	flag.BoolVar(&config.EnableHyperShift, "enable-hypershift", false, "Convert the <name>-admin-kubeconfig Secrets of HyperShift HostedClusters to ArgoSecrets, named after their HostedCluster.")
+	flag.BoolVar(&config.EnableKamaji, "enable-kamaji", false, "Convert the <name>-admin-kubeconfig Secrets of Kamaji TenantControlPlanes to ArgoSecrets, named after their TenantControlPlane.")
+	flag.BoolVar(&config.EnableK0smotron, "enable-k0smotron", false, "Convert the <name>-kubeconfig Secrets of standalone k0smotron Clusters to ArgoSecrets.")
+	flag.StringVar(&config.KubeconfigSource, "kubeconfig-source", config.KubeconfigSource, "Secret kubeconfigs are read from, one of: admin for <cluster-name>-kubeconfig, user to prefer the <cluster-name>-user-kubeconfig Secret CAPA writes for EKS clusters.")
+	flag.BoolVar(&config.RequireControlPlaneReady, "require-control-plane-ready", config.RequireControlPlaneReady, "Hold back ArgoSecret creation until the CAPI Cluster reports ControlPlaneReady=True, requeueing until then.")
+	flag.StringVar(&config.ReadinessGates, "readiness-gates", "", "Comma separated list of further CAPI Cluster conditions, e.g. InfrastructureReady, that must be True before ArgoSecrets get created.")
+	flag.DurationVar(&config.MinClusterAge, "min-cluster-age", 0, "Minimum age of CAPI Clusters before they get synced, younger clusters are requeued until then. 0 disables the check.")
+	flag.Func("value-transformer", "Comma separated transformers applied in order to take-along label values, e.g. truncate:63,lowercase,replace:s/ /-/g. May be repeated.", func(spec string) error {
+		p, err := controllers.ParseValueTransformers(spec)
+		valuePipeline = append(valuePipeline, p...)
+		return err
+	})
+	flag.StringVar(&config.KubeConfigKey, "kubeconfig-key", config.KubeConfigKey, "Data key of CAPI Secrets holding the kubeconfig.")
+	flag.IntVar(&changelogMaxEntries, "changelog-max-entries-per-cluster", controllers.DefaultChangelogMaxEntries, "Number of ArgoSecret changes kept per cluster and served on /clusters/<namespace>/<name>/changelog of the metrics endpoint. 0 disables the changelog.")
+	flag.StringVar(&config.Version, "version", config.Version, "Operator version stamped on ArgoSecrets, defaults to the VERSION environment variable.")
+	flag.StringVar(&takeAlongWebhookMode, "take-along-webhook", "", "Serve a validating webhook for the take-along keys of CAPI Clusters on "+controllers.TakeAlongWebhookPath+", one of: warn to admit malformed keys with warnings, reject to deny them. Disabled when empty.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "", "Directory holding the tls.crt and tls.key of the webhook server, defaults to <temp-dir>/k8s-webhook-server/serving-certs.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. "+"Use this when deploying multiple pods so to ensure there is only one active controller manager.")
 	opts := zap.Options{
 		Development: enableDebugMode,
@@ -72,13 +177,153 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	if config.OutputFormat != controllers.OutputFormatSecret && config.OutputFormat != controllers.OutputFormatSealedSecret {
+		setupLog.Info("unsupported output format", "output-format", config.OutputFormat)
+		os.Exit(1)
+	}
+
+	if config.UseTokenReference && config.OutputFormat == controllers.OutputFormatSealedSecret {
+		setupLog.Info("use-token-reference is not supported with sealed-secret output format")
+		os.Exit(1)
+	}
+
+	if config.MigrateSecretNames && config.OutputFormat == controllers.OutputFormatSealedSecret {
+		setupLog.Info("migrate-secret-names is not supported with sealed-secret output format")
+		os.Exit(1)
+	}
+
+	if config.SkipTLSRotationIfMatching && config.OutputFormat == controllers.OutputFormatSealedSecret {
+		setupLog.Info("skip-tls-rotation-if-matching is not supported with sealed-secret output format")
+		os.Exit(1)
+	}
+
+	if config.FanOutArgoNamespaces != "" && config.OutputFormat == controllers.OutputFormatSealedSecret {
+		setupLog.Info("fan-out-argo-namespaces is not supported with sealed-secret output format")
+		os.Exit(1)
+	}
+
+	if config.EnableDeletionProtection && (!config.EnableGarbageCollection || config.OutputFormat == controllers.OutputFormatSealedSecret) {
+		setupLog.Info("enable-deletion-protection requires ENABLE_GARBAGE_COLLECTION and is not supported with sealed-secret output format")
+		os.Exit(1)
+	}
+
+	if config.ClusterResources != "" {
+		if _, err := strconv.ParseBool(config.ClusterResources); err != nil {
+			setupLog.Error(err, "invalid cluster-resources value")
+			os.Exit(1)
+		}
+	}
+
+	if err := controllers.ValidateAuthPreference(config.AuthPreference); err != nil {
+		setupLog.Error(err, "invalid auth preference")
+		os.Exit(1)
+	}
+
+	if err := controllers.ValidateKubeconfigSource(config.KubeconfigSource); err != nil {
+		setupLog.Error(err, "invalid kubeconfig source")
+		os.Exit(1)
+	}
+
+	if config.ClusterAPIVersion != controllers.ClusterAPIVersionAuto {
+		if err := controllers.ValidateClusterAPIVersion(config.ClusterAPIVersion); err != nil {
+			setupLog.Error(err, "invalid cluster-api version")
+			os.Exit(1)
+		}
+	}
+
+	if err := config.ValidateNamespacePatterns(); err != nil {
+		setupLog.Error(err, "invalid namespace filter")
+		os.Exit(1)
+	}
+
+	if err := controllers.ValidateInstanceID(config.InstanceID); err != nil {
+		setupLog.Error(err, "invalid instance ID")
+		os.Exit(1)
+	}
+
+	if err := controllers.ValidateSelfRegistration(config.SelfRegistration); err != nil {
+		setupLog.Error(err, "invalid self-registration mode")
+		os.Exit(1)
+	}
+
+	if err := config.ValidateManagementCluster(); err != nil {
+		setupLog.Error(err, "invalid management cluster")
+		os.Exit(1)
+	}
+
+	if err := config.ValidateArgoNamespaces(); err != nil {
+		setupLog.Error(err, "invalid argo namespace")
+		os.Exit(1)
+	}
+
+	if err := config.ValidateWatchNamespaces(); err != nil {
+		setupLog.Error(err, "invalid watch namespace")
+		os.Exit(1)
+	}
+
+	if _, err := config.ClusterLabelSelector(); err != nil {
+		setupLog.Error(err, "invalid cluster selector")
+		os.Exit(1)
+	}
+
+	if err := config.ValidateArgoSecretNameAffixes(); err != nil {
+		setupLog.Error(err, "invalid argo secret name")
+		os.Exit(1)
+	}
+
+	for name, tmpl := range map[string]string{"cluster-name-template": config.ClusterNameTemplate, "argo-secret-name-template": config.ArgoSecretNameTemplate} {
+		if tmpl == "" {
+			continue
+		}
+		if err := controllers.ValidateNameTemplate(tmpl); err != nil {
+			setupLog.Error(err, "invalid name template", "flag", name)
+			os.Exit(1)
+		}
+	}
+
+	if err := controllers.ValidateTakeAlongDomain(config.TakeAlongDomain); err != nil {
+		setupLog.Error(err, "invalid take-along domain")
+		os.Exit(1)
+	}
+
+	if takeAlongWebhookMode != "" {
+		if err := controllers.ValidateTakeAlongWebhookMode(takeAlongWebhookMode); err != nil {
+			setupLog.Error(err, "invalid take-along webhook mode")
+			os.Exit(1)
+		}
+	}
+
+	var changelog *controllers.ChangelogRecorder
+	metricsHandlers := map[string]http.Handler{}
+	if changelogMaxEntries > 0 {
+		changelog = controllers.NewChangelogRecorder(changelogMaxEntries)
+		metricsHandlers[controllers.ChangelogPathPrefix] = changelog
+	}
+
+	// ArgoSecrets are read through the manager cache unless written to a platform cluster.
+	cacheNamespaces := config.ArgoNamespaces()
+	if platformClusterKubeconfig != "" {
+		cacheNamespaces = nil
+	}
+
+	// Instances sharing a namespace elect their leaders apart.
+	leaderElectionID := "37cf8926.capi-cluster.x-argoproj.io"
+	if config.InstanceID != "" {
+		leaderElectionID = config.InstanceID + "." + leaderElectionID
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
+		Cache:  config.CacheOptions(cacheNamespaces...),
+		Metrics: metricsserver.Options{
+			BindAddress:   metricsAddr,
+			ExtraHandlers: metricsHandlers,
+		},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "37cf8926.capi-cluster.x-argoproj.io",
-		// MetricsBindAddress:     metricsAddr,
-		// Port:                   9443,
+		LeaderElectionID:       leaderElectionID,
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: webhookPort, CertDir: webhookCertDir}),
 		// SyncPeriod:             &syncDuration,
 		// DryRunClient:           enableDryRun,
 	})
@@ -89,6 +334,10 @@ func main() {
 
 	//+kubebuilder:scaffold:builder
 
+	if takeAlongWebhookMode != "" {
+		mgr.GetWebhookServer().Register(controllers.TakeAlongWebhookPath, &webhook.Admission{Handler: &controllers.TakeAlongWebhook{Mode: takeAlongWebhookMode, LabelKey: config.TakeAlongLabelKey()}})
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -98,15 +347,165 @@ func main() {
 		os.Exit(1)
 	}
 
+	var sealedSecretKeys *controllers.SealedSecretKeyCache
+	if config.OutputFormat == controllers.OutputFormatSealedSecret {
+		cs, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to create clientset for sealed-secrets")
+			os.Exit(1)
+		}
+		fetcher := controllers.NewControllerPublicKeyFetcher(cs, sealedSecretsNamespace, sealedSecretsService)
+		sealedSecretKeys = controllers.NewSealedSecretKeyCache(fetcher, sealedSecretsKeyRefresh)
+		if _, err := sealedSecretKeys.PublicKey(context.Background()); err != nil {
+			setupLog.Error(err, "unable to fetch sealed-secrets public key, retrying on reconcile")
+		}
+	}
+
+	auditLogger, err := controllers.OpenAuditLogger(auditLogOutput, auditLogFile, controllers.OperatorServiceAccount())
+	if err != nil {
+		setupLog.Error(err, "unable to set up audit logger")
+		os.Exit(1)
+	}
+
+	var platformCluster cluster.Cluster
+	argoClient := mgr.GetClient()
+	argoRestConfig := restConfig
+	if platformClusterKubeconfig != "" {
+		reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for platform cluster reference")
+			os.Exit(1)
+		}
+		argoRestConfig, err = controllers.LoadRemoteConfig(context.Background(), reader, platformClusterKubeconfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load platform cluster config")
+			os.Exit(1)
+		}
+		platformCluster, err = cluster.New(argoRestConfig, func(o *cluster.Options) {
+			o.Scheme = scheme
+			o.Cache = config.PlatformCacheOptions()
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to set up platform cluster")
+			os.Exit(1)
+		}
+		if err := mgr.Add(platformCluster); err != nil {
+			setupLog.Error(err, "unable to add platform cluster to manager")
+			os.Exit(1)
+		}
+		argoClient = platformCluster.GetClient()
+	}
+
+	var argoNamespaceValidator *controllers.ArgoNamespaceValidator
+	if validateArgoNamespace {
+		c, err := client.New(argoRestConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for ArgoCD namespace validation")
+			os.Exit(1)
+		}
+		argoNamespaceValidator = controllers.NewArgoNamespaceValidator(c, ctrl.Log.WithName("argo-namespace"), config.ArgoNamespace, syncDuration)
+		// The status ConfigMap lives next to the operator, not on the platform cluster.
+		if platformClusterKubeconfig != "" {
+			statusClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+			if err != nil {
+				setupLog.Error(err, "unable to create client for ArgoCD namespace status")
+				os.Exit(1)
+			}
+			argoNamespaceValidator.StatusClient = statusClient
+		}
+		if _, err := argoNamespaceValidator.Check(context.Background()); err != nil {
+			setupLog.Error(err, "unable to validate ArgoCD namespace, retrying periodically")
+		}
+		if err := mgr.Add(argoNamespaceValidator); err != nil {
+			setupLog.Error(err, "unable to add ArgoCD namespace validation to manager")
+			os.Exit(1)
+		}
+	}
+
+	var sourceCluster cluster.Cluster
+	capiClient := mgr.GetClient()
+	sourceRecorder := mgr.GetEventRecorderFor("capi2argo")
+	if remoteManagementCluster != "" {
+		reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for remote management cluster reference")
+			os.Exit(1)
+		}
+		remoteConfig, err := controllers.LoadRemoteConfig(context.Background(), reader, remoteManagementCluster)
+		if err != nil {
+			setupLog.Error(err, "unable to load remote management cluster config")
+			os.Exit(1)
+		}
+		sourceCluster, err = cluster.New(remoteConfig, func(o *cluster.Options) {
+			o.Scheme = scheme
+			o.Cache = config.CacheOptions()
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to set up remote management cluster")
+			os.Exit(1)
+		}
+		if err := mgr.Add(sourceCluster); err != nil {
+			setupLog.Error(err, "unable to add remote management cluster to manager")
+			os.Exit(1)
+		}
+		capiClient = sourceCluster.GetClient()
+		sourceRecorder = sourceCluster.GetEventRecorderFor("capi2argo")
+	}
+
+	if config.ClusterAPIVersion == controllers.ClusterAPIVersionAuto {
+		config.ClusterAPIVersion, err = controllers.DetectClusterAPIVersion(capiClient.RESTMapper())
+		if err != nil {
+			setupLog.Error(err, "unable to detect cluster-api version")
+			os.Exit(1)
+		}
+		setupLog.Info("Detected cluster-api version", "version", config.ClusterAPIVersion)
+	}
+
+	var triggers chan event.GenericEvent
+	if enableEndpointHealthCheck {
+		triggers = make(chan event.GenericEvent)
+		checker := controllers.NewEndpointHealthChecker(argoClient, ctrl.Log.WithName("endpoint-healthcheck"), config, endpointHealthCheckInterval, mgr.GetEventRecorderFor("capi2argo"), triggers)
+		if err := mgr.Add(checker); err != nil {
+			setupLog.Error(err, "unable to add endpoint healthcheck to manager")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.Capi2Argo{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("capi2argo"),
-		Scheme: mgr.GetScheme(),
+		Client:                 capiClient,
+		ArgoClient:             argoClient,
+		SourceCluster:          sourceCluster,
+		PlatformCluster:        platformCluster,
+		Log:                    ctrl.Log.WithName("capi2argo"),
+		Scheme:                 mgr.GetScheme(),
+		Config:                 config,
+		SealedSecretKeys:       sealedSecretKeys,
+		Audit:                  auditLogger,
+		Changelog:              changelog,
+		Recorder:               mgr.GetEventRecorderFor("capi2argo"),
+		SourceRecorder:         sourceRecorder,
+		ArgoNamespaceValidator: argoNamespaceValidator,
+		Pipeline:               valuePipeline,
+		Triggers:               triggers,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Capi2Argo")
 		os.Exit(1)
 	}
 
+	if enableStatusFeedback {
+		if err = (&controllers.ClusterProbe{
+			Client:          argoClient,
+			PlatformCluster: platformCluster,
+			SourceClient:    capiClient,
+			Log:             ctrl.Log.WithName("clusterprobe"),
+			Config:          config,
+			Recorder:        sourceRecorder,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterProbe")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")